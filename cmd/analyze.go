@@ -19,11 +19,14 @@ package cmd
 
 import (
 	"bufio"
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -39,14 +42,44 @@ var analyzeCmd = &cobra.Command{
 }
 
 func runExplain(cmd *cobra.Command, args []string) {
+	planFile, _ := cmd.Flags().GetString("plan-file")
+	planStdin, _ := cmd.Flags().GetBool("plan-stdin")
+	if planFile != "" || planStdin {
+		if planFile != "" && planStdin {
+			logErrorAndExit("Invalid arguments", fmt.Errorf("use only one of --plan-file or --plan-stdin"))
+		}
+		plan, query, err := getSavedPlanInput(cmd, args, planFile)
+		if err != nil {
+			logErrorAndExit("Failed to get plan input: ", err)
+		}
+		analyzePlan(cmd, query, plan)
+		return
+	}
+
+	requirePsql()
+
 	// Get query from file flag, stdin, or argument
 	query, err := getQueryInput(cmd, args)
 	if err != nil {
 		logErrorAndExit("Failed to get query input: ", err)
 	}
 
+	analyzeQuery(cmd, query)
+}
+
+// analyzeQuery runs the analyze pipeline for a query: EXPLAIN ANALYZE, cost analysis,
+// index recommendations and output. It reads the flags registered by addAnalyzeFlags.
+func analyzeQuery(cmd *cobra.Command, query string) {
+	analyzePlan(cmd, query, "")
+}
+
+// analyzePlan runs the analyze pipeline. A non-empty savedPlan is analyzed and rendered
+// as it is, without running EXPLAIN or otherwise connecting to the database.
+func analyzePlan(cmd *cobra.Command, query, savedPlan string) {
 	// Load configuration
 	config, _ := loadConfig()
+	applyServiceFlag(cmd, config)
+	promptPasswordIfRequested(cmd)
 
 	// Get flag values, using config defaults if flags not explicitly set
 	threshold, _ := cmd.Flags().GetFloat64("threshold")
@@ -59,86 +92,384 @@ func runExplain(cmd *cobra.Command, args []string) {
 		remoteFlag = config.Defaults.Remote
 	}
 
+	checkOnly, _ := cmd.Flags().GetBool("check-only")
+	if checkOnly && threshold <= 0 {
+		logErrorAndExit("Invalid --check-only value", fmt.Errorf("a cost threshold is required, set --threshold or defaults.threshold in the configuration file"))
+	}
+	hypothetical, _ := cmd.Flags().GetBool("hypothetical")
+	if recommendIndexes, _ := cmd.Flags().GetBool("recommend-indexes"); hypothetical && !recommendIndexes {
+		logErrorAndExit("Invalid --hypothetical value", fmt.Errorf("hypothetical indexes measure index recommendations, add --recommend-indexes"))
+	}
+
 	format, _ := cmd.Flags().GetString("format")
 	if !cmd.Flags().Changed("format") && config.Defaults.Format != "" {
 		format = config.Defaults.Format
 	}
 
+	omitPlan, _ := cmd.Flags().GetBool("omit-plan")
+	maxPlanLines, _ := cmd.Flags().GetInt("max-plan-lines")
+	templateFile, _ := cmd.Flags().GetString("template")
+	reportTemplate, err := loadReportTemplate(templateFile)
+	if err != nil {
+		logErrorAndExit("Invalid --template value", err)
+	}
+	outputDir, _ := cmd.Flags().GetString("output-dir")
+
+	csvColumnsFlag, _ := cmd.Flags().GetString("columns")
+	csvColumns, err := parseCSVColumns(csvColumnsFlag, csvPlanColumns)
+	if err != nil {
+		logErrorAndExit("Invalid --columns value", err)
+	}
+	operationThresholds, err = operationThresholdsFromFlags(cmd, config)
+	if err != nil {
+		logErrorAndExit("Invalid --op-threshold value", err)
+	}
+
+	paramValues, _ := cmd.Flags().GetStringArray("param")
+	params, err := parseQueryParams(paramValues)
+	if err != nil {
+		logErrorAndExit("Invalid --param value", err)
+	}
+	if err := validateQueryParams(query, params); err != nil {
+		logErrorAndExit("Query parameters do not match placeholders", err)
+	}
+	transaction, _ := cmd.Flags().GetBool("transaction")
+	rollback, _ := cmd.Flags().GetBool("rollback")
+	useCache, _ := cmd.Flags().GetBool("cache")
+	cacheTTL, _ := cmd.Flags().GetDuration("cache-ttl")
+	schemaVersion, _ := cmd.Flags().GetString("schema-version")
+	explainFormat, _ := cmd.Flags().GetString("explain-format")
+	if err := validateExplainFormat(explainFormat); err != nil {
+		logErrorAndExit("Invalid --explain-format value", err)
+	}
+	// pev2 renders FORMAT JSON plans in more detail than text plans
+	if format == "html" && !checkOnly && !cmd.Flags().Changed("explain-format") {
+		explainFormat = "json"
+	}
+	if savedPlan != "" {
+		explainFormat = detectPlanFormat(savedPlan)
+	}
+	verbose, _ := cmd.Flags().GetBool("verbose")
+	explainOptions := ExplainOptions{
+		Params:        params,
+		Rollback:      transaction || rollback,
+		Cache:         useCache,
+		CacheTTL:      cacheTTL,
+		SchemaVersion: schemaVersion,
+		Format:        explainFormat,
+		Verbose:       verbose,
+	}
+	if err := sessionOptionsFromFlags(cmd, &explainOptions); err != nil {
+		logErrorAndExit("Invalid --set, --guc or --role value", err)
+	}
+	if err := explainModeFromFlags(cmd, &explainOptions); err != nil {
+		logErrorAndExit("Invalid --explain-mode value", err)
+	}
+	if err := conflictRetryOptionsFromFlags(cmd, &explainOptions); err != nil {
+		logErrorAndExit("Invalid deadlock retry value", err)
+	}
+	if err := allowedStatementsFromFlags(cmd, config, &explainOptions); err != nil {
+		logErrorAndExit("Invalid --allowed-statements value", err)
+	}
+
+	// Load the baseline before running EXPLAIN so a bad path fails fast
+	baselinePath, _ := cmd.Flags().GetString("baseline")
+	var baseline *PlanOutput
+	if baselinePath != "" {
+		baseline, err = loadPlanOutput(baselinePath)
+		if err != nil {
+			logErrorAndExit("Invalid --baseline value", err)
+		}
+	}
+
 	// Show friendly start message
 	fmt.Println("\n🔍 Analyzing your query...")
-	fmt.Printf("📊 Output format: %s\n", format)
+	if checkOnly {
+		fmt.Println("🚦 Check only: no report is written")
+	} else {
+		fmt.Printf("📊 Output format: %s\n", format)
+	}
 	if threshold > 0 {
 		fmt.Printf("⚡ Cost threshold: %.0f\n", threshold)
+		if len(operationThresholds) > 0 {
+			fmt.Printf("⚡ Operation thresholds: %s\n", formatOperationThresholds(operationThresholds))
+		}
+	} else if cmd.Flags().Changed("op-threshold") {
+		fmt.Println("⚠️  --op-threshold only applies with a cost threshold (--threshold)")
+	}
+	for _, setting := range explainOptions.Settings {
+		fmt.Printf("⚙️  %s\n", setting)
+	}
+	if explainOptions.Role != "" {
+		fmt.Printf("👤 Role: %s\n", explainOptions.Role)
+	}
+	if len(params) > 0 {
+		fmt.Printf("🧩 Parameters: %d (analyzed via PREPARE / EXPLAIN EXECUTE)\n", len(params))
+	}
+	if explainOptions.Rollback {
+		fmt.Println("↩️  Transaction: changes will be rolled back")
+	}
+	if len(explainOptions.AllowedStatements) > 0 {
+		fmt.Printf("🛡️  Allowed statements: %s\n", formatAllowedStatements(explainOptions.AllowedStatements))
+	}
+	if savedPlan != "" {
+		fmt.Printf("📄 Saved %s plan, EXPLAIN is not run\n", strings.ToUpper(explainFormat))
 	}
 	fmt.Println()
 
-	plan, err := generateExecutionPlan(query, config)
-	if err != nil {
-		fmt.Println("❌ Failed to analyze query")
-		logErrorAndExit("Error: ", err)
+	plan := savedPlan
+	if plan == "" {
+		if note := statementModeNote(query, explainOptions); note != "" && checkAllowedStatement(query, explainOptions.AllowedStatements) == nil {
+			fmt.Printf("🧭 %s\n\n", note)
+		}
+		// Resolved here rather than only in generateExecutionPlan, so the metadata shows the EXPLAIN that ran
+		explainOptions, err = explainOptionsForStatement(query, explainOptions)
+		if err != nil {
+			fmt.Println("❌ Failed to analyze query")
+			logErrorAndExit("Error: ", err)
+		}
+		warnDataModifyingQuery(query, explainOptions)
+
+		plan, err = generateExecutionPlan(query, config, explainOptions)
+		if err != nil {
+			fmt.Println("❌ Failed to analyze query")
+			if hint := connectionLimitHint(err.Error()); hint != "" {
+				fmt.Printf("💡 %s\n", hint)
+			}
+			logErrorAndExit("Error: ", err)
+		}
+
+		fmt.Println("✅ Query analysis complete!")
+		fmt.Println()
 	}
 
-	fmt.Println("✅ Query analysis complete!")
-	fmt.Println()
+	// A pure gate: no metadata or view lookups, no report and no upload
+	if checkOnly {
+		minCost, _ := cmd.Flags().GetFloat64("min-cost")
+		analysisPlan := planForAnalysis(plan, explainFormat)
+		strict, _ := cmd.Flags().GetBool("strict")
+		runThresholdCheck(parseCost(analysisPlan, threshold, minCost), analysisPlan, strict)
+		return
+	}
+
+	if includeMetadata, _ := cmd.Flags().GetBool("metadata"); includeMetadata && savedPlan == "" {
+		reportMetadata = collectReportMetadata(config, query, explainOptions)
+	}
+
+	if expandViews, _ := cmd.Flags().GetBool("expand-views"); expandViews && savedPlan == "" {
+		reportViews = loadViewDefinitions(config, query)
+		displayViewDefinitions(reportViews)
+	}
+
+	// The raw plan is only written to JSON reports and the data passed to custom templates
+	var rawPlan json.RawMessage
+	if includeRawJSON, _ := cmd.Flags().GetBool("include-raw-json"); includeRawJSON && (format == "json" || reportTemplate != nil) {
+		if savedPlan != "" {
+			rawPlan = structuredPlanJSON(plan, explainFormat)
+			if rawPlan == nil {
+				fmt.Printf("⚠️  Raw JSON plan unavailable: the saved plan is in %s format\n\n", strings.ToUpper(explainFormat))
+			}
+		} else {
+			if explainFormat != "json" {
+				fmt.Println("🔁 Running EXPLAIN (FORMAT JSON) for the raw plan...")
+			}
+			raw, err := rawPlanJSON(query, plan, explainFormat, config, explainOptions)
+			if err != nil {
+				fmt.Printf("⚠️  Raw JSON plan unavailable: %v\n\n", err)
+			}
+			rawPlan = raw
+		}
+	}
 
 	title := generateTitle()
+	analysisPlan := planForAnalysis(plan, explainFormat)
+	if notice := largePlanNotice(plan); notice != "" {
+		fmt.Printf("🐘 %s\n\n", notice)
+	}
 
 	// Cost analysis
 	var costInfo *CostInfo
-	if threshold > 0 {
-		costInfo = parseCost(plan, threshold)
-		if costInfo.ExceedsLimit {
-			displayCostAlert(costInfo)
+	if threshold > 0 && analysisPlan != "" {
+		minCost, _ := cmd.Flags().GetFloat64("min-cost")
+		costInfo = parseCost(analysisPlan, threshold, minCost)
+		if explainOps, _ := cmd.Flags().GetBool("explain-ops"); explainOps {
+			explainExpensiveOps(costInfo)
+		}
+		if costInfo.Warning != "" {
+			fmt.Printf("⚠️  %s\n\n", costInfo.Warning)
+		} else {
+			if costInfo.ExceedsLimit {
+				displayCostAlert(costInfo)
+			} else {
+				fmt.Printf("✨ Great! Query cost (%.2f) is below threshold (%.0f)\n\n", costInfo.TotalCost, threshold)
+				if len(costInfo.FullScans) > 0 {
+					displayFullTableScans(costInfo)
+					fmt.Println()
+				}
+				if len(costInfo.WideRows) > 0 {
+					displayWideRowOperations(costInfo)
+					fmt.Println()
+				}
+				if len(costInfo.Subplans) > 0 {
+					displayRepeatedSubplans(costInfo)
+					fmt.Println()
+				}
+				if len(costInfo.MemoizeCaches) > 0 {
+					displayMemoizeCaches(costInfo)
+					fmt.Println()
+				}
+			}
+			fmt.Printf("%s Health grade: %s\n\n", getGradeEmoji(costInfo.Grade), costInfo.Grade)
+			fmt.Printf("🌳 Plan shape: %d nodes, depth %d\n\n", costInfo.NodeCount, costInfo.MaxDepth)
+			if warning := planComplexityWarning(costInfo); warning != "" {
+				fmt.Printf("⚠️  %s\n\n", warning)
+			}
+		}
+		if costInfo.CostPerMs > 0 {
+			fmt.Printf("⏱️  Execution time: %.2f ms (≈ %.2f ms per cost unit for this query, not a universal ratio)\n\n",
+				costInfo.ExecutionTimeMs, msPerCostUnit(costInfo))
+		}
+		if costInfo.IOReadMs > 0 || costInfo.IOWriteMs > 0 {
+			fmt.Printf("💽 I/O time: %s\n\n", formatIOTimings(costInfo))
+			if warning := ioBoundWarning(costInfo); warning != "" {
+				fmt.Printf("⚠️  %s\n\n", warning)
+			}
+		}
+		if costInfo.JITInfo != nil {
+			fmt.Printf("🔥 JIT: %s\n\n", formatJIT(costInfo))
+			if warning := jitOverheadWarning(costInfo); warning != "" {
+				fmt.Printf("⚠️  %s\n\n", warning)
+			}
+		}
+	}
+
+	annotate, _ := cmd.Flags().GetBool("annotate")
+	if annotate && analysisPlan != "" {
+		displayAnnotatedPlan(analysisPlan, costInfo)
+	}
+
+	// Baseline comparison works without a threshold, so the cost is parsed here if needed
+	if baseline != nil && analysisPlan != "" {
+		currentCost := costInfo
+		if currentCost == nil {
+			currentCost = parseCost(analysisPlan, 0, 0)
+		}
+		if currentCost.Warning != "" {
+			fmt.Printf("⚠️  Baseline comparison skipped: the current plan has no cost estimates\n\n")
+		} else if delta, err := compareWithBaseline(currentCost, query, baseline); err != nil {
+			fmt.Printf("⚠️  Baseline comparison skipped: %v\n\n", err)
 		} else {
-			fmt.Printf("✨ Great! Query cost (%.2f) is below threshold (%.0f)\n\n", costInfo.TotalCost, threshold)
+			displayBaselineDelta(delta, baselinePath)
 		}
 	}
 
 	// Index recommendations
+	var indexInfo *IndexRecommendationInfo
 	recommendIndexes, _ := cmd.Flags().GetBool("recommend-indexes")
-	if recommendIndexes {
+	if recommendIndexes && analysisPlan != "" {
 		indexThreshold, _ := cmd.Flags().GetFloat64("index-threshold")
-		indexInfo := analyzeIndexOpportunities(plan, indexThreshold)
-		if indexInfo.TotalFound > 0 {
-			displayIndexRecommendations(indexInfo)
-		} else {
+		recommendOptions := recommendOptionsFromFlags(cmd, config)
+		// A saved plan is analyzed offline, so the catalog is not queried
+		if catalogCheck, _ := cmd.Flags().GetBool("catalog-check"); catalogCheck && savedPlan == "" {
+			recommendOptions.ColumnStats = loadColumnStats(config, analysisPlan)
+		}
+		indexInfo = analyzeIndexOpportunities(analysisPlan, indexThreshold, recommendOptions)
+		if hypothetical {
+			if savedPlan != "" {
+				fmt.Println("⚠️  Skipping hypothetical indexes: a saved plan is analyzed without a database connection")
+				fmt.Println()
+			} else {
+				measureHypotheticalIndexes(config, query, analysisPlan, explainOptions, indexInfo)
+			}
+		}
+		head, _ := cmd.Flags().GetInt("head")
+		displayIndexRecommendations(indexInfo, head)
+		if indexInfo.TotalFound == 0 && len(indexInfo.AlreadyApplied) == 0 && len(indexInfo.TypeMismatches) == 0 {
 			fmt.Printf("✨ No index recommendations (all operations below threshold of %.0f)\n\n", indexThreshold)
 		}
 	}
 
+	if slackWebhook, _ := cmd.Flags().GetString("slack-webhook"); slackWebhook != "" {
+		sendSlackMessage(os.Stdout, slackWebhook, slackPlanMessage(query, analysisPlan, costInfo, indexInfo))
+	}
+
+	if outputDir != "" {
+		if err := os.MkdirAll(outputDir, 0755); err != nil {
+			logErrorAndExit("Failed to create output directory: ", err)
+		}
+	}
+	outputName := filepath.Join(outputDir, title)
+
+	var fileName string
+	switch format {
+	case "json":
+		fmt.Println("💾 Saving as JSON...")
+		fileName = writeJSONPlan(planForOutput(plan, omitPlan), query, outputName, explainFormat, costInfo, indexInfo, rawPlan)
+	case "html":
+		if reportTemplate != nil {
+			fmt.Println("💾 Rendering custom HTML template...")
+			fileName = writeTemplateReport(reportTemplate, outputName+".html", newPlanOutput(plan, query, outputName, explainFormat, costInfo, indexInfo, rawPlan))
+		} else {
+			fmt.Println("💾 Generating interactive HTML report...")
+			// The calibration note needs the execution time, which is parsed without a threshold too
+			fileName = writePlan(plan, query, outputName, calibrationCostInfo(costInfo, plan, explainFormat))
+		}
+	case "markdown":
+		fmt.Println("💾 Generating Markdown report...")
+		markdownPlan := plan
+		if annotate {
+			markdownPlan = annotatePlan(plan)
+		}
+		fileName = writeMarkdownPlan(truncatePlanLines(markdownPlan, maxPlanLines), query, outputName, costInfo, calibrationCostInfo(costInfo, plan, explainFormat), indexInfo)
+	case "csv":
+		fmt.Println("💾 Saving as CSV...")
+		fileName = writeCSVPlan(planForOutput(plan, omitPlan), query, outputName, costInfo, csvColumns)
+	case "mermaid":
+		fmt.Println("💾 Generating Mermaid diagram...")
+		fileName = writeMermaidPlan(analysisPlan, outputName, costInfo)
+	case "slack":
+		fmt.Println("💾 Saving as a Slack message...")
+		fileName = writeJSONToFile(outputName+".slack.json", slackPlanMessage(query, analysisPlan, costInfo, indexInfo))
+	default:
+		logErrorAndExit("Invalid format specified", fmt.Errorf("supported formats: html, json, markdown, csv, mermaid, slack"))
+	}
+
+	fmt.Println("\n" + "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	fmt.Println("📁 Plan saved successfully!")
+	fmt.Printf("   %s\n", fileName)
+	if format == "html" {
+		fmt.Println("\n💡 Tip: Open this file in your browser to view the interactive plan")
+	}
+	if format == "mermaid" {
+		fmt.Println("\n💡 Tip: Paste it into a ```mermaid code block, which GitHub and GitLab render as a diagram")
+	}
+	fmt.Print("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n\n")
+
+	// The local file is written first, so it is kept even if the upload fails
 	if remoteFlag {
 		fmt.Println("☁️  Uploading to remote server...")
-		remoteURL := uploadPlan(plan, query, title)
+		var explainJSON func() (string, error)
+		if uploadJSON, _ := cmd.Flags().GetBool("upload-json"); uploadJSON && savedPlan == "" {
+			explainJSON = func() (string, error) {
+				jsonOptions := explainOptions
+				jsonOptions.Format = "json"
+				return generateExecutionPlan(query, config, jsonOptions)
+			}
+		}
+		remoteURL := uploadPlan(planForUpload(plan, analysisPlan, explainFormat, explainJSON), query, title)
 		fmt.Println("\n" + "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
 		fmt.Println("🌐 Remote URL (share with your team):")
 		fmt.Printf("   %s\n", remoteURL)
-		fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
-	} else {
-		var fileName string
-		switch format {
-		case "json":
-			fmt.Println("💾 Saving as JSON...")
-			fileName = writeJSONPlan(plan, query, title, costInfo)
-		case "html":
-			fmt.Println("💾 Generating interactive HTML report...")
-			fileName = writePlan(plan, query, title)
-		case "markdown":
-			fmt.Println("💾 Generating Markdown report...")
-			fileName = writeMarkdownPlan(plan, query, title, costInfo)
-		case "csv":
-			fmt.Println("💾 Saving as CSV...")
-			fileName = writeCSVPlan(plan, query, title, costInfo)
-		default:
-			logErrorAndExit("Invalid format specified", fmt.Errorf("supported formats: html, json, markdown, csv"))
-		}
+		fmt.Print("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n\n")
+	}
 
-		fmt.Println("\n" + "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
-		fmt.Println("📁 Plan saved successfully!")
-		fmt.Printf("   %s\n", fileName)
-		if format == "html" {
-			fmt.Println("\n💡 Tip: Open this file in your browser to view the interactive plan")
-		}
-		fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+	// Printed last, after every report, so scripts can take the final line of the output
+	if resultLine, _ := cmd.Flags().GetBool("result-line"); resultLine {
+		fmt.Println(formatResultLine(query, analysisPlan, costInfo))
+	}
+
+	if strict, _ := cmd.Flags().GetBool("strict"); strict && analysisPlan != "" {
+		exitOnStrictViolations(strictViolations(costInfo, analysisPlan))
 	}
 }
 
@@ -188,6 +519,46 @@ func getQueryInput(cmd *cobra.Command, args []string) (string, error) {
 	return getQueryFromPrompt()
 }
 
+// getSavedPlanInput reads the plan for --plan-file or --plan-stdin. The query is optional
+// in this mode and taken from the --file flag or the command argument when given.
+func getSavedPlanInput(cmd *cobra.Command, args []string, planFile string) (plan, query string, err error) {
+	if planFile != "" {
+		content, err := os.ReadFile(planFile)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to read plan file %s: %w", planFile, err)
+		}
+		plan = strings.TrimSpace(string(content))
+	} else {
+		stat, err := os.Stdin.Stat()
+		if err != nil || (stat.Mode()&os.ModeCharDevice) != 0 {
+			return "", "", fmt.Errorf("--plan-stdin needs a plan piped to STDIN")
+		}
+		content, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to read from stdin: %w", err)
+		}
+		plan = strings.TrimSpace(string(content))
+	}
+	if plan == "" {
+		return "", "", fmt.Errorf("the plan is empty")
+	}
+	// auto_explain logs a single JSON object, EXPLAIN (FORMAT JSON) an array of them
+	if strings.HasPrefix(plan, "{") {
+		plan = "[" + plan + "]"
+	}
+
+	if filePath, _ := cmd.Flags().GetString("file"); filePath != "" {
+		content, err := os.ReadFile(filePath)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to read file %s: %w", filePath, err)
+		}
+		query = strings.TrimSpace(string(content))
+	} else if len(args) > 0 {
+		query = args[0]
+	}
+	return plan, query, nil
+}
+
 // getQueryFromEditor opens the user's default editor to input the query
 func getQueryFromEditor() (string, error) {
 	// Get editor from environment, fallback to vim
@@ -278,7 +649,62 @@ func getQueryFromPrompt() (string, error) {
 	return query, nil
 }
 
-func generateExecutionPlan(query string, config *Config) (string, error) {
+func generateExecutionPlan(query string, config *Config, options ExplainOptions) (string, error) {
+	options, err := explainOptionsForStatement(query, options)
+	if err != nil {
+		return "", err
+	}
+	statements := buildExplainStatements(query, options)
+
+	var cacheKey string
+	if options.Cache {
+		key, err := planCacheKey(config, statements, options)
+		if err != nil {
+			fmt.Fprintf(options.progress(), "⚠️  Plan cache disabled for this query: %v\n", err)
+		} else if entry, ok := loadCachedPlan(key, options.CacheTTL); ok {
+			fmt.Fprintf(options.progress(), "📦 Using cached plan from %s (timings are from that run, use it for cost and structure only)\n",
+				entry.CreatedAt.Format(time.RFC1123))
+			return entry.Plan, nil
+		} else {
+			cacheKey = key
+		}
+	}
+
+	// Each statement runs as its own -c in the same session. Quiet mode keeps command
+	// tags such as "DO" and "PREPARE" out of the output so only the plan is printed.
+	// ON_ERROR_STOP ends the session at the first failure, which also discards an open transaction.
+	psqlArgs := []string{"-q", "-v", "ON_ERROR_STOP=1", "-c", forceEnglishMessagesSQL}
+	if isStructuredFormat(options.Format) {
+		// Unaligned, tuples-only output keeps the JSON/YAML/XML document free of table borders
+		psqlArgs = append(psqlArgs, "-A", "-t")
+	}
+	for _, statement := range statements {
+		psqlArgs = append(psqlArgs, "-c", statement)
+	}
+
+	plan, err := runPsqlRetryingConflicts(config, psqlArgs, options)
+	if err != nil {
+		return "", fmt.Errorf("unable to analyze the query: %w", err)
+	}
+
+	if !isStructuredFormat(options.Format) && !planLooksParseable(plan) {
+		out := options.progress()
+		fmt.Fprintln(out, "⚠️  Warning: the execution plan does not match the expected EXPLAIN format.")
+		fmt.Fprintln(out, "   Cost analysis and index recommendations may be incomplete.")
+		fmt.Fprintln(out, "   Check that the server emits English EXPLAIN output (lc_messages = 'C').")
+	}
+
+	if cacheKey != "" {
+		if err := storeCachedPlan(cacheKey, query, plan); err != nil {
+			fmt.Fprintf(options.progress(), "⚠️  Failed to write plan cache: %v\n", err)
+		}
+	}
+
+	return plan, nil
+}
+
+// runPsql runs psql with the given arguments against the configured database and returns its output
+func runPsql(config *Config, psqlArgs []string) (string, error) {
 	// Define the psql command and its arguments. Ensure your psql configuration is properly initialized
 	// before executing the command. For more details, @see the PostgreSQL environment variables : https://www.postgresql.org/docs/current/libpq-envars.html
 
@@ -286,55 +712,143 @@ func generateExecutionPlan(query string, config *Config) (string, error) {
 	// For example, you can create a .pgpass file with the following content:
 	// echo "$PGHOST:5432:$PGDATABASE:$PGUSER:$PGPASSWORD" > ~/.pgpass
 	// Refer to the .pgpass file documentation for more information: @see https://www.postgresql.org/docs/current/libpq-pgpass.html
+	user, database, host, password := connectionSettings(config)
+
+	if config.Database.Service != "" {
+		// The service file supplies host, user and database; libpq falls back to the PG* environment variables for the rest
+		psqlArgs = append(psqlArgs, "-d", serviceConninfo(config.Database.Service))
+		password = promptedPassword
+	} else {
+		psqlArgs = append(psqlArgs, "-U", user, "-d", database, "-h", host)
+	}
+	execution := exec.Command(psqlPath, psqlArgs...)
+
+	// Set PGPASSWORD in the command's environment if available
+	// This is more secure than passing it as a command argument
+	if password != "" {
+		env := append(os.Environ(), fmt.Sprintf("PGPASSWORD=%s", password))
+		execution.Env = env
+		// Drop the password from the environment slice once psql has finished
+		defer func() { env[len(env)-1] = "" }()
+	}
 
-	// Use environment variables first, fall back to config
-	user := os.Getenv("PGUSER")
+	var stdout, stderr bytes.Buffer
+	execution.Stdout = &stdout
+	execution.Stderr = &stderr
+
+	if err := execution.Run(); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return "", fmt.Errorf("%w: %s", err, message)
+		}
+		return "", err
+	}
+
+	return stdout.String(), nil
+}
+
+// connectionSettings resolves the connection parameters, using environment variables first and falling back to config.
+// A password entered with --prompt-password is used before PGPASSWORD.
+// When a profile is selected, its settings take precedence over the environment.
+func connectionSettings(config *Config) (user, database, host, password string) {
+	if config.Profile != "" {
+		profile := *config
+		profile.Profile = ""
+		user, database, host, password = connectionSettings(&profile)
+		if config.Database.User != "" {
+			user = config.Database.User
+		}
+		if config.Database.Database != "" {
+			database = config.Database.Database
+		}
+		if config.Database.Host != "" {
+			host = config.Database.Host
+		}
+		if config.Database.Password != "" {
+			password = config.Database.Password
+		}
+		return user, database, host, password
+	}
+
+	user = os.Getenv("PGUSER")
 	if user == "" && config.Database.User != "" {
 		user = config.Database.User
 	}
 
-	database := os.Getenv("PGDATABASE")
+	database = os.Getenv("PGDATABASE")
 	if database == "" && config.Database.Database != "" {
 		database = config.Database.Database
 	}
 
-	host := os.Getenv("PGHOST")
+	host = os.Getenv("PGHOST")
 	if host == "" && config.Database.Host != "" {
 		host = config.Database.Host
 	}
 
-	password := os.Getenv("PGPASSWORD")
+	password = promptedPassword
+	if password == "" {
+		password = os.Getenv("PGPASSWORD")
+	}
 	if password == "" && config.Database.Password != "" {
 		password = config.Database.Password
 	}
 
-	sql := fmt.Sprintf("EXPLAIN (ANALYSE, BUFFERS) %s", query)
-
-	execution := exec.Command("psql", "-c", sql, "-U", user, "-d", database, "-h", host)
+	return user, database, host, password
+}
 
-	// Set PGPASSWORD in the command's environment if available
-	// This is more secure than passing it as a command argument
-	if password != "" {
-		execution.Env = append(os.Environ(), fmt.Sprintf("PGPASSWORD=%s", password))
+// forceEnglishMessagesSQL switches the session to untranslated server messages so the
+// English patterns used by the cost analyzer and index recommender keep matching.
+// Changing lc_messages requires elevated privileges, so a permission error is ignored
+// and the plan is checked with planLooksParseable instead.
+const forceEnglishMessagesSQL = `DO $$
+BEGIN
+    PERFORM set_config('lc_messages', 'C', false);
+EXCEPTION WHEN insufficient_privilege THEN
+    NULL;
+END
+$$`
+
+// planForOutput returns the plan to embed in machine-readable outputs, or an empty
+// string when the caller only wants the cost metrics
+func planForOutput(plan string, omitPlan bool) string {
+	if omitPlan {
+		return ""
 	}
+	return plan
+}
 
-	plan, err := execution.CombinedOutput()
-	if err != nil {
-		return "", fmt.Errorf("unable to analyze the query: %w", err)
+// truncatePlanLines shortens plans longer than maxLines for console and Markdown output.
+// The first and last lines are kept, the last ones hold the planning and execution time.
+func truncatePlanLines(plan string, maxLines int) string {
+	lines := strings.Split(strings.TrimRight(plan, "\n"), "\n")
+	if maxLines <= 0 || len(lines) <= maxLines {
+		return plan
 	}
 
-	return string(plan), nil
+	head := (maxLines + 1) / 2
+	tail := maxLines - head
+	omitted := len(lines) - maxLines
+
+	truncated := append([]string{}, lines[:head]...)
+	truncated = append(truncated, fmt.Sprintf("… (truncated, %d lines omitted) …", omitted))
+	truncated = append(truncated, lines[len(lines)-tail:]...)
+	return strings.Join(truncated, "\n")
+}
+
+// planLooksParseable reports whether the plan contains at least one node with cost information
+func planLooksParseable(plan string) bool {
+	return costRegex.MatchString(plan)
 }
 
 func generateTitle() string {
-	currentTime := time.Now()
-	return fmt.Sprintf("Plan_Created_on_%s_%dth_%d_%02d:%02d:%02d",
-		currentTime.Month(),
-		currentTime.Day(),
-		currentTime.Year(),
-		currentTime.Hour(),
-		currentTime.Minute(),
-		currentTime.Second(),
+	now := currentTime()
+	return fmt.Sprintf("Plan_Created_on_%s_%dth_%d_%02d:%02d:%02d%s",
+		now.Month(),
+		now.Day(),
+		now.Year(),
+		now.Hour(),
+		now.Minute(),
+		now.Second(),
+		fileNameTimeSuffix(),
 	)
 }
 
@@ -351,12 +865,61 @@ func init() {
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
-	analyzeCmd.Flags().BoolP("remote", "r", false, "Send the execution plan to a remote server to share with your individuals")
-	analyzeCmd.Flags().StringP("format", "f", "html", "Output format for local files (html, json, markdown, or csv)")
 	analyzeCmd.Flags().StringP("file", "F", "", "Read SQL query from file")
 	analyzeCmd.Flags().BoolP("editor", "e", false, "Open $EDITOR to write/paste query")
-	analyzeCmd.Flags().Float64P("threshold", "t", 0, "Cost threshold for alerting on expensive queries (0 = disabled)")
-	analyzeCmd.Flags().BoolP("recommend-indexes", "i", false, "Recommend indexes based on query execution plan")
-	analyzeCmd.Flags().Float64("index-threshold", 100.0, "Minimum operation cost to trigger index recommendations")
+	analyzeCmd.Flags().String("plan-file", "", "Analyze an existing EXPLAIN output from this file instead of running EXPLAIN (text, JSON, YAML or XML)")
+	analyzeCmd.Flags().Bool("plan-stdin", false, "Analyze an existing EXPLAIN output read from STDIN instead of running EXPLAIN")
+	addAnalyzeFlags(analyzeCmd)
 	rootCmd.AddCommand(analyzeCmd)
 }
+
+// addAnalyzeFlags registers the flags read by analyzeQuery, shared by every command that runs the analyze pipeline
+func addAnalyzeFlags(command *cobra.Command) {
+	command.Flags().BoolP("remote", "r", false, "Also send the execution plan to a remote server to share with your individuals")
+	command.Flags().Bool("upload-json", false, "With --remote, run the query a second time as EXPLAIN (FORMAT JSON) for the upload when the plan is in another format")
+	command.Flags().StringP("format", "f", "html", "Output format for local files (html, json, markdown, csv, mermaid, or slack)")
+	command.Flags().String("slack-webhook", "", "Post a summary of the analysis to this Slack incoming webhook URL")
+	command.Flags().Float64P("threshold", "t", 0, "Cost threshold for alerting on expensive queries (0 = disabled)")
+	command.Flags().StringP("output-dir", "o", "", "Directory to save output files (default: current directory)")
+	command.Flags().Bool("omit-plan", false, "Leave the execution plan out of JSON and CSV output (included by default)")
+	command.Flags().Bool("include-raw-json", false, "Add the EXPLAIN (FORMAT JSON) output verbatim to JSON output as raw_plan_json, running EXPLAIN again as JSON if needed")
+	command.Flags().String("columns", "", "Comma-separated CSV columns to write, in order (default: all)")
+	command.Flags().Bool("check-only", false, "Only check the cost against the threshold: print PASS or FAIL, exit with status 3 on FAIL and write no report")
+	command.Flags().Bool("explain-ops", false, "Explain each expensive operation in plain English")
+	command.Flags().Bool("annotate", false, "Print the plan with each node's share of the total cost, e.g. (34%), and annotate the plan in Markdown output")
+	command.Flags().Bool("expand-views", false, "Show the definition of the views the query reads, from pg_get_viewdef, next to the plan")
+	command.Flags().Bool("strict", false, "Exit with status 3 if any analyzer warning is found: threshold breach, full table scan, disk spill, severe misestimate, complex or disk-bound plan")
+	command.Flags().Bool("result-line", false, "Print a final single-line summary for scripts: RESULT query_hash=... total_cost=... exceeds=... exec_ms=... grade=...")
+	command.Flags().Float64("min-cost", 0, "Minimum operation cost to list as an expensive operation (0 = list all above threshold)")
+	command.Flags().StringArray("op-threshold", nil, "Cost threshold for one operation type as OPERATION=COST, used instead of --threshold to find expensive operations (repeatable, e.g. --op-threshold \"Nested Loop=1000\")")
+	command.Flags().BoolP("recommend-indexes", "i", false, "Recommend indexes based on query execution plan")
+	command.Flags().Float64("index-threshold", 100.0, "Minimum operation cost to trigger index recommendations")
+	command.Flags().StringSlice("exclude-tables", nil, "Comma-separated table patterns to never recommend indexes for (glob or prefix, e.g. staging_*,tmp_)")
+	command.Flags().Bool("no-consolidate", false, "Keep single-column index recommendations instead of merging them into composite indexes")
+	command.Flags().Int("head", 0, "Show only the top N index recommendations on the console, reports still include all (0 = show all)")
+	command.Flags().String("applied-file", "", "File listing indexes already created or rejected (table:column1,column2 per line), which are not recommended again")
+	command.Flags().Bool("concurrently", false, "Emit CREATE INDEX CONCURRENTLY statements that do not block writes")
+	command.Flags().Bool("catalog-check", false, "Query the database catalog (pg_stats) to refine index recommendations")
+	command.Flags().Bool("hypothetical", false, "Measure the top 3 index recommendations with HypoPG hypothetical indexes and report the projected cost (needs the hypopg extension)")
+	command.Flags().Bool("transaction", false, "Run EXPLAIN ANALYZE inside a transaction that is rolled back, discarding data changes")
+	command.Flags().Bool("rollback", false, "Alias for --transaction")
+	command.Flags().Int("deadlock-retries", 0, "Times to run the EXPLAIN again after a deadlock or serialization failure (0 = no retry)")
+	command.Flags().Duration("deadlock-retry-delay", 500*time.Millisecond, "Delay before each retry after a deadlock or serialization failure")
+	command.Flags().Bool("cache", false, "Reuse cached plans from ~/.pgexplain/cache for unchanged queries and schema (for cost and structure, not timing)")
+	command.Flags().Duration("cache-ttl", 24*time.Hour, "Maximum age of a cached plan (0 = never expires)")
+	command.Flags().String("schema-version", "", "Schema version used in the cache key instead of fingerprinting the catalog")
+	command.Flags().String("template", "", "html/template file used for HTML output instead of the built-in report (receives PlanOutput)")
+	command.Flags().Int("max-plan-lines", 0, "Truncate the plan in Markdown output to N lines, keeping the first and last lines (0 = no limit)")
+	command.Flags().String("explain-format", "text", "EXPLAIN output format (text, json, yaml, or xml)")
+	command.Flags().StringSlice("allowed-statements", nil, "Statement types that may be analyzed, e.g. select,execute; others are rejected before running (select, insert, update, delete, merge, create, execute, or declare)")
+	command.Flags().String("explain-mode", "auto", "How statements are explained: auto (EXPLAIN ANALYZE for queries, rolled back for data changes, plain EXPLAIN for CREATE TABLE AS) or analyze (EXPLAIN ANALYZE as given)")
+	command.Flags().Bool("verbose", false, "Run EXPLAIN VERBOSE to show the output columns of every plan node")
+	command.Flags().Bool("metadata", false, "Record the EXPLAIN SQL, psql and server versions and connection target in the report")
+	command.Flags().StringArray("set", nil, "Run SET NAME=VALUE before the EXPLAIN, e.g. --set search_path=app,public (repeatable)")
+	command.Flags().StringArray("guc", nil, "Alias for --set, e.g. --guc work_mem=256MB (repeatable)")
+	command.Flags().String("role", "", "Run SET ROLE before the EXPLAIN so the query is planned with that role's privileges")
+	command.Flags().String("baseline", "", "JSON plan saved earlier with --format json to compare the cost against (e.g. \"cost 4500, was 3200, +40%\")")
+	command.Flags().StringArray("param", nil, "Value for a $N placeholder as N=VALUE (repeatable, e.g. --param 1=42 --param 2='foo')")
+	command.Flags().String("service", "", "Connect with a service from the libpq connection service file (~/.pg_service.conf), overriding the database settings")
+	command.Flags().Bool("prompt-password", false, "Prompt for the database password without echoing it (instead of .pgpass or PGPASSWORD)")
+}
@@ -0,0 +1,95 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// indexSignature identifies an index by table and key columns, e.g. "orders:customer_id,created_at".
+// Matching is case-insensitive, the column order matters as it does for a B-tree index.
+func indexSignature(table string, columns []string) string {
+	return strings.ToLower(table + ":" + strings.Join(columns, ","))
+}
+
+// loadAppliedIndexes reads the index signatures listed in an --applied-file, one per line.
+// Blank lines and lines starting with # are ignored.
+func loadAppliedIndexes(path string) (map[string]bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open applied indexes file: %w", err)
+	}
+	defer file.Close()
+
+	applied := make(map[string]bool)
+	scanner := bufio.NewScanner(file)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		table, columnList, found := strings.Cut(line, ":")
+		var columns []string
+		for _, column := range strings.Split(columnList, ",") {
+			if column = strings.TrimSpace(column); column != "" {
+				columns = append(columns, column)
+			}
+		}
+		if !found || strings.TrimSpace(table) == "" || len(columns) == 0 {
+			return nil, fmt.Errorf("line %d: expected table:column1,column2, got %q", lineNumber, line)
+		}
+		applied[indexSignature(strings.TrimSpace(table), columns)] = true
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading applied indexes file: %w", err)
+	}
+	return applied, nil
+}
+
+// separateAppliedRecommendations moves the recommendations listed as applied out of the
+// recommendations, into the already applied list of the report
+func separateAppliedRecommendations(info *IndexRecommendationInfo, applied map[string]bool) {
+	if len(applied) == 0 {
+		return
+	}
+
+	remaining := make([]IndexRecommendation, 0, len(info.Recommendations))
+	for _, rec := range info.Recommendations {
+		if applied[indexSignature(rec.TableName, rec.Columns)] {
+			info.AlreadyApplied = append(info.AlreadyApplied, rec)
+			continue
+		}
+		remaining = append(remaining, rec)
+	}
+	info.Recommendations = remaining
+}
+
+// formatAppliedRecommendations lists the skipped recommendations, e.g. "orders(customer_id), users(email)"
+func formatAppliedRecommendations(info *IndexRecommendationInfo) string {
+	var parts []string
+	for _, rec := range info.AlreadyApplied {
+		parts = append(parts, fmt.Sprintf("%s(%s)", rec.TableName, strings.Join(rec.Columns, ", ")))
+	}
+	return strings.Join(parts, ", ")
+}
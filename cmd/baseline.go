@@ -0,0 +1,114 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// ANSI escape codes for terminal output
+const (
+	ansiRed    = "\033[31m"
+	ansiGreen  = "\033[32m"
+	ansiYellow = "\033[33m"
+	ansiReset  = "\033[0m"
+)
+
+// BaselineDelta compares the cost of the current plan with a plan saved earlier as JSON
+type BaselineDelta struct {
+	Cost         float64
+	BaselineCost float64
+	DeltaPct     float64
+	SameQuery    bool
+}
+
+// loadPlanOutput reads a PlanOutput written by 'analyze --format json', used as a baseline
+// or as one side of compare-files
+func loadPlanOutput(path string) (*PlanOutput, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plan file: %w", err)
+	}
+
+	var output PlanOutput
+	if err := json.Unmarshal(content, &output); err != nil {
+		return nil, fmt.Errorf("%s is not a pg_explain JSON plan: %w", path, err)
+	}
+	return &output, nil
+}
+
+// baselineCost returns the total cost recorded in the baseline. Baselines saved without
+// a threshold have no cost analysis, so their execution plan is parsed instead.
+func baselineCost(baseline *PlanOutput) (float64, error) {
+	costInfo := baseline.CostAnalysis
+	if costInfo == nil && baseline.ExecutionPlan != "" {
+		costInfo = parseCost(planForAnalysis(baseline.ExecutionPlan, baseline.ExplainFormat), 0, 0)
+	}
+	if costInfo == nil || costInfo.Warning != "" {
+		return 0, fmt.Errorf("baseline file has no cost analysis or execution plan to compare with")
+	}
+	return costInfo.TotalCost, nil
+}
+
+// compareWithBaseline computes the cost change of the current plan versus the baseline
+func compareWithBaseline(costInfo *CostInfo, query string, baseline *PlanOutput) (*BaselineDelta, error) {
+	cost, err := baselineCost(baseline)
+	if err != nil {
+		return nil, err
+	}
+
+	delta := &BaselineDelta{
+		Cost:         costInfo.TotalCost,
+		BaselineCost: cost,
+		SameQuery:    fingerprintQuery(query) == fingerprintQuery(baseline.Query),
+	}
+	if cost != 0 {
+		delta.DeltaPct = (costInfo.TotalCost - cost) / cost * 100
+	}
+	return delta, nil
+}
+
+// displayBaselineDelta prints e.g. "cost 4500.00, was 3200.00, +40.6%", with regressions in red
+func displayBaselineDelta(delta *BaselineDelta, path string) {
+	change := fmt.Sprintf("%+.1f%%", delta.DeltaPct)
+	switch {
+	case delta.Cost > delta.BaselineCost:
+		change = colorize(change+" (regression)", ansiRed)
+	case delta.Cost < delta.BaselineCost:
+		change = colorize(change+" (improvement)", ansiGreen)
+	default:
+		change = "unchanged"
+	}
+
+	fmt.Printf("📏 Baseline: cost %.2f, was %.2f, %s\n", delta.Cost, delta.BaselineCost, change)
+	fmt.Printf("   %s\n", path)
+	if !delta.SameQuery {
+		fmt.Println("   ⚠️  The baseline was recorded for a different query, so the costs may not be comparable")
+	}
+	fmt.Println()
+}
+
+// colorize wraps text in an ANSI color when stdout is a terminal and NO_COLOR is not set
+func colorize(text, color string) string {
+	if os.Getenv("NO_COLOR") != "" || !isTerminal(os.Stdout) {
+		return text
+	}
+	return color + text + ansiReset
+}
@@ -19,9 +19,13 @@ package cmd
 
 import (
 	"bufio"
+	"encoding/json"
 	"fmt"
+	"html"
+	"math"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
@@ -44,29 +48,76 @@ Example:
 
 // BatchResult stores the analysis result for a single query
 type BatchResult struct {
-	QueryNumber   int        `json:"query_number"`
-	Query         string     `json:"query"`
-	ExecutionPlan string     `json:"execution_plan"`
-	CostAnalysis  *CostInfo  `json:"cost_analysis,omitempty"`
-	Error         string     `json:"error,omitempty"`
-	GeneratedAt   time.Time  `json:"generated_at"`
+	QueryNumber          int                      `json:"query_number"`
+	Query                string                   `json:"query"`
+	Tag                  string                   `json:"tag,omitempty"`
+	ExecutionPlan        string                   `json:"execution_plan"`
+	StructuredPlan       json.RawMessage          `json:"structured_plan,omitempty"`
+	RawPlanJSON          json.RawMessage          `json:"raw_plan_json,omitempty"`
+	CostAnalysis         *CostInfo                `json:"cost_analysis,omitempty"`
+	IndexRecommendations *IndexRecommendationInfo `json:"index_recommendations,omitempty"`
+	Error                string                   `json:"error,omitempty"`
+	ErrorCategory        string                   `json:"error_category,omitempty"`
+	GeneratedAt          time.Time                `json:"generated_at"`
+
+	// rankingCost is the cost of the plan when no threshold was set and CostAnalysis
+	// is nil, so the most expensive queries can still be listed
+	rankingCost *CostInfo
 }
 
 // BatchReport stores all batch analysis results
 type BatchReport struct {
-	FileName      string        `json:"file_name"`
-	TotalQueries  int           `json:"total_queries"`
-	SuccessCount  int           `json:"success_count"`
-	FailureCount  int           `json:"failure_count"`
-	Results       []BatchResult `json:"results"`
-	GeneratedAt   time.Time     `json:"generated_at"`
+	FileName      string           `json:"file_name"`
+	ExplainFormat string           `json:"explain_format,omitempty"`
+	TotalQueries  int              `json:"total_queries"`
+	SuccessCount  int              `json:"success_count"`
+	FailureCount  int              `json:"failure_count"`
+	Aggregates    *BatchAggregates `json:"aggregates,omitempty"`
+	TagAggregates []TagAggregates  `json:"tag_aggregates,omitempty"`
+	// WorkloadRecommendations are the index recommendations shared by several queries
+	WorkloadRecommendations []WorkloadRecommendation `json:"workload_recommendations,omitempty"`
+	Metadata                *ReportMetadata          `json:"metadata,omitempty"`
+	Results                 []BatchResult            `json:"results"`
+	GeneratedAt             time.Time                `json:"generated_at"`
+}
+
+// BatchAggregates summarizes the cost of the queries that were analyzed successfully
+type BatchAggregates struct {
+	AnalyzedQueries   int     `json:"analyzed_queries"`
+	TotalCost         float64 `json:"total_cost"`
+	AverageCost       float64 `json:"average_cost"`
+	MedianCost        float64 `json:"median_cost"`
+	P95Cost           float64 `json:"p95_cost"`
+	ExceededThreshold int     `json:"exceeded_threshold"`
+}
+
+// batchSource describes where the queries of a batch run come from
+type batchSource struct {
+	Label string // Shown in the start message, e.g. "SQL file"
+	Name  string // Used in the report and in output file names
+	// Load returns the queries and their directives, which may be nil or shorter than the queries
+	Load func() ([]string, []QueryDirectives, error)
 }
 
 func runBatch(cmd *cobra.Command, args []string) {
+	requirePsql()
 	sqlFile := args[0]
+	runBatchAnalysis(cmd, batchSource{
+		Label: "SQL file",
+		Name:  sqlFile,
+		Load: func() ([]string, []QueryDirectives, error) {
+			return parseSQLFile(sqlFile)
+		},
+	})
+}
 
+// runBatchAnalysis analyzes the queries of source and writes the batch output.
+// It reads the flags registered by addBatchFlags.
+func runBatchAnalysis(cmd *cobra.Command, source batchSource) {
 	// Load configuration
 	config, _ := loadConfig()
+	applyServiceFlag(cmd, config)
+	promptPasswordIfRequested(cmd)
 
 	// Get flag values
 	threshold, _ := cmd.Flags().GetFloat64("threshold")
@@ -83,36 +134,146 @@ func runBatch(cmd *cobra.Command, args []string) {
 	outputDir, _ := cmd.Flags().GetString("output-dir")
 	recommendIndexes, _ := cmd.Flags().GetBool("recommend-indexes")
 	indexThreshold, _ := cmd.Flags().GetFloat64("index-threshold")
+	recommendOptions := recommendOptionsFromFlags(cmd, config)
+	catalogCheck, _ := cmd.Flags().GetBool("catalog-check")
 	continueOnError, _ := cmd.Flags().GetBool("continue-on-error")
+	reconnectAttempts, _ := cmd.Flags().GetInt("reconnect-attempts")
+	reconnectDelay, _ := cmd.Flags().GetDuration("reconnect-delay")
+	delay, _ := cmd.Flags().GetDuration("delay")
+	if delay < 0 {
+		logErrorAndExit("Invalid --delay value", fmt.Errorf("the delay cannot be negative"))
+	}
+	workers, err := batchWorkersFromFlags(cmd)
+	if err != nil {
+		logErrorAndExit("Invalid --parallel value", err)
+	}
+	minCost, _ := cmd.Flags().GetFloat64("min-cost")
+	explainOps, _ := cmd.Flags().GetBool("explain-ops")
+	strict, _ := cmd.Flags().GetBool("strict")
+	omitPlan, _ := cmd.Flags().GetBool("omit-plan")
+	includeRawJSON, _ := cmd.Flags().GetBool("include-raw-json")
+	csvSummary, _ := cmd.Flags().GetBool("csv-summary")
+	maxPlanLines, _ := cmd.Flags().GetInt("max-plan-lines")
+	templateFile, _ := cmd.Flags().GetString("template")
+	reportTemplate, err := loadReportTemplate(templateFile)
+	if err != nil {
+		logErrorAndExit("Invalid --template value", err)
+	}
+	// The raw plan is only written to JSON reports and the data passed to custom templates
+	includeRawJSON = includeRawJSON && (format == "json" || reportTemplate != nil)
+	plain, _ := cmd.Flags().GetBool("plain")
+	quiet, _ := cmd.Flags().GetBool("quiet")
+	transaction, _ := cmd.Flags().GetBool("transaction")
+	rollback, _ := cmd.Flags().GetBool("rollback")
+	useCache, _ := cmd.Flags().GetBool("cache")
+	cacheTTL, _ := cmd.Flags().GetDuration("cache-ttl")
+	schemaVersion, _ := cmd.Flags().GetString("schema-version")
+	explainFormat, _ := cmd.Flags().GetString("explain-format")
+	if err := validateExplainFormat(explainFormat); err != nil {
+		logErrorAndExit("Invalid --explain-format value", err)
+	}
+	// pev2 renders FORMAT JSON plans in more detail than text plans
+	if format == "html" && !combined && !cmd.Flags().Changed("explain-format") {
+		explainFormat = "json"
+	}
+	verbose, _ := cmd.Flags().GetBool("verbose")
+	explainOptions := ExplainOptions{
+		Rollback:      transaction || rollback,
+		Cache:         useCache,
+		CacheTTL:      cacheTTL,
+		SchemaVersion: schemaVersion,
+		Format:        explainFormat,
+		Verbose:       verbose,
+	}
+	if err := sessionOptionsFromFlags(cmd, &explainOptions); err != nil {
+		logErrorAndExit("Invalid --set, --guc or --role value", err)
+	}
+	if err := explainModeFromFlags(cmd, &explainOptions); err != nil {
+		logErrorAndExit("Invalid --explain-mode value", err)
+	}
+	if err := conflictRetryOptionsFromFlags(cmd, &explainOptions); err != nil {
+		logErrorAndExit("Invalid deadlock retry value", err)
+	}
+	if err := allowedStatementsFromFlags(cmd, config, &explainOptions); err != nil {
+		logErrorAndExit("Invalid --allowed-statements value", err)
+	}
+
+	// Combined CSV reports use the batch column set, individual files the single plan set
+	availableCSVColumns := csvPlanColumns
+	if combined {
+		availableCSVColumns = csvBatchColumns
+	}
+	csvColumnsFlag, _ := cmd.Flags().GetString("columns")
+	csvColumns, err := parseCSVColumns(csvColumnsFlag, availableCSVColumns)
+	if err != nil {
+		logErrorAndExit("Invalid --columns value", err)
+	}
+	operationThresholds, err = operationThresholdsFromFlags(cmd, config)
+	if err != nil {
+		logErrorAndExit("Invalid --op-threshold value", err)
+	}
+	groupByFlag, _ := cmd.Flags().GetString("group-by")
+	groupBy, err := batchGroupByFromFlags(groupByFlag, combined)
+	if err != nil {
+		logErrorAndExit("Invalid --group-by value", err)
+	}
 
 	// Show friendly start message
 	fmt.Println("\n🔍 Starting batch analysis...")
-	fmt.Printf("📁 SQL file: %s\n", sqlFile)
+	fmt.Printf("📁 %s: %s\n", source.Label, source.Name)
 	fmt.Printf("📊 Output format: %s\n", format)
 	if threshold > 0 {
 		fmt.Printf("⚡ Cost threshold: %.0f\n", threshold)
+		if len(operationThresholds) > 0 {
+			fmt.Printf("⚡ Operation thresholds: %s\n", formatOperationThresholds(operationThresholds))
+		}
+	} else if cmd.Flags().Changed("op-threshold") {
+		fmt.Println("⚠️  --op-threshold only applies with a cost threshold (--threshold)")
 	}
 	if combined {
 		fmt.Println("📦 Mode: Combined report")
 	} else {
 		fmt.Println("📦 Mode: Individual files")
+		if groupBy != "" {
+			fmt.Printf("📂 Grouped by: %s\n", groupBy)
+		}
+	}
+	if delay > 0 {
+		fmt.Printf("⏳ Pacing: waiting %s between queries\n", delay)
+	}
+	if workers > 1 {
+		fmt.Printf("🧵 Parallel: %d queries at a time, each in its own psql connection\n", workers)
+	}
+	if explainOptions.Rollback {
+		fmt.Println("↩️  Transaction: changes will be rolled back after each query")
+	}
+	if len(explainOptions.AllowedStatements) > 0 {
+		fmt.Printf("🛡️  Allowed statements: %s\n", formatAllowedStatements(explainOptions.AllowedStatements))
 	}
 	fmt.Println()
 
-	// Read and parse SQL file
-	queries, err := parseSQLFile(sqlFile)
+	// Read the queries
+	queries, directives, err := source.Load()
 	if err != nil {
-		fmt.Println("❌ Failed to read SQL file")
+		fmt.Printf("❌ Failed to read queries from %s\n", source.Name)
 		logErrorAndExit("Error: ", err)
 	}
 
 	if len(queries) == 0 {
-		fmt.Println("⚠️  No valid SQL queries found in file")
+		fmt.Printf("⚠️  No valid SQL queries found in %s\n", source.Name)
 		return
 	}
 
 	fmt.Printf("✅ Found %d queries to analyze\n\n", len(queries))
 
+	// Queries that change the database run one at a time, in file order
+	if workers > 1 {
+		if i := firstAppliedQuery(queries, explainOptions); i >= 0 {
+			fmt.Printf("⚠️  Query %d modifies data and is not rolled back, so the queries run one at a time instead of --parallel %d. Use --transaction to run them in parallel.\n\n", i+1, workers)
+			workers = 1
+		}
+	}
+
 	// Create output directory if specified
 	if outputDir != "" {
 		if err := os.MkdirAll(outputDir, 0755); err != nil {
@@ -120,104 +281,290 @@ func runBatch(cmd *cobra.Command, args []string) {
 		}
 	}
 
+	// The EXPLAIN SQL is the same for every query apart from the query itself
+	if includeMetadata, _ := cmd.Flags().GetBool("metadata"); includeMetadata {
+		reportMetadata = collectReportMetadata(config, batchQueryPlaceholder, explainOptions)
+	}
+
 	// Process queries
 	batchReport := BatchReport{
-		FileName:    filepath.Base(sqlFile),
-		GeneratedAt: time.Now(),
-		Results:     make([]BatchResult, 0),
+		FileName:      filepath.Base(source.Name),
+		ExplainFormat: explainFormat,
+		Metadata:      reportMetadata,
+		GeneratedAt:   currentTime(),
+		Results:       make([]BatchResult, 0),
+	}
+
+	// Combined CSV reports are written as queries finish, so partial results survive an interrupted run
+	var csvStream *csvBatchWriter
+	if combined && format == "csv" {
+		csvStream = newCSVBatchWriter(generateBatchFileName(source.Name, format, outputDir), csvColumns, recommendIndexes)
+		fmt.Printf("📝 Writing results to %s as queries finish\n\n", csvStream.file.Name())
+	}
+
+	// On a terminal a single progress bar replaces the line-per-query log,
+	// warnings and errors are still printed above it
+	var progress *progressBar
+	logf := func(format string, args ...interface{}) {
+		fmt.Printf(format, args...)
+	}
+	if !plain && !quiet && isTerminal(os.Stdout) {
+		progress = newProgressBar(len(queries))
+		logf = progress.Printf
+	}
+
+	// Checks failed under --strict, reported once the reports are written
+	var strictFailures []string
+
+	// explainBatchQuery generates the plan of a query, reconnecting after a lost connection
+	explainBatchQuery := func(query string) (string, error) {
+		plan, err := generateExecutionPlan(query, config, explainOptions)
+		if err != nil && reconnectAttempts > 0 && classifyError(err.Error()) == errorCategoryConnection {
+			// Retrying a data-modifying query could apply it twice, unless it runs in a rolled back transaction
+			if changesAreRolledBack(query, explainOptions) || !isDataModifyingQuery(query) {
+				plan, err = retryAfterConnectionLoss(query, config, explainOptions, reconnectAttempts, reconnectDelay, logf)
+			}
+		}
+		return plan, err
+	}
+
+	// With --parallel the plans are generated ahead by several workers and analyzed in order below
+	var prefetch *batchPrefetch
+	if workers > 1 {
+		prefetch = startBatchPrefetch(queries, workers, delay, !continueOnError, explainBatchQuery)
+		defer prefetch.Stop()
 	}
 
 	for i, query := range queries {
 		queryNum := i + 1
-		fmt.Printf("🔄 Processing query %d/%d...\n", queryNum, len(queries))
+		// EXPLAIN ANALYZE executes every query, pacing spreads the load on a live database
+		if i > 0 && delay > 0 && prefetch == nil {
+			time.Sleep(delay)
+		}
+		if progress != nil {
+			progress.Update(i)
+		} else {
+			fmt.Printf("🔄 Processing query %d/%d...\n", queryNum, len(queries))
+		}
 
+		// Directive comments in the batch file override the threshold and tag the query
+		queryThreshold := threshold
 		result := BatchResult{
 			QueryNumber: queryNum,
 			Query:       query,
-			GeneratedAt: time.Now(),
+			GeneratedAt: currentTime(),
+		}
+		if i < len(directives) {
+			result.Tag = directives[i].Tag
+			if directives[i].Threshold > 0 {
+				queryThreshold = directives[i].Threshold
+			}
+		}
+
+		// A statement outside the allowlist fails in generateExecutionPlan without running
+		if checkAllowedStatement(query, explainOptions.AllowedStatements) == nil {
+			if note := statementModeNote(query, explainOptions); note != "" {
+				logf("   🧭 Query %d: %s\n", queryNum, note)
+			}
+			if !changesAreRolledBack(query, explainOptions) && isDataModifyingQuery(query) {
+				logf("   🚨 Query %d modifies data and will be applied! Use --transaction to roll it back.\n", queryNum)
+			}
 		}
 
-		plan, err := generateExecutionPlan(query, config)
+		var plan string
+		var err error
+		if prefetch != nil {
+			plan, err = prefetch.Plan(i)
+		} else {
+			plan, err = explainBatchQuery(query)
+		}
 		if err != nil {
 			result.Error = err.Error()
+			result.ErrorCategory = classifyError(result.Error)
 			batchReport.FailureCount++
-			fmt.Printf("   ❌ Query %d failed: %v\n\n", queryNum, err)
+			logf("   ❌ Query %d failed (%s error): %v\n\n", queryNum, result.ErrorCategory, err)
+			if hint := connectionLimitHint(result.Error); hint != "" {
+				logf("   💡 %s\n\n", hint)
+			}
 
+			if strict {
+				strictFailures = append(strictFailures, fmt.Sprintf("Query %d: failed (%s error)", queryNum, result.ErrorCategory))
+			}
 			if !continueOnError {
-				fmt.Println("⛔ Stopping batch analysis due to error. Use --continue-on-error to skip failed queries.")
+				logf("⛔ Stopping batch analysis due to error. Use --continue-on-error to skip failed queries.\n")
+				if prefetch != nil {
+					prefetch.Stop()
+				}
 				break
 			}
 		} else {
 			result.ExecutionPlan = plan
+			result.StructuredPlan = structuredPlanJSON(plan, explainFormat)
+			if includeRawJSON {
+				raw, err := rawPlanJSON(query, plan, explainFormat, config, explainOptions)
+				if err != nil {
+					logf("   ⚠️  Query %d: raw JSON plan unavailable: %v\n", queryNum, err)
+				}
+				result.RawPlanJSON = raw
+			}
 			batchReport.SuccessCount++
+			analysisPlan := planForAnalysis(plan, explainFormat)
+			if notice := largePlanNotice(plan); notice != "" {
+				logf("   🐘 Query %d: %s\n", queryNum, notice)
+			}
 
 			// Cost analysis
-			if threshold > 0 {
-				costInfo := parseCost(plan, threshold)
+			if queryThreshold > 0 && analysisPlan != "" {
+				costInfo := parseCost(analysisPlan, queryThreshold, minCost)
+				if explainOps {
+					explainExpensiveOps(costInfo)
+				}
 				result.CostAnalysis = costInfo
-				if costInfo.ExceedsLimit {
-					fmt.Printf("   ⚠️  Query %d exceeds cost threshold (%.2f > %.0f)\n", queryNum, costInfo.TotalCost, threshold)
-				} else {
-					fmt.Printf("   ✅ Query %d cost: %.2f\n", queryNum, costInfo.TotalCost)
+				if costInfo.Warning != "" {
+					logf("   ⚠️  Query %d: %s\n", queryNum, costInfo.Warning)
+				} else if costInfo.ExceedsLimit {
+					logf("   ⚠️  Query %d exceeds cost threshold (%.2f > %.0f) | Grade %s\n", queryNum, costInfo.TotalCost, queryThreshold, costInfo.Grade)
+				} else if progress == nil {
+					fmt.Printf("   ✅ Query %d cost: %.2f | Grade %s\n", queryNum, costInfo.TotalCost, costInfo.Grade)
+				}
+				if warning := planComplexityWarning(costInfo); warning != "" {
+					logf("   ⚠️  Query %d: %s\n", queryNum, warning)
+				}
+				for _, scan := range costInfo.FullScans {
+					logf("   🐘 Query %d: %s\n", queryNum, fullTableScanWarning(scan))
+				}
+				for _, operation := range costInfo.WideRows {
+					logf("   📦 Query %d: %s\n", queryNum, wideRowWarning(operation))
+				}
+				for _, subplan := range costInfo.Subplans {
+					logf("   🔁 Query %d: %s\n", queryNum, repeatedSubplanWarning(subplan))
+				}
+				for _, cache := range poorMemoizeCaches(costInfo) {
+					logf("   🗃️  Query %d: %s\n", queryNum, memoizeCacheWarning(cache))
+				}
+				if warning := ioBoundWarning(costInfo); warning != "" {
+					logf("   💽 Query %d: %s\n", queryNum, warning)
+				}
+				if warning := jitOverheadWarning(costInfo); warning != "" {
+					logf("   🔥 Query %d: %s\n", queryNum, warning)
 				}
 			} else {
-				fmt.Printf("   ✅ Query %d analyzed successfully\n", queryNum)
+				if analysisPlan != "" {
+					result.rankingCost = parseCost(analysisPlan, 0, 0)
+				}
+				if progress == nil {
+					fmt.Printf("   ✅ Query %d analyzed successfully\n", queryNum)
+				}
 			}
 
 			// Index recommendations
-			if recommendIndexes {
-				indexInfo := analyzeIndexOpportunities(plan, indexThreshold)
-				if indexInfo.TotalFound > 0 {
+			if recommendIndexes && analysisPlan != "" {
+				queryRecommendOptions := recommendOptions
+				if catalogCheck {
+					queryRecommendOptions.ColumnStats = loadColumnStats(config, analysisPlan)
+				}
+				indexInfo := analyzeIndexOpportunities(analysisPlan, indexThreshold, queryRecommendOptions)
+				result.IndexRecommendations = indexInfo
+				if indexInfo.TotalFound > 0 && progress == nil {
 					fmt.Printf("   💡 Found %d index recommendations\n", indexInfo.TotalFound)
 				}
 			}
-			fmt.Println()
+			if strict && analysisPlan != "" {
+				for _, violation := range strictViolations(result.CostAnalysis, analysisPlan) {
+					strictFailures = append(strictFailures, fmt.Sprintf("Query %d: %s", queryNum, violation))
+				}
+			}
+			if progress == nil {
+				fmt.Println()
+			}
 		}
 
 		batchReport.Results = append(batchReport.Results, result)
+		if csvStream != nil {
+			csvStream.Write(omitResultPlan(result, omitPlan))
+		}
+	}
+	if progress != nil {
+		progress.Finish()
 	}
 
 	batchReport.TotalQueries = len(queries)
+	batchReport.Aggregates = computeBatchAggregates(batchReport.Results)
+	batchReport.TagAggregates = computeTagAggregates(batchReport.Results)
+	batchReport.WorkloadRecommendations = computeWorkloadRecommendations(batchReport.Results)
 
 	// Generate output
 	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
 	fmt.Printf("📊 Batch Analysis Complete\n")
 	fmt.Printf("   Total: %d | Success: %d | Failed: %d\n",
 		batchReport.TotalQueries, batchReport.SuccessCount, batchReport.FailureCount)
-	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+	if failures := formatErrorCategories(batchReport.Results); failures != "" {
+		fmt.Printf("   Failures: %s\n", failures)
+	}
+	if grades := formatGradeDistribution(batchReport.Results); grades != "" {
+		fmt.Printf("   Grades: %s\n", grades)
+	}
+	if aggregates := batchReport.Aggregates; aggregates != nil {
+		fmt.Printf("   Cost: total %.2f | avg %.2f | median %.2f | p95 %.2f\n",
+			aggregates.TotalCost, aggregates.AverageCost, aggregates.MedianCost, aggregates.P95Cost)
+		fmt.Printf("   Exceeded threshold: %d of %d\n", aggregates.ExceededThreshold, aggregates.AnalyzedQueries)
+	}
+	for _, group := range batchReport.TagAggregates {
+		fmt.Printf("   🏷️  %s: %s\n", group.Tag, formatTagAggregates(group))
+	}
+	if bars, analyzed := batchCostBars(batchReport.Results); len(bars) > 1 {
+		if analyzed > len(bars) {
+			fmt.Printf("   Cost by query (%d most expensive of %d):\n", len(bars), analyzed)
+		} else {
+			fmt.Println("   Cost by query:")
+		}
+		for _, line := range formatCostBars(bars, plain || quiet) {
+			fmt.Printf("     %s\n", line)
+		}
+	}
+	fmt.Print("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n\n")
+	displayWorkloadRecommendations(batchReport.WorkloadRecommendations)
 
 	if combined {
 		// Generate combined report
 		fmt.Println("💾 Generating combined report...")
-		fileName := generateBatchFileName(sqlFile, format, outputDir)
+		fileName := generateBatchFileName(source.Name, format, outputDir)
 
 		switch format {
 		case "json":
-			absPath := writeJSONToFile(fileName, batchReport)
+			absPath := writeJSONToFile(fileName, omitBatchPlans(batchReport, omitPlan))
 			fmt.Println("\n━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
 			fmt.Println("📁 Batch report saved successfully!")
 			fmt.Printf("   %s\n", absPath)
-			fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+			fmt.Print("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n\n")
 		case "html":
-			absPath := writeBatchHTMLReport(batchReport, fileName)
+			var absPath string
+			if reportTemplate != nil {
+				absPath = writeTemplateReport(reportTemplate, fileName, batchReport)
+			} else {
+				absPath = writeBatchHTMLReport(batchReport, fileName)
+			}
 			fmt.Println("\n━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
 			fmt.Println("📁 Batch report saved successfully!")
 			fmt.Printf("   %s\n", absPath)
 			fmt.Println("\n💡 Tip: Open this file in your browser to view all query plans")
-			fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+			fmt.Print("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n\n")
 		case "markdown":
-			absPath := writeMarkdownBatchReport(batchReport, fileName)
+			absPath := writeMarkdownBatchReport(truncateBatchPlans(batchReport, maxPlanLines), fileName)
 			fmt.Println("\n━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
 			fmt.Println("📁 Batch report saved successfully!")
 			fmt.Printf("   %s\n", absPath)
 			fmt.Println("\n💡 Tip: Open this file in your markdown viewer to view all query plans")
-			fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+			fmt.Print("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n\n")
 		case "csv":
-			absPath := writeCSVBatchReport(batchReport, fileName)
+			if csvSummary {
+				csvStream.WriteSummary(batchReport)
+			}
+			absPath := csvStream.Close()
 			fmt.Println("\n━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
 			fmt.Println("📁 Batch report saved successfully!")
 			fmt.Printf("   %s\n", absPath)
-			fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+			fmt.Print("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n\n")
 		default:
 			logErrorAndExit("Invalid format specified", fmt.Errorf("supported formats: html, json, markdown, csv"))
 		}
@@ -225,27 +572,55 @@ func runBatch(cmd *cobra.Command, args []string) {
 		// Generate individual files
 		fmt.Println("💾 Generating individual files...")
 		savedFiles := make([]string, 0)
+		var groups []string
+		groupCounts := make(map[string]int)
 
 		for _, result := range batchReport.Results {
-			if result.Error != "" {
+			group := batchResultGroup(result, groupBy)
+			if result.Error != "" && group != failedGroup {
 				continue // Skip failed queries
 			}
 
-			title := fmt.Sprintf("Query_%d_%s", result.QueryNumber, generateTitle())
-			fileName := filepath.Join(outputDir, title)
+			groupDir := outputDir
+			if group != "" {
+				groupDir = filepath.Join(outputDir, group)
+				if err := os.MkdirAll(groupDir, 0755); err != nil {
+					logErrorAndExit("Failed to create output directory: ", err)
+				}
+				if groupCounts[group] == 0 {
+					groups = append(groups, group)
+				}
+				groupCounts[group]++
+			}
+
+			title := fmt.Sprintf("%s%d_%s", queryFilePrefix, result.QueryNumber, generateTitle())
+			fileName := filepath.Join(groupDir, title)
+
+			// Failed queries only get a file in the failed/ group
+			if result.Error != "" {
+				savedFiles = append(savedFiles, writeBatchErrorFile(result, fileName))
+				continue
+			}
 
 			switch format {
 			case "json":
-				absPath := writeJSONPlan(result.ExecutionPlan, result.Query, fileName, result.CostAnalysis)
+				absPath := writeJSONPlan(planForOutput(result.ExecutionPlan, omitPlan), result.Query, fileName, explainFormat, result.CostAnalysis, result.IndexRecommendations, result.RawPlanJSON)
 				savedFiles = append(savedFiles, absPath)
 			case "html":
-				absPath := writePlan(result.ExecutionPlan, result.Query, fileName)
+				var absPath string
+				if reportTemplate != nil {
+					planOutput := newPlanOutput(result.ExecutionPlan, result.Query, fileName, explainFormat, result.CostAnalysis, result.IndexRecommendations, result.RawPlanJSON)
+					absPath = writeTemplateReport(reportTemplate, fileName+".html", planOutput)
+				} else {
+					absPath = writePlan(result.ExecutionPlan, result.Query, fileName, calibrationCostInfo(result.CostAnalysis, result.ExecutionPlan, explainFormat))
+				}
 				savedFiles = append(savedFiles, absPath)
 			case "markdown":
-				absPath := writeMarkdownPlan(result.ExecutionPlan, result.Query, fileName, result.CostAnalysis)
+				planCost := calibrationCostInfo(result.CostAnalysis, result.ExecutionPlan, explainFormat)
+				absPath := writeMarkdownPlan(truncatePlanLines(result.ExecutionPlan, maxPlanLines), result.Query, fileName, result.CostAnalysis, planCost, result.IndexRecommendations)
 				savedFiles = append(savedFiles, absPath)
 			case "csv":
-				absPath := writeCSVPlan(result.ExecutionPlan, result.Query, fileName, result.CostAnalysis)
+				absPath := writeCSVPlan(planForOutput(result.ExecutionPlan, omitPlan), result.Query, fileName, result.CostAnalysis, csvColumns)
 				savedFiles = append(savedFiles, absPath)
 			}
 		}
@@ -263,43 +638,69 @@ func runBatch(cmd *cobra.Command, args []string) {
 		if outputDir != "" {
 			fmt.Printf("\n💡 All files saved to: %s\n", outputDir)
 		}
-		fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+		if len(groups) > 0 {
+			fmt.Printf("📂 Grouped by %s: %s\n", groupBy, formatGroupCounts(groups, groupCounts))
+		}
+		fmt.Print("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n\n")
 	}
+
+	exitOnStrictViolations(strictFailures)
 }
 
 // parseSQLFile reads a SQL file and extracts individual queries
-// Queries are separated by semicolons, comments and empty lines are ignored
-func parseSQLFile(filePath string) ([]string, error) {
+// Queries are separated by semicolons, comments and empty lines are ignored.
+// psql meta-commands are skipped, except that \set variables are substituted into later queries
+// and \g ends a query like a semicolon, so files exported from psql sessions can be analyzed.
+// Directive comments (-- @tag: name, -- @threshold: 5000) apply to the query they precede or appear in.
+func parseSQLFile(filePath string) ([]string, []QueryDirectives, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
-		return nil, fmt.Errorf("unable to open SQL file: %w", err)
+		return nil, nil, fmt.Errorf("unable to open SQL file: %w", err)
 	}
 	defer file.Close()
 
 	var queries []string
+	var directives []QueryDirectives
 	var currentQuery strings.Builder
+	var currentDirectives QueryDirectives
+	variables := make(map[string]string)
 	scanner := bufio.NewScanner(file)
+	lineNumber := 0
 
 	for scanner.Scan() {
+		lineNumber++
 		line := strings.TrimSpace(scanner.Text())
 
+		if err := applyQueryDirective(line, &currentDirectives); err != nil {
+			return nil, nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+
 		// Skip empty lines and comments
 		if line == "" || strings.HasPrefix(line, "--") || strings.HasPrefix(line, "/*") {
 			continue
 		}
 
+		line, terminated := splitPsqlQueryTerminator(line)
+		if isPsqlMetaCommand(line) {
+			applyPsqlMetaCommand(line, variables)
+			continue
+		}
+		line = substitutePsqlVariables(line, variables)
+
 		// Add line to current query
 		currentQuery.WriteString(line)
 		currentQuery.WriteString(" ")
 
-		// Check if query ends with semicolon
-		if strings.HasSuffix(line, ";") {
+		// Check if query ends with semicolon or \g
+		if terminated || strings.HasSuffix(line, ";") {
 			query := strings.TrimSpace(currentQuery.String())
 			// Remove trailing semicolon for EXPLAIN
 			query = strings.TrimSuffix(query, ";")
 
 			if query != "" {
 				queries = append(queries, query)
+				directives = append(directives, currentDirectives)
+				currentDirectives = QueryDirectives{}
 			}
 			currentQuery.Reset()
 		}
@@ -311,21 +712,241 @@ func parseSQLFile(filePath string) ([]string, error) {
 		query = strings.TrimSuffix(query, ";")
 		if query != "" {
 			queries = append(queries, query)
+			directives = append(directives, currentDirectives)
 		}
 	}
 
 	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error reading SQL file: %w", err)
+		return nil, nil, fmt.Errorf("error reading SQL file: %w", err)
+	}
+
+	return queries, directives, nil
+}
+
+// omitBatchPlans returns a copy of the report without execution plans when omitPlan is set
+func omitBatchPlans(report BatchReport, omitPlan bool) BatchReport {
+	if !omitPlan {
+		return report
+	}
+
+	results := make([]BatchResult, len(report.Results))
+	for i, result := range report.Results {
+		results[i] = omitResultPlan(result, omitPlan)
 	}
+	report.Results = results
+	return report
+}
 
-	return queries, nil
+// omitResultPlan returns a copy of the result without its execution plan when omitPlan is set
+func omitResultPlan(result BatchResult, omitPlan bool) BatchResult {
+	if omitPlan {
+		result.ExecutionPlan = ""
+		result.StructuredPlan = nil
+	}
+	return result
+}
+
+// retryAfterConnectionLoss runs the query again after a connection error, up to attempts
+// times, doubling the delay before each attempt. It stops early when the query fails for
+// another reason, since the connection is back and the error belongs to the query.
+func retryAfterConnectionLoss(query string, config *Config, options ExplainOptions, attempts int, delay time.Duration,
+	logf func(format string, args ...interface{})) (string, error) {
+	var plan string
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		logf("   🔌 Connection failed, reconnecting in %s (attempt %d/%d)...\n", delay, attempt, attempts)
+		time.Sleep(delay)
+
+		plan, err = generateExecutionPlan(query, config, options)
+		if err == nil {
+			logf("   🔌 Reconnected\n")
+			return plan, nil
+		}
+		if classifyError(err.Error()) != errorCategoryConnection {
+			return "", err
+		}
+		delay *= 2
+	}
+	return "", err
+}
+
+// truncateBatchPlans returns a copy of the report with plans shortened to maxLines
+func truncateBatchPlans(report BatchReport, maxLines int) BatchReport {
+	if maxLines <= 0 {
+		return report
+	}
+
+	results := make([]BatchResult, len(report.Results))
+	for i, result := range report.Results {
+		result.ExecutionPlan = truncatePlanLines(result.ExecutionPlan, maxLines)
+		results[i] = result
+	}
+	report.Results = results
+	return report
+}
+
+// formatGradeDistribution summarizes how many queries received each health grade, e.g. "A: 3 | C: 1".
+// It returns an empty string when no query was graded.
+func formatGradeDistribution(results []BatchResult) string {
+	counts := make(map[string]int)
+	for _, result := range results {
+		if result.CostAnalysis != nil && result.CostAnalysis.Grade != "" {
+			counts[result.CostAnalysis.Grade]++
+		}
+	}
+
+	parts := make([]string, 0, len(planGrades))
+	for _, grade := range planGrades {
+		if counts[grade] > 0 {
+			parts = append(parts, fmt.Sprintf("%s: %d", grade, counts[grade]))
+		}
+	}
+	return strings.Join(parts, " | ")
+}
+
+// computeBatchAggregates calculates cost statistics over the queries with a cost analysis.
+// It returns nil when no query was analyzed for cost.
+func computeBatchAggregates(results []BatchResult) *BatchAggregates {
+	var costs []float64
+	aggregates := &BatchAggregates{}
+	for _, result := range results {
+		if result.Error != "" || result.CostAnalysis == nil || result.CostAnalysis.Warning != "" {
+			continue
+		}
+		costs = append(costs, result.CostAnalysis.TotalCost)
+		aggregates.TotalCost += result.CostAnalysis.TotalCost
+		if result.CostAnalysis.ExceedsLimit {
+			aggregates.ExceededThreshold++
+		}
+	}
+
+	if len(costs) == 0 {
+		return nil
+	}
+	sort.Float64s(costs)
+
+	aggregates.AnalyzedQueries = len(costs)
+	aggregates.AverageCost = aggregates.TotalCost / float64(len(costs))
+
+	middle := len(costs) / 2
+	if len(costs)%2 == 0 {
+		aggregates.MedianCost = (costs[middle-1] + costs[middle]) / 2
+	} else {
+		aggregates.MedianCost = costs[middle]
+	}
+
+	// Nearest-rank percentile: the smallest cost that at least 95% of the queries do not exceed
+	rank := int(math.Ceil(0.95 * float64(len(costs))))
+	aggregates.P95Cost = costs[rank-1]
+
+	return aggregates
+}
+
+// formatAggregatesHTML renders the cost statistics for the batch HTML report
+func formatAggregatesHTML(aggregates *BatchAggregates) string {
+	if aggregates == nil {
+		return ""
+	}
+	return fmt.Sprintf(`
+        <div class="aggregates">
+            <strong>Cost Statistics:</strong> Total %.2f | Average %.2f | Median %.2f | P95 %.2f | Exceeded threshold: %d of %d
+        </div>
+`, aggregates.TotalCost, aggregates.AverageCost, aggregates.MedianCost, aggregates.P95Cost,
+		aggregates.ExceededThreshold, aggregates.AnalyzedQueries)
+}
+
+// topQueriesLimit is the number of queries listed in the most expensive queries section
+const topQueriesLimit = 10
+
+// resultCost returns the cost analysis of a query, or the cost of its plan when the
+// batch ran without a threshold
+func resultCost(result BatchResult) *CostInfo {
+	if result.CostAnalysis != nil {
+		return result.CostAnalysis
+	}
+	return result.rankingCost
+}
+
+// topExpensiveQueries returns up to limit analyzed queries, most expensive first.
+// Queries are ranked by total cost, execution time breaks ties.
+func topExpensiveQueries(results []BatchResult, limit int) []BatchResult {
+	ranked := make([]BatchResult, 0, len(results))
+	for _, result := range results {
+		if costInfo := resultCost(result); result.Error == "" && costInfo != nil && costInfo.Warning == "" {
+			ranked = append(ranked, result)
+		}
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		costI, costJ := resultCost(ranked[i]), resultCost(ranked[j])
+		if costI.TotalCost != costJ.TotalCost {
+			return costI.TotalCost > costJ.TotalCost
+		}
+		return costI.ExecutionTimeMs > costJ.ExecutionTimeMs
+	})
+
+	if len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+	return ranked
+}
+
+// queryLabel returns a single-line preview of the query without leading comments,
+// shortened to maxLength characters
+func queryLabel(query string, maxLength int) string {
+	label := strings.Join(strings.Fields(stripLeadingComments(query)), " ")
+	if runes := []rune(label); len(runes) > maxLength {
+		label = string(runes[:maxLength-1]) + "…"
+	}
+	return label
+}
+
+// formatTopQueriesHTML renders the most expensive queries with links to their details
+func formatTopQueriesHTML(results []BatchResult) string {
+	top := topExpensiveQueries(results, topQueriesLimit)
+	if len(top) == 0 {
+		return ""
+	}
+
+	rows := ""
+	for _, result := range top {
+		costInfo := resultCost(result)
+		executionTime := "N/A"
+		if costInfo.ExecutionTimeMs > 0 {
+			executionTime = fmt.Sprintf("%.2f ms", costInfo.ExecutionTimeMs)
+		}
+		rows += fmt.Sprintf(`
+                    <tr>
+                        <td><a href="#query-card-%d" onclick="openQuery(%d)">Query %d</a></td>
+                        <td class="query-label">%s</td>
+                        <td>%.2f</td>
+                        <td>%s</td>
+                    </tr>`,
+			result.QueryNumber, result.QueryNumber, result.QueryNumber,
+			html.EscapeString(queryLabel(result.Query, 80)),
+			costInfo.TotalCost,
+			executionTime)
+	}
+
+	return fmt.Sprintf(`
+        <div class="top-queries">
+            <h4>🐢 Top %d Most Expensive Queries</h4>
+            <table class="table table-sm">
+                <thead>
+                    <tr><th>Query</th><th>SQL</th><th>Cost</th><th>Execution Time</th></tr>
+                </thead>
+                <tbody>%s
+                </tbody>
+            </table>
+        </div>
+`, len(top), rows)
 }
 
 // generateBatchFileName creates a filename for the batch report
 func generateBatchFileName(sqlFile, format, outputDir string) string {
 	baseName := strings.TrimSuffix(filepath.Base(sqlFile), filepath.Ext(sqlFile))
-	timestamp := time.Now().Format("2006-01-02_15-04-05")
-	fileName := fmt.Sprintf("Batch_%s_%s.%s", baseName, timestamp, format)
+	timestamp := currentTime().Format("2006-01-02_15-04-05") + fileNameTimeSuffix()
+	fileName := fmt.Sprintf("%s%s_%s.%s", batchFilePrefix, baseName, timestamp, format)
 
 	if outputDir != "" {
 		return filepath.Join(outputDir, fileName)
@@ -362,6 +983,14 @@ func writeBatchHTMLReport(report BatchReport, fileName string) string {
         .badge { margin-left: 10px; }
         .cost-info { margin-top: 15px; padding: 10px; background-color: #fff3cd; border-radius: 4px; }
         .error-info { margin-top: 15px; padding: 10px; background-color: #f8d7da; border-radius: 4px; color: #721c24; }
+        .grade { display: inline-block; min-width: 2em; margin-left: 10px; padding: 2px 8px; border-radius: 4px; color: white; font-weight: bold; text-align: center; }
+        .grade-summary { margin-bottom: 30px; }
+        .top-queries { margin-bottom: 30px; }
+        .aggregates { margin-bottom: 30px; }
+        .tag-aggregates { margin-bottom: 30px; }
+        .workload-recommendations { margin-bottom: 30px; }
+        .metadata { margin-bottom: 30px; }
+        .query-label { font-family: monospace; font-size: 0.9em; }
     </style>
 </head>
 <body>
@@ -385,14 +1014,18 @@ func writeBatchHTMLReport(report BatchReport, fileName string) string {
                 <div>Failed</div>
             </div>
         </div>
-
+%s%s%s%s
         <div class="queries">`,
 		report.FileName,
 		report.FileName,
-		report.GeneratedAt.Format("January 2, 2006 15:04:05"),
+		formatTimestamp(report.GeneratedAt),
 		report.TotalQueries,
 		report.SuccessCount,
-		report.FailureCount)
+		report.FailureCount,
+		formatGradeSummaryHTML(report.Results),
+		formatAggregatesHTML(report.Aggregates)+formatTagAggregatesHTML(report.TagAggregates),
+		formatTopQueriesHTML(report.Results)+formatWorkloadRecommendationsHTML(report.WorkloadRecommendations),
+		formatMetadataHTML(report.Metadata))
 
 	// Add each query
 	for _, result := range report.Results {
@@ -400,9 +1033,13 @@ func writeBatchHTMLReport(report BatchReport, fileName string) string {
 		if result.Error != "" {
 			statusBadge = `<span class="badge bg-danger">Failed</span>`
 		}
+		if result.CostAnalysis != nil && result.CostAnalysis.Grade != "" {
+			statusBadge += fmt.Sprintf(`<span class="grade" style="background-color: %s" title="Health grade">%s</span>`,
+				gradeColor(result.CostAnalysis.Grade), result.CostAnalysis.Grade)
+		}
 
 		htmlContent += fmt.Sprintf(`
-            <div class="query-card">
+            <div class="query-card" id="query-card-%d">
                 <div class="query-header" onclick="toggleQuery(%d)">
                     <strong>Query %d</strong>
                     %s
@@ -411,6 +1048,7 @@ func writeBatchHTMLReport(report BatchReport, fileName string) string {
                 <div class="query-body" id="query-%d">
                     <h5>SQL Query:</h5>
                     <div class="query-sql">%s</div>`,
+			result.QueryNumber,
 			result.QueryNumber,
 			result.QueryNumber,
 			statusBadge,
@@ -420,14 +1058,19 @@ func writeBatchHTMLReport(report BatchReport, fileName string) string {
 		if result.Error != "" {
 			htmlContent += fmt.Sprintf(`
                     <div class="error-info">
-                        <strong>Error:</strong> %s
-                    </div>`, result.Error)
+                        <strong>Error (%s):</strong> %s
+                    </div>`, result.ErrorCategory, result.Error)
 		} else {
 			htmlContent += fmt.Sprintf(`
                     <h5>Execution Plan:</h5>
-                    <div class="execution-plan">%s</div>`, result.ExecutionPlan)
+                    <div class="execution-plan">%s</div>`, planForHTML(result.ExecutionPlan))
 
-			if result.CostAnalysis != nil {
+			if result.CostAnalysis != nil && result.CostAnalysis.Warning != "" {
+				htmlContent += fmt.Sprintf(`
+                    <div class="cost-info">
+                        <strong>Cost Analysis:</strong> ⚠️ %s
+                    </div>`, html.EscapeString(result.CostAnalysis.Warning))
+			} else if result.CostAnalysis != nil {
 				htmlContent += fmt.Sprintf(`
                     <div class="cost-info">
                         <strong>Cost Analysis:</strong> Total Cost: %.2f`, result.CostAnalysis.TotalCost)
@@ -436,6 +1079,20 @@ func writeBatchHTMLReport(report BatchReport, fileName string) string {
 					htmlContent += fmt.Sprintf(` | ⚠️ Exceeds threshold (%.0f)`, result.CostAnalysis.ThresholdValue)
 				}
 
+				if result.CostAnalysis.CostPerMs > 0 {
+					htmlContent += fmt.Sprintf(`
+                        <br><small>Execution Time: %.2f ms | ≈ %.2f ms per cost unit (this query). Planner cost is unitless, so this ratio is not universal.</small>`,
+						result.CostAnalysis.ExecutionTimeMs, msPerCostUnit(result.CostAnalysis))
+				}
+
+				for _, op := range result.CostAnalysis.ExpensiveOps {
+					if op.Explanation != "" {
+						htmlContent += fmt.Sprintf(`
+                        <br><small><strong>%s</strong> (%.2f): %s</small>`,
+							html.EscapeString(op.Operation), op.Cost, html.EscapeString(op.Explanation))
+					}
+				}
+
 				htmlContent += `</div>`
 			}
 		}
@@ -455,6 +1112,10 @@ func writeBatchHTMLReport(report BatchReport, fileName string) string {
             queryBody.classList.toggle('show');
         }
 
+        function openQuery(queryNum) {
+            document.getElementById('query-' + queryNum).classList.add('show');
+        }
+
         // Expand first query by default
         if (document.getElementById('query-1')) {
             document.getElementById('query-1').classList.add('show');
@@ -482,13 +1143,71 @@ func writeBatchHTMLReport(report BatchReport, fileName string) string {
 	return abs
 }
 
+// formatGradeSummaryHTML renders the grade distribution for the batch HTML report
+func formatGradeSummaryHTML(results []BatchResult) string {
+	grades := formatGradeDistribution(results)
+	if grades == "" {
+		return ""
+	}
+	return fmt.Sprintf(`
+        <div class="grade-summary">
+            <strong>Health Grades:</strong> %s
+        </div>
+`, grades)
+}
+
 func init() {
-	batchCmd.Flags().StringP("format", "f", "html", "Output format for files (html, json, markdown, or csv)")
-	batchCmd.Flags().Float64P("threshold", "t", 0, "Cost threshold for alerting on expensive queries (0 = disabled)")
-	batchCmd.Flags().BoolP("recommend-indexes", "i", false, "Recommend indexes based on query execution plans")
-	batchCmd.Flags().Float64("index-threshold", 100.0, "Minimum operation cost to trigger index recommendations")
-	batchCmd.Flags().BoolP("combined", "c", false, "Generate a single combined report instead of individual files")
-	batchCmd.Flags().StringP("output-dir", "o", "", "Directory to save output files (default: current directory)")
-	batchCmd.Flags().Bool("continue-on-error", true, "Continue processing remaining queries if one fails")
+	addBatchFlags(batchCmd)
 	rootCmd.AddCommand(batchCmd)
+}
+
+// addBatchFlags registers the flags read by runBatchAnalysis, shared by every command that runs a batch
+func addBatchFlags(command *cobra.Command) {
+	command.Flags().StringP("format", "f", "html", "Output format for files (html, json, markdown, or csv)")
+	command.Flags().Float64P("threshold", "t", 0, "Cost threshold for alerting on expensive queries (0 = disabled)")
+	command.Flags().Bool("omit-plan", false, "Leave execution plans out of JSON and CSV output (included by default)")
+	command.Flags().Bool("include-raw-json", false, "Add the EXPLAIN (FORMAT JSON) output of each query verbatim to JSON output as raw_plan_json, running EXPLAIN again as JSON if needed")
+	command.Flags().String("columns", "", "Comma-separated CSV columns to write, in order (default: all)")
+	command.Flags().Bool("csv-summary", false, "Append a totals row to the combined CSV report (query count, success/failure counts, total and average cost)")
+	command.Flags().Bool("explain-ops", false, "Explain each expensive operation in plain English")
+	command.Flags().Bool("strict", false, "Exit with status 3 if any query fails or has an analyzer warning: threshold breach, full table scan, disk spill, severe misestimate, complex or disk-bound plan")
+	command.Flags().Float64("min-cost", 0, "Minimum operation cost to list as an expensive operation (0 = list all above threshold)")
+	command.Flags().StringArray("op-threshold", nil, "Cost threshold for one operation type as OPERATION=COST, used instead of --threshold to find expensive operations (repeatable, e.g. --op-threshold \"Nested Loop=1000\")")
+	command.Flags().BoolP("recommend-indexes", "i", false, "Recommend indexes based on query execution plans")
+	command.Flags().Float64("index-threshold", 100.0, "Minimum operation cost to trigger index recommendations")
+	command.Flags().StringSlice("exclude-tables", nil, "Comma-separated table patterns to never recommend indexes for (glob or prefix, e.g. staging_*,tmp_)")
+	command.Flags().Bool("no-consolidate", false, "Keep single-column index recommendations instead of merging them into composite indexes")
+	command.Flags().String("applied-file", "", "File listing indexes already created or rejected (table:column1,column2 per line), which are not recommended again")
+	command.Flags().Bool("concurrently", false, "Emit CREATE INDEX CONCURRENTLY statements that do not block writes")
+	command.Flags().Bool("catalog-check", false, "Query the database catalog (pg_stats) to refine index recommendations")
+	command.Flags().BoolP("combined", "c", false, "Generate a single combined report instead of individual files")
+	command.Flags().StringP("output-dir", "o", "", "Directory to save output files (default: current directory)")
+	command.Flags().String("group-by", "", "Sort individual files into subdirectories of the output directory: status (successful/, failed/) or tag (one per @tag directive, untagged/ for the rest)")
+	command.Flags().Bool("continue-on-error", true, "Continue processing remaining queries if one fails")
+	command.Flags().Int("reconnect-attempts", 3, "Times to retry a query that failed with a connection error before marking it failed (0 = no retry)")
+	command.Flags().Duration("reconnect-delay", 2*time.Second, "Delay before the first reconnect attempt, doubled after each attempt")
+	command.Flags().Duration("delay", 0, "Wait this long between queries to limit the load on the database, e.g. 500ms (0 = no delay)")
+	command.Flags().Int("parallel", 1, "Number of queries explained at the same time, each by its own psql process and connection")
+	command.Flags().Int("max-concurrency", defaultMaxConcurrency, "Upper limit for --parallel, to stay below the server's max_connections")
+	command.Flags().Int("deadlock-retries", 0, "Times to run the EXPLAIN again after a deadlock or serialization failure (0 = no retry)")
+	command.Flags().Duration("deadlock-retry-delay", 500*time.Millisecond, "Delay before each retry after a deadlock or serialization failure")
+	command.Flags().Bool("plain", false, "Print one line per query instead of a progress bar, and draw the summary cost bars with #")
+	command.Flags().Bool("quiet", false, "Do not draw a progress bar (same as --plain)")
+	command.Flags().String("template", "", "html/template file used for HTML output instead of the built-in report (receives BatchReport, or PlanOutput for individual files)")
+	command.Flags().Int("max-plan-lines", 0, "Truncate plans in Markdown output to N lines, keeping the first and last lines (0 = no limit)")
+	command.Flags().String("explain-format", "text", "EXPLAIN output format (text, json, yaml, or xml)")
+	command.Flags().StringSlice("allowed-statements", nil, "Statement types that may be analyzed, e.g. select,execute; others are rejected before running (select, insert, update, delete, merge, create, execute, or declare)")
+	command.Flags().String("explain-mode", "auto", "How statements are explained: auto (EXPLAIN ANALYZE for queries, rolled back for data changes, plain EXPLAIN for CREATE TABLE AS) or analyze (EXPLAIN ANALYZE as given)")
+	command.Flags().Bool("verbose", false, "Run EXPLAIN VERBOSE to show the output columns of every plan node")
+	command.Flags().Bool("metadata", false, "Record the EXPLAIN SQL, psql and server versions and connection target in the report")
+	command.Flags().StringArray("set", nil, "Run SET NAME=VALUE before the EXPLAIN, e.g. --set search_path=app,public (repeatable)")
+	command.Flags().StringArray("guc", nil, "Alias for --set, e.g. --guc work_mem=256MB (repeatable)")
+	command.Flags().String("role", "", "Run SET ROLE before the EXPLAIN so the query is planned with that role's privileges")
+	command.Flags().Bool("transaction", false, "Run each EXPLAIN ANALYZE inside a transaction that is rolled back, discarding data changes")
+	command.Flags().Bool("rollback", false, "Alias for --transaction")
+	command.Flags().Bool("cache", false, "Reuse cached plans from ~/.pgexplain/cache for unchanged queries and schema (for cost and structure, not timing)")
+	command.Flags().Duration("cache-ttl", 24*time.Hour, "Maximum age of a cached plan (0 = never expires)")
+	command.Flags().String("schema-version", "", "Schema version used in the cache key instead of fingerprinting the catalog")
+	command.Flags().String("service", "", "Connect with a service from the libpq connection service file (~/.pg_service.conf), overriding the database settings")
+	command.Flags().Bool("prompt-password", false, "Prompt for the database password without echoing it (instead of .pgpass or PGPASSWORD)")
 }
\ No newline at end of file
@@ -0,0 +1,116 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// batchGroupByValues lists the supported --group-by values
+var batchGroupByValues = []string{"status", "tag"}
+
+// Subdirectories used by --group-by
+const (
+	successfulGroup = "successful"
+	failedGroup     = "failed"
+	untaggedGroup   = "untagged"
+)
+
+// unsafeGroupNameRegex matches the characters of a tag that are not kept in its directory name
+var unsafeGroupNameRegex = regexp.MustCompile(`[^\p{L}\p{N}._-]+`)
+
+// batchGroupByFromFlags reads --group-by. Grouping only applies to individual files.
+func batchGroupByFromFlags(groupBy string, combined bool) (string, error) {
+	groupBy = strings.ToLower(strings.TrimSpace(groupBy))
+	if groupBy == "" {
+		return "", nil
+	}
+	if !containsString(batchGroupByValues, groupBy) {
+		return "", fmt.Errorf("supported values: %s", strings.Join(batchGroupByValues, ", "))
+	}
+	if combined {
+		return "", fmt.Errorf("--group-by sorts individual files into subdirectories and cannot be used with --combined")
+	}
+	return groupBy, nil
+}
+
+// batchResultGroup returns the subdirectory the file of a result is written to,
+// or "" when files are not grouped
+func batchResultGroup(result BatchResult, groupBy string) string {
+	switch groupBy {
+	case "status":
+		if result.Error != "" {
+			return failedGroup
+		}
+		return successfulGroup
+	case "tag":
+		if result.Tag == "" {
+			return untaggedGroup
+		}
+		return groupDirectoryName(result.Tag)
+	default:
+		return ""
+	}
+}
+
+// groupDirectoryName turns a tag into a directory name, e.g. "billing/monthly" into "billing_monthly"
+func groupDirectoryName(tag string) string {
+	name := strings.Trim(unsafeGroupNameRegex.ReplaceAllString(tag, "_"), "._")
+	if name == "" {
+		return "_"
+	}
+	return name
+}
+
+// writeBatchErrorFile writes a failed query as a SQL file with the error in a comment, so
+// --group-by status keeps a record of it in failed/. Returns absolute path of generated file
+func writeBatchErrorFile(result BatchResult, fileName string) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("-- Query %d failed", result.QueryNumber))
+	if result.ErrorCategory != "" {
+		sb.WriteString(fmt.Sprintf(" (%s)", result.ErrorCategory))
+	}
+	sb.WriteString("\n")
+	for _, line := range strings.Split(strings.TrimSpace(result.Error), "\n") {
+		sb.WriteString("-- " + line + "\n")
+	}
+	sb.WriteString("\n" + strings.TrimSuffix(strings.TrimSpace(result.Query), ";") + ";\n")
+
+	if err := os.WriteFile(fileName+".sql", []byte(sb.String()), 0644); err != nil {
+		logErrorAndExit("unable to write error file: ", err)
+	}
+	absPath, err := filepath.Abs(fileName + ".sql")
+	if err != nil {
+		logErrorAndExit("unable to get file absolute path: ", err)
+	}
+	return absPath
+}
+
+// formatGroupCounts summarizes the files per group in order of first appearance,
+// e.g. "successful (12), failed (2)"
+func formatGroupCounts(groups []string, counts map[string]int) string {
+	parts := make([]string, 0, len(groups))
+	for _, group := range groups {
+		parts = append(parts, fmt.Sprintf("%s (%d)", group, counts[group]))
+	}
+	return strings.Join(parts, ", ")
+}
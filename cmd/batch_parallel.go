@@ -0,0 +1,169 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// defaultMaxConcurrency caps --parallel unless --max-concurrency says otherwise. Each
+// worker holds its own connection, so the cap keeps a batch well below max_connections.
+const defaultMaxConcurrency = 4
+
+// batchWorkersFromFlags returns how many queries a batch explains at the same time:
+// --parallel, capped at --max-concurrency
+func batchWorkersFromFlags(cmd *cobra.Command) (int, error) {
+	parallel, _ := cmd.Flags().GetInt("parallel")
+	maxConcurrency, _ := cmd.Flags().GetInt("max-concurrency")
+	if parallel < 1 {
+		return 0, fmt.Errorf("--parallel must be at least 1, got %d", parallel)
+	}
+	if maxConcurrency < 1 {
+		return 0, fmt.Errorf("--max-concurrency must be at least 1, got %d", maxConcurrency)
+	}
+	if parallel > maxConcurrency {
+		fmt.Printf("⚠️  --parallel %d is capped at --max-concurrency %d\n", parallel, maxConcurrency)
+		return maxConcurrency, nil
+	}
+	return parallel, nil
+}
+
+// firstAppliedQuery returns the index of the first query whose changes are applied to the
+// database, or -1. Such queries are not explained in parallel, since their order matters.
+func firstAppliedQuery(queries []string, options ExplainOptions) int {
+	for i, query := range queries {
+		// A statement outside the allowlist fails without running
+		if checkAllowedStatement(query, options.AllowedStatements) != nil {
+			continue
+		}
+		if !changesAreRolledBack(query, options) && isDataModifyingQuery(query) {
+			return i
+		}
+	}
+	return -1
+}
+
+// batchPlan is the outcome of explaining one query of a parallel batch
+type batchPlan struct {
+	plan string
+	err  error
+}
+
+// batchPrefetch explains the queries of a batch with several psql processes at a time.
+// The queries are started in file order, but run concurrently, so one may finish before
+// an earlier one. The plans are handed out in query order, so the analysis and the reports
+// are the same as for a sequential run.
+type batchPrefetch struct {
+	plans []chan batchPlan
+	// window holds a token for every query that may be started ahead of the one being
+	// analyzed, so the workers never run more than their number ahead of the reports
+	window chan struct{}
+	// stopAfter is the index of the last query that may be started
+	stopAfter atomic.Int64
+	done      chan struct{}
+	stopOnce  sync.Once
+}
+
+// startBatchPrefetch starts workers that explain the queries in order. delay is waited
+// between the start of two queries, as in a sequential run. With stopOnError no query is
+// started after one that failed.
+func startBatchPrefetch(queries []string, workers int, delay time.Duration, stopOnError bool, explain func(query string) (string, error)) *batchPrefetch {
+	prefetch := &batchPrefetch{
+		plans:  make([]chan batchPlan, len(queries)),
+		window: make(chan struct{}, workers),
+		done:   make(chan struct{}),
+	}
+	prefetch.stopAfter.Store(int64(len(queries)))
+	for i := range prefetch.plans {
+		prefetch.plans[i] = make(chan batchPlan, 1)
+	}
+	for w := 0; w < workers; w++ {
+		prefetch.window <- struct{}{}
+	}
+
+	next := make(chan int)
+	go func() {
+		defer close(next)
+		for i := range queries {
+			select {
+			case <-prefetch.window:
+			case <-prefetch.done:
+				return
+			}
+			if i > 0 && delay > 0 {
+				time.Sleep(delay)
+			}
+			if !prefetch.mayStart(i) {
+				return
+			}
+			next <- i
+		}
+	}()
+
+	for w := 0; w < workers; w++ {
+		go func() {
+			for i := range next {
+				// Queries up to the failed one are still run, the batch reports them before stopping
+				if !prefetch.mayStart(i) {
+					continue
+				}
+				plan, err := explain(queries[i])
+				if err != nil && stopOnError {
+					prefetch.StopAfter(i)
+				}
+				prefetch.plans[i] <- batchPlan{plan: plan, err: err}
+			}
+		}()
+	}
+	return prefetch
+}
+
+// mayStart reports whether query i may still be started
+func (p *batchPrefetch) mayStart(i int) bool {
+	return int64(i) <= p.stopAfter.Load()
+}
+
+// Plan waits for the plan of query i, counting from 0, and lets the workers start the
+// next query
+func (p *batchPrefetch) Plan(i int) (string, error) {
+	result := <-p.plans[i]
+	p.window <- struct{}{}
+	return result.plan, result.err
+}
+
+// StopAfter keeps the workers from starting the queries after query i. Queries already
+// running finish.
+func (p *batchPrefetch) StopAfter(i int) {
+	for {
+		current := p.stopAfter.Load()
+		if int64(i) >= current || p.stopAfter.CompareAndSwap(current, int64(i)) {
+			return
+		}
+	}
+}
+
+// Stop keeps the workers from starting further queries. Queries already running finish.
+func (p *batchPrefetch) Stop() {
+	p.StopAfter(-1)
+	p.stopOnce.Do(func() { close(p.done) })
+}
@@ -0,0 +1,117 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBatchPrefetchHandsOutPlansInOrder(t *testing.T) {
+	queries := []string{"q1", "q2", "q3", "q4", "q5"}
+	prefetch := startBatchPrefetch(queries, 3, 0, true, func(query string) (string, error) {
+		return "plan of " + query, nil
+	})
+	defer prefetch.Stop()
+
+	for i, query := range queries {
+		plan, err := prefetch.Plan(i)
+		if err != nil || plan != "plan of "+query {
+			t.Fatalf("Plan(%d) = %q, %v, want %q", i, plan, err, "plan of "+query)
+		}
+	}
+}
+
+func TestBatchPrefetchStaysWithinWindow(t *testing.T) {
+	queries := make([]string, 10)
+	for i := range queries {
+		queries[i] = fmt.Sprintf("q%d", i)
+	}
+	var mu sync.Mutex
+	var started []string
+	prefetch := startBatchPrefetch(queries, 2, 0, true, func(query string) (string, error) {
+		mu.Lock()
+		started = append(started, query)
+		mu.Unlock()
+		return "", nil
+	})
+	defer prefetch.Stop()
+
+	// Nothing is consumed, so only as many queries as there are workers are run
+	time.Sleep(50 * time.Millisecond)
+	mu.Lock()
+	count := len(started)
+	mu.Unlock()
+	if count != 2 {
+		t.Errorf("started %d queries before any plan was consumed, want 2", count)
+	}
+}
+
+func TestBatchPrefetchStopsAfterFailure(t *testing.T) {
+	queries := []string{"q1", "fail", "q3", "q4", "q5", "q6"}
+	var mu sync.Mutex
+	var started []string
+	release := make(chan struct{})
+	prefetch := startBatchPrefetch(queries, 2, 0, true, func(query string) (string, error) {
+		mu.Lock()
+		started = append(started, query)
+		mu.Unlock()
+		if query == "fail" {
+			return "", errors.New("syntax error")
+		}
+		// The first query is still running when the second one fails
+		if query == "q1" {
+			<-release
+		}
+		return "", nil
+	})
+	defer prefetch.Stop()
+
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	if _, err := prefetch.Plan(0); err != nil {
+		t.Fatalf("Plan(0) failed: %v", err)
+	}
+	if _, err := prefetch.Plan(1); err == nil {
+		t.Fatal("Plan(1) did not return the error")
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(started) != 2 {
+		t.Errorf("started %v, want only the queries up to the failed one", started)
+	}
+}
+
+func TestFirstAppliedQuery(t *testing.T) {
+	queries := []string{"SELECT 1", "UPDATE users SET name = 'x'", "SELECT 2"}
+
+	if got := firstAppliedQuery(queries, ExplainOptions{Mode: explainModeAnalyze}); got != 1 {
+		t.Errorf("firstAppliedQuery() = %d, want 1", got)
+	}
+	if got := firstAppliedQuery(queries, ExplainOptions{Mode: explainModeAnalyze, Rollback: true}); got != -1 {
+		t.Errorf("firstAppliedQuery() with rollback = %d, want -1", got)
+	}
+	if got := firstAppliedQuery(queries, ExplainOptions{Mode: explainModeAnalyze, AllowedStatements: []string{"select"}}); got != -1 {
+		t.Errorf("firstAppliedQuery() with a SELECT allowlist = %d, want -1", got)
+	}
+}
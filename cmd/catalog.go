@@ -0,0 +1,105 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// columnStatsSQL reads the planner statistics for the columns of the given tables.
+// The %s placeholder receives a list of quoted table names.
+const columnStatsSQL = `SELECT tablename || '.' || attname, n_distinct, coalesce(most_common_freqs[1], 0)
+FROM pg_stats
+WHERE schemaname NOT IN ('pg_catalog', 'information_schema') AND tablename IN (%s)`
+
+// loadColumnStats queries pg_stats for the tables scanned in the plan.
+// Failures only print a warning, recommendations are then made without statistics.
+func loadColumnStats(config *Config, plan string) map[string]ColumnStats {
+	tables := planTableNames(plan)
+	if len(tables) == 0 {
+		return nil
+	}
+
+	stats, err := fetchColumnStats(config, tables)
+	if err != nil {
+		fmt.Printf("⚠️  Skipping column statistics check: %v\n", err)
+		fmt.Println("   Drop --catalog-check to skip catalog queries.")
+		return nil
+	}
+	return stats
+}
+
+// fetchColumnStats runs columnStatsSQL and parses its unaligned output
+func fetchColumnStats(config *Config, tables []string) (map[string]ColumnStats, error) {
+	quoted := make([]string, 0, len(tables))
+	for _, table := range tables {
+		quoted = append(quoted, quoteLiteral(table))
+	}
+
+	output, err := runPsql(config, []string{"-q", "-A", "-t", "-F", "|", "-c", fmt.Sprintf(columnStatsSQL, strings.Join(quoted, ", "))})
+	if err != nil {
+		return nil, fmt.Errorf("unable to read pg_stats: %w", err)
+	}
+
+	stats := make(map[string]ColumnStats)
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Split(strings.TrimSpace(line), "|")
+		if len(fields) != 3 {
+			continue
+		}
+
+		nDistinct, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			continue
+		}
+		mostCommonFreq, _ := strconv.ParseFloat(fields[2], 64)
+
+		// The same table name can exist in several schemas, keep the first entry
+		if _, exists := stats[fields[0]]; !exists {
+			stats[fields[0]] = ColumnStats{NDistinct: nDistinct, MostCommonFreq: mostCommonFreq}
+		}
+	}
+	return stats, nil
+}
+
+// planTableNames returns the distinct tables scanned in the plan
+func planTableNames(plan string) []string {
+	var tables []string
+	for _, match := range tableNameRegex.FindAllStringSubmatch(plan, -1) {
+		if !containsString(tables, match[1]) {
+			tables = append(tables, match[1])
+		}
+	}
+	return tables
+}
+
+// serverVersionNum returns the server version as a number, e.g. 160002 for 16.2
+func serverVersionNum(config *Config) (int, error) {
+	output, err := runPsql(config, []string{"-q", "-A", "-t", "-c", "SHOW server_version_num"})
+	if err != nil {
+		return 0, fmt.Errorf("unable to read the server version: %w", err)
+	}
+
+	version, err := strconv.Atoi(strings.TrimSpace(output))
+	if err != nil {
+		return 0, fmt.Errorf("unable to parse the server version %q", strings.TrimSpace(output))
+	}
+	return version, nil
+}
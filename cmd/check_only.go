@@ -0,0 +1,48 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+)
+
+// thresholdCheckLine returns the one-line result of --check-only, e.g.
+// "❌ FAIL: cost 1250.75 exceeds threshold 1000"
+func thresholdCheckLine(costInfo *CostInfo) string {
+	switch {
+	case costInfo.Warning != "":
+		return fmt.Sprintf("❌ FAIL: cost unavailable, %s", costInfo.Warning)
+	case costInfo.ExceedsLimit:
+		return fmt.Sprintf("❌ FAIL: cost %.2f exceeds threshold %.0f", costInfo.TotalCost, costInfo.ThresholdValue)
+	}
+	return fmt.Sprintf("✅ PASS: cost %.2f is within threshold %.0f", costInfo.TotalCost, costInfo.ThresholdValue)
+}
+
+// runThresholdCheck prints the pass/fail line of --check-only and exits with strictExitCode
+// when the plan's cost exceeds the threshold or could not be read. With strict, every
+// analyzer warning fails the check, as with --strict.
+func runThresholdCheck(costInfo *CostInfo, analysisPlan string, strict bool) {
+	fmt.Println(thresholdCheckLine(costInfo))
+	if strict {
+		exitOnStrictViolations(strictViolations(costInfo, analysisPlan))
+	}
+	if costInfo.Warning != "" || costInfo.ExceedsLimit {
+		os.Exit(strictExitCode)
+	}
+}
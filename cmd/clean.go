@@ -0,0 +1,122 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var cleanCmd = &cobra.Command{
+	Use:   "clean",
+	Short: "Delete old report files generated by pg_explain",
+	Long: `Delete the report files pg_explain writes (Plan_*, Query_*, Comparison_*, Recommendations_* and Batch_*) that are
+older than --older-than. Only files whose whole name follows pg_explain's naming scheme are
+touched, and subdirectories are left alone.
+
+Example:
+  pg_explain clean --dry-run
+  pg_explain clean --older-than 24h --dir reports/`,
+	Args: cobra.NoArgs,
+	Run:  runClean,
+}
+
+func runClean(cmd *cobra.Command, args []string) {
+	dir, _ := cmd.Flags().GetString("dir")
+	olderThan, _ := cmd.Flags().GetDuration("older-than")
+	if olderThan < 0 {
+		logErrorAndExit("Invalid --older-than value", fmt.Errorf("expected a positive duration, got %s", olderThan))
+	}
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		logErrorAndExit("Invalid --dir value", err)
+	}
+	files, err := generatedFilesOlderThan(absDir, olderThan)
+	if err != nil {
+		logErrorAndExit("Failed to list generated files: ", err)
+	}
+
+	fmt.Printf("\n🧹 Generated files older than %s in %s\n", olderThan, absDir)
+	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	if len(files) == 0 {
+		fmt.Println("✨ Nothing to clean")
+		fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+		fmt.Println()
+		return
+	}
+
+	deleted := 0
+	for _, file := range files {
+		if dryRun {
+			fmt.Printf("   Would delete %s\n", filepath.Base(file))
+			continue
+		}
+		if err := os.Remove(file); err != nil {
+			fmt.Printf("   ⚠️  Unable to delete %s: %v\n", filepath.Base(file), err)
+			continue
+		}
+		fmt.Printf("   🗑️  %s\n", filepath.Base(file))
+		deleted++
+	}
+
+	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	if dryRun {
+		fmt.Printf("🔍 Dry run: %d file(s) would be deleted, run without --dry-run to delete them\n", len(files))
+	} else {
+		fmt.Printf("✅ Deleted %d of %d file(s)\n", deleted, len(files))
+	}
+	fmt.Println()
+}
+
+// generatedFilesOlderThan returns the files in dir named like pg_explain output and last
+// modified more than age ago. Symlinks and other non-regular files are skipped.
+func generatedFilesOlderThan(dir string, age time.Duration) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	cutoff := time.Now().Add(-age)
+	var files []string
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || !generatedFileRegex.MatchString(entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		if info.ModTime().Before(cutoff) {
+			files = append(files, filepath.Join(dir, entry.Name()))
+		}
+	}
+	return files, nil
+}
+
+func init() {
+	cleanCmd.Flags().String("dir", ".", "Directory to clean")
+	cleanCmd.Flags().Duration("older-than", 7*24*time.Hour, "Only delete files last modified longer ago than this, e.g. 24h (0 = all generated files)")
+	cleanCmd.Flags().Bool("dry-run", false, "List the files that would be deleted without deleting them")
+	rootCmd.AddCommand(cleanCmd)
+}
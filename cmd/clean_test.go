@@ -0,0 +1,60 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import "testing"
+
+func TestGeneratedFileRegex(t *testing.T) {
+	tests := []struct {
+		name  string
+		match bool
+	}{
+		{"Plan_Created_on_January_11th_2026_16:20:00.html", true},
+		{"Plan_Created_on_January_1th_2026_16:20:00_UTC.json", true},
+		{"Plan_Created_on_January_11th_2026_16:20:00.slack.json", true},
+		{"Plan_Created_on_January_11th_2026_16:20:00.mmd", true},
+		{"Query_3_Plan_Created_on_January_11th_2026_16:20:00_UTC.json", true},
+		{"Query_12_Plan_Created_on_January_11th_2026_16:20:00.sql", true},
+		{"Recommendations_Plan_Created_on_January_11th_2026_16:20:00.md", true},
+		{"Comparison_Plan_Created_on_January_11th_2026_16:20:00.github.md", true},
+		{"Comparison_Plan_Created_on_January_11th_2026_16:20:00_UTC_plan1.html", true},
+		{"Comparison_Plan_Created_on_January_11th_2026_16:20:00_plan2.html", true},
+		{"Comparison_Plan_Created_on_January_11th_2026_16:20:00_operations.csv", true},
+		{"Batch_queries_2026-01-11_16-20-00.markdown.md", true},
+		{"Batch_nightly_run_2026-01-11_16-20-00_UTC.html", true},
+
+		{"notes.md", false},
+		{"Plan_notes.txt", false},
+		{"Plan_Created_on_x.html", false},
+		{"Plan_Created_on_January_11th_2026_16:20:00", false},
+		{"Plan_Created_on_January_11th_2026_16:20:00.html.bak", false},
+		{"Plan_Created_on_January_11th_2026_16:20:00_plan1.html", false},
+		{"Query_x_Plan_Created_on_January_11th_2026_16:20:00.json", false},
+		{"Recommendations_notes.md", false},
+		{"Comparison_foo.txt", false},
+		{"Comparison_Plan_Created_on_January_11th_2026_16:20:00_plan3.html", false},
+		{"Batch_queries.sql", false},
+		{"queries.sql", false},
+	}
+
+	for _, tt := range tests {
+		if got := generatedFileRegex.MatchString(tt.name); got != tt.match {
+			t.Errorf("generatedFileRegex.MatchString(%q) = %v, want %v", tt.name, got, tt.match)
+		}
+	}
+}
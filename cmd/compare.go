@@ -20,7 +20,11 @@ package cmd
 import (
 	"bufio"
 	"fmt"
+	"html"
+	"html/template"
+	"io"
 	"math"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -32,130 +36,432 @@ import (
 var compareCmd = &cobra.Command{
 	Use:   "compare [QUERY1] [QUERY2]",
 	Short: "Compare execution plans of two SQL queries",
-	Long:  "Generate and compare execution plans for two queries side-by-side to identify performance differences",
-	Args:  cobra.MaximumNArgs(2),
-	Run:   runCompare,
+	Long: `Generate and compare execution plans for two queries side-by-side to identify performance differences.
+
+With --profile1 and --profile2 a single query is run against two connection profiles
+from the configuration file, e.g. to find out whether it plans differently in staging and production.`,
+	Args: cobra.MaximumNArgs(2),
+	Run:  runCompare,
 }
 
 type ComparisonResult struct {
-	Query1        string     `json:"query1"`
-	Query2        string     `json:"query2"`
-	Plan1         string     `json:"plan1"`
-	Plan2         string     `json:"plan2"`
-	Cost1         *CostInfo  `json:"cost_analysis1"`
-	Cost2         *CostInfo  `json:"cost_analysis2"`
-	Winner        string     `json:"winner"`
-	CostDiff      float64    `json:"cost_difference"`
-	CostDiffPct   float64    `json:"cost_difference_percentage"`
+	Query1         string    `json:"query1"`
+	Query2         string    `json:"query2"`
+	Plan1          string    `json:"plan1"`
+	Plan2          string    `json:"plan2"`
+	Cost1          *CostInfo `json:"cost_analysis1"`
+	Cost2          *CostInfo `json:"cost_analysis2"`
+	Winner         string    `json:"winner"`
+	CostDiff       float64   `json:"cost_difference"`
+	CostDiffPct    float64   `json:"cost_difference_percentage"`
 	Recommendation string    `json:"recommendation"`
+	Verdict        *Verdict  `json:"verdict"`
+	Label1         string    `json:"label1"`
+	Label2         string    `json:"label2"`
+	Metric         string    `json:"metric"`
+	MetricValue1   float64   `json:"metric_value1"`
+	MetricValue2   float64   `json:"metric_value2"`
+	MetricDiff     float64   `json:"metric_difference"`
+	MetricDiffPct  float64   `json:"metric_difference_percentage"`
+	// PlanFile1 and PlanFile2 are the interactive pev2 reports of each plan written by --pev2
+	PlanFile1 string `json:"plan_file1,omitempty"`
+	PlanFile2 string `json:"plan_file2,omitempty"`
+}
+
+// Verdict is the machine-readable outcome of a comparison.
+// Winner is 1 or 2, or 0 for a tie. Confidence is "high", "low", "tie", or "unknown"
+// when a plan had no cost estimates to compare.
+// Margin is the difference in the compared metric as a percentage of the more expensive query.
+type Verdict struct {
+	Winner     int     `json:"winner"`
+	Confidence string  `json:"confidence"`
+	Margin     float64 `json:"margin_pct"`
 }
 
 func runCompare(cmd *cobra.Command, args []string) {
+	requirePsql()
+	profile1, _ := cmd.Flags().GetString("profile1")
+	profile2, _ := cmd.Flags().GetString("profile2")
+	compareProfiles := profile1 != "" || profile2 != ""
+	if compareProfiles && (profile1 == "" || profile2 == "") {
+		logErrorAndExit("Invalid profile flags", fmt.Errorf("--profile1 and --profile2 must be used together"))
+	}
+
 	// Get queries from file flags or arguments
-	query1, query2, err := getCompareQueryInput(cmd, args)
+	var query1, query2 string
+	var err error
+	if compareProfiles {
+		query1, err = getProfileCompareQueryInput(cmd, args)
+		query2 = query1
+	} else {
+		query1, query2, err = getCompareQueryInput(cmd, args)
+	}
 	if err != nil {
 		logErrorAndExit("Failed to get query input: ", err)
 	}
 
 	// Load configuration
 	config, _ := loadConfig()
+	applyServiceFlag(cmd, config)
+	promptPasswordIfRequested(cmd)
 
-	fmt.Println("\n🔬 Starting query comparison...")
-	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+	config1, config2 := config, config
+	label1, label2 := "Query 1", "Query 2"
+	if compareProfiles {
+		config1, err = configForProfile(config, profile1)
+		if err != nil {
+			logErrorAndExit("Invalid --profile1 value", err)
+		}
+		config2, err = configForProfile(config, profile2)
+		if err != nil {
+			logErrorAndExit("Invalid --profile2 value", err)
+		}
+		label1, label2 = profileLabel(config1), profileLabel(config2)
+	}
 
-	fmt.Println("🔍 Analyzing Query 1...")
-	plan1, err := generateExecutionPlan(query1, config)
+	templateFile, _ := cmd.Flags().GetString("template")
+	reportTemplate, err := loadReportTemplate(templateFile)
 	if err != nil {
-		fmt.Println("❌ Failed to analyze Query 1")
+		logErrorAndExit("Invalid --template value", err)
+	}
+
+	transaction, _ := cmd.Flags().GetBool("transaction")
+	rollback, _ := cmd.Flags().GetBool("rollback")
+	verbose, _ := cmd.Flags().GetBool("verbose")
+	explainOptions := ExplainOptions{Rollback: transaction || rollback, Verbose: verbose}
+	if err := sessionOptionsFromFlags(cmd, &explainOptions); err != nil {
+		logErrorAndExit("Invalid --set, --guc or --role value", err)
+	}
+	if err := explainModeFromFlags(cmd, &explainOptions); err != nil {
+		logErrorAndExit("Invalid --explain-mode value", err)
+	}
+	if err := allowedStatementsFromFlags(cmd, config, &explainOptions); err != nil {
+		logErrorAndExit("Invalid --allowed-statements value", err)
+	}
+
+	// The GitHub comment goes to stdout, so progress messages are sent to stderr to keep it clean
+	format, _ := cmd.Flags().GetString("format")
+	outputDir, _ := cmd.Flags().GetString("output-dir")
+	var progress io.Writer = os.Stdout
+	if format == "github" && outputDir == "" {
+		progress = os.Stderr
+	}
+	explainOptions.Progress = progress
+
+	fmt.Fprintln(progress, "\n🔬 Starting query comparison...")
+	fmt.Fprint(progress, "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n\n")
+
+	warnDataModifyingQuery(query1, explainOptions)
+	warnDataModifyingQuery(query2, explainOptions)
+
+	fmt.Fprintf(progress, "🔍 Analyzing %s...\n", label1)
+	plan1, err := generateExecutionPlan(query1, config1, explainOptions)
+	if err != nil {
+		fmt.Fprintf(progress, "❌ Failed to analyze %s\n", label1)
 		logErrorAndExit("Error: ", err)
 	}
-	fmt.Println("✅ Query 1 complete!")
+	fmt.Fprintf(progress, "✅ %s complete!\n", label1)
 
-	fmt.Println("\n🔍 Analyzing Query 2...")
-	plan2, err := generateExecutionPlan(query2, config)
+	fmt.Fprintf(progress, "\n🔍 Analyzing %s...\n", label2)
+	plan2, err := generateExecutionPlan(query2, config2, explainOptions)
 	if err != nil {
-		fmt.Println("❌ Failed to analyze Query 2")
+		fmt.Fprintf(progress, "❌ Failed to analyze %s\n", label2)
 		logErrorAndExit("Error: ", err)
 	}
-	fmt.Println("✅ Query 2 complete!")
-	fmt.Println()
+	fmt.Fprintf(progress, "✅ %s complete!\n", label2)
+	fmt.Fprintln(progress)
 
+	var recommend func(winner, label1, label2 string) string
+	if compareProfiles {
+		recommend = profileRecommendation
+	}
+	result := buildComparisonResult(cmd, query1, query2, plan1, plan2, label1, label2, recommend, progress)
+	writeComparisonOutput(cmd, result, reportTemplate, progress, os.Stdout)
+}
+
+// buildComparisonResult parses the cost of both plans and decides the winner. recommend, when
+// not nil, replaces the default recommendation, e.g. to explain a cross-environment comparison.
+// Warnings about unreadable plans are written to progress.
+// It reads the --min-cost, --explain-ops, --noise-threshold and --compare-metric flags.
+func buildComparisonResult(cmd *cobra.Command, query1, query2, plan1, plan2, label1, label2 string,
+	recommend func(winner, label1, label2 string) string, progress io.Writer) *ComparisonResult {
 	// Parse costs for both queries
-	cost1 := parseCost(plan1, 0)
-	cost2 := parseCost(plan2, 0)
+	minCost, _ := cmd.Flags().GetFloat64("min-cost")
+	cost1 := parseCost(plan1, 0, minCost)
+	cost2 := parseCost(plan2, 0, minCost)
+	if explainOps, _ := cmd.Flags().GetBool("explain-ops"); explainOps {
+		explainExpensiveOps(cost1)
+		explainExpensiveOps(cost2)
+	}
+
+	metric, err := compareMetricFromFlags(cmd)
+	if err != nil {
+		logErrorAndExit("Invalid --compare-metric value", err)
+	}
+	value1, err := compareMetricValue(metric, plan1, cost1)
+	if err != nil {
+		logErrorAndExit("Invalid --compare-metric value", fmt.Errorf("%s: %w", label1, err))
+	}
+	value2, err := compareMetricValue(metric, plan2, cost2)
+	if err != nil {
+		logErrorAndExit("Invalid --compare-metric value", fmt.Errorf("%s: %w", label2, err))
+	}
 
 	// Create comparison result
 	result := &ComparisonResult{
-		Query1:   query1,
-		Query2:   query2,
-		Plan1:    plan1,
-		Plan2:    plan2,
-		Cost1:    cost1,
-		Cost2:    cost2,
-		CostDiff: cost1.TotalCost - cost2.TotalCost,
+		Query1:       query1,
+		Query2:       query2,
+		Plan1:        plan1,
+		Plan2:        plan2,
+		Cost1:        cost1,
+		Cost2:        cost2,
+		CostDiff:     cost1.TotalCost - cost2.TotalCost,
+		Label1:       label1,
+		Label2:       label2,
+		Metric:       metric,
+		MetricValue1: value1,
+		MetricValue2: value2,
+		MetricDiff:   value1 - value2,
 	}
 
 	// Calculate percentage difference
 	if cost2.TotalCost != 0 {
 		result.CostDiffPct = (result.CostDiff / cost2.TotalCost) * 100
 	}
+	if value2 != 0 {
+		result.MetricDiffPct = (result.MetricDiff / value2) * 100
+	}
 
 	// Determine winner
-	if cost1.TotalCost < cost2.TotalCost {
-		result.Winner = "Query 1"
-		result.Recommendation = "Query 1 is more efficient. Consider using this approach."
-	} else if cost2.TotalCost < cost1.TotalCost {
-		result.Winner = "Query 2"
-		result.Recommendation = "Query 2 is more efficient. Consider using this approach."
+	if value1 < value2 {
+		result.Winner = label1
+		result.Recommendation = fmt.Sprintf("%s is more efficient. Consider using this approach.", label1)
+	} else if value2 < value1 {
+		result.Winner = label2
+		result.Recommendation = fmt.Sprintf("%s is more efficient. Consider using this approach.", label2)
 	} else {
 		result.Winner = "Tie"
-		result.Recommendation = "Both queries have similar costs. Choose based on readability and maintainability."
+		result.Recommendation = fmt.Sprintf("Both queries have similar %s. Choose based on readability and maintainability.", compareMetricPlural(metric))
+	}
+	if recommend != nil {
+		result.Recommendation = recommend(result.Winner, label1, label2)
 	}
 
-	// Output format
+	noiseThreshold, _ := cmd.Flags().GetFloat64("noise-threshold")
+	result.Verdict = computeVerdict(cost1, cost2, value1, value2, noiseThreshold)
+	if result.Verdict.Confidence == "low" {
+		result.Recommendation += " The difference is within the noise margin, so treat this verdict with low confidence."
+	}
+
+	// A plan without cost estimates has a total cost of zero, which would otherwise win every comparison
+	if cost1.Warning != "" || cost2.Warning != "" {
+		for _, side := range []struct {
+			label string
+			cost  *CostInfo
+		}{{label1, cost1}, {label2, cost2}} {
+			if side.cost.Warning != "" {
+				fmt.Fprintf(progress, "⚠️  %s: %s\n", side.label, side.cost.Warning)
+			}
+		}
+		fmt.Fprintln(progress)
+
+		result.Winner = "Unknown"
+		result.CostDiff = 0
+		result.CostDiffPct = 0
+		result.MetricDiff = 0
+		result.MetricDiffPct = 0
+		result.Verdict = &Verdict{Confidence: "unknown"}
+		result.Recommendation = "No winner could be determined because the cost of at least one plan could not be read. Check the execution plans below."
+	}
+
+	return result
+}
+
+// writeComparisonOutput posts the comparison to Slack when requested and writes it in the
+// format given by --format. Slack and pev2 progress goes to progress, and the GitHub comment
+// is printed to commentOut.
+func writeComparisonOutput(cmd *cobra.Command, result *ComparisonResult, reportTemplate *template.Template, progress, commentOut io.Writer) {
 	format, _ := cmd.Flags().GetString("format")
+	outputDir, _ := cmd.Flags().GetString("output-dir")
+	fields, err := comparisonFieldsFromFlags(cmd)
+	if err != nil {
+		logErrorAndExit("Invalid --fields value", err)
+	}
+
+	if slackWebhook, _ := cmd.Flags().GetString("slack-webhook"); slackWebhook != "" {
+		sendSlackMessage(progress, slackWebhook, slackComparisonMessage(result))
+	}
+
+	// Output format
+	maxPlanLines, _ := cmd.Flags().GetInt("max-plan-lines")
+	displayed := result
+	if annotate, _ := cmd.Flags().GetBool("annotate"); annotate {
+		displayed = annotateComparisonPlans(result)
+	}
+	pev2, _ := cmd.Flags().GetBool("pev2")
+	if outputDir != "" && (format != "text" || pev2) {
+		if err := os.MkdirAll(outputDir, 0755); err != nil {
+			logErrorAndExit("Failed to create output directory: ", err)
+		}
+	}
+	if pev2 {
+		writeComparisonPev2Plans(progress, result, outputDir)
+	}
 
 	switch format {
 	case "json":
-		writeComparisonJSON(result)
+		writeComparisonJSON(result, outputDir)
 	case "text":
-		displayComparisonText(result)
+		plain, _ := cmd.Flags().GetBool("plain")
+		displayComparisonText(truncateComparisonPlans(displayed, maxPlanLines), plain)
 	case "html":
-		writeComparisonHTML(result)
+		if reportTemplate != nil {
+			fmt.Println("💾 Rendering custom HTML template...")
+			fileName := filepath.Join(outputDir, comparisonFilePrefix+generateTitle()+".html")
+			absPath := writeTemplateReport(reportTemplate, fileName, result)
+			fmt.Println("\n" + "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+			fmt.Println("📁 Comparison report saved successfully!")
+			fmt.Printf("   %s\n", absPath)
+			fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+			fmt.Println()
+		} else {
+			writeComparisonHTML(result, outputDir)
+		}
 	case "markdown":
-		writeComparisonMarkdown(result)
+		writeComparisonMarkdown(truncateComparisonPlans(displayed, maxPlanLines), outputDir, formatComparisonFieldsMarkdown(result, fields))
 	case "csv":
-		writeComparisonCSV(result)
+		if detailed, _ := cmd.Flags().GetBool("csv-detailed"); detailed {
+			writeComparisonDetailedCSV(result, outputDir)
+		} else {
+			writeComparisonCSV(result, outputDir)
+		}
+	case "slack":
+		fmt.Println("💾 Saving comparison as a Slack message...")
+		absPath := writeJSONToFile(filepath.Join(outputDir, comparisonFilePrefix+generateTitle()+".slack.json"), slackComparisonMessage(result))
+		fmt.Println("\n" + "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+		fmt.Println("📁 Comparison saved successfully!")
+		fmt.Printf("   %s\n", absPath)
+		fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+		fmt.Println()
+	case "github":
+		writeComparisonGitHub(commentOut, truncateComparisonPlans(displayed, maxPlanLines), outputDir)
 	default:
-		logErrorAndExit("Invalid format specified", fmt.Errorf("supported formats: text, json, html, markdown, csv"))
+		logErrorAndExit("Invalid format specified", fmt.Errorf("supported formats: text, json, html, markdown, csv, github, slack"))
+	}
+}
+
+// truncateComparisonPlans returns a copy of the result with both plans shortened to maxLines
+func truncateComparisonPlans(result *ComparisonResult, maxLines int) *ComparisonResult {
+	truncated := *result
+	truncated.Plan1 = truncatePlanLines(result.Plan1, maxLines)
+	truncated.Plan2 = truncatePlanLines(result.Plan2, maxLines)
+	return &truncated
+}
+
+// computeVerdict decides the winner by the compared metric values, the total cost unless
+// --compare-metric says otherwise, and rates how trustworthy that decision is.
+// A margin below noiseThreshold percent is low confidence. When both plans include
+// execution time, the verdict is also low confidence if timing is within the noise margin
+// or points the other way.
+func computeVerdict(cost1, cost2 *CostInfo, value1, value2, noiseThreshold float64) *Verdict {
+	verdict := &Verdict{Confidence: "tie"}
+
+	maxValue := math.Max(value1, value2)
+	if maxValue == 0 || value1 == value2 {
+		return verdict
+	}
+
+	verdict.Margin = math.Abs(value1-value2) / maxValue * 100
+	verdict.Winner = 1
+	if value2 < value1 {
+		verdict.Winner = 2
+	}
+
+	verdict.Confidence = "high"
+	if verdict.Margin < noiseThreshold {
+		verdict.Confidence = "low"
+	}
+
+	if cost1.ExecutionTimeMs > 0 && cost2.ExecutionTimeMs > 0 {
+		maxTime := math.Max(cost1.ExecutionTimeMs, cost2.ExecutionTimeMs)
+		timeMargin := math.Abs(cost1.ExecutionTimeMs-cost2.ExecutionTimeMs) / maxTime * 100
+
+		timeWinner := 1
+		if cost2.ExecutionTimeMs < cost1.ExecutionTimeMs {
+			timeWinner = 2
+		}
+
+		if timeMargin < noiseThreshold || timeWinner != verdict.Winner {
+			verdict.Confidence = "low"
+		}
+	}
+
+	return verdict
+}
+
+// comparisonWinnerEmoji returns the emoji shown next to the winner of a comparison
+func comparisonWinnerEmoji(winner string) string {
+	switch winner {
+	case "Tie":
+		return "🤝"
+	case "Unknown":
+		return "❓"
 	}
+	return "🏆"
 }
 
-func displayComparisonText(result *ComparisonResult) {
+// performanceMultiplier describes how many times better the winning query is by the compared metric,
+// e.g. "Query 2 is 3.50x faster" or "Query 2 reads 2.00x fewer buffers".
+// A winner with a value of zero has no meaningful ratio, so the cost is reported as unavailable.
+func performanceMultiplier(result *ComparisonResult) string {
+	fasterLabel, fasterValue, slowerValue := result.Label2, result.MetricValue2, result.MetricValue1
+	if result.MetricDiff < 0 {
+		fasterLabel, fasterValue, slowerValue = result.Label1, result.MetricValue1, result.MetricValue2
+	}
+	if fasterValue == 0 {
+		return fmt.Sprintf("%s is better (cost unavailable: its %s is 0, so the multiplier is unavailable)", fasterLabel, strings.ToLower(compareMetricName(result.Metric)))
+	}
+	multiplier := slowerValue / fasterValue
+	switch result.Metric {
+	case compareMetricBuffers:
+		return fmt.Sprintf("%s reads %.2fx fewer buffers", fasterLabel, multiplier)
+	case compareMetricRows:
+		return fmt.Sprintf("%s processes %.2fx fewer rows", fasterLabel, multiplier)
+	}
+	return fmt.Sprintf("%s is %.2fx faster", fasterLabel, multiplier)
+}
+
+func displayComparisonText(result *ComparisonResult, plain bool) {
 	fmt.Println("\n" + strings.Repeat("=", 80))
 	fmt.Println("QUERY COMPARISON REPORT")
 	fmt.Println(strings.Repeat("=", 80))
 
 	// Query 1
-	fmt.Println("\nQuery 1:")
+	fmt.Printf("\n%s:\n", result.Label1)
 	fmt.Printf("  %s\n", result.Query1)
 	fmt.Printf("  Total Cost: %.2f\n", result.Cost1.TotalCost)
 	if len(result.Cost1.ExpensiveOps) > 0 {
 		fmt.Printf("  Most Expensive Operation: %s (%.2f)\n",
 			result.Cost1.ExpensiveOps[0].Operation,
 			result.Cost1.ExpensiveOps[0].Cost)
+		if explanation := result.Cost1.ExpensiveOps[0].Explanation; explanation != "" {
+			fmt.Printf("    ℹ️  %s\n", explanation)
+		}
 	}
 
 	fmt.Println(strings.Repeat("-", 80))
 
 	// Query 2
-	fmt.Println("\nQuery 2:")
+	fmt.Printf("\n%s:\n", result.Label2)
 	fmt.Printf("  %s\n", result.Query2)
 	fmt.Printf("  Total Cost: %.2f\n", result.Cost2.TotalCost)
 	if len(result.Cost2.ExpensiveOps) > 0 {
 		fmt.Printf("  Most Expensive Operation: %s (%.2f)\n",
 			result.Cost2.ExpensiveOps[0].Operation,
 			result.Cost2.ExpensiveOps[0].Cost)
+		if explanation := result.Cost2.ExpensiveOps[0].Explanation; explanation != "" {
+			fmt.Printf("    ℹ️  %s\n", explanation)
+		}
 	}
 
 	fmt.Println(strings.Repeat("=", 80))
@@ -165,18 +471,23 @@ func displayComparisonText(result *ComparisonResult) {
 	fmt.Println(strings.Repeat("-", 80))
 
 	// Add winner emoji
-	winnerEmoji := "🏆"
-	if result.Winner == "Tie" {
-		winnerEmoji = "🤝"
-	}
+	winnerEmoji := comparisonWinnerEmoji(result.Winner)
 	fmt.Printf("Winner: %s %s\n", winnerEmoji, result.Winner)
+	fmt.Printf("Confidence: %s (margin %.2f%%)\n", result.Verdict.Confidence, result.Verdict.Margin)
 	fmt.Printf("Cost Difference: %.2f (%.2f%%)\n", result.CostDiff, result.CostDiffPct)
+	if line := metricDifferenceLine(result); line != "" {
+		fmt.Println(line)
+	}
 
-	if result.CostDiff != 0 {
-		if result.CostDiff > 0 {
-			fmt.Printf("⚡ Query 2 is %.2fx faster\n", (result.Cost1.TotalCost / result.Cost2.TotalCost))
-		} else {
-			fmt.Printf("⚡ Query 1 is %.2fx faster\n", (result.Cost2.TotalCost / result.Cost1.TotalCost))
+	if result.MetricDiff != 0 {
+		fmt.Printf("⚡ %s\n", performanceMultiplier(result))
+	}
+
+	// Without cost estimates for both plans there is nothing to chart
+	if result.Winner != "Unknown" {
+		fmt.Println()
+		for _, line := range formatCostBars(comparisonCostBars(result), plain) {
+			fmt.Println(line)
 		}
 	}
 
@@ -187,41 +498,43 @@ func displayComparisonText(result *ComparisonResult) {
 	fmt.Println("\nDETAILED EXECUTION PLANS")
 	fmt.Println(strings.Repeat("-", 80))
 
-	fmt.Println("\n[Query 1 Execution Plan]")
-	fmt.Println(result.Plan1)
+	plan1, plan2 := result.Plan1, result.Plan2
+	if !plain {
+		plan1, plan2 = colorizePlan(plan1, result.Cost1), colorizePlan(plan2, result.Cost2)
+	}
+
+	fmt.Printf("\n[%s Execution Plan]\n", result.Label1)
+	fmt.Println(plan1)
 
 	fmt.Println("\n" + strings.Repeat("-", 80))
-	fmt.Println("\n[Query 2 Execution Plan]")
-	fmt.Println(result.Plan2)
+	fmt.Printf("\n[%s Execution Plan]\n", result.Label2)
+	fmt.Println(plan2)
 
 	fmt.Println(strings.Repeat("=", 80) + "\n")
 }
 
-func writeComparisonJSON(result *ComparisonResult) {
+func writeComparisonJSON(result *ComparisonResult, outputDir string) {
 	fmt.Println("💾 Saving comparison as JSON...")
 	title := generateTitle()
-	fileName := fmt.Sprintf("Comparison_%s.json", title)
+	fileName := filepath.Join(outputDir, comparisonFilePrefix+title+".json")
 
-	writeJSONToFile(fileName, result)
+	absPath := writeJSONToFile(fileName, result)
 
 	fmt.Println("\n" + "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
 	fmt.Println("📁 Comparison saved successfully!")
-	fmt.Printf("   %s\n", fileName)
+	fmt.Printf("   %s\n", absPath)
 
 	// Show quick summary
-	winnerEmoji := "🏆"
-	if result.Winner == "Tie" {
-		winnerEmoji = "🤝"
-	}
-	fmt.Printf("\n%s Winner: %s (Cost diff: %.2f%%)\n", winnerEmoji, result.Winner, result.CostDiffPct)
-	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+	winnerEmoji := comparisonWinnerEmoji(result.Winner)
+	fmt.Printf("\n%s Winner: %s (Cost diff: %.2f%%, confidence: %s)\n", winnerEmoji, result.Winner, result.CostDiffPct, result.Verdict.Confidence)
+	fmt.Print("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n\n")
 }
 
-func writeComparisonHTML(result *ComparisonResult) {
+func writeComparisonHTML(result *ComparisonResult, outputDir string) {
 	fmt.Println("💾 Generating visual comparison report...")
 
 	title := generateTitle()
-	fileName := fmt.Sprintf("Comparison_%s.html", title)
+	fileName := filepath.Join(outputDir, comparisonFilePrefix+title+".html")
 
 	// Determine winner styling
 	winnerEmoji := "🏆"
@@ -229,18 +542,15 @@ func writeComparisonHTML(result *ComparisonResult) {
 	if result.Winner == "Tie" {
 		winnerEmoji = "🤝"
 		winnerClass = "tie"
+	} else if result.Winner == "Unknown" {
+		winnerEmoji = "❓"
+		winnerClass = "tie"
 	}
 
 	// Calculate performance multiplier
 	var perfMultiplier string
-	if result.CostDiff != 0 {
-		if result.CostDiff > 0 {
-			mult := result.Cost1.TotalCost / result.Cost2.TotalCost
-			perfMultiplier = fmt.Sprintf("Query 2 is %.2fx faster", mult)
-		} else {
-			mult := result.Cost2.TotalCost / result.Cost1.TotalCost
-			perfMultiplier = fmt.Sprintf("Query 1 is %.2fx faster", mult)
-		}
+	if result.MetricDiff != 0 {
+		perfMultiplier = html.EscapeString(performanceMultiplier(result))
 	} else {
 		perfMultiplier = "Both queries have identical cost"
 	}
@@ -370,6 +680,13 @@ func writeComparisonHTML(result *ComparisonResult) {
             overflow-y: auto;
             border: 1px solid #dee2e6;
         }
+        .pev2-link {
+            display: inline-block;
+            margin-top: 15px;
+            color: #667eea;
+            font-weight: bold;
+            text-decoration: none;
+        }
 
         /* Stats Cards */
         .stats-grid {
@@ -449,10 +766,10 @@ func writeComparisonHTML(result *ComparisonResult) {
             <h2 class="text-center mb-4">📊 Cost Analysis</h2>
             <div class="cost-bar-container">
                 <div class="cost-bar cost-bar-1" style="width: %.2f%%%%">
-                    Query 1: %.2f
+                    %s: %.2f
                 </div>
                 <div class="cost-bar cost-bar-2" style="width: %.2f%%%%">
-                    Query 2: %.2f
+                    %s: %.2f
                 </div>
             </div>
 
@@ -481,19 +798,20 @@ func writeComparisonHTML(result *ComparisonResult) {
         <div class="comparison-grid">
             <!-- Query 1 -->
             <div class="query-panel">
-                <h3>Query 1</h3>
+                <h3>%s</h3>
                 <div class="query-sql">%s</div>
                 <div class="stat-card">
                     <div class="stat-label">Total Cost</div>
                     <div class="stat-value">%.2f</div>
                 </div>`,
-		winnerClass, winnerEmoji, result.Winner,
-		cost1Width, result.Cost1.TotalCost,
-		cost2Width, result.Cost2.TotalCost,
+		winnerClass, winnerEmoji, html.EscapeString(result.Winner),
+		cost1Width, html.EscapeString(result.Label1), result.Cost1.TotalCost,
+		cost2Width, html.EscapeString(result.Label2), result.Cost2.TotalCost,
 		math.Abs(result.CostDiff),
 		math.Abs(result.CostDiffPct),
 		perfMultiplier,
-		result.Recommendation,
+		html.EscapeString(result.Recommendation),
+		html.EscapeString(result.Label1),
 		result.Query1,
 		result.Cost1.TotalCost)
 
@@ -505,7 +823,13 @@ func writeComparisonHTML(result *ComparisonResult) {
 		for _, op := range result.Cost1.ExpensiveOps {
 			if len(result.Cost1.ExpensiveOps) <= 3 {
 				htmlContent += fmt.Sprintf(`
-                    <span class="op-badge">%s (%.2f)</span>`, op.Operation, op.Cost)
+                    <span class="op-badge" title="%s">%s (%.2f)</span>`, html.EscapeString(op.Explanation), op.Operation, op.Cost)
+			}
+		}
+		for _, op := range result.Cost1.ExpensiveOps {
+			if op.Explanation != "" && len(result.Cost1.ExpensiveOps) <= 3 {
+				htmlContent += fmt.Sprintf(`
+                    <small class="d-block text-muted mt-1"><strong>%s:</strong> %s</small>`, op.Operation, html.EscapeString(op.Explanation))
 			}
 		}
 		htmlContent += `
@@ -514,18 +838,20 @@ func writeComparisonHTML(result *ComparisonResult) {
 
 	htmlContent += fmt.Sprintf(`
                 <h4 class="mt-4 mb-3">Execution Plan:</h4>
-                <div class="execution-plan">%s</div>
+                <div class="execution-plan">%s</div>%s
             </div>
 
             <!-- Query 2 -->
             <div class="query-panel">
-                <h3>Query 2</h3>
+                <h3>%s</h3>
                 <div class="query-sql">%s</div>
                 <div class="stat-card">
                     <div class="stat-label">Total Cost</div>
                     <div class="stat-value">%.2f</div>
                 </div>`,
-		result.Plan1,
+		planForHTML(result.Plan1),
+		pev2LinkHTML(result.PlanFile1),
+		html.EscapeString(result.Label2),
 		result.Query2,
 		result.Cost2.TotalCost)
 
@@ -537,7 +863,13 @@ func writeComparisonHTML(result *ComparisonResult) {
 		for _, op := range result.Cost2.ExpensiveOps {
 			if len(result.Cost2.ExpensiveOps) <= 3 {
 				htmlContent += fmt.Sprintf(`
-                    <span class="op-badge">%s (%.2f)</span>`, op.Operation, op.Cost)
+                    <span class="op-badge" title="%s">%s (%.2f)</span>`, html.EscapeString(op.Explanation), op.Operation, op.Cost)
+			}
+		}
+		for _, op := range result.Cost2.ExpensiveOps {
+			if op.Explanation != "" && len(result.Cost2.ExpensiveOps) <= 3 {
+				htmlContent += fmt.Sprintf(`
+                    <small class="d-block text-muted mt-1"><strong>%s:</strong> %s</small>`, op.Operation, html.EscapeString(op.Explanation))
 			}
 		}
 		htmlContent += `
@@ -546,12 +878,12 @@ func writeComparisonHTML(result *ComparisonResult) {
 
 	htmlContent += fmt.Sprintf(`
                 <h4 class="mt-4 mb-3">Execution Plan:</h4>
-                <div class="execution-plan">%s</div>
+                <div class="execution-plan">%s</div>%s
             </div>
         </div>
     </div>
 </body>
-</html>`, result.Plan2)
+</html>`, planForHTML(result.Plan2), pev2LinkHTML(result.PlanFile2))
 
 	file, err := os.Create(fileName)
 	if err != nil {
@@ -577,7 +909,30 @@ func writeComparisonHTML(result *ComparisonResult) {
 	fmt.Printf("\n%s Winner: %s\n", winnerEmoji, result.Winner)
 	fmt.Printf("Cost Difference: %.2f (%.2f%%)\n", math.Abs(result.CostDiff), math.Abs(result.CostDiffPct))
 	fmt.Println("\n💡 Tip: Open this file in your browser to view the interactive visual diff")
-	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+	fmt.Print("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n\n")
+}
+
+// writeComparisonPev2Plans writes each plan as a standalone interactive pev2 report, the same
+// as 'analyze -f html', and records the files in the result so the comparison report links them
+func writeComparisonPev2Plans(out io.Writer, result *ComparisonResult, outputDir string) {
+	fmt.Fprintln(out, "💾 Generating interactive pev2 reports of both plans...")
+	title := generateTitle()
+	result.PlanFile1 = writePlan(result.Plan1, result.Query1, filepath.Join(outputDir, comparisonFilePrefix+title+comparisonPlan1Suffix), result.Cost1)
+	result.PlanFile2 = writePlan(result.Plan2, result.Query2, filepath.Join(outputDir, comparisonFilePrefix+title+comparisonPlan2Suffix), result.Cost2)
+	fmt.Fprintf(out, "   %s: %s\n", result.Label1, result.PlanFile1)
+	fmt.Fprintf(out, "   %s: %s\n", result.Label2, result.PlanFile2)
+	fmt.Fprintln(out)
+}
+
+// pev2LinkHTML links a pev2 report from the comparison report, or returns "" without one. Both
+// reports are written to the same directory, so the link is relative and survives moving them.
+func pev2LinkHTML(planFile string) string {
+	if planFile == "" {
+		return ""
+	}
+	return fmt.Sprintf(`
+                <a class="pev2-link" href="%s" target="_blank">🔎 Open the interactive plan (pev2) →</a>`,
+		html.EscapeString("./"+url.PathEscape(filepath.Base(planFile))))
 }
 
 // getCompareQueryInput retrieves two SQL queries from various input sources
@@ -645,6 +1000,42 @@ func getCompareQueryInput(cmd *cobra.Command, args []string) (string, string, er
 	return query1, query2, err
 }
 
+// getProfileCompareQueryInput retrieves the single query run against both profiles
+// Priority: --file1 flag > the usual analyze input sources (stdin, argument, --editor, prompt)
+func getProfileCompareQueryInput(cmd *cobra.Command, args []string) (string, error) {
+	if len(args) > 1 {
+		return "", fmt.Errorf("comparing profiles takes a single query, got %d", len(args))
+	}
+
+	file1, _ := cmd.Flags().GetString("file1")
+	if file1 != "" {
+		content, err := os.ReadFile(file1)
+		if err != nil {
+			return "", fmt.Errorf("failed to read file1 %s: %w", file1, err)
+		}
+		query := strings.TrimSpace(string(content))
+		if query == "" {
+			return "", fmt.Errorf("file1 %s is empty", file1)
+		}
+		return query, nil
+	}
+
+	return getQueryInput(cmd, args)
+}
+
+// profileRecommendation explains a cross-environment comparison, where the query is the same
+// and any difference comes from the data, statistics, indexes or settings of the databases
+func profileRecommendation(winner, label1, label2 string) string {
+	switch winner {
+	case label1:
+		return fmt.Sprintf("The plan on %s is cheaper. Compare indexes, table statistics and planner settings on %s.", label1, label2)
+	case label2:
+		return fmt.Sprintf("The plan on %s is cheaper. Compare indexes, table statistics and planner settings on %s.", label2, label1)
+	default:
+		return "Both databases produce plans with the same cost."
+	}
+}
+
 // getQueryFromEditorCompare opens editor for compare command
 func getQueryFromEditorCompare(queryName string) (string, error) {
 	editor := os.Getenv("EDITOR")
@@ -729,9 +1120,34 @@ func getQueryFromPromptCompare() (string, error) {
 }
 
 func init() {
-	compareCmd.Flags().StringP("format", "f", "text", "Output format (text, json, html, markdown, or csv)")
+	compareCmd.Flags().StringP("format", "f", "text", "Output format (text, json, html, markdown, csv, github, or slack)")
+	compareCmd.Flags().String("slack-webhook", "", "Post a summary of the comparison to this Slack incoming webhook URL")
 	compareCmd.Flags().StringP("file1", "", "", "Read first SQL query from file")
 	compareCmd.Flags().StringP("file2", "", "", "Read second SQL query from file")
 	compareCmd.Flags().BoolP("editor", "e", false, "Open $EDITOR to write/paste queries")
+	compareCmd.Flags().StringP("output-dir", "o", "", "Directory to save output files (default: current directory)")
+	compareCmd.Flags().Float64("noise-threshold", 5.0, "Cost difference percentage below which the verdict is low confidence")
+	compareCmd.Flags().String("compare-metric", "cost", "Metric that decides the winner and the reported difference: cost, time, buffers, or rows (time, buffers and rows need EXPLAIN ANALYZE)")
+	compareCmd.Flags().String("template", "", "html/template file used for HTML output instead of the built-in report (receives ComparisonResult)")
+	compareCmd.Flags().Int("max-plan-lines", 0, "Truncate plans in text and Markdown output to N lines, keeping the first and last lines (0 = no limit)")
+	compareCmd.Flags().Bool("plain", false, "Print the text plans and cost bars without colors, drawing bars with # (colors are also disabled by NO_COLOR or when output is not a terminal)")
+	compareCmd.Flags().Bool("explain-ops", false, "Explain each expensive operation in plain English")
+	compareCmd.Flags().Bool("pev2", false, "Also write each plan as an interactive pev2 HTML report, linked from the HTML comparison report")
+	compareCmd.Flags().Bool("csv-detailed", false, "With --format csv, write one row per expensive operation of each query instead of one row per comparison")
+	compareCmd.Flags().String("fields", "", "Comma-separated rows of the Markdown \"Detailed Comparison\" table, in order: total_cost, top_operation, top_op_cost, execution_time, planning_time, buffers, rows, io_time, grade, node_count, max_depth (default: total_cost,top_operation,top_op_cost)")
+	compareCmd.Flags().Bool("annotate", false, "Annotate each node of the text, Markdown and GitHub plans with its share of the total cost, e.g. (34%)")
+	compareCmd.Flags().Float64("min-cost", 0, "Minimum operation cost to list as an expensive operation")
+	compareCmd.Flags().Bool("transaction", false, "Run each EXPLAIN ANALYZE inside a transaction that is rolled back, discarding data changes")
+	compareCmd.Flags().Bool("rollback", false, "Alias for --transaction")
+	compareCmd.Flags().StringSlice("allowed-statements", nil, "Statement types that may be analyzed, e.g. select,execute; others are rejected before running (select, insert, update, delete, merge, create, execute, or declare)")
+	compareCmd.Flags().String("explain-mode", "auto", "How statements are explained: auto (EXPLAIN ANALYZE for queries, rolled back for data changes, plain EXPLAIN for CREATE TABLE AS) or analyze (EXPLAIN ANALYZE as given)")
+	compareCmd.Flags().Bool("verbose", false, "Run EXPLAIN VERBOSE to show the output columns of every plan node")
+	compareCmd.Flags().StringArray("set", nil, "Run SET NAME=VALUE before the EXPLAIN, e.g. --set search_path=app,public (repeatable)")
+	compareCmd.Flags().StringArray("guc", nil, "Alias for --set, e.g. --guc work_mem=256MB (repeatable)")
+	compareCmd.Flags().String("role", "", "Run SET ROLE before the EXPLAIN so the query is planned with that role's privileges")
+	compareCmd.Flags().String("profile1", "", "Run the query against this connection profile from the configuration file (requires --profile2)")
+	compareCmd.Flags().String("profile2", "", "Connection profile to compare --profile1 against")
+	compareCmd.Flags().String("service", "", "Connect with a service from the libpq connection service file (~/.pg_service.conf), overriding the database settings")
+	compareCmd.Flags().Bool("prompt-password", false, "Prompt for the database password without echoing it (instead of .pgpass or PGPASSWORD)")
 	rootCmd.AddCommand(compareCmd)
 }
\ No newline at end of file
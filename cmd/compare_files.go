@@ -0,0 +1,125 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+)
+
+var compareFilesCmd = &cobra.Command{
+	Use:   "compare-files BEFORE.json AFTER.json",
+	Short: "Compare two plans saved earlier with 'analyze --format json'",
+	Long: `Compare two execution plans saved as JSON, without connecting to a database.
+
+This is the offline sibling of compare: capture a plan before a change, such as a deploy,
+a migration or a new index, capture it again afterwards and compare the two files.
+
+Example:
+  pg_explain analyze --file report.sql -f json -o before/
+  pg_explain compare-files before/Plan_*.json after/Plan_*.json -f markdown`,
+	Args: cobra.ExactArgs(2),
+	Run:  runCompareFiles,
+}
+
+func runCompareFiles(cmd *cobra.Command, args []string) {
+	before, err := loadSavedComparisonPlan(args[0])
+	if err != nil {
+		logErrorAndExit("Invalid before plan", err)
+	}
+	after, err := loadSavedComparisonPlan(args[1])
+	if err != nil {
+		logErrorAndExit("Invalid after plan", err)
+	}
+
+	templateFile, _ := cmd.Flags().GetString("template")
+	reportTemplate, err := loadReportTemplate(templateFile)
+	if err != nil {
+		logErrorAndExit("Invalid --template value", err)
+	}
+
+	// The GitHub comment goes to stdout, so progress messages are sent to stderr to keep it clean
+	format, _ := cmd.Flags().GetString("format")
+	outputDir, _ := cmd.Flags().GetString("output-dir")
+	var progress io.Writer = os.Stdout
+	if format == "github" && outputDir == "" {
+		progress = os.Stderr
+	}
+
+	fmt.Fprintln(progress, "\n🔬 Comparing saved plans...")
+	fmt.Fprint(progress, "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n\n")
+	fmt.Fprintf(progress, "⏮️  Before: %s (saved %s)\n", filepath.Base(args[0]), formatTimestamp(before.GeneratedAt))
+	fmt.Fprintf(progress, "⏭️  After:  %s (saved %s)\n", filepath.Base(args[1]), formatTimestamp(after.GeneratedAt))
+	if fingerprintQuery(before.Query) != fingerprintQuery(after.Query) {
+		fmt.Fprintln(progress, "⚠️  The plans were recorded for different queries, so the costs may not be comparable")
+	}
+	fmt.Fprintln(progress)
+
+	result := buildComparisonResult(cmd, before.Query, after.Query,
+		planForAnalysis(before.ExecutionPlan, before.ExplainFormat), planForAnalysis(after.ExecutionPlan, after.ExplainFormat),
+		"Before", "After", planChangeRecommendation, progress)
+	writeComparisonOutput(cmd, result, reportTemplate, progress, os.Stdout)
+}
+
+// loadSavedComparisonPlan reads a JSON plan for compare-files. Plans saved with --omit-plan
+// have nothing to compare, as the cost is parsed from the execution plan.
+func loadSavedComparisonPlan(path string) (*PlanOutput, error) {
+	output, err := loadPlanOutput(path)
+	if err != nil {
+		return nil, err
+	}
+	if output.ExecutionPlan == "" {
+		return nil, fmt.Errorf("%s has no execution plan, was it saved with --omit-plan?", path)
+	}
+	return output, nil
+}
+
+// planChangeRecommendation explains a before/after comparison, where a cheaper "After"
+// plan means the change helped and a cheaper "Before" plan means it caused a regression
+func planChangeRecommendation(winner, label1, label2 string) string {
+	switch winner {
+	case label1:
+		return fmt.Sprintf("The plan got more expensive. Check what changed between %s and %s: indexes, statistics, settings or the query itself.", label1, label2)
+	case label2:
+		return "The plan got cheaper, so the change improved this query."
+	default:
+		return "The plan cost did not change."
+	}
+}
+
+func init() {
+	compareFilesCmd.Flags().StringP("format", "f", "text", "Output format (text, json, html, markdown, csv, github, or slack)")
+	compareFilesCmd.Flags().String("slack-webhook", "", "Post a summary of the comparison to this Slack incoming webhook URL")
+	compareFilesCmd.Flags().StringP("output-dir", "o", "", "Directory to save output files (default: current directory)")
+	compareFilesCmd.Flags().Float64("noise-threshold", 5.0, "Cost difference percentage below which the verdict is low confidence")
+	compareFilesCmd.Flags().String("compare-metric", "cost", "Metric that decides the winner and the reported difference: cost, time, buffers, or rows (time, buffers and rows need EXPLAIN ANALYZE)")
+	compareFilesCmd.Flags().String("template", "", "html/template file used for HTML output instead of the built-in report (receives ComparisonResult)")
+	compareFilesCmd.Flags().Int("max-plan-lines", 0, "Truncate plans in text and Markdown output to N lines, keeping the first and last lines (0 = no limit)")
+	compareFilesCmd.Flags().Bool("plain", false, "Print the text plans and cost bars without colors, drawing bars with # (colors are also disabled by NO_COLOR or when output is not a terminal)")
+	compareFilesCmd.Flags().Bool("explain-ops", false, "Explain each expensive operation in plain English")
+	compareFilesCmd.Flags().Bool("pev2", false, "Also write each plan as an interactive pev2 HTML report, linked from the HTML comparison report")
+	compareFilesCmd.Flags().Bool("csv-detailed", false, "With --format csv, write one row per expensive operation of each query instead of one row per comparison")
+	compareFilesCmd.Flags().String("fields", "", "Comma-separated rows of the Markdown \"Detailed Comparison\" table, in order: total_cost, top_operation, top_op_cost, execution_time, planning_time, buffers, rows, io_time, grade, node_count, max_depth (default: total_cost,top_operation,top_op_cost)")
+	compareFilesCmd.Flags().Bool("annotate", false, "Annotate each node of the text, Markdown and GitHub plans with its share of the total cost, e.g. (34%)")
+	compareFilesCmd.Flags().Float64("min-cost", 0, "Minimum operation cost to list as an expensive operation")
+	rootCmd.AddCommand(compareFilesCmd)
+}
@@ -0,0 +1,178 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// Metrics accepted by --compare-metric
+const (
+	compareMetricCost    = "cost"
+	compareMetricTime    = "time"
+	compareMetricBuffers = "buffers"
+	compareMetricRows    = "rows"
+)
+
+var compareMetrics = []string{compareMetricCost, compareMetricTime, compareMetricBuffers, compareMetricRows}
+
+var (
+	// blockCountRegex matches the hit and read block counts of a "Buffers:" detail line
+	blockCountRegex = regexp.MustCompile(`(?:hit|read)=(\d+)`)
+	// actualRowsLoopsRegex captures the actual rows and loops of a node run with ANALYZE
+	actualRowsLoopsRegex = regexp.MustCompile(`\(actual(?: time=\S+)? rows=(\d+(?:\.\d+)?) loops=(\d+)\)`)
+)
+
+// compareMetricFromFlags reads --compare-metric, defaulting to cost
+func compareMetricFromFlags(cmd *cobra.Command) (string, error) {
+	metric, _ := cmd.Flags().GetString("compare-metric")
+	metric = strings.ToLower(strings.TrimSpace(metric))
+	if metric == "" {
+		return compareMetricCost, nil
+	}
+	if !containsString(compareMetrics, metric) {
+		return "", fmt.Errorf("unknown metric %q, expected one of %s", metric, strings.Join(compareMetrics, ", "))
+	}
+	return metric, nil
+}
+
+// compareMetricValue returns the value of the metric for one plan, lower being better.
+// It fails when the plan lacks the metric, e.g. execution time for a plan run without ANALYZE.
+func compareMetricValue(metric, plan string, costInfo *CostInfo) (float64, error) {
+	switch metric {
+	case compareMetricTime:
+		if costInfo.ExecutionTimeMs <= 0 {
+			return 0, fmt.Errorf("the plan has no execution time, which needs EXPLAIN ANALYZE")
+		}
+		return costInfo.ExecutionTimeMs, nil
+	case compareMetricBuffers:
+		blocks, ok := planBufferBlocks(plan)
+		if !ok {
+			return 0, fmt.Errorf("the plan has no buffer counts, which need EXPLAIN (ANALYZE, BUFFERS)")
+		}
+		return blocks, nil
+	case compareMetricRows:
+		rows, ok := planRowsProcessed(plan)
+		if !ok {
+			return 0, fmt.Errorf("the plan has no actual row counts, which need EXPLAIN ANALYZE")
+		}
+		return rows, nil
+	}
+	return costInfo.TotalCost, nil
+}
+
+// planBufferBlocks returns the blocks hit or read by the root node. Its "Buffers:" line already
+// includes the blocks of every node below it, while the Planning section is left out.
+func planBufferBlocks(plan string) (float64, bool) {
+	nodes := 0
+	for line := range planLines(plan) {
+		trimmed := strings.TrimSpace(line)
+		if costRegex.MatchString(line) {
+			nodes++
+		}
+		if nodes > 1 || strings.HasPrefix(trimmed, "Planning") {
+			break
+		}
+		if nodes == 1 && strings.HasPrefix(trimmed, "Buffers:") {
+			blocks := 0.0
+			for _, match := range blockCountRegex.FindAllStringSubmatch(trimmed, -1) {
+				count, _ := strconv.ParseFloat(match[1], 64)
+				blocks += count
+			}
+			return blocks, true
+		}
+	}
+	return 0, false
+}
+
+// planRowsProcessed returns the rows produced by all nodes together, each node's actual rows
+// multiplied by its loops, as a measure of how much work the plan did
+func planRowsProcessed(plan string) (float64, bool) {
+	rows, found := 0.0, false
+	for _, match := range actualRowsLoopsRegex.FindAllStringSubmatch(plan, -1) {
+		nodeRows, _ := strconv.ParseFloat(match[1], 64)
+		loops, _ := strconv.ParseFloat(match[2], 64)
+		rows += nodeRows * loops
+		found = true
+	}
+	return rows, found
+}
+
+// compareMetricName returns the display name of a metric, e.g. "Execution Time"
+func compareMetricName(metric string) string {
+	switch metric {
+	case compareMetricTime:
+		return "Execution Time"
+	case compareMetricBuffers:
+		return "Buffers"
+	case compareMetricRows:
+		return "Rows Processed"
+	}
+	return "Cost"
+}
+
+// formatCompareMetric formats a metric value with its unit, e.g. "12.50 ms" or "1,024 blocks"
+func formatCompareMetric(metric string, value float64) string {
+	switch metric {
+	case compareMetricTime:
+		return fmt.Sprintf("%.2f ms", value)
+	case compareMetricBuffers:
+		return formatSignedThousands(value) + " blocks"
+	case compareMetricRows:
+		return formatSignedThousands(value) + " rows"
+	}
+	return fmt.Sprintf("%.2f", value)
+}
+
+// formatSignedThousands rounds value to a whole number with thousands separators, keeping its sign
+func formatSignedThousands(value float64) string {
+	formatted := formatThousands(int64(math.Round(math.Abs(value))))
+	if value < 0 {
+		return "-" + formatted
+	}
+	return formatted
+}
+
+// metricDifferenceLine describes the difference in the compared metric when it is not cost,
+// e.g. "Execution Time Difference: 12.50 ms (25.00%)". Cost is already reported on its own line.
+func metricDifferenceLine(result *ComparisonResult) string {
+	if result.Metric == compareMetricCost || result.Winner == "Unknown" {
+		return ""
+	}
+	return fmt.Sprintf("%s Difference: %s (%.2f%%)", compareMetricName(result.Metric),
+		formatCompareMetric(result.Metric, result.MetricDiff), result.MetricDiffPct)
+}
+
+// compareMetricPlural names what is compared in a sentence, e.g. "Both queries have similar execution times"
+func compareMetricPlural(metric string) string {
+	switch metric {
+	case compareMetricTime:
+		return "execution times"
+	case compareMetricBuffers:
+		return "buffer usage"
+	case compareMetricRows:
+		return "row counts"
+	}
+	return "costs"
+}
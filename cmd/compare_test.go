@@ -0,0 +1,118 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	zeroCostPlan = `Seq Scan on empty_table  (cost=0.00..0.00 rows=1 width=4)`
+	costlyPlan   = `Seq Scan on orders  (cost=0.00..1250.00 rows=50000 width=64)`
+)
+
+// captureStdout returns what fn prints to os.Stdout
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("os.Pipe: %v", err)
+	}
+	stdout := os.Stdout
+	os.Stdout = writer
+	defer func() { os.Stdout = stdout }()
+
+	done := make(chan string)
+	go func() {
+		out, _ := io.ReadAll(reader)
+		done <- string(out)
+	}()
+	fn()
+	writer.Close()
+	return <-done
+}
+
+// readOnlyFile returns the content of the single file written to dir
+func readOnlyFile(t *testing.T, dir string) string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("expected one report in %s, got %d (%v)", dir, len(entries), err)
+	}
+	content, err := os.ReadFile(filepath.Join(dir, entries[0].Name()))
+	if err != nil {
+		t.Fatalf("reading report: %v", err)
+	}
+	return string(content)
+}
+
+func TestComparisonWithZeroCostPlan(t *testing.T) {
+	formats := map[string]func(t *testing.T, result *ComparisonResult) string{
+		"text": func(t *testing.T, result *ComparisonResult) string {
+			return captureStdout(t, func() { displayComparisonText(result, true) })
+		},
+		"html": func(t *testing.T, result *ComparisonResult) string {
+			dir := t.TempDir()
+			captureStdout(t, func() { writeComparisonHTML(result, dir) })
+			return readOnlyFile(t, dir)
+		},
+		"markdown": func(t *testing.T, result *ComparisonResult) string {
+			dir := t.TempDir()
+			captureStdout(t, func() { writeComparisonMarkdown(result, dir, "") })
+			return readOnlyFile(t, dir)
+		},
+	}
+
+	tests := []struct {
+		name         string
+		plan1, plan2 string
+		winner       string
+	}{
+		{name: "Cost1 is zero", plan1: zeroCostPlan, plan2: costlyPlan, winner: "Query 1"},
+		{name: "Cost2 is zero", plan1: costlyPlan, plan2: zeroCostPlan, winner: "Query 2"},
+	}
+
+	for _, tt := range tests {
+		for format, render := range formats {
+			t.Run(tt.name+"/"+format, func(t *testing.T) {
+				var result *ComparisonResult
+				captureStdout(t, func() {
+					result = buildComparisonResult(compareCmd, "SELECT 1", "SELECT 2", tt.plan1, tt.plan2, "Query 1", "Query 2", nil, io.Discard)
+				})
+				if result.Winner != tt.winner {
+					t.Fatalf("winner = %q, want %q", result.Winner, tt.winner)
+				}
+
+				output := render(t, result)
+				for _, invalid := range []string{"Inf", "NaN"} {
+					if strings.Contains(output, invalid) {
+						t.Errorf("%s output contains %q:\n%s", format, invalid, output)
+					}
+				}
+				want := tt.winner + " is better (cost unavailable: its cost is 0, so the multiplier is unavailable)"
+				if !strings.Contains(output, want) {
+					t.Errorf("%s output does not contain %q:\n%s", format, want, output)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,174 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// planningTimeRegex matches the "Planning Time: X ms" footer of EXPLAIN ANALYZE
+var planningTimeRegex = regexp.MustCompile(`Planning Time:\s*(\d+\.?\d*)\s*ms`)
+
+// comparisonField is a row of the "Detailed Comparison" table of Markdown comparisons. Value
+// returns the cell of one plan, or false when the plan does not have the metric.
+type comparisonField struct {
+	Name  string
+	Label string
+	Value func(plan string, costInfo *CostInfo) (string, bool)
+}
+
+// comparisonFields are the rows available to --fields, in their default order
+var comparisonFields = []comparisonField{
+	{"total_cost", "Total Cost", func(plan string, costInfo *CostInfo) (string, bool) {
+		return fmt.Sprintf("%.2f", costInfo.TotalCost), true
+	}},
+	{"top_operation", "Top Operation", func(plan string, costInfo *CostInfo) (string, bool) {
+		if len(costInfo.ExpensiveOps) == 0 {
+			return "", false
+		}
+		return escapeMarkdownSpecialChars(costInfo.ExpensiveOps[0].Operation), true
+	}},
+	{"top_op_cost", "Top Op Cost", func(plan string, costInfo *CostInfo) (string, bool) {
+		if len(costInfo.ExpensiveOps) == 0 {
+			return "", false
+		}
+		return fmt.Sprintf("%.2f", costInfo.ExpensiveOps[0].Cost), true
+	}},
+	{"execution_time", "Execution Time", func(plan string, costInfo *CostInfo) (string, bool) {
+		if costInfo.ExecutionTimeMs <= 0 {
+			return "", false
+		}
+		return fmt.Sprintf("%.2f ms", costInfo.ExecutionTimeMs), true
+	}},
+	{"planning_time", "Planning Time", func(plan string, costInfo *CostInfo) (string, bool) {
+		matches := planningTimeRegex.FindStringSubmatch(plan)
+		if matches == nil {
+			return "", false
+		}
+		planningMs, _ := strconv.ParseFloat(matches[1], 64)
+		return fmt.Sprintf("%.2f ms", planningMs), true
+	}},
+	{"buffers", "Buffers", func(plan string, costInfo *CostInfo) (string, bool) {
+		blocks, ok := planBufferBlocks(plan)
+		if !ok {
+			return "", false
+		}
+		return formatCompareMetric(compareMetricBuffers, blocks), true
+	}},
+	{"rows", "Rows Processed", func(plan string, costInfo *CostInfo) (string, bool) {
+		rows, ok := planRowsProcessed(plan)
+		if !ok {
+			return "", false
+		}
+		return formatCompareMetric(compareMetricRows, rows), true
+	}},
+	{"io_time", "I/O Time", func(plan string, costInfo *CostInfo) (string, bool) {
+		if costInfo.IOReadMs <= 0 && costInfo.IOWriteMs <= 0 {
+			return "", false
+		}
+		return fmt.Sprintf("%.2f ms", costInfo.IOReadMs+costInfo.IOWriteMs), true
+	}},
+	{"grade", "Health Grade", func(plan string, costInfo *CostInfo) (string, bool) {
+		if costInfo.Grade == "" {
+			return "", false
+		}
+		return fmt.Sprintf("%s %s", getGradeEmoji(costInfo.Grade), costInfo.Grade), true
+	}},
+	{"node_count", "Plan Nodes", func(plan string, costInfo *CostInfo) (string, bool) {
+		return strconv.Itoa(costInfo.NodeCount), true
+	}},
+	{"max_depth", "Plan Depth", func(plan string, costInfo *CostInfo) (string, bool) {
+		return strconv.Itoa(costInfo.MaxDepth), true
+	}},
+}
+
+// defaultComparisonFields are the rows of the table without --fields
+var defaultComparisonFields = []string{"total_cost", "top_operation", "top_op_cost"}
+
+// comparisonFieldsFromFlags reads --fields, a comma-separated list of comparisonFields names.
+// Commands without the flag get the default rows.
+func comparisonFieldsFromFlags(cmd *cobra.Command) ([]comparisonField, error) {
+	value, _ := cmd.Flags().GetString("fields")
+	names := defaultComparisonFields
+	if strings.TrimSpace(value) != "" {
+		names = nil
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+				names = append(names, name)
+			}
+		}
+		if len(names) == 0 {
+			return nil, fmt.Errorf("no fields selected (available: %s)", strings.Join(comparisonFieldNames(), ", "))
+		}
+	}
+
+	fields := make([]comparisonField, 0, len(names))
+	for _, name := range names {
+		field, ok := lookupComparisonField(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown field %q (available: %s)", name, strings.Join(comparisonFieldNames(), ", "))
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+func lookupComparisonField(name string) (comparisonField, bool) {
+	for _, field := range comparisonFields {
+		if field.Name == name {
+			return field, true
+		}
+	}
+	return comparisonField{}, false
+}
+
+func comparisonFieldNames() []string {
+	names := make([]string, len(comparisonFields))
+	for i, field := range comparisonFields {
+		names[i] = field.Name
+	}
+	return names
+}
+
+// formatComparisonFieldsMarkdown renders the "Detailed Comparison" table. A row is left out
+// when neither plan has the metric, e.g. execution time for plans not run with ANALYZE.
+func formatComparisonFieldsMarkdown(result *ComparisonResult, fields []comparisonField) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("| Aspect | %s | %s |\n", strings.ReplaceAll(result.Label1, "|", "\\|"), strings.ReplaceAll(result.Label2, "|", "\\|")))
+	sb.WriteString("|--------|---------|--------|\n")
+	for _, field := range fields {
+		value1, ok1 := field.Value(result.Plan1, result.Cost1)
+		value2, ok2 := field.Value(result.Plan2, result.Cost2)
+		if !ok1 && !ok2 {
+			continue
+		}
+		if !ok1 {
+			value1 = "N/A"
+		}
+		if !ok2 {
+			value2 = "N/A"
+		}
+		sb.WriteString(fmt.Sprintf("| %s | %s | %s |\n", field.Label, value1, value2))
+	}
+	return sb.String()
+}
@@ -21,23 +21,53 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/spf13/cobra"
-	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
 	Defaults struct {
-		Format    string  `yaml:"format"`
-		Threshold float64 `yaml:"threshold"`
-		Remote    bool    `yaml:"remote"`
-	} `yaml:"defaults"`
+		Format    string  `yaml:"format" json:"format" toml:"format"`
+		Threshold float64 `yaml:"threshold" json:"threshold" toml:"threshold"`
+		Remote    bool    `yaml:"remote" json:"remote" toml:"remote"`
+	} `yaml:"defaults" json:"defaults" toml:"defaults"`
 	Database struct {
-		Host     string `yaml:"host"`
-		User     string `yaml:"user"`
-		Database string `yaml:"database"`
-		Password string `yaml:"password"`
-	} `yaml:"database"`
+		Host     string `yaml:"host" json:"host" toml:"host"`
+		User     string `yaml:"user" json:"user" toml:"user"`
+		Database string `yaml:"database" json:"database" toml:"database"`
+		Password string `yaml:"password" json:"password" toml:"password"`
+		Service  string `yaml:"service" json:"service" toml:"service"`
+		PsqlPath string `yaml:"psql_path" json:"psql_path" toml:"psql_path"`
+	} `yaml:"database" json:"database" toml:"database"`
+	Recommendations struct {
+		ExcludeTables []string `yaml:"exclude_tables" json:"exclude_tables" toml:"exclude_tables"`
+		AppliedFile   string   `yaml:"applied_file" json:"applied_file" toml:"applied_file"`
+	} `yaml:"recommendations" json:"recommendations" toml:"recommendations"`
+	Timestamps struct {
+		UTC     bool `yaml:"utc" json:"utc" toml:"utc"`
+		RFC3339 bool `yaml:"rfc3339" json:"rfc3339" toml:"rfc3339"`
+	} `yaml:"timestamps" json:"timestamps" toml:"timestamps"`
+	Profiles map[string]DatabaseProfile `yaml:"profiles" json:"profiles" toml:"profiles"`
+	// Thresholds are cost thresholds per operation type, e.g. "Nested Loop": 1000
+	Thresholds map[string]float64 `yaml:"thresholds" json:"thresholds" toml:"thresholds"`
+	// AllowedStatements restricts the statement types that may be analyzed, e.g. [select]
+	AllowedStatements []string `yaml:"allowed_statements" json:"allowed_statements" toml:"allowed_statements"`
+
+	// Profile is the name of the profile applied with configForProfile, if any
+	Profile string `yaml:"-" json:"-" toml:"-"`
+	// EnvOverrides lists the PGEXPLAIN_* environment variables applied over the file, as NAME=value
+	EnvOverrides []string `yaml:"-" json:"-" toml:"-"`
+}
+
+// DatabaseProfile is a named set of connection settings, e.g. staging or production
+type DatabaseProfile struct {
+	Host     string `yaml:"host" json:"host" toml:"host"`
+	User     string `yaml:"user" json:"user" toml:"user"`
+	Database string `yaml:"database" json:"database" toml:"database"`
+	Password string `yaml:"password" json:"password" toml:"password"`
+	Service  string `yaml:"service" json:"service" toml:"service"`
 }
 
 var configCmd = &cobra.Command{
@@ -49,7 +79,7 @@ var configCmd = &cobra.Command{
 var configInitCmd = &cobra.Command{
 	Use:   "init",
 	Short: "Create a default configuration file",
-	Long:  "Generate a .pgexplainrc configuration file in your home directory",
+	Long:  "Generate a .pgexplainrc configuration file in your home directory, in YAML (default), JSON or TOML",
 	Run:   runConfigInit,
 }
 
@@ -61,7 +91,12 @@ var configShowCmd = &cobra.Command{
 }
 
 func runConfigInit(cmd *cobra.Command, args []string) {
-	configPath := getConfigPath()
+	format, _ := cmd.Flags().GetString("format")
+	format = strings.ToLower(format)
+	if !containsString(configFormats, format) {
+		logErrorAndExit("Invalid --format value", fmt.Errorf("supported formats: %s", strings.Join(configFormats, ", ")))
+	}
+	configPath := getConfigPath(format)
 
 	fmt.Println("\n⚙️  Initializing pgexplain configuration...")
 	fmt.Println()
@@ -73,7 +108,7 @@ func runConfigInit(cmd *cobra.Command, args []string) {
 		var response string
 		fmt.Scanln(&response)
 		if response != "y" && response != "Y" {
-			fmt.Println("❌ Configuration creation cancelled.\n")
+			fmt.Print("❌ Configuration creation cancelled.\n\n")
 			return
 		}
 	}
@@ -98,8 +133,35 @@ func runConfigInit(cmd *cobra.Command, args []string) {
 		defaultConfig.Database.Database = "mydb"
 	}
 
-	// Add comments to make it more user-friendly
-	configContent := `# PG Explain Configuration File
+	configContent, err := configTemplate(format, defaultConfig)
+	if err != nil {
+		logErrorAndExit("Invalid --format value", err)
+	}
+
+	err = os.WriteFile(configPath, []byte(configContent), 0644)
+	if err != nil {
+		fmt.Println("❌ Failed to create configuration file")
+		logErrorAndExit("Error: ", err)
+	}
+
+	fmt.Print("✅ Configuration file created successfully!\n\n")
+	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	fmt.Println("📝 Configuration file location:")
+	fmt.Printf("   %s\n\n", configPath)
+	fmt.Println("💡 Next steps:")
+	fmt.Println("   1. Edit the file to customize your settings")
+	fmt.Println("   2. Run 'pg_explain config show' to verify")
+	fmt.Println("   3. Start using pgexplain with your defaults!")
+	if _, activePath := findConfigFile(); activePath != "" && activePath != configPath {
+		fmt.Printf("\n   ⚠️  %s is read before this file, remove it to use the new one\n", activePath)
+	}
+	fmt.Println("\n   Note: Command-line flags will override config settings")
+	fmt.Print("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n\n")
+}
+
+// yamlConfigTemplate returns the YAML configuration file, with comments to make it more user-friendly
+func yamlConfigTemplate(defaultConfig Config) string {
+	return `# PG Explain Configuration File
 # This file contains default settings for pgexplain
 # You can override these settings using command-line flags
 
@@ -116,6 +178,41 @@ database:
   user: ` + defaultConfig.Database.User + `
   database: ` + defaultConfig.Database.Database + `
   password: ""      # Leave empty to use PGPASSWORD env var or .pgpass file
+  # service: mydb   # Connect with a service from ~/.pg_service.conf instead of the settings above
+  # psql_path: /usr/lib/postgresql/16/bin/psql  # psql binary to run instead of psql from PATH
+
+# Index recommendation settings
+recommendations:
+  exclude_tables: []  # Table patterns never recommended for indexing, e.g. ["staging_*", "tmp_"]
+  # applied_file: applied_indexes.txt  # Indexes already created or rejected, one table:column1,column2 per line
+
+# Named connection profiles, used by 'pg_explain compare --profile1 staging --profile2 production'
+# Profile settings take precedence over PGHOST, PGUSER, PGDATABASE and PGPASSWORD
+# profiles:
+#   staging:
+#     host: staging-db.internal
+#     user: postgres
+#     database: mydb
+#   production:
+#     host: prod-db.internal
+#     user: readonly
+#     database: mydb
+
+# Cost thresholds per operation type, used instead of the threshold above to decide
+# which operations are expensive, e.g. a Nested Loop is alarming long before a Sort
+# thresholds:
+#   Nested Loop: 1000
+#   Sort: 5000
+
+# Statement types that may be analyzed; anything else is rejected before it runs.
+# Use [select] on shared or production databases to forbid EXPLAIN ANALYZE of data changes.
+# Types: select, insert, update, delete, merge, create (CREATE TABLE AS), execute, declare
+# allowed_statements: [select]
+
+# Report timestamps
+timestamps:
+  utc: false        # Show timestamps in reports and file names in UTC
+  rfc3339: false    # Use RFC 3339 (2006-01-02T15:04:05Z) instead of "January 2, 2006 15:04:05"
 
 # Password Authentication (in order of priority):
 # 1. PGPASSWORD environment variable (recommended for development)
@@ -128,80 +225,145 @@ database:
 #
 # For more info: https://www.postgresql.org/docs/current/libpq-pgpass.html
 `
-
-	err := os.WriteFile(configPath, []byte(configContent), 0644)
-	if err != nil {
-		fmt.Println("❌ Failed to create configuration file")
-		logErrorAndExit("Error: ", err)
-	}
-
-	fmt.Println("✅ Configuration file created successfully!\n")
-	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
-	fmt.Println("📝 Configuration file location:")
-	fmt.Printf("   %s\n\n", configPath)
-	fmt.Println("💡 Next steps:")
-	fmt.Println("   1. Edit the file to customize your settings")
-	fmt.Println("   2. Run 'pg_explain config show' to verify")
-	fmt.Println("   3. Start using pgexplain with your defaults!")
-	fmt.Println("\n   Note: Command-line flags will override config settings")
-	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
 }
 
 func runConfigShow(cmd *cobra.Command, args []string) {
 	config, configPath := loadConfig()
 
 	if configPath == "" {
-		fmt.Println("\n❌ No configuration file found.\n")
+		fmt.Print("\n❌ No configuration file found.\n\n")
 		fmt.Println("💡 Create one by running:")
-		fmt.Println("   pg_explain config init\n")
+		fmt.Print("   pg_explain config init\n\n")
 		return
 	}
 
 	fmt.Println("\n⚙️  Current Configuration")
 	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
 	fmt.Printf("📁 File: %s\n", configPath)
-	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+	fmt.Print("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n\n")
 
 	fmt.Println("📊 Defaults:")
 	fmt.Printf("   Format:      %s\n", config.Defaults.Format)
 	fmt.Printf("   Threshold:   %.0f\n", config.Defaults.Threshold)
 	fmt.Printf("   Remote:      %v\n", config.Defaults.Remote)
+	if len(config.EnvOverrides) > 0 {
+		fmt.Printf("   Overridden:  %s\n", strings.Join(config.EnvOverrides, ", "))
+	}
 
 	fmt.Println("\n🗄️  Database:")
 	fmt.Printf("   Host:        %s\n", config.Database.Host)
 	fmt.Printf("   User:        %s\n", config.Database.User)
 	fmt.Printf("   Database:    %s\n", config.Database.Database)
+	if config.Database.Service != "" {
+		fmt.Printf("   Service:     %s\n", config.Database.Service)
+	}
+	if config.Database.PsqlPath != "" {
+		fmt.Printf("   psql:        %s\n", config.Database.PsqlPath)
+	}
+
+	if len(config.Recommendations.ExcludeTables) > 0 || config.Recommendations.AppliedFile != "" {
+		fmt.Println("\n💡 Recommendations:")
+		if len(config.Recommendations.ExcludeTables) > 0 {
+			fmt.Printf("   Exclude:     %s\n", strings.Join(config.Recommendations.ExcludeTables, ", "))
+		}
+		if config.Recommendations.AppliedFile != "" {
+			fmt.Printf("   Applied:     %s\n", config.Recommendations.AppliedFile)
+		}
+	}
+
+	if len(config.Thresholds) > 0 {
+		fmt.Println("\n⚡ Operation Thresholds:")
+		fmt.Printf("   %s\n", formatOperationThresholds(config.Thresholds))
+	}
+
+	if len(config.AllowedStatements) > 0 {
+		fmt.Println("\n🛡️  Allowed Statements:")
+		fmt.Printf("   %s\n", formatAllowedStatements(config.AllowedStatements))
+	}
+
+	if len(config.Profiles) > 0 {
+		fmt.Println("\n🌐 Profiles:")
+		names := make([]string, 0, len(config.Profiles))
+		for name := range config.Profiles {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			profile := config.Profiles[name]
+			if profile.Service != "" {
+				fmt.Printf("   %-12s service=%s\n", name+":", profile.Service)
+				continue
+			}
+			fmt.Printf("   %-12s host=%s user=%s database=%s\n", name+":", profile.Host, profile.User, profile.Database)
+		}
+	}
+
+	if config.Timestamps.UTC || config.Timestamps.RFC3339 {
+		fmt.Println("\n🕒 Timestamps:")
+		fmt.Printf("   UTC:         %v\n", config.Timestamps.UTC)
+		fmt.Printf("   RFC 3339:    %v\n", config.Timestamps.RFC3339)
+	}
 
 	fmt.Println("\n━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
 	fmt.Println("💡 Note: Command-line flags will override these settings")
-	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+	fmt.Print("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n\n")
 }
 
-func getConfigPath() string {
+// getConfigPath returns the path of the configuration file in the given format in the home directory
+func getConfigPath(format string) string {
 	home, err := os.UserHomeDir()
 	if err != nil {
-		return ".pgexplainrc"
+		return configFileName(format)
 	}
-	return filepath.Join(home, ".pgexplainrc")
+	return filepath.Join(home, configFileName(format))
 }
 
+// findConfigFile returns the first configuration file found in the home directory, then in
+// the current directory, trying each of configFileNames. Returns "" when there is none.
+func findConfigFile() (string, string) {
+	var dirs []string
+	if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, home)
+	}
+	// Try current directory as fallback
+	dirs = append(dirs, "")
+
+	for _, dir := range dirs {
+		for _, name := range configFileNames {
+			path := filepath.Join(dir, name)
+			if _, err := os.Stat(path); err == nil {
+				return configFormat(name), path
+			}
+		}
+	}
+	return "", ""
+}
+
+// loadConfig reads the configuration file, then applies the PGEXPLAIN_* environment variables.
+// Settings are resolved as flag > environment > configuration file > built-in default.
 func loadConfig() (*Config, string) {
-	// Try to load from home directory
-	configPath := getConfigPath()
+	config, configPath := readConfigFile()
+
+	applied, err := applyConfigEnv(config)
+	if err != nil {
+		logErrorAndExit("Invalid environment variable", err)
+	}
+	config.EnvOverrides = applied
+
+	return config, configPath
+}
+
+func readConfigFile() (*Config, string) {
 	config := &Config{}
 
 	// Check if file exists
-	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		// Try current directory as fallback
-		localConfigPath := ".pgexplainrc"
-		if _, err := os.Stat(localConfigPath); os.IsNotExist(err) {
-			// No config file found, return defaults
-			config.Defaults.Format = "html"
-			config.Defaults.Threshold = 0
-			config.Defaults.Remote = false
-			return config, ""
-		}
-		configPath = localConfigPath
+	format, configPath := findConfigFile()
+	if configPath == "" {
+		// No config file found, return defaults
+		config.Defaults.Format = "html"
+		config.Defaults.Threshold = 0
+		config.Defaults.Remote = false
+		return config, ""
 	}
 
 	// Read config file
@@ -214,8 +376,8 @@ func loadConfig() (*Config, string) {
 		return config, ""
 	}
 
-	// Parse YAML
-	err = yaml.Unmarshal(data, config)
+	// Parse YAML, JSON or TOML, by the file extension
+	err = unmarshalConfig(data, format, config)
 	if err != nil {
 		fmt.Printf("Warning: Failed to parse config file: %v\n", err)
 		// Return defaults on parse error
@@ -229,6 +391,7 @@ func loadConfig() (*Config, string) {
 }
 
 func init() {
+	configInitCmd.Flags().String("format", "yaml", "Configuration file format: yaml (~/.pgexplainrc), json (~/.pgexplainrc.json) or toml (~/.pgexplainrc.toml)")
 	configCmd.AddCommand(configInitCmd)
 	configCmd.AddCommand(configShowCmd)
 	rootCmd.AddCommand(configCmd)
@@ -0,0 +1,64 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Environment variables that override the defaults of the configuration file, e.g. in CI
+// where editing a file is inconvenient. Flags still take precedence over them.
+const (
+	envFormat    = "PGEXPLAIN_FORMAT"
+	envThreshold = "PGEXPLAIN_THRESHOLD"
+	envRemote    = "PGEXPLAIN_REMOTE"
+)
+
+// applyConfigEnv overrides config defaults with the PGEXPLAIN_* environment variables that are
+// set and not empty. Returns the applied variables as NAME=value.
+func applyConfigEnv(config *Config) ([]string, error) {
+	var applied []string
+
+	if value := strings.TrimSpace(os.Getenv(envFormat)); value != "" {
+		config.Defaults.Format = strings.ToLower(value)
+		applied = append(applied, envFormat+"="+value)
+	}
+
+	if value := strings.TrimSpace(os.Getenv(envThreshold)); value != "" {
+		threshold, err := strconv.ParseFloat(value, 64)
+		if err != nil || threshold < 0 {
+			return nil, fmt.Errorf("%s must be a non-negative number, got %q", envThreshold, value)
+		}
+		config.Defaults.Threshold = threshold
+		applied = append(applied, envThreshold+"="+value)
+	}
+
+	if value := strings.TrimSpace(os.Getenv(envRemote)); value != "" {
+		remote, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be true or false, got %q", envRemote, value)
+		}
+		config.Defaults.Remote = remote
+		applied = append(applied, envRemote+"="+value)
+	}
+
+	return applied, nil
+}
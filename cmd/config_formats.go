@@ -0,0 +1,182 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// configFormats lists the supported configuration file formats
+var configFormats = []string{"yaml", "json", "toml"}
+
+// configFileNames are the configuration file names looked up in each directory, in order.
+// .pgexplainrc without an extension is YAML.
+var configFileNames = []string{
+	".pgexplainrc",
+	".pgexplainrc.yaml",
+	".pgexplainrc.yml",
+	".pgexplainrc.json",
+	".pgexplainrc.toml",
+}
+
+// configFileName returns the file name 'config init' writes for a format
+func configFileName(format string) string {
+	if format == "yaml" {
+		return ".pgexplainrc"
+	}
+	return ".pgexplainrc." + format
+}
+
+// configFormat detects the format of a configuration file by its extension
+func configFormat(path string) string {
+	lower := strings.ToLower(path)
+	switch {
+	case strings.HasSuffix(lower, ".json"):
+		return "json"
+	case strings.HasSuffix(lower, ".toml"):
+		return "toml"
+	default:
+		return "yaml"
+	}
+}
+
+// unmarshalConfig parses a configuration file in the given format
+func unmarshalConfig(data []byte, format string, config *Config) error {
+	switch format {
+	case "json":
+		return json.Unmarshal(data, config)
+	case "toml":
+		return toml.Unmarshal(data, config)
+	default:
+		return yaml.Unmarshal(data, config)
+	}
+}
+
+// configTemplate returns the content 'config init' writes for a format. JSON has no comments,
+// so only the YAML and TOML files describe each setting.
+func configTemplate(format string, config Config) (string, error) {
+	switch format {
+	case "yaml":
+		return yamlConfigTemplate(config), nil
+	case "json":
+		return jsonConfigTemplate(config), nil
+	case "toml":
+		return tomlConfigTemplate(config), nil
+	default:
+		return "", fmt.Errorf("unknown format %q (supported: %s)", format, strings.Join(configFormats, ", "))
+	}
+}
+
+// quoteConfigString quotes a string for a JSON or TOML file; JSON escapes are valid TOML
+func quoteConfigString(value string) string {
+	quoted, _ := json.Marshal(value)
+	return string(quoted)
+}
+
+func jsonConfigTemplate(config Config) string {
+	return `{
+  "defaults": {
+    "format": "html",
+    "threshold": 0,
+    "remote": false
+  },
+  "database": {
+    "host": ` + quoteConfigString(config.Database.Host) + `,
+    "user": ` + quoteConfigString(config.Database.User) + `,
+    "database": ` + quoteConfigString(config.Database.Database) + `,
+    "password": ""
+  },
+  "recommendations": {
+    "exclude_tables": []
+  },
+  "timestamps": {
+    "utc": false,
+    "rfc3339": false
+  }
+}
+`
+}
+
+func tomlConfigTemplate(config Config) string {
+	return `# PG Explain Configuration File
+# This file contains default settings for pgexplain
+# You can override these settings using command-line flags
+
+# Statement types that may be analyzed; anything else is rejected before it runs.
+# Use ["select"] on shared or production databases to forbid EXPLAIN ANALYZE of data changes.
+# Types: select, insert, update, delete, merge, create (CREATE TABLE AS), execute, declare
+# TOML keys outside a [table] must come before the first table.
+# allowed_statements = ["select"]
+
+# Default settings for analyze command
+[defaults]
+format = "html"     # Output format: html, json, markdown, or csv
+threshold = 0       # Cost threshold for alerts (0 = disabled)
+remote = false      # Upload to remote server by default
+
+# Database connection settings
+# These override environment variables (PGHOST, PGUSER, PGDATABASE, PGPASSWORD)
+[database]
+host = ` + quoteConfigString(config.Database.Host) + `
+user = ` + quoteConfigString(config.Database.User) + `
+database = ` + quoteConfigString(config.Database.Database) + `
+password = ""       # Leave empty to use PGPASSWORD env var or .pgpass file
+# service = "mydb"  # Connect with a service from ~/.pg_service.conf instead of the settings above
+# psql_path = "/usr/lib/postgresql/16/bin/psql"  # psql binary to run instead of psql from PATH
+
+# Index recommendation settings
+[recommendations]
+exclude_tables = []  # Table patterns never recommended for indexing, e.g. ["staging_*", "tmp_"]
+# applied_file = "applied_indexes.txt"  # Indexes already created or rejected, one table:column1,column2 per line
+
+# Report timestamps
+[timestamps]
+utc = false         # Show timestamps in reports and file names in UTC
+rfc3339 = false     # Use RFC 3339 (2006-01-02T15:04:05Z) instead of "January 2, 2006 15:04:05"
+
+# Cost thresholds per operation type, used instead of the threshold above to decide
+# which operations are expensive, e.g. a Nested Loop is alarming long before a Sort
+# [thresholds]
+# "Nested Loop" = 1000
+# Sort = 5000
+
+# Named connection profiles, used by 'pg_explain compare --profile1 staging --profile2 production'
+# Profile settings take precedence over PGHOST, PGUSER, PGDATABASE and PGPASSWORD
+# [profiles.staging]
+# host = "staging-db.internal"
+# user = "postgres"
+# database = "mydb"
+#
+# [profiles.production]
+# host = "prod-db.internal"
+# user = "readonly"
+# database = "mydb"
+
+# Password Authentication (in order of priority):
+# 1. PGPASSWORD environment variable (recommended for development)
+# 2. password field above (not recommended - stored in plain text)
+# 3. .pgpass file (recommended for production)
+#
+# For more info: https://www.postgresql.org/docs/current/libpq-pgpass.html
+`
+}
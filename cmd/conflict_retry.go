@@ -0,0 +1,66 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// transactionConflictRegex matches the errors PostgreSQL raises when a transaction is aborted
+// because of concurrent activity: deadlocks (40P01) and serialization failures (40001).
+// The transaction is rolled back by the server, so running the statements again is safe.
+var transactionConflictRegex = regexp.MustCompile(`(?i)deadlock detected|could not serialize access`)
+
+// isTransactionConflict reports whether err is a deadlock or serialization failure
+func isTransactionConflict(err error) bool {
+	return err != nil && transactionConflictRegex.MatchString(err.Error())
+}
+
+// runPsqlRetryingConflicts runs psql and, when the EXPLAIN was aborted by a deadlock or a
+// serialization failure, runs it again up to options.ConflictRetries times. Connection
+// errors are not retried here, as they are not caused by a concurrent transaction.
+func runPsqlRetryingConflicts(config *Config, psqlArgs []string, options ExplainOptions) (string, error) {
+	plan, err := runPsql(config, psqlArgs)
+	for attempt := 1; attempt <= options.ConflictRetries && isTransactionConflict(err); attempt++ {
+		fmt.Fprintf(options.progress(), "🔒 %s, retrying in %s (attempt %d/%d)...\n", transactionConflictRegex.FindString(err.Error()),
+			options.ConflictRetryDelay, attempt, options.ConflictRetries)
+		time.Sleep(options.ConflictRetryDelay)
+		plan, err = runPsql(config, psqlArgs)
+	}
+	return plan, err
+}
+
+// conflictRetryOptionsFromFlags reads --deadlock-retries and --deadlock-retry-delay into options
+func conflictRetryOptionsFromFlags(cmd *cobra.Command, options *ExplainOptions) error {
+	retries, _ := cmd.Flags().GetInt("deadlock-retries")
+	if retries < 0 {
+		return fmt.Errorf("--deadlock-retries must not be negative, got %d", retries)
+	}
+	delay, _ := cmd.Flags().GetDuration("deadlock-retry-delay")
+	if delay < 0 {
+		return fmt.Errorf("--deadlock-retry-delay must not be negative, got %s", delay)
+	}
+
+	options.ConflictRetries = retries
+	options.ConflictRetryDelay = delay
+	return nil
+}
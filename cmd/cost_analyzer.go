@@ -20,25 +20,65 @@ package cmd
 import (
 	"fmt"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 )
 
 type CostInfo struct {
-	TotalCost      float64
-	ExpensiveOps   []ExpensiveOperation
-	ExceedsLimit   bool
-	ThresholdValue float64
+	TotalCost       float64
+	ExpensiveOps    []ExpensiveOperation
+	ExceedsLimit    bool
+	ThresholdValue  float64
+	ExecutionTimeMs float64
+	CostPerMs       float64
+	Grade           string
+	NodeCount       int
+	MaxDepth        int
+	Warning         string
+	FullScans       []FullTableScan
+	WideRows        []WideRowOperation
+	Subplans        []RepeatedSubplan
+	MemoizeCaches   []MemoizeCache
+	IOReadMs        float64
+	IOWriteMs       float64
+	JITInfo         *JITInfo `json:",omitempty"`
 }
 
+// planGrades lists the health grades from best to worst
+var planGrades = []string{"A", "B", "C", "D", "F"}
+
+// noCostLinesWarning explains a CostInfo that has no cost because no plan line had a cost estimate
+const noCostLinesWarning = "No cost estimates found in the EXPLAIN output, so the plan could not be analyzed. " +
+	"This happens with COSTS OFF, unsupported or localized output, or statements EXPLAIN does not plan"
+
+// Plans with more nodes or deeper nesting than this are flagged as complex
+const (
+	complexPlanNodes = 100
+	complexPlanDepth = 10
+)
+
+// executionTimeRegex matches the "Execution Time: X ms" footer of EXPLAIN ANALYZE
+var executionTimeRegex = regexp.MustCompile(`Execution Time:\s*(\d+\.?\d*)\s*ms`)
+
 type ExpensiveOperation struct {
-	Operation string
-	Cost      float64
-	Line      string
+	Operation   string
+	Cost        float64
+	Line        string
+	Explanation string `json:",omitempty"`
+	// EstimatedBytes is the estimated rows times the row width, the data the operation produces
+	EstimatedBytes int64 `json:",omitempty"`
+	// Loops, ActualTimeMs and ActualRows are set with EXPLAIN ANALYZE. The time and rows are
+	// totals over all loops, while the plan line shows them for a single loop.
+	Loops        int64   `json:",omitempty"`
+	ActualTimeMs float64 `json:",omitempty"`
+	ActualRows   float64 `json:",omitempty"`
 }
 
-// parseCost extracts cost information from a PostgreSQL EXPLAIN plan
-func parseCost(plan string, threshold float64) *CostInfo {
+// parseCost extracts cost information from a PostgreSQL EXPLAIN plan.
+// Operations cheaper than minCost are never reported as expensive, even if they exceed the threshold.
+// With a threshold set, operation types listed in operationThresholds use their own threshold instead.
+func parseCost(plan string, threshold, minCost float64) *CostInfo {
 	costInfo := &CostInfo{
 		TotalCost:      0,
 		ExpensiveOps:   []ExpensiveOperation{},
@@ -49,8 +89,21 @@ func parseCost(plan string, threshold float64) *CostInfo {
 	// Regex to match cost in format: cost=X..Y
 	costRegex := regexp.MustCompile(`cost=(\d+\.?\d*)\.\.(\d+\.?\d*)`)
 
-	lines := strings.Split(plan, "\n")
-	for _, line := range lines {
+	// Indentation of the "->" arrow of each open ancestor node, -1 for a root node
+	var ancestors []int
+	// Nodes run with ANALYZE that are cheaper than the threshold, which may still be expensive
+	// over all their loops once the execution time is known
+	var cheapAnalyzedOps []ExpensiveOperation
+
+	for line := range planLines(plan) {
+		// Execution time is only present when the plan was generated with ANALYZE
+		if timeMatches := executionTimeRegex.FindStringSubmatch(line); len(timeMatches) > 1 {
+			if execTime, err := strconv.ParseFloat(timeMatches[1], 64); err == nil {
+				costInfo.ExecutionTimeMs = execTime
+			}
+			continue
+		}
+
 		matches := costRegex.FindStringSubmatch(line)
 		if len(matches) >= 3 {
 			totalCost, err := strconv.ParseFloat(matches[2], 64)
@@ -58,57 +111,245 @@ func parseCost(plan string, threshold float64) *CostInfo {
 				continue
 			}
 
+			// A node is nested below every preceding node whose arrow is indented less
+			indent := strings.Index(line, "->")
+			for len(ancestors) > 0 && ancestors[len(ancestors)-1] >= indent {
+				ancestors = ancestors[:len(ancestors)-1]
+			}
+			ancestors = append(ancestors, indent)
+			costInfo.NodeCount++
+			if len(ancestors) > costInfo.MaxDepth {
+				costInfo.MaxDepth = len(ancestors)
+			}
+
 			// Track the highest cost as the total query cost
 			if totalCost > costInfo.TotalCost {
 				costInfo.TotalCost = totalCost
 			}
 
-			// Identify expensive operations
-			if totalCost >= threshold {
-				operation := extractOperationType(line)
-				expensiveOp := ExpensiveOperation{
-					Operation: operation,
-					Cost:      totalCost,
-					Line:      strings.TrimSpace(line),
-				}
-				costInfo.ExpensiveOps = append(costInfo.ExpensiveOps, expensiveOp)
+			// Identify expensive operations, by the threshold of their operation type if one is set
+			operation := extractOperationType(line)
+			timeMs, rows, loops, analyzed := nodeActualTotals(line)
+			if totalCost >= thresholdForOperation(operation, threshold) && totalCost >= minCost {
+				_, _, bytes := estimatedBytes(line)
+				costInfo.ExpensiveOps = append(costInfo.ExpensiveOps, ExpensiveOperation{
+					Operation:      operation,
+					Cost:           totalCost,
+					Line:           strings.TrimSpace(line),
+					EstimatedBytes: bytes,
+					Loops:          loops,
+					ActualTimeMs:   timeMs,
+					ActualRows:     rows,
+				})
+			} else if analyzed && loops > 1 && timeMs > 0 {
+				_, _, bytes := estimatedBytes(line)
+				cheapAnalyzedOps = append(cheapAnalyzedOps, ExpensiveOperation{
+					Operation:      operation,
+					Cost:           totalCost,
+					Line:           strings.TrimSpace(line),
+					EstimatedBytes: bytes,
+					Loops:          loops,
+					ActualTimeMs:   timeMs,
+					ActualRows:     rows,
+				})
 			}
 		}
 	}
 
+	// Without a single cost line the zero total is not a measurement, so no verdict is given
+	if costInfo.NodeCount == 0 {
+		costInfo.Warning = noCostLinesWarning
+		return costInfo
+	}
+
+	selectOpsByActualTime(costInfo, cheapAnalyzedOps, threshold, minCost)
+
+	costInfo.FullScans = detectFullTableScans(plan)
+	costInfo.WideRows = detectWideRowOperations(plan)
+	costInfo.Subplans = detectRepeatedSubplans(plan)
+	costInfo.MemoizeCaches = detectMemoizeCaches(plan)
+	costInfo.IOReadMs, costInfo.IOWriteMs = parseIOTimings(plan)
+	costInfo.JITInfo = parseJIT(plan)
+
 	if costInfo.TotalCost >= threshold {
 		costInfo.ExceedsLimit = true
 	}
 
+	// Cost units are not milliseconds; the ratio only holds for this query on this server
+	if costInfo.TotalCost > 0 && costInfo.ExecutionTimeMs > 0 {
+		costInfo.CostPerMs = costInfo.TotalCost / costInfo.ExecutionTimeMs
+	}
+
+	if threshold > 0 {
+		costInfo.Grade = gradePlan(costInfo, plan)
+	}
+
 	return costInfo
 }
 
-// extractOperationType extracts the operation type from an EXPLAIN line
-func extractOperationType(line string) string {
-	trimmed := strings.TrimSpace(line)
+// selectOpsByActualTime uses the EXPLAIN ANALYZE timings of a plan to find expensive operations.
+// The cost of a node is the estimate for one loop, so an inner Index Scan run 50,000 times at cost 8
+// stays below any threshold even when it takes most of the execution time. Such a node is added
+// when its time over all loops, converted to cost units at this query's ms per cost unit, reaches
+// the threshold. The expensive operations are then ordered by their time over all loops.
+func selectOpsByActualTime(costInfo *CostInfo, candidates []ExpensiveOperation, threshold, minCost float64) {
+	if costInfo.ExecutionTimeMs <= 0 || costInfo.TotalCost <= 0 {
+		return
+	}
+
+	costPerMs := costInfo.TotalCost / costInfo.ExecutionTimeMs
+	for _, op := range candidates {
+		if loopsCost := op.ActualTimeMs * costPerMs; loopsCost >= thresholdForOperation(op.Operation, threshold) && loopsCost >= minCost {
+			costInfo.ExpensiveOps = append(costInfo.ExpensiveOps, op)
+		}
+	}
+
+	sort.SliceStable(costInfo.ExpensiveOps, func(i, j int) bool {
+		return costInfo.ExpensiveOps[i].ActualTimeMs > costInfo.ExpensiveOps[j].ActualTimeMs
+	})
+}
+
+// planComplexityWarning describes why a plan is unusually large or deeply nested,
+// or returns "" for a plan of ordinary size
+func planComplexityWarning(costInfo *CostInfo) string {
+	if costInfo == nil {
+		return ""
+	}
 
-	// Common operation types in PostgreSQL
-	operations := []string{
-		"Seq Scan", "Index Scan", "Index Only Scan", "Bitmap Heap Scan",
-		"Bitmap Index Scan", "Nested Loop", "Hash Join", "Merge Join",
-		"Sort", "Aggregate", "Hash", "Materialize", "Gather", "Parallel Seq Scan",
+	var reasons []string
+	if costInfo.NodeCount > complexPlanNodes {
+		reasons = append(reasons, fmt.Sprintf("%d nodes (more than %d)", costInfo.NodeCount, complexPlanNodes))
+	}
+	if costInfo.MaxDepth > complexPlanDepth {
+		reasons = append(reasons, fmt.Sprintf("depth %d (more than %d)", costInfo.MaxDepth, complexPlanDepth))
 	}
+	if len(reasons) == 0 {
+		return ""
+	}
+	return "Complex plan: " + strings.Join(reasons, ", ") + ". Consider splitting the query or simplifying views and subqueries"
+}
 
-	for _, op := range operations {
-		if strings.Contains(trimmed, op) {
-			return op
+// gradePlanSignalPoints is the score a plan loses for each disk spill, row misestimate or
+// poorly hit Memoize cache, and for being disk-bound. Each kind of signal loses at most
+// twice as many points.
+const gradePlanSignalPoints = 15
+
+// gradePlan condenses the cost metrics into a health grade from A (healthy) to F.
+// The score starts at 100 and loses points for breaching the threshold, scaled by how far
+// the query is over it, and for each expensive operation. EXPLAIN ANALYZE plans also lose
+// points for sorts, hashes and aggregates that spilled to disk, severe row misestimates,
+// Memoize caches that mostly miss and I/O taking most of the execution time.
+func gradePlan(costInfo *CostInfo, plan string) string {
+	if costInfo == nil {
+		return ""
+	}
+
+	score := 100
+	if costInfo.ExceedsLimit && costInfo.ThresholdValue > 0 {
+		ratio := costInfo.TotalCost / costInfo.ThresholdValue
+		switch {
+		case ratio >= 10:
+			score -= 50
+		case ratio >= 5:
+			score -= 35
+		case ratio >= 2:
+			score -= 25
+		default:
+			score -= 15
 		}
+		score -= minInt(len(costInfo.ExpensiveOps)*5, 20)
+	}
+
+	for _, signals := range []int{len(detectDiskSpills(plan)), len(detectMisestimates(plan)), len(poorMemoizeCaches(costInfo))} {
+		score -= minInt(signals, 2) * gradePlanSignalPoints
+	}
+	if ioBoundWarning(costInfo) != "" {
+		score -= gradePlanSignalPoints
+	}
+
+	switch {
+	case score >= 90:
+		return "A"
+	case score >= 80:
+		return "B"
+	case score >= 70:
+		return "C"
+	case score >= 60:
+		return "D"
+	default:
+		return "F"
+	}
+}
+
+// gradeColor returns the HTML color used to display a health grade
+func gradeColor(grade string) string {
+	switch grade {
+	case "A":
+		return "#2e7d32"
+	case "B":
+		return "#689f38"
+	case "C":
+		return "#f9a825"
+	case "D":
+		return "#ef6c00"
+	default:
+		return "#c62828"
+	}
+}
+
+// getGradeEmoji returns an emoji for a health grade
+func getGradeEmoji(grade string) string {
+	switch grade {
+	case "A", "B":
+		return "🟢"
+	case "C":
+		return "🟡"
+	case "D":
+		return "🟠"
+	default:
+		return "🔴"
 	}
+}
+
+// msPerCostUnit returns the approximate milliseconds spent per planner cost unit
+func msPerCostUnit(costInfo *CostInfo) float64 {
+	if costInfo == nil || costInfo.CostPerMs == 0 {
+		return 0
+	}
+	return 1 / costInfo.CostPerMs
+}
 
-	// If no specific operation found, extract the first few words
-	parts := strings.Fields(trimmed)
-	if len(parts) >= 2 {
-		return strings.Join(parts[:2], " ")
-	} else if len(parts) == 1 {
-		return parts[0]
+// costCalibrationNote describes the execution time per cost unit of a plan run with ANALYZE,
+// or returns an empty string when the plan has no execution time
+func costCalibrationNote(costInfo *CostInfo) string {
+	if costInfo == nil || costInfo.CostPerMs == 0 {
+		return ""
+	}
+	return fmt.Sprintf("Execution time: %.2f ms, ≈ %.2f ms per cost unit (this query). Planner cost is unitless, so this ratio is not universal.",
+		costInfo.ExecutionTimeMs, msPerCostUnit(costInfo))
+}
+
+// calibrationCostInfo returns costInfo or, when it is nil because no threshold is set, the
+// plan parsed without a threshold, so reports can still show the calibration note
+func calibrationCostInfo(costInfo *CostInfo, plan, format string) *CostInfo {
+	if costInfo != nil {
+		return costInfo
+	}
+	analysisPlan := planForAnalysis(plan, format)
+	if analysisPlan == "" {
+		return nil
 	}
+	return parseCost(analysisPlan, 0, 0)
+}
 
-	return "Unknown Operation"
+// explainExpensiveOps adds a plain-English explanation to every expensive operation
+func explainExpensiveOps(costInfo *CostInfo) {
+	if costInfo == nil {
+		return
+	}
+	for i := range costInfo.ExpensiveOps {
+		costInfo.ExpensiveOps[i].Explanation = explainOperation(costInfo.ExpensiveOps[i].Operation)
+	}
 }
 
 // displayCostAlert prints cost threshold alerts to the user
@@ -128,10 +369,41 @@ func displayCostAlert(costInfo *CostInfo) {
 		fmt.Println(strings.Repeat("-", 70))
 		for i, op := range costInfo.ExpensiveOps {
 			fmt.Printf("%d. %s (Cost: %.2f)\n", i+1, op.Operation, op.Cost)
+			if op.Loops > 1 {
+				fmt.Printf("   ⏱️  %s\n", actualTotalsSummary(op))
+			}
+			if op.Explanation != "" {
+				fmt.Printf("   ℹ️  %s\n", op.Explanation)
+			}
 			fmt.Printf("   %s\n", op.Line)
 		}
 	}
 
+	if len(costInfo.FullScans) > 0 {
+		fmt.Println(strings.Repeat("-", 70))
+		displayFullTableScans(costInfo)
+	}
+	if len(costInfo.WideRows) > 0 {
+		fmt.Println(strings.Repeat("-", 70))
+		displayWideRowOperations(costInfo)
+	}
+	if len(costInfo.Subplans) > 0 {
+		fmt.Println(strings.Repeat("-", 70))
+		displayRepeatedSubplans(costInfo)
+	}
+	if len(costInfo.MemoizeCaches) > 0 {
+		fmt.Println(strings.Repeat("-", 70))
+		displayMemoizeCaches(costInfo)
+	}
+
 	fmt.Println(strings.Repeat("=", 70))
-	fmt.Println("💡 Consider: Adding indexes, optimizing joins, or limiting result sets\n")
+	fmt.Print("💡 Consider: Adding indexes, optimizing joins, or limiting result sets\n\n")
+}
+
+// displayFullTableScans prints a warning for every likely accidental full table scan
+func displayFullTableScans(costInfo *CostInfo) {
+	for _, scan := range costInfo.FullScans {
+		fmt.Printf("🐘 %s\n", fullTableScanWarning(scan))
+		fmt.Printf("   %s\n", scan.Line)
+	}
 }
\ No newline at end of file
@@ -0,0 +1,111 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const nestedLoopAnalyzePlan = ` Nested Loop  (cost=0.42..9000.00 rows=50000 width=16) (actual time=0.050..620.000 rows=50000 loops=1)
+   ->  Seq Scan on orders o  (cost=0.00..1000.00 rows=50000 width=8) (actual time=0.010..40.000 rows=50000 loops=1)
+   ->  Index Scan using customers_pkey on customers c  (cost=0.42..8.00 rows=1 width=8) (actual time=0.010..0.011 rows=1 loops=50000)
+         Index Cond: (id = o.customer_id)
+ Planning Time: 0.200 ms
+ Execution Time: 640.000 ms`
+
+func TestParseCostSelectsOpsByTimeOverAllLoops(t *testing.T) {
+	costInfo := parseCost(nestedLoopAnalyzePlan, 5000, 0)
+
+	var operations []string
+	for _, op := range costInfo.ExpensiveOps {
+		operations = append(operations, op.Operation)
+	}
+	if len(operations) != 2 || operations[0] != "Nested Loop" || operations[1] != "Index Scan" {
+		t.Fatalf("expensive operations = %q, want the Nested Loop and the inner Index Scan", operations)
+	}
+
+	inner := costInfo.ExpensiveOps[1]
+	if inner.Loops != 50000 || inner.ActualTimeMs != 550 || inner.ActualRows != 50000 {
+		t.Errorf("inner Index Scan totals = %d loops, %.2f ms, %.0f rows, want 50000 loops, 550.00 ms, 50000 rows",
+			inner.Loops, inner.ActualTimeMs, inner.ActualRows)
+	}
+}
+
+func TestParseCostWithoutAnalyzeKeepsCostSelection(t *testing.T) {
+	plan := ` Nested Loop  (cost=0.42..9000.00 rows=50000 width=16)
+   ->  Seq Scan on orders o  (cost=0.00..1000.00 rows=50000 width=8)
+   ->  Index Scan using customers_pkey on customers c  (cost=0.42..8.00 rows=1 width=8)`
+
+	costInfo := parseCost(plan, 5000, 0)
+	if len(costInfo.ExpensiveOps) != 1 || costInfo.ExpensiveOps[0].Operation != "Nested Loop" {
+		t.Fatalf("expensive operations = %+v, want only the Nested Loop", costInfo.ExpensiveOps)
+	}
+}
+
+func TestGradePlanSignals(t *testing.T) {
+	const (
+		scan     = " Seq Scan on orders  (cost=0.00..1000.00 rows=5000 width=8) (actual time=0.010..40.000 rows=5000 loops=1)\n"
+		sort     = " Sort  (cost=1000.00..1100.00 rows=5000 width=8) (actual time=60.000..80.000 rows=5000 loops=1)\n   Sort Method: external merge  Disk: 4712kB\n"
+		hash     = "   ->  Hash  (cost=10.00..10.00 rows=100 width=4) (actual time=5.000..5.000 rows=100 loops=1)\n         Buckets: 1024  Batches: 4 (originally 1)  Memory Usage: 64kB\n"
+		estimate = " Seq Scan on orders  (cost=0.00..1000.00 rows=10 width=8) (actual time=0.010..40.000 rows=5000 loops=1)\n"
+		ioBound  = "   I/O Timings: shared read=80.000\n"
+		executed = " Execution Time: 100.000 ms\n"
+	)
+
+	tests := []struct {
+		name  string
+		plan  string
+		grade string
+	}{
+		{"healthy", scan + executed, "A"},
+		{"disk spill", sort + executed, "B"},
+		{"two disk spills", sort + hash + executed, "C"},
+		{"row misestimate", estimate + executed, "B"},
+		{"poor memoize cache", planForAnalysis(poorMemoizeJSONPlan, "json"), "B"},
+		{"disk-bound", scan + ioBound + executed, "B"},
+		{"every signal", sort + hash + estimate + ioBound + executed, "F"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			costInfo := parseCost(tt.plan, 100000, 0)
+			if costInfo.Grade != tt.grade {
+				t.Errorf("grade = %s, want %s", costInfo.Grade, tt.grade)
+			}
+		})
+	}
+}
+
+func TestCalibrationNoteWithoutThreshold(t *testing.T) {
+	planCost := calibrationCostInfo(nil, nestedLoopAnalyzePlan, "text")
+	if planCost == nil || planCost.ExecutionTimeMs != 640 {
+		t.Fatalf("calibrationCostInfo() = %+v, want an execution time of 640 ms", planCost)
+	}
+
+	fileName := writeMarkdownPlan(nestedLoopAnalyzePlan, "SELECT 1", filepath.Join(t.TempDir(), "plan"), nil, planCost, nil)
+	content, err := os.ReadFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if note := escapeMarkdownSpecialChars(costCalibrationNote(planCost)); !strings.Contains(string(content), note) {
+		t.Errorf("Markdown report without a threshold lacks the calibration note %q:\n%s", note, content)
+	}
+}
@@ -0,0 +1,123 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// costBarWidth is the number of characters of the longest bar
+const costBarWidth = 40
+
+// batchCostBarLimit is the number of queries charted in the batch summary. Larger batches
+// chart their most expensive queries.
+const batchCostBarLimit = 20
+
+// CostBar is one labelled bar of a cost chart
+type CostBar struct {
+	Label string
+	Cost  float64
+	Color string
+}
+
+// formatCostBars renders one line per bar, e.g. "Query 1  ██████████████  1250.75", with each
+// bar proportional to the highest cost. Plain charts use # and no colors, for logs and files.
+func formatCostBars(bars []CostBar, plain bool) []string {
+	maxCost, labelWidth := 0.0, 0
+	for _, bar := range bars {
+		maxCost = math.Max(maxCost, bar.Cost)
+		labelWidth = max(labelWidth, len([]rune(bar.Label)))
+	}
+
+	block := "█"
+	if plain {
+		block = "#"
+	}
+
+	lines := make([]string, 0, len(bars))
+	for _, bar := range bars {
+		length := 0
+		if maxCost > 0 {
+			length = int(math.Round(bar.Cost / maxCost * costBarWidth))
+			// A non-zero cost always gets a visible bar
+			if length == 0 && bar.Cost > 0 {
+				length = 1
+			}
+		}
+		chart := strings.Repeat(block, length) + strings.Repeat(" ", costBarWidth-length)
+		if !plain && bar.Color != "" {
+			chart = colorize(chart, bar.Color)
+		}
+		padding := strings.Repeat(" ", labelWidth-len([]rune(bar.Label)))
+		lines = append(lines, fmt.Sprintf("%s%s  %s  %.2f", bar.Label, padding, chart, bar.Cost))
+	}
+	return lines
+}
+
+// comparisonCostBars charts the cost of both queries, with the winner in green
+func comparisonCostBars(result *ComparisonResult) []CostBar {
+	bars := []CostBar{
+		{Label: result.Label1, Cost: result.Cost1.TotalCost},
+		{Label: result.Label2, Cost: result.Cost2.TotalCost},
+	}
+	switch result.Winner {
+	case result.Label1:
+		bars[0].Color = ansiGreen
+	case result.Label2:
+		bars[1].Color = ansiGreen
+	}
+	return bars
+}
+
+// batchCostBars charts the cost of every analyzed query in query order, with queries over
+// their threshold in red. Only the batchCostBarLimit most expensive are kept. The second
+// return value is the number of analyzed queries.
+func batchCostBars(results []BatchResult) ([]CostBar, int) {
+	var analyzed []BatchResult
+	for _, result := range results {
+		if result.Error != "" || result.CostAnalysis == nil || result.CostAnalysis.Warning != "" {
+			continue
+		}
+		analyzed = append(analyzed, result)
+	}
+
+	charted := analyzed
+	if len(charted) > batchCostBarLimit {
+		charted = append([]BatchResult(nil), analyzed...)
+		sort.SliceStable(charted, func(i, j int) bool {
+			return charted[i].CostAnalysis.TotalCost > charted[j].CostAnalysis.TotalCost
+		})
+		charted = charted[:batchCostBarLimit]
+		sort.SliceStable(charted, func(i, j int) bool {
+			return charted[i].QueryNumber < charted[j].QueryNumber
+		})
+	}
+
+	bars := make([]CostBar, 0, len(charted))
+	for _, result := range charted {
+		bar := CostBar{Label: fmt.Sprintf("Query %d", result.QueryNumber), Cost: result.CostAnalysis.TotalCost}
+		if result.CostAnalysis.ExceedsLimit {
+			bar.Color = ansiRed
+		}
+		bars = append(bars, bar)
+	}
+	return bars, len(analyzed)
+}
@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -23,11 +24,83 @@ func createCSVWriter(file *os.File) *csv.Writer {
 	return writer
 }
 
-// writeCSVPlan generates a CSV file for analyze command
+// csvPlanColumns lists the columns available to writeCSVPlan in their default order
+var csvPlanColumns = []string{
+	"title",
+	"query",
+	"execution_plan",
+	"total_cost",
+	"exceeds_threshold",
+	"threshold_value",
+	"expensive_ops_count",
+	"grade",
+	"generated_at",
+	"node_count",
+	"max_depth",
+}
+
+// csvBatchColumns lists the columns available to newCSVBatchWriter in their default order
+var csvBatchColumns = []string{
+	"query_number",
+	"query",
+	"execution_plan",
+	"total_cost",
+	"exceeds_threshold",
+	"grade",
+	"error",
+	"status",
+	"generated_at",
+	"index_recommendation_count",
+	"index_recommendations",
+	"error_category",
+	"node_count",
+	"max_depth",
+}
+
+// parseCSVColumns validates a comma-separated column list against the available columns.
+// An empty value selects nil, meaning the writer's default column set.
+func parseCSVColumns(value string, available []string) ([]string, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+
+	var columns []string
+	for _, column := range strings.Split(value, ",") {
+		column = strings.TrimSpace(column)
+		if column == "" {
+			continue
+		}
+		if !containsString(available, column) {
+			return nil, fmt.Errorf("unknown CSV column %q (available: %s)", column, strings.Join(available, ", "))
+		}
+		columns = append(columns, column)
+	}
+
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("no CSV columns selected (available: %s)", strings.Join(available, ", "))
+	}
+	return columns, nil
+}
+
+// selectCSVFields returns the values of the given columns in order
+func selectCSVFields(values map[string]string, columns []string) []string {
+	row := make([]string, 0, len(columns))
+	for _, column := range columns {
+		row = append(row, values[column])
+	}
+	return row
+}
+
+// writeCSVPlan generates a CSV file for analyze command.
+// Columns selects and orders the written fields; nil writes all of csvPlanColumns.
 // Returns absolute path of generated file
-func writeCSVPlan(plan, query, title string, costInfo *CostInfo) string {
+func writeCSVPlan(plan, query, title string, costInfo *CostInfo, columns []string) string {
 	fileName := title + ".csv"
 
+	if columns == nil {
+		columns = csvPlanColumns
+	}
+
 	file, err := os.Create(fileName)
 	if err != nil {
 		logErrorAndExit("unable to create CSV file: ", err)
@@ -38,17 +111,7 @@ func writeCSVPlan(plan, query, title string, costInfo *CostInfo) string {
 	defer writer.Flush()
 
 	// Write header row
-	header := []string{
-		"title",
-		"query",
-		"execution_plan",
-		"total_cost",
-		"exceeds_threshold",
-		"threshold_value",
-		"expensive_ops_count",
-		"generated_at",
-	}
-	if err := writer.Write(header); err != nil {
+	if err := writer.Write(columns); err != nil {
 		logErrorAndExit("unable to write CSV header: ", err)
 	}
 
@@ -57,26 +120,35 @@ func writeCSVPlan(plan, query, title string, costInfo *CostInfo) string {
 	exceedsThreshold := "false"
 	thresholdValue := "0"
 	expensiveOpsCount := "0"
+	grade := ""
+	nodeCount := ""
+	maxDepth := ""
 
-	if costInfo != nil {
+	if costInfo != nil && costInfo.Warning == "" {
 		totalCost = fmt.Sprintf("%.2f", costInfo.TotalCost)
 		exceedsThreshold = strconv.FormatBool(costInfo.ExceedsLimit)
 		thresholdValue = fmt.Sprintf("%.2f", costInfo.ThresholdValue)
 		expensiveOpsCount = strconv.Itoa(len(costInfo.ExpensiveOps))
+		grade = costInfo.Grade
+		nodeCount = strconv.Itoa(costInfo.NodeCount)
+		maxDepth = strconv.Itoa(costInfo.MaxDepth)
 	}
 
-	row := []string{
-		title,
-		query,
-		escapeExecutionPlan(plan),
-		totalCost,
-		exceedsThreshold,
-		thresholdValue,
-		expensiveOpsCount,
-		time.Now().Format(time.RFC3339),
+	values := map[string]string{
+		"title":               title,
+		"query":               query,
+		"execution_plan":      escapeExecutionPlan(plan),
+		"total_cost":          totalCost,
+		"exceeds_threshold":   exceedsThreshold,
+		"threshold_value":     thresholdValue,
+		"expensive_ops_count": expensiveOpsCount,
+		"grade":               grade,
+		"generated_at":        currentTime().Format(time.RFC3339),
+		"node_count":          nodeCount,
+		"max_depth":           maxDepth,
 	}
 
-	if err := writer.Write(row); err != nil {
+	if err := writer.Write(selectCSVFields(values, columns)); err != nil {
 		logErrorAndExit("unable to write CSV data: ", err)
 	}
 
@@ -90,9 +162,9 @@ func writeCSVPlan(plan, query, title string, costInfo *CostInfo) string {
 
 // writeComparisonCSV generates a CSV file for compare command
 // Returns absolute path of generated file
-func writeComparisonCSV(result *ComparisonResult) {
+func writeComparisonCSV(result *ComparisonResult, outputDir string) {
 	title := generateTitle()
-	fileName := fmt.Sprintf("Comparison_%s.csv", title)
+	fileName := filepath.Join(outputDir, comparisonFilePrefix+title+".csv")
 
 	file, err := os.Create(fileName)
 	if err != nil {
@@ -115,6 +187,9 @@ func writeComparisonCSV(result *ComparisonResult) {
 		"cost_diff",
 		"cost_diff_pct",
 		"recommendation",
+		"metric",
+		"metric_diff",
+		"metric_diff_pct",
 	}
 	if err := writer.Write(header); err != nil {
 		logErrorAndExit("unable to write CSV header: ", err)
@@ -132,6 +207,9 @@ func writeComparisonCSV(result *ComparisonResult) {
 		fmt.Sprintf("%.2f", result.CostDiff),
 		fmt.Sprintf("%.2f", result.CostDiffPct),
 		result.Recommendation,
+		result.Metric,
+		fmt.Sprintf("%.2f", result.MetricDiff),
+		fmt.Sprintf("%.2f", result.MetricDiffPct),
 	}
 
 	if err := writer.Write(row); err != nil {
@@ -151,15 +229,16 @@ func writeComparisonCSV(result *ComparisonResult) {
 	if result.CostDiff != 0 {
 		fmt.Printf("Cost Difference: %.2f (%.2f%%)\n", result.CostDiff, result.CostDiffPct)
 	}
-	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+	fmt.Print("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n\n")
 }
 
-// writeCSVBatchReport generates a CSV file for batch command (combined mode)
-// Returns absolute path of generated file
-func writeCSVBatchReport(report BatchReport, fileName string) string {
-	csvFileName := fileName + ".csv"
+// writeComparisonDetailedCSV generates a CSV file for compare command with one row per
+// expensive operation of each query, for pivoting operations in a spreadsheet
+func writeComparisonDetailedCSV(result *ComparisonResult, outputDir string) {
+	title := generateTitle()
+	fileName := filepath.Join(outputDir, comparisonFilePrefix+title+comparisonOperationsSuffix+".csv")
 
-	file, err := os.Create(csvFileName)
+	file, err := os.Create(fileName)
 	if err != nil {
 		logErrorAndExit("unable to create CSV file: ", err)
 	}
@@ -170,58 +249,200 @@ func writeCSVBatchReport(report BatchReport, fileName string) string {
 
 	// Write header row
 	header := []string{
-		"query_number",
-		"query",
-		"execution_plan",
-		"total_cost",
-		"exceeds_threshold",
-		"error",
-		"status",
-		"generated_at",
+		"query_id",
+		"label",
+		"operation",
+		"cost",
+		"line",
 	}
 	if err := writer.Write(header); err != nil {
 		logErrorAndExit("unable to write CSV header: ", err)
 	}
 
-	// Write data rows (one per query)
-	for _, result := range report.Results {
-		status := "success"
-		if result.Error != "" {
-			status = "failed"
+	rows := 0
+	for i, side := range []struct {
+		label    string
+		costInfo *CostInfo
+	}{{result.Label1, result.Cost1}, {result.Label2, result.Cost2}} {
+		for _, op := range side.costInfo.ExpensiveOps {
+			row := []string{
+				strconv.Itoa(i + 1),
+				side.label,
+				op.Operation,
+				fmt.Sprintf("%.2f", op.Cost),
+				op.Line,
+			}
+			if err := writer.Write(row); err != nil {
+				logErrorAndExit("unable to write CSV data: ", err)
+			}
+			rows++
 		}
+	}
+
+	abs, err := filepath.Abs(file.Name())
+	if err != nil {
+		logErrorAndExit("unable to get file absolute path: ", err)
+	}
+
+	// Display success message
+	fmt.Println("\n" + "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	fmt.Println("📁 Comparison saved successfully!")
+	fmt.Printf("   %s\n", abs)
+	fmt.Printf("   %d expensive operations\n", rows)
+	fmt.Printf("\n🏆 Winner: %s\n", result.Winner)
+	if result.CostDiff != 0 {
+		fmt.Printf("Cost Difference: %.2f (%.2f%%)\n", result.CostDiff, result.CostDiffPct)
+	}
+	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	fmt.Println()
+}
+
+// csvBatchWriter streams batch results to a CSV file, one row per query as soon as it finishes,
+// so partial results survive an interrupted run and the file can be followed with tail -f.
+// Writes are serialized, so results may be written from several goroutines.
+type csvBatchWriter struct {
+	mu      sync.Mutex
+	file    *os.File
+	writer  *csv.Writer
+	columns []string
+}
 
-		totalCost := ""
-		exceedsThreshold := "false"
-		executionPlan := ""
+// newCSVBatchWriter creates the CSV file for batch command (combined mode) and writes the header row.
+// Columns selects and orders the written fields; nil writes the default batch columns, with the
+// index recommendation columns only when includeIndexes is set.
+func newCSVBatchWriter(fileName string, columns []string, includeIndexes bool) *csvBatchWriter {
+	if !strings.HasSuffix(fileName, ".csv") {
+		fileName += ".csv"
+	}
+	file, err := os.Create(fileName)
+	if err != nil {
+		logErrorAndExit("unable to create CSV file: ", err)
+	}
 
-		if result.Error == "" {
-			if result.CostAnalysis != nil {
-				totalCost = fmt.Sprintf("%.2f", result.CostAnalysis.TotalCost)
-				exceedsThreshold = strconv.FormatBool(result.CostAnalysis.ExceedsLimit)
+	if columns == nil {
+		for _, column := range csvBatchColumns {
+			if !includeIndexes && strings.HasPrefix(column, "index_recommendation") {
+				continue
 			}
-			executionPlan = escapeExecutionPlan(result.ExecutionPlan)
+			columns = append(columns, column)
 		}
+	}
 
-		row := []string{
-			strconv.Itoa(result.QueryNumber),
-			result.Query,
-			executionPlan,
-			totalCost,
-			exceedsThreshold,
-			result.Error,
-			status,
-			result.GeneratedAt.Format(time.RFC3339),
-		}
+	w := &csvBatchWriter{file: file, writer: createCSVWriter(file), columns: columns}
+	w.writeRow(columns, "unable to write CSV header: ")
+	return w
+}
 
-		if err := writer.Write(row); err != nil {
-			logErrorAndExit("unable to write CSV data: ", err)
-		}
+// Write appends the row of one batch result and flushes it to disk
+func (w *csvBatchWriter) Write(result BatchResult) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.writeRow(selectCSVFields(csvBatchValues(result), w.columns), "unable to write CSV data: ")
+}
+
+// WriteSummary appends a totals row after the query rows. The row is marked with "summary"
+// in the query_number and status columns, so it can be filtered out in a spreadsheet.
+func (w *csvBatchWriter) WriteSummary(report BatchReport) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	totalCost := ""
+	costStatistics := "average cost N/A"
+	if aggregates := report.Aggregates; aggregates != nil {
+		totalCost = fmt.Sprintf("%.2f", aggregates.TotalCost)
+		costStatistics = fmt.Sprintf("average cost %.2f, median cost %.2f, p95 cost %.2f, %d of %d exceeded threshold",
+			aggregates.AverageCost, aggregates.MedianCost, aggregates.P95Cost, aggregates.ExceededThreshold, aggregates.AnalyzedQueries)
 	}
 
-	abs, err := filepath.Abs(file.Name())
+	values := map[string]string{
+		"query_number": "summary",
+		"query": fmt.Sprintf("Summary: %d queries, %d succeeded, %d failed, %s",
+			report.TotalQueries, report.SuccessCount, report.FailureCount, costStatistics),
+		"total_cost":   totalCost,
+		"status":       "summary",
+		"generated_at": report.GeneratedAt.Format(time.RFC3339),
+	}
+	w.writeRow(selectCSVFields(values, w.columns), "unable to write CSV summary: ")
+}
+
+// Close flushes and closes the file. Returns absolute path of the file
+func (w *csvBatchWriter) Close() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.writer.Flush()
+	if err := w.file.Close(); err != nil {
+		logErrorAndExit("unable to write CSV file: ", err)
+	}
+
+	abs, err := filepath.Abs(w.file.Name())
 	if err != nil {
 		logErrorAndExit("unable to get file absolute path: ", err)
 	}
 
 	return abs
 }
+
+func (w *csvBatchWriter) writeRow(row []string, errorMessage string) {
+	if err := w.writer.Write(row); err != nil {
+		logErrorAndExit(errorMessage, err)
+	}
+	w.writer.Flush()
+	if err := w.writer.Error(); err != nil {
+		logErrorAndExit(errorMessage, err)
+	}
+}
+
+// csvBatchValues returns the CSV fields of one batch result by column name
+func csvBatchValues(result BatchResult) map[string]string {
+	status := "success"
+	if result.Error != "" {
+		status = "failed"
+	}
+
+	totalCost := ""
+	exceedsThreshold := "false"
+	grade := ""
+	nodeCount := ""
+	maxDepth := ""
+	executionPlan := ""
+
+	if result.Error == "" {
+		if result.CostAnalysis != nil && result.CostAnalysis.Warning == "" {
+			totalCost = fmt.Sprintf("%.2f", result.CostAnalysis.TotalCost)
+			exceedsThreshold = strconv.FormatBool(result.CostAnalysis.ExceedsLimit)
+			grade = result.CostAnalysis.Grade
+			nodeCount = strconv.Itoa(result.CostAnalysis.NodeCount)
+			maxDepth = strconv.Itoa(result.CostAnalysis.MaxDepth)
+		}
+		executionPlan = escapeExecutionPlan(result.ExecutionPlan)
+	}
+
+	indexCount := "0"
+	indexStatements := ""
+	if result.IndexRecommendations != nil {
+		indexCount = strconv.Itoa(result.IndexRecommendations.TotalFound)
+		statements := make([]string, 0, len(result.IndexRecommendations.Recommendations))
+		for _, rec := range result.IndexRecommendations.Recommendations {
+			statements = append(statements, rec.CreateStatement)
+		}
+		indexStatements = escapeExecutionPlan(strings.Join(statements, "\n"))
+	}
+
+	return map[string]string{
+		"query_number":               strconv.Itoa(result.QueryNumber),
+		"query":                      result.Query,
+		"execution_plan":             executionPlan,
+		"total_cost":                 totalCost,
+		"exceeds_threshold":          exceedsThreshold,
+		"grade":                      grade,
+		"error":                      result.Error,
+		"status":                     status,
+		"generated_at":               result.GeneratedAt.Format(time.RFC3339),
+		"index_recommendation_count": indexCount,
+		"index_recommendations":      indexStatements,
+		"error_category":             result.ErrorCategory,
+		"node_count":                 nodeCount,
+		"max_depth":                  maxDepth,
+	}
+}
@@ -0,0 +1,62 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+)
+
+// loadReportTemplate parses a user supplied html/template file for HTML output.
+// It returns nil when no file is given, meaning the built-in templates are used.
+func loadReportTemplate(templateFile string) (*template.Template, error) {
+	if templateFile == "" {
+		return nil, nil
+	}
+
+	tmpl, err := template.ParseFiles(templateFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse template %s: %w", templateFile, err)
+	}
+	return tmpl, nil
+}
+
+// writeTemplateReport executes a custom template with the report data (PlanOutput,
+// BatchReport or ComparisonResult) and writes the result to fileName.
+// It returns the absolute path of the generated file.
+func writeTemplateReport(tmpl *template.Template, fileName string, data interface{}) string {
+	file, err := os.Create(fileName)
+	if err != nil {
+		logErrorAndExit("unable to create report file: ", err)
+	}
+	defer file.Close()
+
+	err = tmpl.Execute(file, data)
+	if err != nil {
+		logErrorAndExit("unable to render custom template: ", err)
+	}
+
+	abs, err := filepath.Abs(file.Name())
+	if err != nil {
+		logErrorAndExit("unable to get report file absolute path: ", err)
+	}
+
+	return abs
+}
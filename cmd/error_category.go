@@ -0,0 +1,101 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Error categories of failed batch queries
+const (
+	errorCategoryConnection = "connection"
+	errorCategorySyntax     = "syntax"
+	errorCategoryPermission = "permission"
+	errorCategoryTimeout    = "timeout"
+	errorCategoryUnknown    = "unknown"
+)
+
+// errorCategoryOrder is the order categories are listed in summaries
+var errorCategoryOrder = []string{
+	errorCategoryConnection,
+	errorCategorySyntax,
+	errorCategoryPermission,
+	errorCategoryTimeout,
+	errorCategoryUnknown,
+}
+
+// errorCategoryPatterns match psql error output, checked in order. Statement timeouts come
+// before connection errors because both can mention a timeout. Syntax also covers statements
+// rejected while resolving names, such as an unknown table or column, as those are query bugs too.
+// Permission covers statements rejected by allowed_statements.
+var errorCategoryPatterns = []struct {
+	category string
+	pattern  *regexp.Regexp
+}{
+	{errorCategoryTimeout, regexp.MustCompile(`(?i)canceling statement due to (statement|lock|user request)|statement timeout|lock timeout|lock_timeout|idle.in.transaction.session.timeout`)},
+	{errorCategoryConnection, regexp.MustCompile(`(?i)could not connect|connection to server|connection refused|could not translate host name|server closed the connection|no pg_hba\.conf entry|password authentication failed|database "[^"]*" does not exist|role "[^"]*" does not exist|too many clients|the database system is (starting up|shutting down)|timeout expired|SSL (error|connection)`)},
+	{errorCategoryPermission, regexp.MustCompile(`(?i)permission denied|must be owner|must be superuser|insufficient.privilege|not allowed to be analyzed`)},
+	{errorCategorySyntax, regexp.MustCompile(`(?i)syntax error|(relation|column|function|operator|type|schema) .*does not exist|is ambiguous|invalid input syntax|unterminated (quoted|dollar)|cannot be used in|must appear in the GROUP BY`)},
+}
+
+// connectionLimitRegex matches the errors of a server that has no connection slots left
+var connectionLimitRegex = regexp.MustCompile(`(?i)too many clients already|remaining connection slots are reserved`)
+
+// connectionLimitHint explains a rejected connection when the server reached max_connections,
+// or returns "" for any other error
+func connectionLimitHint(message string) string {
+	if !connectionLimitRegex.MatchString(message) {
+		return ""
+	}
+	return "The server has no free connections (max_connections reached). Each query is explained by its own psql process " +
+		"and connection: run batch with a lower --parallel, close idle sessions or use a connection pooler, then try again"
+}
+
+// classifyError assigns a failed query's error message to a category
+func classifyError(message string) string {
+	for _, entry := range errorCategoryPatterns {
+		if entry.pattern.MatchString(message) {
+			return entry.category
+		}
+	}
+	return errorCategoryUnknown
+}
+
+// formatErrorCategories summarizes failed queries by category, e.g. "3 connection errors, 2 syntax errors"
+func formatErrorCategories(results []BatchResult) string {
+	counts := make(map[string]int)
+	for _, result := range results {
+		if result.Error != "" {
+			counts[result.ErrorCategory]++
+		}
+	}
+
+	var parts []string
+	for _, category := range errorCategoryOrder {
+		switch counts[category] {
+		case 0:
+		case 1:
+			parts = append(parts, fmt.Sprintf("1 %s error", category))
+		default:
+			parts = append(parts, fmt.Sprintf("%d %s errors", counts[category], category))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,308 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// ExplainOptions holds the per-run settings that change how the EXPLAIN statement is issued
+type ExplainOptions struct {
+	Params        map[int]string
+	Rollback      bool
+	Cache         bool
+	CacheTTL      time.Duration
+	SchemaVersion string
+	Format        string
+	Verbose       bool
+	// Mode is explainModeAuto or explainModeAnalyze, see explainOptionsForStatement
+	Mode string
+	// EstimateOnly runs plain EXPLAIN without ANALYZE, so the statement is planned but not executed
+	EstimateOnly bool
+	// Settings are the SET statements from --set, run before the EXPLAIN in the same session
+	Settings []string
+	// Role is switched to with SET ROLE before the EXPLAIN when not empty
+	Role string
+	// ConflictRetries is how many times the EXPLAIN is run again after a deadlock or
+	// serialization failure, waiting ConflictRetryDelay before each attempt
+	ConflictRetries    int
+	ConflictRetryDelay time.Duration
+	// AllowedStatements are the statement types that may be explained, see checkAllowedStatement.
+	// Empty allows every statement.
+	AllowedStatements []string
+	// Progress receives the warnings printed while the plan is generated, os.Stdout when nil
+	Progress io.Writer
+}
+
+// progress returns the writer for warnings printed while the plan is generated
+func (o ExplainOptions) progress() io.Writer {
+	if o.Progress == nil {
+		return os.Stdout
+	}
+	return o.Progress
+}
+
+// preparedStatementName is the name used when a parameterized query is analyzed via PREPARE
+const preparedStatementName = "pgexplain_stmt"
+
+// placeholderRegex matches positional parameters such as $1 or $12
+var placeholderRegex = regexp.MustCompile(`\$(\d+)`)
+
+// settingNameRegex matches a configuration parameter name, including custom ones such as myapp.tenant_id
+var settingNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_$]*(\.[A-Za-z_][A-Za-z0-9_$]*)?$`)
+
+// dataModifyingRegex matches statements that change data when executed by EXPLAIN ANALYZE,
+// including data-modifying CTEs such as WITH moved AS (DELETE ...) SELECT ...
+var dataModifyingRegex = regexp.MustCompile(`(?i)^(INSERT|UPDATE|DELETE|MERGE)\b|^WITH\b[\s\S]*\b(INSERT|UPDATE|DELETE|MERGE)\b`)
+
+// buildExplainStatements returns the statements psql runs, in order, to produce the plan.
+// Parameterized queries are prepared first and explained through EXECUTE so the planner
+// sees the same statement shape an application would send.
+// With Rollback set the statements are wrapped in BEGIN / ROLLBACK so any changes made
+// while measuring the plan are discarded.
+// Session settings from --set and --role come first, so they apply to everything after them.
+func buildExplainStatements(query string, options ExplainOptions) []string {
+	statements := explainStatements(query, options)
+	if options.Rollback {
+		statements = append([]string{"BEGIN"}, statements...)
+		statements = append(statements, "ROLLBACK")
+	}
+	return append(sessionStatements(options), statements...)
+}
+
+// sessionStatements returns the SET and SET ROLE statements that prepare the session for the query
+func sessionStatements(options ExplainOptions) []string {
+	statements := append([]string{}, options.Settings...)
+	if options.Role != "" {
+		statements = append(statements, "SET ROLE "+quoteIdentifier(options.Role))
+	}
+	return statements
+}
+
+// explainStatements returns the statements that prepare and explain the query
+func explainStatements(query string, options ExplainOptions) []string {
+	explain := explainCommand(options)
+	if len(options.Params) == 0 {
+		return []string{fmt.Sprintf("%s %s", explain, query)}
+	}
+
+	positions := make([]int, 0, len(options.Params))
+	for position := range options.Params {
+		positions = append(positions, position)
+	}
+	sort.Ints(positions)
+
+	values := make([]string, 0, len(positions))
+	for _, position := range positions {
+		values = append(values, quoteLiteral(options.Params[position]))
+	}
+
+	return []string{
+		fmt.Sprintf("PREPARE %s AS %s", preparedStatementName, strings.TrimRight(strings.TrimSpace(query), ";")),
+		fmt.Sprintf("%s EXECUTE %s(%s)", explain, preparedStatementName, strings.Join(values, ", ")),
+	}
+}
+
+// explainCommand returns the EXPLAIN command with its options, e.g. EXPLAIN (ANALYSE, BUFFERS, VERBOSE, FORMAT JSON)
+func explainCommand(options ExplainOptions) string {
+	explainOptions := []string{"ANALYSE", "BUFFERS"}
+	if options.EstimateOnly {
+		// BUFFERS needs ANALYZE before PostgreSQL 13
+		explainOptions = nil
+	}
+	if options.Verbose {
+		// VERBOSE adds the Output column list of every node and schema-qualifies relation names
+		explainOptions = append(explainOptions, "VERBOSE")
+	}
+	if isStructuredFormat(options.Format) {
+		explainOptions = append(explainOptions, "FORMAT "+strings.ToUpper(options.Format))
+	}
+	if len(explainOptions) == 0 {
+		return "EXPLAIN"
+	}
+	return fmt.Sprintf("EXPLAIN (%s)", strings.Join(explainOptions, ", "))
+}
+
+// isDataModifyingQuery reports whether the query writes data, ignoring leading comments
+func isDataModifyingQuery(query string) bool {
+	return dataModifyingRegex.MatchString(stripLeadingComments(query))
+}
+
+// stripLeadingComments removes leading whitespace and -- or /* */ comments from a query
+func stripLeadingComments(query string) string {
+	trimmed := strings.TrimSpace(query)
+	for {
+		switch {
+		case strings.HasPrefix(trimmed, "--"):
+			end := strings.Index(trimmed, "\n")
+			if end == -1 {
+				return ""
+			}
+			trimmed = strings.TrimSpace(trimmed[end+1:])
+		case strings.HasPrefix(trimmed, "/*"):
+			end := strings.Index(trimmed, "*/")
+			if end == -1 {
+				return ""
+			}
+			trimmed = strings.TrimSpace(trimmed[end+2:])
+		default:
+			return trimmed
+		}
+	}
+}
+
+// warnDataModifyingQuery prints a prominent warning when a data-modifying query
+// is about to be executed by EXPLAIN ANALYZE outside a rolled back transaction
+func warnDataModifyingQuery(query string, options ExplainOptions) {
+	if changesAreRolledBack(query, options) || !isDataModifyingQuery(query) {
+		return
+	}
+
+	out := options.progress()
+	fmt.Fprintln(out, strings.Repeat("=", 70))
+	fmt.Fprintln(out, "🚨 WARNING: DATA-MODIFYING STATEMENT")
+	fmt.Fprintln(out, strings.Repeat("=", 70))
+	fmt.Fprintln(out, "EXPLAIN ANALYZE executes the statement, so these changes WILL be applied.")
+	fmt.Fprintln(out, "Re-run with --transaction to measure the plan and roll the changes back.")
+	fmt.Fprintln(out, strings.Repeat("=", 70))
+	fmt.Fprintln(out)
+}
+
+// parseSettings turns repeated --set NAME=VALUE flags into SET statements. Values are
+// quoted as literals, and a comma-separated value such as search_path=app,public becomes a list.
+func parseSettings(values []string) ([]string, error) {
+	statements := make([]string, 0, len(values))
+	for _, value := range values {
+		parts := strings.SplitN(value, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid setting %q, expected NAME=VALUE", value)
+		}
+
+		name := strings.TrimSpace(parts[0])
+		if !settingNameRegex.MatchString(name) {
+			return nil, fmt.Errorf("invalid setting name %q", name)
+		}
+
+		var items []string
+		for _, item := range strings.Split(parts[1], ",") {
+			item = strings.TrimSpace(item)
+			if item == "" {
+				return nil, fmt.Errorf("invalid setting %q, the value is empty", value)
+			}
+			items = append(items, quoteLiteral(unquoteParam(item)))
+		}
+
+		statements = append(statements, fmt.Sprintf("SET %s TO %s", name, strings.Join(items, ", ")))
+	}
+	return statements, nil
+}
+
+// sessionOptionsFromFlags reads --set (or its alias --guc) and --role into options
+func sessionOptionsFromFlags(cmd *cobra.Command, options *ExplainOptions) error {
+	settingValues, _ := cmd.Flags().GetStringArray("set")
+	gucValues, _ := cmd.Flags().GetStringArray("guc")
+	settingValues = append(settingValues, gucValues...)
+	settings, err := parseSettings(settingValues)
+	if err != nil {
+		return err
+	}
+
+	role, _ := cmd.Flags().GetString("role")
+	if cmd.Flags().Changed("role") && strings.TrimSpace(role) == "" {
+		return fmt.Errorf("--role needs a role name")
+	}
+
+	options.Settings = settings
+	options.Role = role
+	return nil
+}
+
+// parseQueryParams parses repeated --param N=VALUE flags into values keyed by placeholder position
+func parseQueryParams(values []string) (map[int]string, error) {
+	params := make(map[int]string)
+	for _, value := range values {
+		parts := strings.SplitN(value, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid param %q, expected N=VALUE", value)
+		}
+
+		position, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+		if err != nil || position < 1 {
+			return nil, fmt.Errorf("invalid param position %q, expected a number starting at 1", parts[0])
+		}
+		if _, exists := params[position]; exists {
+			return nil, fmt.Errorf("param %d is set more than once", position)
+		}
+
+		params[position] = unquoteParam(parts[1])
+	}
+	return params, nil
+}
+
+// validateQueryParams checks that every $N placeholder in the query has a value and no extra values were supplied.
+// PostgreSQL numbers parameters contiguously, so a query using $3 also needs values for $1 and $2.
+func validateQueryParams(query string, params map[int]string) error {
+	highest := 0
+	for _, match := range placeholderRegex.FindAllStringSubmatch(query, -1) {
+		position, err := strconv.Atoi(match[1])
+		if err != nil {
+			continue
+		}
+		if position > highest {
+			highest = position
+		}
+	}
+
+	if highest != len(params) {
+		return fmt.Errorf("query has %d placeholder(s) but %d param(s) were supplied", highest, len(params))
+	}
+	for position := 1; position <= highest; position++ {
+		if _, ok := params[position]; !ok {
+			return fmt.Errorf("no value supplied for placeholder $%d, use --param %d=VALUE", position, position)
+		}
+	}
+	return nil
+}
+
+// unquoteParam strips one pair of surrounding single quotes so --param 2='foo' and --param 2=foo are equivalent
+func unquoteParam(value string) string {
+	if len(value) >= 2 && strings.HasPrefix(value, "'") && strings.HasSuffix(value, "'") {
+		return strings.ReplaceAll(value[1:len(value)-1], "''", "'")
+	}
+	return value
+}
+
+// quoteIdentifier renders a name as a SQL identifier, keeping its case
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// quoteLiteral renders a value as a SQL string literal. Untyped literals are coerced
+// to the parameter type PostgreSQL inferred for the prepared statement.
+func quoteLiteral(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
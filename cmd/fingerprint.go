@@ -0,0 +1,212 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/spf13/cobra"
+)
+
+var fingerprintCmd = &cobra.Command{
+	Use:   "fingerprint [SQL_QUERY]",
+	Short: "Show the normalized form and fingerprint of a query",
+	Long: `Normalize a query the way pg_explain compares queries: comments are removed, whitespace
+is collapsed, keywords and identifiers are lower-cased and literal values are replaced with ?.
+Queries that differ only in constants share the same fingerprint.
+
+Example:
+  pg_explain fingerprint "SELECT * FROM users WHERE id = 42"
+  pg_explain fingerprint --file query.sql`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  runFingerprint,
+}
+
+// fingerprintLength is the number of hex characters kept from the SHA-256 hash
+const fingerprintLength = 16
+
+// operatorChars are the characters PostgreSQL operators are made of
+const operatorChars = "+-*/<>=~!@#%^&|`?"
+
+func runFingerprint(cmd *cobra.Command, args []string) {
+	query, err := getQueryInput(cmd, args)
+	if err != nil {
+		logErrorAndExit("Failed to get query input: ", err)
+	}
+
+	fmt.Println("\n🧬 Query Fingerprint")
+	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	fmt.Printf("Normalized:  %s\n", normalizeQuery(query))
+	fmt.Printf("Fingerprint: %s\n", fingerprintQuery(query))
+	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	fmt.Println()
+}
+
+// fingerprintQuery returns a stable hash of the normalized query, shared by queries that
+// only differ in literal values, comments, whitespace or keyword case
+func fingerprintQuery(query string) string {
+	hash := sha256.Sum256([]byte(normalizeQuery(query)))
+	return hex.EncodeToString(hash[:])[:fingerprintLength]
+}
+
+// normalizeQuery removes comments, lower-cases keywords and unquoted identifiers, replaces
+// literals and $N parameters with ? and separates tokens with single spaces.
+// IN lists are collapsed to a single ? so the number of values does not matter.
+func normalizeQuery(query string) string {
+	tokens := collapseInLists(tokenizeQuery(query))
+	for len(tokens) > 0 && tokens[len(tokens)-1] == ";" {
+		tokens = tokens[:len(tokens)-1]
+	}
+	return strings.Join(tokens, " ")
+}
+
+// tokenizeQuery splits a query into normalized tokens
+func tokenizeQuery(query string) []string {
+	runes := []rune(query)
+	var tokens []string
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '-' && i+1 < len(runes) && runes[i+1] == '-':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			end := strings.Index(string(runes[i+2:]), "*/")
+			if end == -1 {
+				return tokens
+			}
+			i += 2 + len([]rune(string(runes[i+2:])[:end])) + 2
+		case r == '\'' || ((r == 'e' || r == 'E') && i+1 < len(runes) && runes[i+1] == '\''):
+			if r != '\'' {
+				i++
+			}
+			i = skipQuoted(runes, i, '\'')
+			tokens = append(tokens, "?")
+		case r == '"':
+			start := i
+			i = skipQuoted(runes, i, '"')
+			tokens = append(tokens, string(runes[start:i]))
+		case r == '$' && i+1 < len(runes) && unicode.IsDigit(runes[i+1]):
+			i++
+			for i < len(runes) && unicode.IsDigit(runes[i]) {
+				i++
+			}
+			tokens = append(tokens, "?")
+		case r == '$':
+			i = skipDollarQuoted(runes, i)
+			tokens = append(tokens, "?")
+		case unicode.IsDigit(r) || (r == '.' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.' || runes[i] == 'e' || runes[i] == 'E' ||
+				((runes[i] == '+' || runes[i] == '-') && (runes[i-1] == 'e' || runes[i-1] == 'E'))) {
+				i++
+			}
+			tokens = append(tokens, "?")
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_' || runes[i] == '$') {
+				i++
+			}
+			tokens = append(tokens, strings.ToLower(string(runes[start:i])))
+		case strings.ContainsRune(operatorChars, r):
+			start := i
+			for i < len(runes) && strings.ContainsRune(operatorChars, runes[i]) {
+				i++
+			}
+			tokens = append(tokens, string(runes[start:i]))
+		case r == ':' && i+1 < len(runes) && runes[i+1] == ':':
+			tokens = append(tokens, "::")
+			i += 2
+		default:
+			tokens = append(tokens, string(r))
+			i++
+		}
+	}
+	return tokens
+}
+
+// skipQuoted returns the index after the quoted string starting at start, treating a
+// doubled quote as an escaped quote
+func skipQuoted(runes []rune, start int, quote rune) int {
+	for i := start + 1; i < len(runes); i++ {
+		if runes[i] == '\\' && quote == '\'' && i+1 < len(runes) {
+			i++
+			continue
+		}
+		if runes[i] == quote {
+			if i+1 < len(runes) && runes[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(runes)
+}
+
+// skipDollarQuoted returns the index after a dollar-quoted string such as $$...$$ or $tag$...$tag$
+func skipDollarQuoted(runes []rune, start int) int {
+	end := start + 1
+	for end < len(runes) && runes[end] != '$' {
+		end++
+	}
+	if end >= len(runes) {
+		return len(runes)
+	}
+
+	tag := string(runes[start : end+1])
+	body := string(runes[end+1:])
+	closing := strings.Index(body, tag)
+	if closing == -1 {
+		return len(runes)
+	}
+	return end + 1 + len([]rune(body[:closing])) + len([]rune(tag))
+}
+
+// collapseInLists replaces IN (?, ?, ...) with IN (?)
+func collapseInLists(tokens []string) []string {
+	collapsed := make([]string, 0, len(tokens))
+	for i := 0; i < len(tokens); i++ {
+		collapsed = append(collapsed, tokens[i])
+		if tokens[i] != "in" || i+2 >= len(tokens) || tokens[i+1] != "(" || tokens[i+2] != "?" {
+			continue
+		}
+
+		end := i + 3
+		for end+1 < len(tokens) && tokens[end] == "," && tokens[end+1] == "?" {
+			end += 2
+		}
+		if end < len(tokens) && tokens[end] == ")" {
+			collapsed = append(collapsed, "(", "?", ")")
+			i = end
+		}
+	}
+	return collapsed
+}
+
+func init() {
+	fingerprintCmd.Flags().StringP("file", "F", "", "Read SQL query from file")
+	rootCmd.AddCommand(fingerprintCmd)
+}
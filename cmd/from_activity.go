@@ -0,0 +1,120 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var fromActivityCmd = &cobra.Command{
+	Use:   "from-activity",
+	Short: "Analyze a query taken from pg_stat_activity or pg_stat_statements",
+	Long: `Fetch the text of a running or recently run query and analyze it like the analyze command.
+
+Use --pid to take the query of a backend from pg_stat_activity, or --query-id to take a
+normalized query from pg_stat_statements. Normalized queries contain $N placeholders,
+so supply their values with --param.
+
+Example:
+  pg_explain from-activity --pid 12345 --transaction
+  pg_explain from-activity --query-id -4567890123456789 --param 1=42 -f json`,
+	Args: cobra.NoArgs,
+	Run:  runFromActivity,
+}
+
+// unitSeparator separates the columns of the activity lookups; query text can contain
+// any printable character and newlines, but not this control character
+const unitSeparator = "\x1f"
+
+// activityQuerySQL reads the current or last query of a backend. The query is flagged as
+// truncated when its length reached track_activity_query_size, which includes a terminating byte.
+const activityQuerySQL = `SELECT octet_length(query) >= current_setting('track_activity_query_size')::int - 1, query
+FROM pg_stat_activity
+WHERE pid = %d`
+
+// statementsQuerySQL reads a normalized query from pg_stat_statements
+const statementsQuerySQL = `SELECT false, query
+FROM pg_stat_statements
+WHERE queryid = %d
+LIMIT 1`
+
+func runFromActivity(cmd *cobra.Command, args []string) {
+	requirePsql()
+	pid, _ := cmd.Flags().GetInt("pid")
+	queryID, _ := cmd.Flags().GetInt64("query-id")
+	if (pid == 0) == (queryID == 0) {
+		logErrorAndExit("Invalid arguments", fmt.Errorf("use exactly one of --pid or --query-id"))
+	}
+
+	config, _ := loadConfig()
+	applyServiceFlag(cmd, config)
+	promptPasswordIfRequested(cmd)
+
+	var query string
+	var err error
+	if pid != 0 {
+		fmt.Printf("\n🔎 Reading query of backend %d from pg_stat_activity...\n", pid)
+		query, err = fetchActivityQuery(config, fmt.Sprintf(activityQuerySQL, pid))
+	} else {
+		fmt.Printf("\n🔎 Reading query %d from pg_stat_statements...\n", queryID)
+		query, err = fetchActivityQuery(config, fmt.Sprintf(statementsQuerySQL, queryID))
+	}
+	if err != nil {
+		fmt.Println("❌ Failed to read the query")
+		logErrorAndExit("Error: ", err)
+	}
+
+	fmt.Println("✅ Query found:")
+	fmt.Printf("   %s\n", queryLabel(query, 120))
+
+	analyzeQuery(cmd, query)
+}
+
+// fetchActivityQuery runs one of the activity lookups and returns the query text
+func fetchActivityQuery(config *Config, lookupSQL string) (string, error) {
+	output, err := runPsql(config, []string{"-q", "-A", "-t", "-F", unitSeparator, "-c", lookupSQL})
+	if err != nil {
+		return "", fmt.Errorf("unable to look up the query: %w", err)
+	}
+
+	truncated, query, found := strings.Cut(strings.TrimRight(output, "\n"), unitSeparator)
+	if !found {
+		return "", fmt.Errorf("no query found, the backend may have exited or the query id is unknown")
+	}
+
+	query = strings.TrimSpace(query)
+	switch {
+	case query == "":
+		return "", fmt.Errorf("the backend has not run a query yet")
+	case query == "<insufficient privilege>":
+		return "", fmt.Errorf("not allowed to see the query of this backend, connect as its user or a member of pg_read_all_stats")
+	case truncated == "t":
+		return "", fmt.Errorf("the query text was cut off at track_activity_query_size, raise that setting or use --query-id with pg_stat_statements")
+	}
+	return query, nil
+}
+
+func init() {
+	fromActivityCmd.Flags().Int("pid", 0, "Process id of the backend whose query to analyze (pg_stat_activity)")
+	fromActivityCmd.Flags().Int64("query-id", 0, "Query id of the statement to analyze (pg_stat_statements)")
+	addAnalyzeFlags(fromActivityCmd)
+	rootCmd.AddCommand(fromActivityCmd)
+}
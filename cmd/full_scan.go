@@ -0,0 +1,116 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// fullScanRows is the estimated row count above which an unfiltered Seq Scan is reported
+const fullScanRows = 1000000
+
+// FullTableScan is a Seq Scan that reads a large table without any filter,
+// which usually means a forgotten WHERE clause or LIMIT rather than a missing index
+type FullTableScan struct {
+	Table string
+	Rows  int64
+	Line  string
+}
+
+var (
+	seqScanRegex      = regexp.MustCompile(`Seq Scan on (\S+)`)
+	estimatedRowRegex = regexp.MustCompile(`cost=\d+\.?\d*\.\.\d+\.?\d* rows=(\d+)`)
+)
+
+// detectFullTableScans finds Seq Scan nodes estimated to return at least fullScanRows rows
+// that have no Filter line. Scans below a Limit node are skipped, as they stop early.
+func detectFullTableScans(plan string) []FullTableScan {
+	var scans []FullTableScan
+
+	// Open ancestor nodes, by the indentation of their "->" arrow
+	type planNode struct {
+		indent int
+		limit  bool
+	}
+	var ancestors []planNode
+	var candidate *FullTableScan
+
+	for line := range planLines(plan) {
+		rowMatches := estimatedRowRegex.FindStringSubmatch(line)
+		if rowMatches == nil {
+			// Detail lines such as "Filter: ..." belong to the node above them
+			if candidate != nil && strings.HasPrefix(strings.TrimSpace(line), "Filter:") {
+				candidate = nil
+			}
+			continue
+		}
+
+		if candidate != nil {
+			scans = append(scans, *candidate)
+			candidate = nil
+		}
+
+		indent := strings.Index(line, "->")
+		for len(ancestors) > 0 && ancestors[len(ancestors)-1].indent >= indent {
+			ancestors = ancestors[:len(ancestors)-1]
+		}
+		underLimit := false
+		for _, ancestor := range ancestors {
+			underLimit = underLimit || ancestor.limit
+		}
+		node := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "->"))
+		ancestors = append(ancestors, planNode{indent: indent, limit: strings.HasPrefix(node, "Limit ")})
+
+		tableMatches := seqScanRegex.FindStringSubmatch(line)
+		if tableMatches == nil || underLimit {
+			continue
+		}
+		rows, err := strconv.ParseInt(rowMatches[1], 10, 64)
+		if err != nil || rows < fullScanRows {
+			continue
+		}
+		candidate = &FullTableScan{Table: tableMatches[1], Rows: rows, Line: strings.TrimSpace(line)}
+	}
+
+	if candidate != nil {
+		scans = append(scans, *candidate)
+	}
+	return scans
+}
+
+// fullTableScanWarning explains a full table scan, e.g. "Seq Scan on events reads ~12,000,000 rows with no filter"
+func fullTableScanWarning(scan FullTableScan) string {
+	return fmt.Sprintf("Seq Scan on %s reads ~%s rows with no filter. Is a WHERE clause or LIMIT missing? An index will not help a query that needs every row",
+		scan.Table, formatThousands(scan.Rows))
+}
+
+// formatThousands formats n with comma thousands separators
+func formatThousands(n int64) string {
+	digits := strconv.FormatInt(n, 10)
+	var sb strings.Builder
+	for i, digit := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteRune(digit)
+	}
+	return sb.String()
+}
@@ -0,0 +1,163 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// writeComparisonGitHub writes the comparison as a GitHub PR comment. The comment is printed
+// to out unless an output directory is given, so it can be piped straight into a bot or `gh pr comment`.
+func writeComparisonGitHub(out io.Writer, result *ComparisonResult, outputDir string) {
+	comment := formatComparisonGitHub(result)
+
+	if outputDir == "" {
+		fmt.Fprint(out, comment)
+		return
+	}
+
+	fileName := filepath.Join(outputDir, comparisonFilePrefix+generateTitle()+".github.md")
+	if err := os.WriteFile(fileName, []byte(comment), 0644); err != nil {
+		logErrorAndExit("unable to write GitHub comment: ", err)
+	}
+	absPath, err := filepath.Abs(fileName)
+	if err != nil {
+		logErrorAndExit("unable to get file absolute path: ", err)
+	}
+
+	fmt.Fprintln(out, "\n"+"━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	fmt.Fprintln(out, "📁 GitHub comment saved successfully!")
+	fmt.Fprintf(out, "   %s\n", absPath)
+	fmt.Fprintln(out, "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	fmt.Fprintln(out)
+}
+
+// formatComparisonGitHub renders a compact GitHub-flavored Markdown summary: badges and a
+// cost table up front, with the queries and plans folded into <details> blocks
+func formatComparisonGitHub(result *ComparisonResult) string {
+	var sb strings.Builder
+
+	headline := fmt.Sprintf("%s wins", result.Winner)
+	switch result.Winner {
+	case "Tie":
+		headline = "tie"
+	case "Unknown":
+		headline = "no winner"
+	}
+	sb.WriteString(fmt.Sprintf("### %s Query comparison: %s\n\n", comparisonWinnerEmoji(result.Winner), escapeMarkdownSpecialChars(headline)))
+
+	winnerColor := "brightgreen"
+	if result.Winner == "Tie" || result.Winner == "Unknown" {
+		winnerColor = "lightgrey"
+	}
+	confidenceColor := "lightgrey"
+	switch result.Verdict.Confidence {
+	case "high":
+		confidenceColor = "brightgreen"
+	case "low":
+		confidenceColor = "yellow"
+	}
+	sb.WriteString(shieldsBadge("winner", result.Winner, winnerColor) + " ")
+	sb.WriteString(shieldsBadge("cost diff", fmt.Sprintf("%.2f%%", result.CostDiffPct), winnerColor) + " ")
+	sb.WriteString(shieldsBadge("confidence", result.Verdict.Confidence, confidenceColor) + "\n\n")
+
+	// Summary table
+	sb.WriteString(fmt.Sprintf("| | %s | %s |\n", escapeGitHubTableCell(result.Label1), escapeGitHubTableCell(result.Label2)))
+	sb.WriteString("|---|---:|---:|\n")
+	sb.WriteString(fmt.Sprintf("| Total Cost | %s | %s |\n", githubCostCell(result.Cost1), githubCostCell(result.Cost2)))
+	if result.Cost1.ExecutionTimeMs > 0 || result.Cost2.ExecutionTimeMs > 0 {
+		sb.WriteString(fmt.Sprintf("| Execution Time | %.2f ms | %.2f ms |\n", result.Cost1.ExecutionTimeMs, result.Cost2.ExecutionTimeMs))
+	}
+	if len(result.Cost1.ExpensiveOps) > 0 || len(result.Cost2.ExpensiveOps) > 0 {
+		sb.WriteString(fmt.Sprintf("| Top Operation | %s | %s |\n", githubTopOperation(result.Cost1), githubTopOperation(result.Cost2)))
+	}
+	if result.Cost1.Grade != "" || result.Cost2.Grade != "" {
+		sb.WriteString(fmt.Sprintf("| Health Grade | %s | %s |\n", githubGradeCell(result.Cost1), githubGradeCell(result.Cost2)))
+	}
+	sb.WriteString("\n")
+
+	delta := fmt.Sprintf("**Cost delta:** %.2f (%.2f%%)", result.CostDiff, result.CostDiffPct)
+	if line := metricDifferenceLine(result); line != "" {
+		delta += " · " + escapeMarkdownSpecialChars(line)
+	}
+	if result.MetricDiff != 0 {
+		delta += " · " + escapeMarkdownSpecialChars(performanceMultiplier(result))
+	}
+	sb.WriteString(delta + "\n\n")
+	sb.WriteString(fmt.Sprintf("> 💡 %s\n\n", escapeMarkdownSpecialChars(result.Recommendation)))
+
+	writeGitHubPlanDetails(&sb, result.Label1, result.Query1, result.Plan1, result.Cost1)
+	writeGitHubPlanDetails(&sb, result.Label2, result.Query2, result.Plan2, result.Cost2)
+
+	sb.WriteString(fmt.Sprintf("<sub>Generated by pg_explain on %s</sub>\n", formatTimestamp(currentTime())))
+	return sb.String()
+}
+
+// writeGitHubPlanDetails adds a collapsed section with the SQL and execution plan of one query
+func writeGitHubPlanDetails(sb *strings.Builder, label, query, plan string, costInfo *CostInfo) {
+	sb.WriteString("<details>\n")
+	sb.WriteString(fmt.Sprintf("<summary><b>%s</b> · cost %s</summary>\n\n", html.EscapeString(label), githubCostCell(costInfo)))
+	sb.WriteString("```sql\n")
+	sb.WriteString(strings.TrimSpace(query))
+	sb.WriteString("\n```\n\n")
+	sb.WriteString("```\n")
+	sb.WriteString(strings.TrimRight(plan, "\n"))
+	sb.WriteString("\n```\n\n")
+	sb.WriteString("</details>\n\n")
+}
+
+// shieldsBadge returns a shields.io static badge image, e.g. "winner | Query 2"
+func shieldsBadge(label, message, color string) string {
+	// shields.io treats - and _ as separators, so literal ones are doubled
+	escape := strings.NewReplacer("-", "--", "_", "__")
+	return fmt.Sprintf("![%s](https://img.shields.io/badge/%s-%s-%s)", label,
+		url.PathEscape(escape.Replace(label)), url.PathEscape(escape.Replace(message)), color)
+}
+
+// escapeGitHubTableCell escapes text placed inside a Markdown table cell
+func escapeGitHubTableCell(text string) string {
+	return strings.ReplaceAll(escapeMarkdownSpecialChars(text), "|", "\\|")
+}
+
+func githubCostCell(costInfo *CostInfo) string {
+	if costInfo.Warning != "" {
+		return "unknown"
+	}
+	return fmt.Sprintf("%.2f", costInfo.TotalCost)
+}
+
+func githubGradeCell(costInfo *CostInfo) string {
+	if costInfo.Grade == "" {
+		return "N/A"
+	}
+	return fmt.Sprintf("%s %s", getGradeEmoji(costInfo.Grade), costInfo.Grade)
+}
+
+func githubTopOperation(costInfo *CostInfo) string {
+	if len(costInfo.ExpensiveOps) == 0 {
+		return "N/A"
+	}
+	op := costInfo.ExpensiveOps[0]
+	return fmt.Sprintf("%s (%.2f)", escapeGitHubTableCell(op.Operation), op.Cost)
+}
@@ -0,0 +1,129 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// hypotheticalIndexLimit is the number of top recommendations measured with --hypothetical
+const hypotheticalIndexLimit = 3
+
+// hypopgInstalledSQL returns a row when the HypoPG extension is installed in the database
+const hypopgInstalledSQL = "SELECT 1 FROM pg_extension WHERE extname = 'hypopg'"
+
+// hypotheticalIndexRegex matches the name HypoPG gives a hypothetical index in plans, e.g. <13543>btree_orders_status
+var hypotheticalIndexRegex = regexp.MustCompile(`<\d+>\w+`)
+
+// HypotheticalResult is the cost of the query planned with and without a hypothetical index
+type HypotheticalResult struct {
+	CostBefore float64 `json:"cost_before"`
+	CostAfter  float64 `json:"cost_after"`
+	ChangePct  float64 `json:"change_pct"`
+	// IndexUsed reports whether the planner chose the hypothetical index
+	IndexUsed bool   `json:"index_used"`
+	Error     string `json:"error,omitempty"`
+}
+
+// measureHypotheticalIndexes plans the query again with each of the top recommendations created
+// as a HypoPG hypothetical index, and records the projected cost on the recommendation.
+// Nothing is built: the index only exists in the planner of that psql session.
+func measureHypotheticalIndexes(config *Config, query, analysisPlan string, options ExplainOptions, info *IndexRecommendationInfo) {
+	if len(info.Recommendations) == 0 {
+		return
+	}
+
+	installed, err := runPsql(config, []string{"-q", "-A", "-t", "-c", hypopgInstalledSQL})
+	if err != nil {
+		fmt.Printf("⚠️  Skipping hypothetical indexes: %v\n\n", err)
+		return
+	}
+	if strings.TrimSpace(installed) == "" {
+		fmt.Println("⚠️  Skipping hypothetical indexes: the HypoPG extension is not installed in this database")
+		fmt.Println("   Install it from https://github.com/HypoPG/hypopg and run CREATE EXTENSION hypopg;")
+		fmt.Println()
+		return
+	}
+
+	before := parseCost(analysisPlan, 0, 0)
+	if before.Warning != "" {
+		fmt.Printf("⚠️  Skipping hypothetical indexes: %s\n\n", before.Warning)
+		return
+	}
+
+	count := minInt(hypotheticalIndexLimit, len(info.Recommendations))
+	fmt.Printf("🧪 Measuring %d of %d recommendations with hypothetical indexes (HypoPG)...\n", count, len(info.Recommendations))
+	for i := 0; i < count; i++ {
+		result := measureHypotheticalIndex(config, query, options, info.Recommendations[i])
+		result.CostBefore = before.TotalCost
+		if result.Error == "" && before.TotalCost != 0 {
+			result.ChangePct = (result.CostAfter - before.TotalCost) / before.TotalCost * 100
+		}
+		info.Recommendations[i].Hypothetical = result
+	}
+}
+
+// measureHypotheticalIndex runs plain EXPLAIN in a session where the index exists hypothetically.
+// EXPLAIN ANALYZE would execute the query and ignore hypothetical indexes, so only the
+// estimated cost is measured.
+func measureHypotheticalIndex(config *Config, query string, options ExplainOptions, rec IndexRecommendation) *HypotheticalResult {
+	explainOptions := ExplainOptions{Params: options.Params, EstimateOnly: true}
+
+	psqlArgs := []string{"-q", "-v", "ON_ERROR_STOP=1", "-c", forceEnglishMessagesSQL}
+	for _, statement := range sessionStatements(options) {
+		psqlArgs = append(psqlArgs, "-c", statement)
+	}
+	psqlArgs = append(psqlArgs, "-c", fmt.Sprintf("DO $$ BEGIN PERFORM hypopg_create_index(%s); END $$", quoteLiteral(hypotheticalIndexStatement(rec))))
+	for _, statement := range explainStatements(query, explainOptions) {
+		psqlArgs = append(psqlArgs, "-c", statement)
+	}
+	psqlArgs = append(psqlArgs, "-c", "DO $$ BEGIN PERFORM hypopg_reset(); END $$")
+
+	plan, err := runPsql(config, psqlArgs)
+	if err != nil {
+		return &HypotheticalResult{Error: err.Error()}
+	}
+	after := parseCost(plan, 0, 0)
+	if after.Warning != "" {
+		return &HypotheticalResult{Error: after.Warning}
+	}
+	return &HypotheticalResult{CostAfter: after.TotalCost, IndexUsed: hypotheticalIndexRegex.MatchString(plan)}
+}
+
+// hypotheticalIndexStatement returns the CREATE INDEX statement passed to hypopg_create_index,
+// which takes no index name, IF NOT EXISTS or CONCURRENTLY
+func hypotheticalIndexStatement(rec IndexRecommendation) string {
+	include := ""
+	if len(rec.IncludeColumns) > 0 {
+		include = fmt.Sprintf(" INCLUDE (%s)", strings.Join(rec.IncludeColumns, ", "))
+	}
+	return fmt.Sprintf("CREATE INDEX ON %s USING %s (%s)%s", rec.TableName, rec.IndexType, strings.Join(rec.Columns, ", "), include)
+}
+
+// formatHypotheticalResult describes the projection, e.g. "cost 25000.00 → 120.50 (-99.5%)"
+func formatHypotheticalResult(result *HypotheticalResult) string {
+	switch {
+	case result.Error != "":
+		return "not measured: " + result.Error
+	case !result.IndexUsed:
+		return fmt.Sprintf("the planner does not choose this index, cost stays at %.2f", result.CostBefore)
+	}
+	return fmt.Sprintf("cost %.2f → %.2f (%+.1f%%)", result.CostBefore, result.CostAfter, result.ChangePct)
+}
@@ -19,68 +19,166 @@ package cmd
 
 import (
 	"fmt"
+	"path"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 )
 
 // IndexRecommendation represents a single index recommendation
 type IndexRecommendation struct {
-	TableName       string
-	Columns         []string
-	IndexType       string
-	Reason          string
-	OperationType   string
-	OperationCost   float64
-	CreateStatement string
-	Priority        int
+	TableName        string   `json:"table_name"`
+	Columns          []string `json:"columns"`
+	IndexType        string   `json:"index_type"`
+	Reason           string   `json:"reason"`
+	OperationType    string   `json:"operation_type"`
+	OperationCost    float64  `json:"operation_cost"`
+	CreateStatement  string   `json:"create_statement"`
+	Priority         int      `json:"priority"`
+	EstimatedBenefit float64  `json:"estimated_benefit"`
+	EstimatedImpact  string   `json:"estimated_impact"`
+	SelectivityHint  string   `json:"selectivity_hint,omitempty"`
+	IncludeColumns   []string `json:"include_columns,omitempty"`
+	// Hypothetical is the cost projected with HypoPG, set with --hypothetical
+	Hypothetical *HypotheticalResult `json:"hypothetical,omitempty"`
 }
 
 // IndexRecommendationInfo aggregates all recommendations
 type IndexRecommendationInfo struct {
-	Recommendations []IndexRecommendation
-	TotalFound      int
-	HighPriority    int
-	ThresholdUsed   float64
+	Recommendations []IndexRecommendation `json:"recommendations"`
+	TotalFound      int                   `json:"total_found"`
+	HighPriority    int                   `json:"high_priority"`
+	ThresholdUsed   float64               `json:"threshold_used"`
+	Concurrently    bool                  `json:"concurrently,omitempty"`
+	// AlreadyApplied are the recommendations skipped because --applied-file lists them
+	AlreadyApplied []IndexRecommendation `json:"already_applied,omitempty"`
+	// TypeMismatches are filters that cast a column, which no plain index on it can serve
+	TypeMismatches []TypeMismatch `json:"type_mismatches,omitempty"`
 }
 
+// RecommendOptions holds user settings that shape which recommendations are produced
+type RecommendOptions struct {
+	// ExcludeTables lists table name patterns that must never receive recommendations
+	ExcludeTables []string
+	// NoConsolidate keeps single-column recommendations from one operation separate
+	// instead of merging them into a composite index
+	NoConsolidate bool
+	// Concurrently emits CREATE INDEX CONCURRENTLY so building the index does not block writes
+	Concurrently bool
+	// ColumnStats holds pg_stats data keyed by "table.column", nil when the catalog is not consulted
+	ColumnStats map[string]ColumnStats
+	// Applied holds the signatures of indexes already created or rejected, see indexSignature
+	Applied map[string]bool
+}
+
+// ColumnStats is the planner statistics PostgreSQL keeps for a column in pg_stats
+type ColumnStats struct {
+	// NDistinct is the number of distinct values, or minus the fraction of rows when it grows with the table
+	NDistinct float64
+	// MostCommonFreq is the fraction of rows holding the most common value (0 when unknown)
+	MostCommonFreq float64
+}
+
+// lowCardinalityDistinct is the largest number of distinct values for which an index is
+// unlikely to help, such as a boolean column
+const lowCardinalityDistinct = 2
+
+// columnStats returns the statistics for a column, or nil when none were loaded
+func (options RecommendOptions) columnStats(tableName, column string) *ColumnStats {
+	stats, ok := options.ColumnStats[tableName+"."+column]
+	if !ok {
+		return nil
+	}
+	return &stats
+}
+
+// concurrentlyNote explains the restriction that comes with CREATE INDEX CONCURRENTLY
+const concurrentlyNote = "CREATE INDEX CONCURRENTLY cannot run inside a transaction block, run each statement on its own (not in BEGIN/COMMIT or psql --single-transaction)"
+
 // OperationContext holds parsed information about a single EXPLAIN line
 type OperationContext struct {
-	Line          string
-	OperationType string
-	TableName     string
-	FilterColumns []string
-	JoinColumns   []string
-	SortColumns   []string
-	Cost          float64
-	RowsEstimate  int64
+	Line                string
+	OperationType       string
+	TableName           string
+	FilterColumns       []string
+	JoinColumns         []string
+	SortColumns         []string
+	IndexCond           string
+	IndexCondColumns    []string
+	Filter              string
+	RowsRemovedByFilter int64
+	Cost                float64
+	RowsEstimate        int64
+	Loops               int64
+	// OutputColumns are the plain columns of the node's Output line, only present in EXPLAIN VERBOSE plans
+	OutputColumns []string
+	// OutputExpressions reports that the Output line also lists expressions such as count(*)
+	OutputExpressions bool
+	// TypeMismatches are the filter columns cast to another type, see detectTypeMismatches
+	TypeMismatches []TypeMismatch
 }
 
+// minRowsRemovedByFilter is the number of rows an index scan must discard by filter
+// before a better composite index is suggested
+const minRowsRemovedByFilter = 1000
+
+// maxIncludeColumns is the largest number of selected columns added to an index with INCLUDE,
+// wider covering indexes cost more to store and maintain than the index-only scan saves
+const maxIncludeColumns = 3
+
 // Regex patterns for parsing EXPLAIN output
 var (
-	tableNameRegex    = regexp.MustCompile(`(?:Seq Scan|Parallel Seq Scan|Index Scan|Index Only Scan|Bitmap Heap Scan)\s+on\s+(\w+)`)
-	filterRegex       = regexp.MustCompile(`Filter:\s*\(([^)]+(?:\([^)]*\)[^)]*)*)\)`)
+	tableNameRegex    = regexp.MustCompile(`(?:Parallel\s+)?(?:Seq Scan|Index Scan|Index Only Scan|Bitmap Heap Scan)(?:\s+Backward)?(?:\s+using\s+\w+)?\s+on\s+(?:\w+\.)?(\w+)`)
+	filterRegex       = regexp.MustCompile(`Filter:\s*\((.+)\)\s*$`)
+	indexCondRegex    = regexp.MustCompile(`Index Cond:\s*\((.+)\)\s*$`)
+	recheckCondRegex  = regexp.MustCompile(`Recheck Cond:\s*\((.+)\)\s*$`)
+	rowsRemovedRegex  = regexp.MustCompile(`Rows Removed by Filter:\s*(\d+)`)
 	filterColumnRegex = regexp.MustCompile(`\b(\w+)\s*(?:=|>|<|>=|<=|!=|<>|~~|LIKE|IN|IS)`)
 	hashCondRegex     = regexp.MustCompile(`Hash Cond:\s*\(([^)]+)\)`)
 	mergeCondRegex    = regexp.MustCompile(`Merge Cond:\s*\(([^)]+)\)`)
 	sortKeyRegex      = regexp.MustCompile(`Sort Key:\s*(.+)`)
+	modifyTableRegex  = regexp.MustCompile(`(?:Insert|Update|Delete|Merge) on (?:\w+\.)?(\w+)`)
+	outputRegex       = regexp.MustCompile(`^\s*Output:\s*(.+)$`)
+	outputColumnRegex = regexp.MustCompile(`^(?:\w+\.)?(\w+)$`)
 	costRegex         = regexp.MustCompile(`cost=(\d+\.?\d*)\.\.(\d+\.?\d*)`)
 	rowsRegex         = regexp.MustCompile(`rows=(\d+)`)
 	joinColumnRegex   = regexp.MustCompile(`(\w+)\.(\w+)\s*=\s*(\w+)\.(\w+)`)
+
+	// predicateOperatorRegex matches a column, an optional cast and an equality,
+	// IN or IS operator, as used by predicateRank
+	predicateOperatorRegex = regexp.MustCompile(`\b(\w+)\)?(?:::[a-z ]+?)?(?:\s*(=)|\s+((?i:IN|IS))\b)`)
 )
 
 // analyzeIndexOpportunities is the main entry point for index recommendation analysis
-func analyzeIndexOpportunities(plan string, threshold float64) *IndexRecommendationInfo {
+func analyzeIndexOpportunities(plan string, threshold float64, options RecommendOptions) *IndexRecommendationInfo {
 	contexts := parseExplainForIndexes(plan, threshold)
-	return generateIndexRecommendations(contexts, threshold)
+	return generateIndexRecommendations(contexts, threshold, options)
 }
 
 // parseExplainForIndexes parses the EXPLAIN output and extracts operation contexts
 func parseExplainForIndexes(plan string, threshold float64) []OperationContext {
 	contexts := []OperationContext{}
-	lines := strings.Split(plan, "\n")
 
-	for i, line := range lines {
+	// The operation whose detail lines (Output, Filter, Hash Cond, Sort Key...) may follow,
+	// and the number of lines read since. Lines are read one at a time, see planLines.
+	var current *OperationContext
+	detailLines := 0
+
+	for line := range planLines(plan) {
+		if current != nil {
+			// Details are the next few indented lines, up to the next plan node whose
+			// details belong to that node
+			indented := strings.HasPrefix(line, "  ") || strings.HasPrefix(line, "\t")
+			if detailLines < 5 && indented && !strings.Contains(line, "->") {
+				detailLines++
+				parseIndexDetail(current, line)
+			} else {
+				contexts = append(contexts, *current)
+				current = nil
+			}
+		}
+
 		// Skip empty lines
 		if strings.TrimSpace(line) == "" {
 			continue
@@ -101,108 +199,204 @@ func parseExplainForIndexes(plan string, threshold float64) []OperationContext {
 			continue
 		}
 
-		context := OperationContext{
+		if current != nil {
+			contexts = append(contexts, *current)
+		}
+		current = &OperationContext{
 			Line: strings.TrimSpace(line),
 			Cost: cost,
 		}
+		detailLines = 0
 
 		// Extract operation type
-		context.OperationType = extractOperationType(line)
+		current.OperationType = extractOperationType(line)
 
 		// Extract table name
 		if tableMatches := tableNameRegex.FindStringSubmatch(line); len(tableMatches) > 1 {
-			context.TableName = tableMatches[1]
+			current.TableName = tableMatches[1]
 		}
 
 		// Extract row estimate
 		if rowMatches := rowsRegex.FindStringSubmatch(line); len(rowMatches) > 1 {
-			context.RowsEstimate, _ = strconv.ParseInt(rowMatches[1], 10, 64)
+			current.RowsEstimate, _ = strconv.ParseInt(rowMatches[1], 10, 64)
 		}
 
-		// Look ahead for Filter, Hash Cond, Sort Key in next few lines (indented child lines)
-		for j := i + 1; j < len(lines) && j < i+5; j++ {
-			nextLine := lines[j]
+		// Executions of the node, only known from EXPLAIN ANALYZE
+		current.Loops = nodeLoops(line)
+	}
+	if current != nil {
+		contexts = append(contexts, *current)
+	}
+
+	return contexts
+}
+
+// parseIndexDetail reads a detail line of an operation, e.g. its Filter or Sort Key, into context
+func parseIndexDetail(context *OperationContext, line string) {
+	// Extract the selected columns of EXPLAIN VERBOSE plans
+	if outputMatches := outputRegex.FindStringSubmatch(line); len(outputMatches) > 1 {
+		context.OutputColumns, context.OutputExpressions = parseOutputColumns(outputMatches[1])
+	}
 
-			// Check if still indented (child of current operation)
-			if !strings.HasPrefix(nextLine, "  ") && !strings.HasPrefix(nextLine, "\t") {
-				break
+	// Extract index condition columns, a bitmap heap scan repeats the condition of
+	// its bitmap index scans as Recheck Cond
+	indexCondMatches := indexCondRegex.FindStringSubmatch(line)
+	if len(indexCondMatches) < 2 {
+		indexCondMatches = recheckCondRegex.FindStringSubmatch(line)
+	}
+	if len(indexCondMatches) > 1 {
+		context.IndexCond = indexCondMatches[1]
+		for _, match := range filterColumnRegex.FindAllStringSubmatch(stripCasts(context.IndexCond), -1) {
+			if len(match) > 1 && !containsString(context.IndexCondColumns, match[1]) {
+				context.IndexCondColumns = append(context.IndexCondColumns, match[1])
 			}
+		}
+	}
+
+	// Extract rows discarded after the scan
+	if removedMatches := rowsRemovedRegex.FindStringSubmatch(line); len(removedMatches) > 1 {
+		context.RowsRemovedByFilter, _ = strconv.ParseInt(removedMatches[1], 10, 64)
+	}
 
-			// Extract filter columns
-			if filterMatches := filterRegex.FindStringSubmatch(nextLine); len(filterMatches) > 1 {
-				filterExpr := filterMatches[1]
-				columnMatches := filterColumnRegex.FindAllStringSubmatch(filterExpr, -1)
-				for _, match := range columnMatches {
-					if len(match) > 1 {
-						// Avoid duplicates
-						col := match[1]
-						found := false
-						for _, existing := range context.FilterColumns {
-							if existing == col {
-								found = true
-								break
-							}
-						}
-						if !found {
-							context.FilterColumns = append(context.FilterColumns, col)
-						}
+	// Extract filter columns
+	if filterMatches := filterRegex.FindStringSubmatch(line); len(filterMatches) > 1 {
+		filterExpr := filterMatches[1]
+		context.Filter = filterExpr
+		context.TypeMismatches = detectTypeMismatches(filterExpr)
+		columnMatches := filterColumnRegex.FindAllStringSubmatch(stripCasts(filterExpr), -1)
+		for _, match := range columnMatches {
+			if len(match) > 1 {
+				// Avoid duplicates
+				col := match[1]
+				found := false
+				for _, existing := range context.FilterColumns {
+					if existing == col {
+						found = true
+						break
 					}
 				}
+				if !found {
+					context.FilterColumns = append(context.FilterColumns, col)
+				}
 			}
+		}
+	}
+
+	// Extract hash join columns
+	if hashMatches := hashCondRegex.FindStringSubmatch(line); len(hashMatches) > 1 {
+		joinExpr := hashMatches[1]
+		if joinColMatches := joinColumnRegex.FindStringSubmatch(joinExpr); len(joinColMatches) > 4 {
+			// Store as "table.column" pairs
+			context.JoinColumns = append(context.JoinColumns,
+				joinColMatches[1]+"."+joinColMatches[2],
+				joinColMatches[3]+"."+joinColMatches[4])
+		}
+	}
+
+	// Extract merge join columns
+	if mergeMatches := mergeCondRegex.FindStringSubmatch(line); len(mergeMatches) > 1 {
+		joinExpr := mergeMatches[1]
+		if joinColMatches := joinColumnRegex.FindStringSubmatch(joinExpr); len(joinColMatches) > 4 {
+			context.JoinColumns = append(context.JoinColumns,
+				joinColMatches[1]+"."+joinColMatches[2],
+				joinColMatches[3]+"."+joinColMatches[4])
+		}
+	}
 
-			// Extract hash join columns
-			if hashMatches := hashCondRegex.FindStringSubmatch(nextLine); len(hashMatches) > 1 {
-				joinExpr := hashMatches[1]
-				if joinColMatches := joinColumnRegex.FindStringSubmatch(joinExpr); len(joinColMatches) > 4 {
-					// Store as "table.column" pairs
-					context.JoinColumns = append(context.JoinColumns,
-						joinColMatches[1]+"."+joinColMatches[2],
-						joinColMatches[3]+"."+joinColMatches[4])
+	// Extract sort keys
+	if sortMatches := sortKeyRegex.FindStringSubmatch(line); len(sortMatches) > 1 {
+		sortKeys := strings.Split(sortMatches[1], ",")
+		for _, key := range sortKeys {
+			key = strings.TrimSpace(key)
+			// Remove DESC/ASC keywords
+			key = strings.TrimSuffix(key, " DESC")
+			key = strings.TrimSuffix(key, " ASC")
+			// Handle table.column or just column
+			if strings.Contains(key, ".") {
+				parts := strings.Split(key, ".")
+				if len(parts) >= 2 {
+					context.SortColumns = append(context.SortColumns, parts[1])
 				}
+			} else {
+				context.SortColumns = append(context.SortColumns, key)
 			}
+		}
+	}
+}
 
-			// Extract merge join columns
-			if mergeMatches := mergeCondRegex.FindStringSubmatch(nextLine); len(mergeMatches) > 1 {
-				joinExpr := mergeMatches[1]
-				if joinColMatches := joinColumnRegex.FindStringSubmatch(joinExpr); len(joinColMatches) > 4 {
-					context.JoinColumns = append(context.JoinColumns,
-						joinColMatches[1]+"."+joinColMatches[2],
-						joinColMatches[3]+"."+joinColMatches[4])
+// parseOutputColumns splits an Output line into its plain column names, without the
+// table alias. It also reports whether the line contains expressions or function calls.
+func parseOutputColumns(output string) ([]string, bool) {
+	var columns []string
+	hasExpressions := false
+
+	depth, start := 0, 0
+	for i := 0; i <= len(output); i++ {
+		if i < len(output) {
+			switch output[i] {
+			case '(':
+				depth++
+				continue
+			case ')':
+				depth--
+				continue
+			case ',':
+				if depth > 0 {
+					continue
 				}
+			default:
+				continue
 			}
+		}
 
-			// Extract sort keys
-			if sortMatches := sortKeyRegex.FindStringSubmatch(nextLine); len(sortMatches) > 1 {
-				sortKeys := strings.Split(sortMatches[1], ",")
-				for _, key := range sortKeys {
-					key = strings.TrimSpace(key)
-					// Remove DESC/ASC keywords
-					key = strings.TrimSuffix(key, " DESC")
-					key = strings.TrimSuffix(key, " ASC")
-					// Handle table.column or just column
-					if strings.Contains(key, ".") {
-						parts := strings.Split(key, ".")
-						if len(parts) >= 2 {
-							context.SortColumns = append(context.SortColumns, parts[1])
-						}
-					} else {
-						context.SortColumns = append(context.SortColumns, key)
-					}
-				}
+		item := strings.TrimSpace(output[start:i])
+		start = i + 1
+		if matches := outputColumnRegex.FindStringSubmatch(item); len(matches) > 1 {
+			if !containsString(columns, matches[1]) {
+				columns = append(columns, matches[1])
 			}
+		} else if item != "" {
+			hasExpressions = true
 		}
+	}
+	return columns, hasExpressions
+}
 
-		contexts = append(contexts, context)
+// coveringColumns returns the selected columns to add with INCLUDE so an index on columns
+// can answer the scan with an index-only scan. It needs the Output line of EXPLAIN VERBOSE
+// and returns nil when the scan selects expressions or more than maxIncludeColumns extra columns.
+func coveringColumns(ctx OperationContext, columns []string) []string {
+	if len(ctx.OutputColumns) == 0 || ctx.OutputExpressions {
+		return nil
 	}
 
-	return contexts
+	var include []string
+	for _, column := range ctx.OutputColumns {
+		if !containsString(columns, column) {
+			include = append(include, column)
+		}
+	}
+	if len(include) > maxIncludeColumns {
+		return nil
+	}
+	return include
+}
+
+// addCoveringColumns turns rec into a covering index when the scan selects only a few columns
+func addCoveringColumns(rec *IndexRecommendation, ctx OperationContext) {
+	rec.IncludeColumns = coveringColumns(ctx, rec.Columns)
+	if len(rec.IncludeColumns) > 0 {
+		rec.Reason += fmt.Sprintf(". INCLUDE (%s) covers the selected columns so the scan can become an index-only scan",
+			strings.Join(rec.IncludeColumns, ", "))
+	}
 }
 
 // generateIndexRecommendations analyzes operation contexts and generates recommendations
-func generateIndexRecommendations(contexts []OperationContext, threshold float64) *IndexRecommendationInfo {
+func generateIndexRecommendations(contexts []OperationContext, threshold float64, options RecommendOptions) *IndexRecommendationInfo {
 	info := &IndexRecommendationInfo{
 		Recommendations: []IndexRecommendation{},
 		ThresholdUsed:   threshold,
+		Concurrently:    options.Concurrently,
 	}
 
 	// Track recommendations to avoid duplicates (key: "table:column1,column2")
@@ -214,9 +408,29 @@ func generateIndexRecommendations(contexts []OperationContext, threshold float64
 			continue
 		}
 
+		// Recommendations from this operation start here, see the consolidation pass below
+		start := len(info.Recommendations)
+
+		// A column cast to another type cannot use a plain index, so it is reported instead
+		castCols := castColumns(ctx.TypeMismatches)
+		filterCols := []string{}
+		for _, col := range ctx.FilterColumns {
+			if !containsString(castCols, col) {
+				filterCols = append(filterCols, col)
+			}
+		}
+		for _, mismatch := range ctx.TypeMismatches {
+			mismatch.TableName = ctx.TableName
+			key := fmt.Sprintf("cast:%s:%s", mismatch.TableName, mismatch.Column)
+			if !seen[key] && !isExcludedTable(mismatch.TableName, options.ExcludeTables) {
+				info.TypeMismatches = append(info.TypeMismatches, mismatch)
+				seen[key] = true
+			}
+		}
+
 		// Rule 1: Sequential Scan with Filter -> Recommend index on filtered columns
-		if strings.Contains(ctx.OperationType, "Seq Scan") && len(ctx.FilterColumns) > 0 {
-			for _, col := range ctx.FilterColumns {
+		if strings.Contains(ctx.OperationType, "Seq Scan") && len(filterCols) > 0 {
+			for _, col := range filterCols {
 				rec := IndexRecommendation{
 					TableName:     ctx.TableName,
 					Columns:       []string{col},
@@ -224,12 +438,18 @@ func generateIndexRecommendations(contexts []OperationContext, threshold float64
 					Reason:        fmt.Sprintf("Sequential scan with filter on '%s'", col),
 					OperationType: ctx.OperationType,
 					OperationCost: ctx.Cost,
-					Priority:      calculatePriority(ctx.Cost, ctx.RowsEstimate, "filter"),
+					Priority:      calculatePriority(ctx.Cost, ctx.RowsEstimate, "filter", options.columnStats(ctx.TableName, col)),
+				}
+				if ctx.Loops > 1 {
+					rec.Reason += fmt.Sprintf(", executed %s times", formatThousands(ctx.Loops))
 				}
-				rec.CreateStatement = formatCreateIndexStatement(rec)
+				rec.EstimatedBenefit = estimateBenefit(ctx, "filter")
+				rec.SelectivityHint = selectivityHint(options.columnStats(ctx.TableName, col))
+				addCoveringColumns(&rec, ctx)
+				rec.CreateStatement = formatCreateIndexStatement(rec, options.Concurrently)
 
 				key := fmt.Sprintf("%s:%s", rec.TableName, strings.Join(rec.Columns, ","))
-				if validateRecommendation(rec) && !seen[key] {
+				if validateRecommendation(rec, options) && !seen[key] {
 					info.Recommendations = append(info.Recommendations, rec)
 					seen[key] = true
 				}
@@ -257,12 +477,14 @@ func generateIndexRecommendations(contexts []OperationContext, threshold float64
 					Reason:        fmt.Sprintf("Join condition on '%s.%s'", tableName, columnName),
 					OperationType: ctx.OperationType,
 					OperationCost: ctx.Cost,
-					Priority:      calculatePriority(ctx.Cost, ctx.RowsEstimate, "join"),
+					Priority:      calculatePriority(ctx.Cost, ctx.RowsEstimate, "join", options.columnStats(tableName, columnName)),
 				}
-				rec.CreateStatement = formatCreateIndexStatement(rec)
+				rec.EstimatedBenefit = estimateBenefit(ctx, "join")
+				rec.SelectivityHint = selectivityHint(options.columnStats(tableName, columnName))
+				rec.CreateStatement = formatCreateIndexStatement(rec, options.Concurrently)
 
 				key := fmt.Sprintf("%s:%s", rec.TableName, strings.Join(rec.Columns, ","))
-				if validateRecommendation(rec) && !seen[key] {
+				if validateRecommendation(rec, options) && !seen[key] {
 					info.Recommendations = append(info.Recommendations, rec)
 					seen[key] = true
 				}
@@ -279,20 +501,78 @@ func generateIndexRecommendations(contexts []OperationContext, threshold float64
 				Reason:        fmt.Sprintf("Expensive sort operation on %s", strings.Join(ctx.SortColumns, ", ")),
 				OperationType: ctx.OperationType,
 				OperationCost: ctx.Cost,
-				Priority:      calculatePriority(ctx.Cost, ctx.RowsEstimate, "sort"),
+				Priority:      calculatePriority(ctx.Cost, ctx.RowsEstimate, "sort", options.columnStats(ctx.TableName, ctx.SortColumns[0])),
 			}
-			rec.CreateStatement = formatCreateIndexStatement(rec)
+			rec.EstimatedBenefit = estimateBenefit(ctx, "sort")
+			rec.SelectivityHint = selectivityHint(options.columnStats(ctx.TableName, ctx.SortColumns[0]))
+			rec.CreateStatement = formatCreateIndexStatement(rec, options.Concurrently)
 
 			key := fmt.Sprintf("%s:%s", rec.TableName, strings.Join(rec.Columns, ","))
-			if validateRecommendation(rec) && !seen[key] {
+			if validateRecommendation(rec, options) && !seen[key] {
 				info.Recommendations = append(info.Recommendations, rec)
 				seen[key] = true
 			}
 		}
+
+		// Rule 4: Index or bitmap heap scan discarding many rows by filter -> Recommend a composite index covering the filter
+		if (strings.Contains(ctx.OperationType, "Index") || strings.Contains(ctx.OperationType, "Bitmap Heap Scan")) &&
+			ctx.IndexCond != "" && len(filterCols) > 0 &&
+			ctx.RowsRemovedByFilter >= minRowsRemovedByFilter && ctx.RowsRemovedByFilter > ctx.RowsEstimate {
+
+			columns := append([]string{}, ctx.IndexCondColumns...)
+			for _, col := range filterCols {
+				if !containsString(columns, col) {
+					columns = append(columns, col)
+				}
+			}
+
+			rec := IndexRecommendation{
+				TableName: ctx.TableName,
+				Columns:   columns,
+				IndexType: "BTREE",
+				Reason: fmt.Sprintf("Index scan removes %d rows by filter on %s after index condition (%s)",
+					ctx.RowsRemovedByFilter, strings.Join(filterCols, ", "), ctx.IndexCond),
+				OperationType: ctx.OperationType,
+				OperationCost: ctx.Cost,
+				Priority:      calculatePriority(ctx.Cost, ctx.RowsRemovedByFilter, "filter", options.columnStats(ctx.TableName, columns[0])),
+			}
+			rec.EstimatedBenefit = estimateBenefit(ctx, "filter")
+			rec.SelectivityHint = selectivityHint(options.columnStats(ctx.TableName, columns[0]))
+			addCoveringColumns(&rec, ctx)
+			rec.CreateStatement = formatCreateIndexStatement(rec, options.Concurrently)
+
+			key := fmt.Sprintf("%s:%s", rec.TableName, strings.Join(rec.Columns, ","))
+			if validateRecommendation(rec, options) && !seen[key] {
+				info.Recommendations = append(info.Recommendations, rec)
+				seen[key] = true
+			}
+		}
+
+		// Consolidation pass: several single-column indexes on one table for the same
+		// operation are usually better served by a single composite index
+		if !options.NoConsolidate {
+			// Forget the merged single-column keys so a later operation can still
+			// recommend one of those indexes on its own
+			for _, rec := range info.Recommendations[start:] {
+				delete(seen, fmt.Sprintf("%s:%s", rec.TableName, strings.Join(rec.Columns, ",")))
+			}
+			consolidated := consolidateRecommendations(info.Recommendations[start:], ctx, options)
+			info.Recommendations = append(info.Recommendations[:start], consolidated...)
+			for _, rec := range consolidated {
+				seen[fmt.Sprintf("%s:%s", rec.TableName, strings.Join(rec.Columns, ","))] = true
+			}
+		}
+	}
+
+	// Describe the expected gain and the write overhead of each index
+	writeTables := modifiedTables(contexts)
+	for i := range info.Recommendations {
+		info.Recommendations[i].EstimatedImpact = describeImpact(info.Recommendations[i], writeTables[info.Recommendations[i].TableName])
 	}
 
-	// Sort by priority (descending) then by cost (descending)
+	// Sort by priority (descending) then by estimated benefit (descending)
 	sortRecommendations(info.Recommendations)
+	separateAppliedRecommendations(info, options.Applied)
 
 	info.TotalFound = len(info.Recommendations)
 	for _, rec := range info.Recommendations {
@@ -304,8 +584,101 @@ func generateIndexRecommendations(contexts []OperationContext, threshold float64
 	return info
 }
 
-// calculatePriority assigns priority based on cost, rows, and operation type
-func calculatePriority(cost float64, rows int64, operationType string) int {
+// consolidateRecommendations merges single-column recommendations on the same table,
+// all generated for one operation, into one composite recommendation per table.
+// Columns are ordered by priority, then equality predicates before IN/IS and range
+// predicates so the most selective lookups lead the index.
+func consolidateRecommendations(recs []IndexRecommendation, ctx OperationContext, options RecommendOptions) []IndexRecommendation {
+	singleByTable := make(map[string][]IndexRecommendation)
+	for _, rec := range recs {
+		if len(rec.Columns) == 1 {
+			singleByTable[rec.TableName] = append(singleByTable[rec.TableName], rec)
+		}
+	}
+
+	result := []IndexRecommendation{}
+	merged := make(map[string]bool)
+	for _, rec := range recs {
+		group := singleByTable[rec.TableName]
+		if len(rec.Columns) != 1 || len(group) < 2 {
+			result = append(result, rec)
+			continue
+		}
+		if merged[rec.TableName] {
+			continue
+		}
+		merged[rec.TableName] = true
+
+		// Stable insertion sort keeps the plan order for columns that rank the same
+		ordered := append([]IndexRecommendation{}, group...)
+		for i := 1; i < len(ordered); i++ {
+			for j := i; j > 0 && columnRanksBefore(ordered[j], ordered[j-1], ctx.Filter); j-- {
+				ordered[j], ordered[j-1] = ordered[j-1], ordered[j]
+			}
+		}
+
+		columns := make([]string, 0, len(ordered))
+		priority := 0
+		benefit := 0.0
+		for _, single := range ordered {
+			columns = append(columns, single.Columns[0])
+			if single.Priority > priority {
+				priority = single.Priority
+			}
+			if single.EstimatedBenefit > benefit {
+				benefit = single.EstimatedBenefit
+			}
+		}
+
+		composite := IndexRecommendation{
+			TableName: rec.TableName,
+			Columns:   columns,
+			IndexType: rec.IndexType,
+			Reason: fmt.Sprintf("%s with conditions on %s, consolidated from %d single-column indexes. "+
+				"One composite index costs less to maintain, but only helps queries that filter on its leading column '%s' "+
+				"(use --no-consolidate to see the individual indexes)",
+				ctx.OperationType, strings.Join(columns, ", "), len(columns), columns[0]),
+			OperationType:    rec.OperationType,
+			OperationCost:    rec.OperationCost,
+			Priority:         priority,
+			EstimatedBenefit: benefit,
+			SelectivityHint:  ordered[0].SelectivityHint,
+		}
+		addCoveringColumns(&composite, ctx)
+		composite.CreateStatement = formatCreateIndexStatement(composite, options.Concurrently)
+		result = append(result, composite)
+	}
+
+	return result
+}
+
+// columnRanksBefore reports whether a should lead b in a consolidated index
+func columnRanksBefore(a, b IndexRecommendation, filter string) bool {
+	if a.Priority != b.Priority {
+		return a.Priority > b.Priority
+	}
+	return predicateRank(filter, a.Columns[0]) < predicateRank(filter, b.Columns[0])
+}
+
+// predicateRank estimates how selective the predicate on a column is:
+// 0 for equality, 1 for IN / IS, 2 for ranges, LIKE and anything else
+func predicateRank(filter, column string) int {
+	rank := 2
+	for _, match := range predicateOperatorRegex.FindAllStringSubmatch(filter, -1) {
+		if match[1] != column {
+			continue
+		}
+		if match[2] == "=" {
+			return 0
+		}
+		rank = 1
+	}
+	return rank
+}
+
+// calculatePriority assigns priority based on cost, rows, operation type and, when
+// available, the selectivity of the leading column from pg_stats
+func calculatePriority(cost float64, rows int64, operationType string, stats *ColumnStats) int {
 	// Base priority on cost
 	priority := 1
 
@@ -329,26 +702,111 @@ func calculatePriority(cost float64, rows int64, operationType string) int {
 		priority = minInt(5, priority+1)
 	}
 
+	// Selective columns make good index keys, columns with few values rarely do
+	if stats != nil {
+		if stats.NDistinct < 0 || stats.NDistinct > 1000 {
+			priority = minInt(5, priority+1)
+		} else if stats.NDistinct <= 10 && priority > 1 {
+			priority--
+		}
+	}
+
 	return priority
 }
 
-// formatCreateIndexStatement generates the CREATE INDEX SQL
-func formatCreateIndexStatement(rec IndexRecommendation) string {
+// selectivityHint describes how selective a column is according to pg_stats
+func selectivityHint(stats *ColumnStats) string {
+	if stats == nil {
+		return ""
+	}
+
+	var hint string
+	switch {
+	case stats.NDistinct < 0:
+		hint = fmt.Sprintf("high (about %.0f%% of rows are distinct)", -stats.NDistinct*100)
+	case stats.NDistinct > 1000:
+		hint = fmt.Sprintf("high (%.0f distinct values)", stats.NDistinct)
+	case stats.NDistinct > 10:
+		hint = fmt.Sprintf("medium (%.0f distinct values)", stats.NDistinct)
+	default:
+		hint = fmt.Sprintf("low (%.0f distinct values)", stats.NDistinct)
+	}
+
+	if stats.MostCommonFreq >= 0.5 {
+		hint += fmt.Sprintf(", most common value covers %.0f%% of rows", stats.MostCommonFreq*100)
+	}
+	return hint
+}
+
+// estimateBenefit approximates the operation cost an index could save.
+// For filters it is the share of the scanned rows that the filter throws away, when
+// EXPLAIN ANALYZE reports it; otherwise half the operation cost is assumed.
+func estimateBenefit(ctx OperationContext, operationType string) float64 {
+	if operationType == "filter" && ctx.RowsRemovedByFilter > 0 {
+		scanned := ctx.RowsRemovedByFilter + ctx.RowsEstimate
+		return ctx.Cost * float64(ctx.RowsRemovedByFilter) / float64(scanned)
+	}
+	return ctx.Cost * 0.5
+}
+
+// modifiedTables returns the tables written by Insert, Update, Delete or Merge nodes in the plan
+func modifiedTables(contexts []OperationContext) map[string]bool {
+	tables := make(map[string]bool)
+	for _, ctx := range contexts {
+		if matches := modifyTableRegex.FindStringSubmatch(ctx.Line); len(matches) > 1 {
+			tables[matches[1]] = true
+		}
+	}
+	return tables
+}
+
+// describeImpact summarizes the expected cost reduction and the write-amplification risk of an index
+func describeImpact(rec IndexRecommendation, writeHeavy bool) string {
+	impact := fmt.Sprintf("Could save up to ~%.0f cost units", rec.EstimatedBenefit)
+	if rec.OperationCost > 0 {
+		impact += fmt.Sprintf(" (%.0f%% of the %s cost)", rec.EstimatedBenefit/rec.OperationCost*100, rec.OperationType)
+	}
+
+	if writeHeavy {
+		return impact + ". High write-amplification risk: this statement modifies " + rec.TableName +
+			", and every index on it slows down INSERT/UPDATE/DELETE"
+	}
+	return impact + ". Write overhead depends on how often " + rec.TableName + " is modified"
+}
+
+// formatCreateIndexStatement generates the CREATE INDEX SQL.
+// IF NOT EXISTS makes re-applying a script of recommendations safe. CONCURRENTLY avoids
+// the lock that blocks writes while the index is built, which matters on production databases.
+func formatCreateIndexStatement(rec IndexRecommendation, concurrently bool) string {
 	// Generate a meaningful index name: idx_<table>_<col1>_<col2>
 	indexName := fmt.Sprintf("idx_%s_%s", rec.TableName, strings.Join(rec.Columns, "_"))
 
 	// Format columns
 	columnList := strings.Join(rec.Columns, ", ")
 
-	return fmt.Sprintf("CREATE INDEX %s ON %s USING %s (%s);",
+	createIndex := "CREATE INDEX"
+	if concurrently {
+		createIndex = "CREATE INDEX CONCURRENTLY"
+	}
+
+	// INCLUDE needs PostgreSQL 11 or later
+	include := ""
+	if len(rec.IncludeColumns) > 0 {
+		include = fmt.Sprintf(" INCLUDE (%s)", strings.Join(rec.IncludeColumns, ", "))
+	}
+
+	return fmt.Sprintf("%s IF NOT EXISTS %s ON %s USING %s (%s)%s;",
+		createIndex,
 		indexName,
 		rec.TableName,
 		rec.IndexType,
-		columnList)
+		columnList,
+		include)
 }
 
-// validateRecommendation checks if a recommendation is valid
-func validateRecommendation(rec IndexRecommendation) bool {
+// validateRecommendation checks if a recommendation is valid, its table is not excluded
+// and, when statistics are available, its leading column is not low cardinality
+func validateRecommendation(rec IndexRecommendation, options RecommendOptions) bool {
 	// Must have table name
 	if rec.TableName == "" {
 		return false
@@ -367,9 +825,20 @@ func validateRecommendation(rec IndexRecommendation) bool {
 		}
 	}
 
+	// Exclude tables the user never wants indexed
+	if isExcludedTable(rec.TableName, options.ExcludeTables) {
+		return false
+	}
+
+	// Skip columns such as booleans where an index is unlikely to help
+	if stats := options.columnStats(rec.TableName, rec.Columns[0]); stats != nil &&
+		stats.NDistinct > 0 && stats.NDistinct <= lowCardinalityDistinct {
+		return false
+	}
+
 	// Valid column names (alphanumeric + underscore)
 	validColumnName := regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
-	for _, col := range rec.Columns {
+	for _, col := range append(append([]string{}, rec.Columns...), rec.IncludeColumns...) {
 		if !validColumnName.MatchString(col) {
 			return false
 		}
@@ -378,18 +847,51 @@ func validateRecommendation(rec IndexRecommendation) bool {
 	return true
 }
 
-// sortRecommendations sorts by priority (desc) then cost (desc)
-func sortRecommendations(recommendations []IndexRecommendation) {
-	// Bubble sort - simple implementation
-	for i := 0; i < len(recommendations); i++ {
-		for j := i + 1; j < len(recommendations); j++ {
-			if recommendations[j].Priority > recommendations[i].Priority ||
-				(recommendations[j].Priority == recommendations[i].Priority &&
-					recommendations[j].OperationCost > recommendations[i].OperationCost) {
-				recommendations[i], recommendations[j] = recommendations[j], recommendations[i]
+// isExcludedTable reports whether a table matches one of the exclusion patterns.
+// Patterns containing *, ? or [ are glob patterns (staging_*), any other pattern
+// matches as a prefix (tmp_ excludes tmp_import). Matching is case-insensitive.
+func isExcludedTable(tableName string, patterns []string) bool {
+	name := strings.ToLower(tableName)
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if pattern == "" {
+			continue
+		}
+
+		if strings.ContainsAny(pattern, "*?[") {
+			if matched, err := path.Match(pattern, name); err == nil && matched {
+				return true
 			}
+			continue
+		}
+
+		if strings.HasPrefix(name, pattern) {
+			return true
 		}
 	}
+	return false
+}
+
+// sortRecommendations sorts by priority (desc) then estimated benefit (desc)
+func sortRecommendations(recommendations []IndexRecommendation) {
+	// Stable, so recommendations that rank the same keep their plan order and
+	// the same plan always yields the same output
+	sort.SliceStable(recommendations, func(i, j int) bool {
+		if recommendations[i].Priority != recommendations[j].Priority {
+			return recommendations[i].Priority > recommendations[j].Priority
+		}
+		return recommendations[i].EstimatedBenefit > recommendations[j].EstimatedBenefit
+	})
+}
+
+// containsString reports whether values contains value
+func containsString(values []string, value string) bool {
+	for _, existing := range values {
+		if existing == value {
+			return true
+		}
+	}
+	return false
 }
 
 // minInt returns the minimum of two integers
@@ -400,8 +902,24 @@ func minInt(a, b int) int {
 	return b
 }
 
-// displayIndexRecommendations prints recommendations to console
-func displayIndexRecommendations(info *IndexRecommendationInfo) {
+// displayIndexRecommendations prints recommendations to console. A positive head shows only
+// the first head recommendations by priority and benefit, with a note on how many were left out.
+func displayIndexRecommendations(info *IndexRecommendationInfo, head int) {
+	if len(info.AlreadyApplied) > 0 {
+		fmt.Printf("✅ Skipped %d already applied: %s\n", len(info.AlreadyApplied), formatAppliedRecommendations(info))
+		if info.TotalFound == 0 {
+			fmt.Println()
+		}
+	}
+	if len(info.TypeMismatches) > 0 {
+		fmt.Println("🔀 Casts on filtered columns prevent index use:")
+		for _, mismatch := range info.TypeMismatches {
+			fmt.Printf("   • %s\n", typeMismatchWarning(mismatch))
+		}
+		if info.TotalFound == 0 {
+			fmt.Println()
+		}
+	}
 	if info.TotalFound == 0 {
 		return
 	}
@@ -409,16 +927,24 @@ func displayIndexRecommendations(info *IndexRecommendationInfo) {
 	fmt.Println("\n" + strings.Repeat("=", 70))
 	fmt.Printf("🎯 INDEX RECOMMENDATIONS\n")
 	fmt.Println(strings.Repeat("=", 70))
+	shown := info.Recommendations
+	if head > 0 && head < len(shown) {
+		shown = shown[:head]
+	}
+
 	fmt.Printf("Found: %d recommendations", info.TotalFound)
 	if info.HighPriority > 0 {
 		fmt.Printf(" (%d high priority)", info.HighPriority)
 	}
+	if len(shown) < len(info.Recommendations) {
+		fmt.Printf(", showing the top %d", len(shown))
+	}
 	fmt.Println()
 	fmt.Printf("Threshold: Operations with cost >= %.0f\n", info.ThresholdUsed)
 
 	// Group by priority for better readability
 	priorityGroups := make(map[int][]IndexRecommendation)
-	for _, rec := range info.Recommendations {
+	for _, rec := range shown {
 		priorityGroups[rec.Priority] = append(priorityGroups[rec.Priority], rec)
 	}
 
@@ -437,17 +963,32 @@ func displayIndexRecommendations(info *IndexRecommendationInfo) {
 			fmt.Printf("   Columns: %s\n", strings.Join(rec.Columns, ", "))
 			fmt.Printf("   Reason: %s\n", rec.Reason)
 			fmt.Printf("   Operation: %s (Cost: %.2f)\n", rec.OperationType, rec.OperationCost)
+			fmt.Printf("   Impact: %s\n", rec.EstimatedImpact)
+			if rec.SelectivityHint != "" {
+				fmt.Printf("   Selectivity: %s\n", rec.SelectivityHint)
+			}
+			if rec.Hypothetical != nil {
+				fmt.Printf("   Projected (HypoPG): %s\n", formatHypotheticalResult(rec.Hypothetical))
+			}
 			fmt.Printf("   \n")
 			fmt.Printf("   %s\n", rec.CreateStatement)
 		}
 	}
 
+	if omitted := len(info.Recommendations) - len(shown); omitted > 0 {
+		fmt.Println("\n" + strings.Repeat("-", 70))
+		fmt.Printf("… %d lower-ranked recommendations omitted, run without --head to see all (JSON and Markdown reports include every one)\n", omitted)
+	}
+
 	fmt.Println("\n" + strings.Repeat("=", 70))
 	fmt.Println("💡 Tips:")
 	fmt.Println("   • Test indexes on a development database first")
 	fmt.Println("   • Monitor index usage with pg_stat_user_indexes")
 	fmt.Println("   • Consider impact on INSERT/UPDATE performance")
 	fmt.Println("   • Combine multiple single-column indexes into composite indexes where appropriate")
+	if info.Concurrently {
+		fmt.Printf("   ⚠️  %s\n", concurrentlyNote)
+	}
 	fmt.Println(strings.Repeat("=", 70) + "\n")
 }
 
@@ -0,0 +1,80 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRecommendationsForParallelScans(t *testing.T) {
+	tests := []struct {
+		file      string
+		operation string
+		table     string
+		columns   []string
+	}{
+		{"pg12.txt", "Parallel Index Scan", "orders", []string{"created_at", "status"}},
+		{"pg16.txt", "Parallel Bitmap Heap Scan", "orders", []string{"customer_id", "status"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			info := analyzeIndexOpportunities(string(data), 1000, RecommendOptions{})
+			for _, rec := range info.Recommendations {
+				if rec.OperationType != tt.operation {
+					continue
+				}
+				if rec.TableName != tt.table || !reflect.DeepEqual(rec.Columns, tt.columns) {
+					t.Errorf("%s recommendation = %s(%v), want %s(%v)", tt.operation, rec.TableName, rec.Columns, tt.table, tt.columns)
+				}
+				return
+			}
+			t.Errorf("no recommendation for the %s, got %+v", tt.operation, info.Recommendations)
+		})
+	}
+}
+
+func TestRecommendationsFromSavedJSONPlan(t *testing.T) {
+	plan := `[{"Plan": {"Node Type": "Seq Scan", "Relation Name": "orders", "Alias": "orders",
+		"Startup Cost": 0.00, "Total Cost": 25000.00, "Plan Rows": 120, "Plan Width": 64,
+		"Filter": "(customer_id = 42)"}}]`
+
+	analysisPlan, err := savedPlanForRecommendations(plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := analyzeIndexOpportunities(analysisPlan, 1000, RecommendOptions{})
+	if len(info.Recommendations) != 1 {
+		t.Fatalf("got %d recommendations from the JSON plan, want 1: %+v", len(info.Recommendations), info.Recommendations)
+	}
+	if rec := info.Recommendations[0]; rec.TableName != "orders" || !reflect.DeepEqual(rec.Columns, []string{"customer_id"}) {
+		t.Errorf("recommendation = %s(%v), want orders([customer_id])", rec.TableName, rec.Columns)
+	}
+
+	if _, err := savedPlanForRecommendations("<explain></explain>"); err == nil {
+		t.Error("an XML plan was accepted")
+	}
+}
@@ -0,0 +1,102 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ioBoundShare is the share of the execution time spent waiting for I/O above which a query is reported as disk-bound
+const ioBoundShare = 0.5
+
+var (
+	// ioTimingsRegex matches the "I/O Timings:" detail line that EXPLAIN (ANALYZE, BUFFERS) adds with track_io_timing on
+	ioTimingsRegex = regexp.MustCompile(`^\s*I/O Timings:`)
+	// ioTimeRegex matches the read and write times of the line, e.g. "read=12.345" or "shared read=12.345"
+	ioTimeRegex = regexp.MustCompile(`\b(read|write)=(\d+\.?\d*)`)
+)
+
+// parseIOTimings returns the time the query spent reading and writing blocks. The first
+// "I/O Timings:" line belongs to the root node, whose figures include all of its children.
+// PostgreSQL 16 splits the line into shared, local and temp blocks, which are summed.
+// I/O during planning is listed after the plan and is not counted.
+func parseIOTimings(plan string) (readMs, writeMs float64) {
+	for line := range planLines(plan) {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "Planning") {
+			break
+		}
+		if !ioTimingsRegex.MatchString(line) {
+			continue
+		}
+
+		for _, match := range ioTimeRegex.FindAllStringSubmatch(line, -1) {
+			ms, err := strconv.ParseFloat(match[2], 64)
+			if err != nil {
+				continue
+			}
+			if match[1] == "read" {
+				readMs += ms
+			} else {
+				writeMs += ms
+			}
+		}
+		break
+	}
+	return readMs, writeMs
+}
+
+// ioShare returns the share of the execution time spent on I/O, or 0 without timings
+func ioShare(costInfo *CostInfo) float64 {
+	if costInfo == nil || costInfo.ExecutionTimeMs <= 0 {
+		return 0
+	}
+	return (costInfo.IOReadMs + costInfo.IOWriteMs) / costInfo.ExecutionTimeMs
+}
+
+// ioBoundWarning explains a query that spends most of its time waiting for the disk,
+// or returns "" when I/O does not dominate or was not timed
+func ioBoundWarning(costInfo *CostInfo) string {
+	share := ioShare(costInfo)
+	if share < ioBoundShare {
+		return ""
+	}
+	return fmt.Sprintf("Disk-bound: %.0f%% of the execution time was spent waiting for I/O. "+
+		"An index that reads fewer blocks, or more memory for caching (shared_buffers), would help more than a cheaper plan",
+		minFloat(share, 1)*100)
+}
+
+// formatIOTimings summarizes the I/O time, e.g. "read 8.20 ms, write 0.00 ms (64% of execution time)"
+func formatIOTimings(costInfo *CostInfo) string {
+	summary := fmt.Sprintf("read %.2f ms, write %.2f ms", costInfo.IOReadMs, costInfo.IOWriteMs)
+	if share := ioShare(costInfo); share > 0 {
+		summary += fmt.Sprintf(" (%.0f%% of execution time)", minFloat(share, 1)*100)
+	}
+	return summary
+}
+
+// minFloat returns the minimum of two floats
+func minFloat(a, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,62 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseIOTimings(t *testing.T) {
+	pg16, err := os.ReadFile(filepath.Join("testdata", "pg16.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		plan      string
+		format    string
+		readMs    float64
+		writeMs   float64
+		diskBound bool
+	}{
+		{"text plan", string(pg16), "text", 98.411, 0, true},
+		{"JSON plan before PostgreSQL 16", `[{"Plan": {"Node Type": "Seq Scan", "Relation Name": "orders",
+			"Startup Cost": 0, "Total Cost": 1000, "Plan Rows": 1000, "Plan Width": 4,
+			"Actual Startup Time": 0.1, "Actual Total Time": 90, "Actual Rows": 1000, "Actual Loops": 1,
+			"I/O Read Time": 80.5, "I/O Write Time": 1.5}, "Execution Time": 100}]`, "json", 80.5, 1.5, true},
+		{"JSON plan from PostgreSQL 16", `[{"Plan": {"Node Type": "Seq Scan", "Relation Name": "orders",
+			"Startup Cost": 0, "Total Cost": 1000, "Plan Rows": 1000, "Plan Width": 4,
+			"Actual Startup Time": 0.1, "Actual Total Time": 90, "Actual Rows": 1000, "Actual Loops": 1,
+			"Shared I/O Read Time": 60, "Shared I/O Write Time": 0, "Temp I/O Read Time": 10, "Temp I/O Write Time": 5}, "Execution Time": 100}]`, "json", 70, 5, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			costInfo := parseCost(planForAnalysis(tt.plan, tt.format), 0, 0)
+			if costInfo.IOReadMs != tt.readMs || costInfo.IOWriteMs != tt.writeMs {
+				t.Errorf("I/O read %.3f ms, write %.3f ms, want %.3f and %.3f ms", costInfo.IOReadMs, costInfo.IOWriteMs, tt.readMs, tt.writeMs)
+			}
+			if warned := ioBoundWarning(costInfo) != ""; warned != tt.diskBound {
+				t.Errorf("disk-bound warning = %v, want %v", warned, tt.diskBound)
+			}
+		})
+	}
+}
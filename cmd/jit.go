@@ -0,0 +1,142 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// jitOverheadShare is the share of the execution time spent on JIT compilation above which it is reported
+const jitOverheadShare = 0.2
+
+// JITInfo is the "JIT:" section EXPLAIN prints after the plan when the query was JIT-compiled.
+// The timings are only present with ANALYZE.
+type JITInfo struct {
+	Functions      int
+	GenerationMs   float64
+	InliningMs     float64
+	OptimizationMs float64
+	EmissionMs     float64
+	TotalMs        float64
+}
+
+var (
+	jitFunctionsRegex = regexp.MustCompile(`^\s*Functions:\s*(\d+)`)
+	// jitTimingRegex matches the phases of the "Timing:" line, e.g. "Generation 1.234 ms (Deform 0.512 ms)".
+	// The Deform part that PostgreSQL 17 adds is included in the Generation time.
+	jitTimingRegex = regexp.MustCompile(`\b(Generation|Inlining|Optimization|Emission|Total) (\d+\.?\d*) ms`)
+)
+
+// parseJIT reads the JIT section of the plan, or returns nil when the query was not JIT-compiled.
+// Parallel workers may print their own sections inside the plan with VERBOSE; the last one is
+// the summary for the whole query.
+func parseJIT(plan string) *JITInfo {
+	var jit *JITInfo
+	inSection := false
+	for line := range planLines(plan) {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "JIT:" {
+			jit = &JITInfo{}
+			inSection = true
+			continue
+		}
+		if !inSection {
+			continue
+		}
+		if !strings.HasPrefix(trimmed, "Functions:") && !strings.HasPrefix(trimmed, "Options:") && !strings.HasPrefix(trimmed, "Timing:") {
+			inSection = false
+			continue
+		}
+		if matches := jitFunctionsRegex.FindStringSubmatch(line); matches != nil {
+			jit.Functions, _ = strconv.Atoi(matches[1])
+		}
+		if !strings.HasPrefix(trimmed, "Timing:") {
+			continue
+		}
+		for _, match := range jitTimingRegex.FindAllStringSubmatch(line, -1) {
+			ms, err := strconv.ParseFloat(match[2], 64)
+			if err != nil {
+				continue
+			}
+			switch match[1] {
+			case "Generation":
+				jit.GenerationMs = ms
+			case "Inlining":
+				jit.InliningMs = ms
+			case "Optimization":
+				jit.OptimizationMs = ms
+			case "Emission":
+				jit.EmissionMs = ms
+			case "Total":
+				jit.TotalMs = ms
+			}
+		}
+	}
+	if jit == nil {
+		return nil
+	}
+
+	if jit.TotalMs == 0 {
+		jit.TotalMs = jit.GenerationMs + jit.InliningMs + jit.OptimizationMs + jit.EmissionMs
+	}
+	return jit
+}
+
+// jitShare returns the share of the execution time spent on JIT compilation, or 0 without timings
+func jitShare(costInfo *CostInfo) float64 {
+	if costInfo == nil || costInfo.JITInfo == nil || costInfo.ExecutionTimeMs <= 0 {
+		return 0
+	}
+	return costInfo.JITInfo.TotalMs / costInfo.ExecutionTimeMs
+}
+
+// jitOverheadWarning explains a query whose JIT compilation takes a large part of its run time,
+// or returns "" when JIT was not used, not timed or is cheap compared to the execution
+func jitOverheadWarning(costInfo *CostInfo) string {
+	share := jitShare(costInfo)
+	if share < jitOverheadShare {
+		return ""
+	}
+
+	advice := fmt.Sprintf("Raise jit_above_cost above the plan's cost of %.0f, or SET jit = off for such short queries", costInfo.TotalCost)
+	jit := costInfo.JITInfo
+	if jit.InliningMs+jit.OptimizationMs >= jit.TotalMs/2 {
+		advice = fmt.Sprintf("Most of it is inlining and optimization, so raising jit_inline_above_cost and jit_optimize_above_cost "+
+			"above the plan's cost of %.0f may be enough; SET jit = off avoids JIT altogether", costInfo.TotalCost)
+	}
+	return fmt.Sprintf("%.0f%% of the execution time was spent JIT-compiling %d functions. %s",
+		minFloat(share, 1)*100, jit.Functions, advice)
+}
+
+// formatJIT summarizes the JIT section, e.g.
+// "12 functions, 91.60 ms (generation 1.23, inlining 10.12, optimization 50.10, emission 30.20) (76% of execution time)"
+func formatJIT(costInfo *CostInfo) string {
+	jit := costInfo.JITInfo
+	if jit.TotalMs == 0 {
+		return fmt.Sprintf("%d functions (no timing without ANALYZE)", jit.Functions)
+	}
+	summary := fmt.Sprintf("%d functions, %.2f ms (generation %.2f, inlining %.2f, optimization %.2f, emission %.2f)",
+		jit.Functions, jit.TotalMs, jit.GenerationMs, jit.InliningMs, jit.OptimizationMs, jit.EmissionMs)
+	if share := jitShare(costInfo); share > 0 {
+		summary += fmt.Sprintf(" (%.0f%% of execution time)", minFloat(share, 1)*100)
+	}
+	return summary
+}
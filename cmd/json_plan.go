@@ -25,24 +25,46 @@ import (
 )
 
 type PlanOutput struct {
-	Title         string     `json:"title"`
-	Query         string     `json:"query"`
-	ExecutionPlan string     `json:"execution_plan"`
-	GeneratedAt   time.Time  `json:"generated_at"`
-	CostAnalysis  *CostInfo  `json:"cost_analysis,omitempty"`
+	Title                string                   `json:"title"`
+	Query                string                   `json:"query"`
+	ExecutionPlan        string                   `json:"execution_plan"`
+	ExplainFormat        string                   `json:"explain_format,omitempty"`
+	StructuredPlan       json.RawMessage          `json:"structured_plan,omitempty"`
+	RawPlanJSON          json.RawMessage          `json:"raw_plan_json,omitempty"`
+	GeneratedAt          time.Time                `json:"generated_at"`
+	CostAnalysis         *CostInfo                `json:"cost_analysis,omitempty"`
+	IndexRecommendations *IndexRecommendationInfo `json:"index_recommendations,omitempty"`
+	Metadata             *ReportMetadata          `json:"metadata,omitempty"`
+	Views                []ViewDefinition         `json:"views,omitempty"`
 }
 
+// newPlanOutput collects the analysis of a single plan, as written to JSON and passed to custom templates.
+// rawPlan is the EXPLAIN (FORMAT JSON) output from --include-raw-json, or nil.
+func newPlanOutput(plan, query, title, explainFormat string, costInfo *CostInfo, indexInfo *IndexRecommendationInfo, rawPlan json.RawMessage) PlanOutput {
+	return PlanOutput{
+		Title:                title,
+		Query:                query,
+		ExecutionPlan:        plan,
+		ExplainFormat:        explainFormat,
+		StructuredPlan:       structuredPlanJSON(plan, explainFormat),
+		RawPlanJSON:          rawPlan,
+		GeneratedAt:          currentTime(),
+		CostAnalysis:         costInfo,
+		IndexRecommendations: indexInfo,
+		Metadata:             reportMetadata,
+		Views:                reportViews,
+	}
+}
+
+// compactJSON is set by --compact to write JSON on a single line without indentation
+var compactJSON bool
+
 // writeJSONPlan generates a JSON file with the execution plan and query.
+// Plans produced with FORMAT JSON are also embedded as structured JSON, and rawPlan as raw_plan_json.
 // It returns the absolute path of the generated file.
-func writeJSONPlan(plan, query, title string, costInfo *CostInfo) string {
+func writeJSONPlan(plan, query, title, explainFormat string, costInfo *CostInfo, indexInfo *IndexRecommendationInfo, rawPlan json.RawMessage) string {
 	name := title + ".json"
-	data := PlanOutput{
-		Title:         title,
-		Query:         query,
-		ExecutionPlan: plan,
-		GeneratedAt:   time.Now(),
-		CostAnalysis:  costInfo,
-	}
+	data := newPlanOutput(plan, query, title, explainFormat, costInfo, indexInfo, rawPlan)
 
 	file, err := os.Create(name)
 	if err != nil {
@@ -51,7 +73,9 @@ func writeJSONPlan(plan, query, title string, costInfo *CostInfo) string {
 	defer file.Close()
 
 	encoder := json.NewEncoder(file)
-	encoder.SetIndent("", "  ")
+	if !compactJSON {
+		encoder.SetIndent("", "  ")
+	}
 
 	err = encoder.Encode(data)
 	if err != nil {
@@ -76,7 +100,9 @@ func writeJSONToFile(fileName string, data interface{}) string {
 	defer file.Close()
 
 	encoder := json.NewEncoder(file)
-	encoder.SetIndent("", "  ")
+	if !compactJSON {
+		encoder.SetIndent("", "  ")
+	}
 
 	err = encoder.Encode(data)
 	if err != nil {
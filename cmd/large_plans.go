@@ -0,0 +1,140 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"iter"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// defaultLargePlanBytes is the plan size above which a plan is treated as large
+const defaultLargePlanBytes = 8 * 1024 * 1024
+
+// largePlanBytes is the plan size above which plans are scanned line by line instead of being
+// split into a slice of lines, shortened in HTML reports and not annotated. Set with --large-plan-bytes.
+var largePlanBytes = defaultLargePlanBytes
+
+// configureLargePlans applies the --large-plan-bytes flag, 0 disables the limit
+func configureLargePlans(cmd *cobra.Command) {
+	if limit, err := cmd.Flags().GetInt("large-plan-bytes"); err == nil && limit >= 0 {
+		largePlanBytes = limit
+	}
+}
+
+// isLargePlan reports whether the plan is above largePlanBytes
+func isLargePlan(plan string) bool {
+	return largePlanBytes > 0 && len(plan) > largePlanBytes
+}
+
+// planLines iterates over the lines of a plan. A large plan is read with a scanner, so that
+// parsing it does not hold a slice with every line of a plan that may take megabytes.
+func planLines(plan string) iter.Seq[string] {
+	return func(yield func(string) bool) {
+		if !isLargePlan(plan) {
+			for _, line := range strings.Split(plan, "\n") {
+				if !yield(line) {
+					return
+				}
+			}
+			return
+		}
+
+		scanner := bufio.NewScanner(strings.NewReader(plan))
+		// A single line, e.g. the Output of a node with thousands of columns, may be huge too
+		scanner.Buffer(make([]byte, 0, 64*1024), len(plan)+1)
+		for scanner.Scan() {
+			if !yield(scanner.Text()) {
+				return
+			}
+		}
+		// Like strings.Split, give a plan ending in a newline an empty last line
+		if strings.HasSuffix(plan, "\n") {
+			yield("")
+		}
+	}
+}
+
+// planForHTML returns the plan to show as text in an HTML report, shortened by cutLargePlan
+// with a notice at the end
+func planForHTML(plan string) string {
+	shown, notice := cutLargePlan(plan)
+	switch {
+	case notice == "":
+		return shown
+	case shown == "":
+		return fmt.Sprintf("… (%s) …", notice)
+	}
+	return fmt.Sprintf("%s\n… (%s) …", shown, notice)
+}
+
+// planForPev2 returns the plan to embed in the pev2 report, shortened by cutLargePlan, and a
+// notice to show above it. The plan is empty when a large plan cannot be shown at all.
+func planForPev2(plan string) (string, string) {
+	return cutLargePlan(plan)
+}
+
+// cutLargePlan returns a large plan cut at the last line that fits in largePlanBytes, as browsers
+// struggle with pages of many megabytes, and a notice saying so. A JSON or YAML document cut
+// anywhere cannot be parsed, so a large structured plan is converted to the text layout first,
+// which pev2 reads too, and is left out when it cannot be converted. JSON, CSV and text output
+// keep the complete plan.
+func cutLargePlan(plan string) (string, string) {
+	if !isLargePlan(plan) {
+		return plan, ""
+	}
+	const completePlanHint = "use --format json or csv for the complete plan"
+
+	shown := plan
+	var notices []string
+	if format := detectPlanFormat(plan); isStructuredFormat(format) {
+		text, err := renderStructuredPlan(plan, format)
+		if err != nil {
+			return "", fmt.Sprintf("plan too large to show: %s, %s", planAboveSizeLimit(plan), completePlanHint)
+		}
+		shown = text
+		notices = append(notices, fmt.Sprintf("%s plan of %s shown as text", strings.ToUpper(format), formatByteSize(int64(len(plan)))))
+	}
+	if len(shown) > largePlanBytes {
+		total := len(shown)
+		shown = shown[:largePlanBytes]
+		if end := strings.LastIndex(shown, "\n"); end > 0 {
+			shown = shown[:end]
+		}
+		notices = append(notices, fmt.Sprintf("plan truncated: %s of %s shown", formatByteSize(int64(len(shown))), formatByteSize(int64(total))))
+	}
+	return shown, strings.Join(append(notices, completePlanHint), ", ")
+}
+
+// planAboveSizeLimit describes the size of a plan above largePlanBytes, for notices
+func planAboveSizeLimit(plan string) string {
+	return fmt.Sprintf("the plan is %s, above --large-plan-bytes (%s)",
+		formatByteSize(int64(len(plan))), formatByteSize(int64(largePlanBytes)))
+}
+
+// largePlanNotice explains how a large plan is handled, or returns an empty string for other plans
+func largePlanNotice(plan string) string {
+	if !isLargePlan(plan) {
+		return ""
+	}
+	return fmt.Sprintf("The plan is %s, above --large-plan-bytes (%s): it is parsed line by line and shortened in HTML reports",
+		formatByteSize(int64(len(plan))), formatByteSize(int64(largePlanBytes)))
+}
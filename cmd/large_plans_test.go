@@ -0,0 +1,130 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const largeJSONPlan = `[
+  {
+    "Plan": {
+      "Node Type": "Hash Join",
+      "Join Type": "Inner",
+      "Startup Cost": 12.5,
+      "Total Cost": 1250.75,
+      "Plan Rows": 500,
+      "Plan Width": 64,
+      "Hash Cond": "(o.user_id = u.id)",
+      "Plans": [
+        {
+          "Node Type": "Seq Scan",
+          "Parent Relationship": "Outer",
+          "Relation Name": "orders",
+          "Alias": "o",
+          "Startup Cost": 0.00,
+          "Total Cost": 1100.00,
+          "Plan Rows": 500,
+          "Plan Width": 32,
+          "Filter": "((status)::text = 'pending'::text)"
+        }
+      ]
+    }
+  }
+]`
+
+// withLargePlanBytes sets largePlanBytes for the duration of the test
+func withLargePlanBytes(t *testing.T, limit int) {
+	previous := largePlanBytes
+	largePlanBytes = limit
+	t.Cleanup(func() { largePlanBytes = previous })
+}
+
+func TestCutLargePlanShowsStructuredPlansAsText(t *testing.T) {
+	withLargePlanBytes(t, 100)
+
+	shown, notice := planForPev2(largeJSONPlan)
+	if json.Valid([]byte(shown)) || detectPlanFormat(shown) != "text" {
+		t.Fatalf("large JSON plan embedded as %q, want its text layout", shown)
+	}
+	if !strings.Contains(shown, "Hash Join") || !strings.Contains(notice, "JSON plan of") {
+		t.Errorf("shown plan %q with notice %q, want the Hash Join and a notice about the text layout", shown, notice)
+	}
+	if strings.Contains(shown, "…") {
+		t.Errorf("pev2 plan %q contains the notice, want it above the plan", shown)
+	}
+}
+
+func TestCutLargePlanCutsTextAtLineEnd(t *testing.T) {
+	withLargePlanBytes(t, 100)
+
+	plan := strings.Repeat("->  Seq Scan on orders  (cost=0.00..1.00 rows=1 width=4)\n", 5)
+	shown, notice := cutLargePlan(plan)
+	if len(shown) > 100 || !strings.HasSuffix(shown, "width=4)") {
+		t.Errorf("shown plan %q, want whole lines within 100 bytes", shown)
+	}
+	if !strings.Contains(notice, "plan truncated") {
+		t.Errorf("notice = %q, want it to say the plan is truncated", notice)
+	}
+
+	if shown, notice := cutLargePlan("<explain>" + strings.Repeat(" ", 200) + "</explain>"); shown != "" || !strings.Contains(notice, "too large to show") {
+		t.Errorf("large XML plan shown as %q with notice %q, want nothing and a notice", shown, notice)
+	}
+}
+
+func TestLargePlansParseLineByLine(t *testing.T) {
+	for _, file := range []string{"pg12.txt", "pg16.txt"} {
+		t.Run(file, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			plan := string(data)
+
+			withLargePlanBytes(t, 0)
+			contexts := parseExplainForIndexes(plan, 0)
+			roots, treeErr := parsePlanTree(plan)
+			jit := parseJIT(plan)
+			colored := colorizePlan(plan, nil)
+
+			withLargePlanBytes(t, 100)
+			if got := parseExplainForIndexes(plan, 0); !reflect.DeepEqual(got, contexts) {
+				t.Errorf("index contexts of the large plan differ:\n%+v\nwant\n%+v", got, contexts)
+			}
+			if got, err := parsePlanTree(plan); err != treeErr || !reflect.DeepEqual(got, roots) {
+				t.Errorf("plan tree of the large plan differs")
+			}
+			if got := parseJIT(plan); !reflect.DeepEqual(got, jit) {
+				t.Errorf("JIT section of the large plan = %+v, want %+v", got, jit)
+			}
+			if got := colorizePlan(plan, nil); got != colored {
+				t.Errorf("colored large plan differs")
+			}
+			var annotated string
+			output := captureStdout(t, func() { annotated = annotatePlan(plan) })
+			if annotated != plan || !strings.Contains(output, "Plan annotations are unavailable") {
+				t.Errorf("large plan annotated as %q with output %q, want it unchanged after a warning", annotated, output)
+			}
+		})
+	}
+}
@@ -0,0 +1,303 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var locksCmd = &cobra.Command{
+	Use:   "locks",
+	Short: "Show which queries are blocking others and analyze the blocker",
+	Long: `List the sessions waiting for a lock together with the sessions holding them up, as a tree
+from each blocking session down to the queries it blocks, and optionally analyze the blocking query.
+
+Use --explain to analyze the query of the session blocking the most others, or pick a blocking
+session with --pid. The EXPLAIN runs with lock_timeout set to 5s unless another value is given
+with --set, so it gives up instead of joining the queue behind the blocker.
+
+Example:
+  pg_explain locks
+  pg_explain locks --explain --transaction
+  pg_explain locks --explain --pid 4242 -f markdown`,
+	Args: cobra.NoArgs,
+	Run:  runLocks,
+}
+
+// blockingPairsSQL lists every waiting session with a session it waits for, the lock it
+// waits for, how long it has waited and how long the blocker's transaction has been open
+const blockingPairsSQL = `SELECT blocked.pid, blocking.pid,
+  coalesce((SELECT l.mode || ' on ' || coalesce(l.relation::regclass::text, l.locktype)
+            FROM pg_locks l WHERE l.pid = blocked.pid AND NOT l.granted LIMIT 1), ''),
+  coalesce(round(extract(epoch FROM now() - blocked.query_start)), 0),
+  coalesce(blocking.state, ''),
+  coalesce(round(extract(epoch FROM now() - coalesce(blocking.xact_start, blocking.query_start))), 0),
+  blocked.query, blocking.query
+FROM pg_stat_activity blocked
+CROSS JOIN LATERAL unnest(pg_blocking_pids(blocked.pid)) AS blocker(pid)
+JOIN pg_stat_activity blocking ON blocking.pid = blocker.pid
+ORDER BY blocking.pid, blocked.pid`
+
+// locksExplainLockTimeout keeps the EXPLAIN of a blocking query from waiting behind the blocker
+const locksExplainLockTimeout = "lock_timeout=5s"
+
+// LockSession is a backend taking part in lock contention
+type LockSession struct {
+	PID   int
+	Query string
+	// State and TransactionAge are known for blocking sessions
+	State          string
+	TransactionAge time.Duration
+	// WaitingFor and Waited are known for blocked sessions
+	WaitingFor string
+	Waited     time.Duration
+	Blocks     []int
+}
+
+// LockGraph holds the sessions and which sessions each of them blocks
+type LockGraph struct {
+	Sessions map[int]*LockSession
+	Blocked  map[int]bool
+}
+
+func runLocks(cmd *cobra.Command, args []string) {
+	requirePsql()
+	explain, _ := cmd.Flags().GetBool("explain")
+	pid, _ := cmd.Flags().GetInt("pid")
+
+	config, _ := loadConfig()
+	applyServiceFlag(cmd, config)
+	promptPasswordIfRequested(cmd)
+
+	fmt.Println("\n🔎 Reading lock waits from pg_locks and pg_stat_activity...")
+	graph, err := fetchLockGraph(config)
+	if err != nil {
+		fmt.Println("❌ Failed to read lock waits")
+		logErrorAndExit("Error: ", err)
+	}
+
+	if len(graph.Blocked) == 0 {
+		fmt.Println("✅ No query is waiting for a lock")
+		fmt.Println()
+		return
+	}
+
+	roots := graph.roots()
+	displayLockGraph(graph, roots)
+
+	if !explain && pid == 0 {
+		fmt.Println("💡 Tip: Run with --explain to analyze the blocking query")
+		fmt.Println()
+		return
+	}
+
+	if pid == 0 {
+		pid = roots[0]
+	}
+	blocker, ok := graph.Sessions[pid]
+	if !ok || len(blocker.Blocks) == 0 {
+		logErrorAndExit("Invalid --pid value", fmt.Errorf("backend %d is not blocking any query", pid))
+	}
+	if err := explainableActivityQuery(blocker.Query); err != nil {
+		logErrorAndExit(fmt.Sprintf("Unable to analyze the query of backend %d", pid), err)
+	}
+
+	fmt.Printf("🔬 Analyzing the query of blocking backend %d...\n", pid)
+	if blocker.State == "idle in transaction" {
+		fmt.Println("   ⚠️  The backend is idle in a transaction: this is its last query, which may not be the one that took the lock")
+	}
+	if !hasSetting(cmd, "lock_timeout") {
+		cmd.Flags().Set("set", locksExplainLockTimeout)
+	}
+	analyzeQuery(cmd, blocker.Query)
+}
+
+// fetchLockGraph runs blockingPairsSQL and builds the graph of blocking sessions
+func fetchLockGraph(config *Config) (*LockGraph, error) {
+	output, err := runPsql(config, []string{"-q", "-A", "-t", "-F", unitSeparator, "-R", recordSeparator, "-c", blockingPairsSQL})
+	if err != nil {
+		return nil, fmt.Errorf("unable to read lock waits: %w", err)
+	}
+
+	graph := &LockGraph{Sessions: make(map[int]*LockSession), Blocked: make(map[int]bool)}
+	session := func(pid int) *LockSession {
+		if graph.Sessions[pid] == nil {
+			graph.Sessions[pid] = &LockSession{PID: pid}
+		}
+		return graph.Sessions[pid]
+	}
+
+	for _, record := range strings.Split(output, recordSeparator) {
+		fields := strings.SplitN(strings.TrimSpace(record), unitSeparator, 8)
+		if len(fields) != 8 {
+			continue
+		}
+		blockedPID, err := strconv.Atoi(fields[0])
+		if err != nil {
+			continue
+		}
+		blockingPID, err := strconv.Atoi(fields[1])
+		if err != nil {
+			continue
+		}
+
+		blocked := session(blockedPID)
+		blocked.WaitingFor = fields[2]
+		blocked.Waited = parseSeconds(fields[3])
+		blocked.Query = strings.TrimSpace(fields[6])
+
+		blocking := session(blockingPID)
+		blocking.State = fields[4]
+		blocking.TransactionAge = parseSeconds(fields[5])
+		blocking.Query = strings.TrimSpace(fields[7])
+		blocking.Blocks = append(blocking.Blocks, blockedPID)
+
+		graph.Blocked[blockedPID] = true
+	}
+	return graph, nil
+}
+
+// roots returns the blocking sessions that are not waiting themselves, the ones blocking the
+// most sessions first. In a lock cycle every session waits, so all blockers are returned.
+func (g *LockGraph) roots() []int {
+	var roots, blockers []int
+	for pid, session := range g.Sessions {
+		if len(session.Blocks) == 0 {
+			continue
+		}
+		blockers = append(blockers, pid)
+		if !g.Blocked[pid] {
+			roots = append(roots, pid)
+		}
+	}
+	if len(roots) == 0 {
+		roots = blockers
+	}
+
+	sort.Slice(roots, func(i, j int) bool {
+		ci, cj := g.blockedCount(roots[i]), g.blockedCount(roots[j])
+		if ci != cj {
+			return ci > cj
+		}
+		return roots[i] < roots[j]
+	})
+	return roots
+}
+
+// blockedCount returns the number of sessions waiting directly or indirectly on pid
+func (g *LockGraph) blockedCount(pid int) int {
+	seen := map[int]bool{pid: true}
+	queue := []int{pid}
+	for len(queue) > 0 {
+		for _, blocked := range g.Sessions[queue[0]].Blocks {
+			if !seen[blocked] {
+				seen[blocked] = true
+				queue = append(queue, blocked)
+			}
+		}
+		queue = queue[1:]
+	}
+	return len(seen) - 1
+}
+
+// displayLockGraph prints each blocking session with the tree of sessions waiting on it
+func displayLockGraph(graph *LockGraph, roots []int) {
+	fmt.Println()
+	fmt.Println("🔒 Lock Contention")
+	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	for _, pid := range roots {
+		root := graph.Sessions[pid]
+		fmt.Printf("🧱 PID %d · %s · transaction open %s · blocks %d\n",
+			root.PID, root.State, root.TransactionAge, graph.blockedCount(pid))
+		fmt.Printf("   %s\n", queryLabel(root.Query, 100))
+		displayBlockedSessions(graph, root, "   ", map[int]bool{pid: true})
+		fmt.Println()
+	}
+	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	fmt.Println()
+}
+
+func displayBlockedSessions(graph *LockGraph, session *LockSession, indent string, visited map[int]bool) {
+	for i, pid := range session.Blocks {
+		branch, continuation := "├─ ", "│  "
+		if i == len(session.Blocks)-1 {
+			branch, continuation = "└─ ", "   "
+		}
+
+		blocked := graph.Sessions[pid]
+		waiting := fmt.Sprintf("waiting %s", blocked.Waited)
+		if blocked.WaitingFor != "" {
+			waiting += " for " + blocked.WaitingFor
+		}
+		fmt.Printf("%s%s⏳ PID %d · %s\n", indent, branch, pid, waiting)
+		fmt.Printf("%s%s   %s\n", indent, continuation, queryLabel(blocked.Query, 100))
+
+		if visited[pid] {
+			fmt.Printf("%s%s   ↻ PID %d is already shown above (lock cycle)\n", indent, continuation, pid)
+			continue
+		}
+		visited[pid] = true
+		displayBlockedSessions(graph, blocked, indent+continuation, visited)
+	}
+}
+
+// explainableActivityQuery checks that a query read from pg_stat_activity can be analyzed
+func explainableActivityQuery(query string) error {
+	switch query {
+	case "":
+		return fmt.Errorf("the backend has not run a query yet")
+	case "<insufficient privilege>":
+		return fmt.Errorf("not allowed to see the query of this backend, connect as its user or a member of pg_read_all_stats")
+	}
+	return nil
+}
+
+// hasSetting reports whether --set or --guc already sets the named parameter
+func hasSetting(cmd *cobra.Command, name string) bool {
+	settings, _ := cmd.Flags().GetStringArray("set")
+	gucs, _ := cmd.Flags().GetStringArray("guc")
+	for _, setting := range append(settings, gucs...) {
+		settingName, _, _ := strings.Cut(setting, "=")
+		if strings.EqualFold(strings.TrimSpace(settingName), name) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseSeconds converts a whole number of seconds from psql output to a duration
+func parseSeconds(value string) time.Duration {
+	seconds, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+func init() {
+	locksCmd.Flags().Bool("explain", false, "Analyze the query of the session blocking the most others")
+	locksCmd.Flags().Int("pid", 0, "Process id of the blocking session whose query to analyze (implies --explain)")
+	addAnalyzeFlags(locksCmd)
+	rootCmd.AddCommand(locksCmd)
+}
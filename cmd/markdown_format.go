@@ -5,7 +5,6 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
-	"time"
 )
 
 // escapeMarkdownSpecialChars escapes special markdown characters in text
@@ -25,6 +24,9 @@ func formatCostInfoMarkdown(costInfo *CostInfo) string {
 	if costInfo == nil {
 		return "_Cost analysis not available (threshold not set)_\n"
 	}
+	if costInfo.Warning != "" {
+		return fmt.Sprintf("_⚠️ %s_\n", costInfo.Warning)
+	}
 
 	var sb strings.Builder
 
@@ -34,6 +36,50 @@ func formatCostInfoMarkdown(costInfo *CostInfo) string {
 	sb.WriteString(fmt.Sprintf("| Total Cost | %.2f |\n", costInfo.TotalCost))
 	sb.WriteString(fmt.Sprintf("| Exceeds Threshold | %t |\n", costInfo.ExceedsLimit))
 	sb.WriteString(fmt.Sprintf("| Threshold Value | %.2f |\n", costInfo.ThresholdValue))
+	if costInfo.Grade != "" {
+		sb.WriteString(fmt.Sprintf("| Health Grade | %s %s |\n", getGradeEmoji(costInfo.Grade), costInfo.Grade))
+	}
+	sb.WriteString(fmt.Sprintf("| Plan Nodes | %d |\n", costInfo.NodeCount))
+	sb.WriteString(fmt.Sprintf("| Plan Depth | %d |\n", costInfo.MaxDepth))
+	if warning := planComplexityWarning(costInfo); warning != "" {
+		sb.WriteString(fmt.Sprintf("| Complexity | ⚠️ %s |\n", warning))
+	}
+	for _, scan := range costInfo.FullScans {
+		sb.WriteString(fmt.Sprintf("| Full Table Scan | 🐘 %s |\n", escapeMarkdownSpecialChars(fullTableScanWarning(scan))))
+	}
+	for _, operation := range costInfo.WideRows {
+		sb.WriteString(fmt.Sprintf("| Wide Rows | 📦 %s |\n", escapeMarkdownSpecialChars(wideRowWarning(operation))))
+	}
+	for _, subplan := range costInfo.Subplans {
+		sb.WriteString(fmt.Sprintf("| Repeated Subplan | 🔁 %s |\n", escapeMarkdownSpecialChars(repeatedSubplanWarning(subplan))))
+	}
+	for _, cache := range costInfo.MemoizeCaches {
+		if isPoorMemoizeCache(cache) {
+			sb.WriteString(fmt.Sprintf("| Memoize Cache | 🗃️ %s |\n", escapeMarkdownSpecialChars(memoizeCacheWarning(cache))))
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("| Memoize Cache | %s |\n", escapeMarkdownSpecialChars(memoizeCacheSummary(cache))))
+	}
+
+	if costInfo.CostPerMs > 0 {
+		sb.WriteString(fmt.Sprintf("| Execution Time | %.2f ms |\n", costInfo.ExecutionTimeMs))
+		sb.WriteString(fmt.Sprintf("| Cost Calibration | ≈ %.2f ms per cost unit (this query) |\n", msPerCostUnit(costInfo)))
+	}
+	if costInfo.IOReadMs > 0 || costInfo.IOWriteMs > 0 {
+		sb.WriteString(fmt.Sprintf("| I/O Time | %s |\n", formatIOTimings(costInfo)))
+		if warning := ioBoundWarning(costInfo); warning != "" {
+			sb.WriteString(fmt.Sprintf("| I/O Bound | 💽 %s |\n", escapeMarkdownSpecialChars(warning)))
+		}
+	}
+	if costInfo.JITInfo != nil {
+		sb.WriteString(fmt.Sprintf("| JIT | %s |\n", formatJIT(costInfo)))
+		if warning := jitOverheadWarning(costInfo); warning != "" {
+			sb.WriteString(fmt.Sprintf("| JIT Overhead | 🔥 %s |\n", escapeMarkdownSpecialChars(warning)))
+		}
+	}
+	if costInfo.CostPerMs > 0 {
+		sb.WriteString("\n_Note: planner cost is unitless. The ratio above only describes this query on this server and is not a universal conversion._\n")
+	}
 
 	return sb.String()
 }
@@ -46,10 +92,35 @@ func formatExpensiveOpsMarkdown(ops []ExpensiveOperation) string {
 
 	var sb strings.Builder
 
-	sb.WriteString("| Operation | Cost | Details |\n")
-	sb.WriteString("|-----------|------|---------||\n")
+	// The explanation column is only added when --explain-ops filled it in
+	explained := false
+	for _, op := range ops {
+		if op.Explanation != "" {
+			explained = true
+			break
+		}
+	}
+
+	if explained {
+		sb.WriteString("| Operation | Cost | What It Means | Details |\n")
+		sb.WriteString("|-----------|------|---------------|---------|\n")
+	} else {
+		sb.WriteString("| Operation | Cost | Details |\n")
+		sb.WriteString("|-----------|------|---------||\n")
+	}
 
 	for _, op := range ops {
+		if op.Loops > 1 {
+			op.Line += " — " + actualTotalsSummary(op)
+		}
+		if explained {
+			sb.WriteString(fmt.Sprintf("| %s | %.2f | %s | %s |\n",
+				escapeMarkdownSpecialChars(op.Operation),
+				op.Cost,
+				escapeMarkdownSpecialChars(op.Explanation),
+				escapeMarkdownSpecialChars(op.Line)))
+			continue
+		}
 		sb.WriteString(fmt.Sprintf("| %s | %.2f | %s |\n",
 			escapeMarkdownSpecialChars(op.Operation),
 			op.Cost,
@@ -59,9 +130,56 @@ func formatExpensiveOpsMarkdown(ops []ExpensiveOperation) string {
 	return sb.String()
 }
 
-// writeMarkdownPlan generates a Markdown file for analyze command
+// formatIndexRecommendationsMarkdown formats index recommendations as markdown table
+func formatIndexRecommendationsMarkdown(info *IndexRecommendationInfo) string {
+	var notes string
+	if info != nil && len(info.AlreadyApplied) > 0 {
+		notes = fmt.Sprintf("_Skipped %d already applied: %s_\n\n", len(info.AlreadyApplied),
+			escapeMarkdownSpecialChars(formatAppliedRecommendations(info)))
+	}
+	if info != nil && len(info.TypeMismatches) > 0 {
+		notes += "**Casts on filtered columns prevent index use:**\n\n"
+		for _, mismatch := range info.TypeMismatches {
+			notes += fmt.Sprintf("- %s\n", escapeMarkdownSpecialChars(typeMismatchWarning(mismatch)))
+		}
+		notes += "\n"
+	}
+	if info == nil || info.TotalFound == 0 {
+		return notes + "_No index recommendations found_\n"
+	}
+
+	var sb strings.Builder
+	sb.WriteString(notes)
+
+	sb.WriteString("| Priority | Table | Columns | Est. Benefit | Reason | Impact | Statement |\n")
+	sb.WriteString("|----------|-------|---------|--------------|--------|--------|-----------|\n")
+
+	for _, rec := range info.Recommendations {
+		impact := rec.EstimatedImpact
+		if rec.Hypothetical != nil {
+			impact += ". Projected with HypoPG: " + formatHypotheticalResult(rec.Hypothetical)
+		}
+		sb.WriteString(fmt.Sprintf("| %d | %s | %s | %.2f | %s | %s | `%s` |\n",
+			rec.Priority,
+			escapeMarkdownSpecialChars(rec.TableName),
+			escapeMarkdownSpecialChars(strings.Join(rec.Columns, ", ")),
+			rec.EstimatedBenefit,
+			escapeMarkdownSpecialChars(rec.Reason),
+			escapeMarkdownSpecialChars(impact),
+			rec.CreateStatement))
+	}
+
+	if info.Concurrently {
+		sb.WriteString(fmt.Sprintf("\n> ⚠️ %s\n", concurrentlyNote))
+	}
+
+	return sb.String()
+}
+
+// writeMarkdownPlan generates a Markdown file for analyze command. planCost is only used for
+// the calibration note when costInfo is nil because no threshold is set.
 // Returns absolute path of generated file
-func writeMarkdownPlan(plan, query, title string, costInfo *CostInfo) string {
+func writeMarkdownPlan(plan, query, title string, costInfo, planCost *CostInfo, indexInfo *IndexRecommendationInfo) string {
 	fileName := title + ".md"
 
 	var sb strings.Builder
@@ -70,13 +188,19 @@ func writeMarkdownPlan(plan, query, title string, costInfo *CostInfo) string {
 	sb.WriteString("# Query Execution Plan\n\n")
 
 	// Metadata
-	sb.WriteString(fmt.Sprintf("**Generated:** %s  \n", time.Now().Format("January 2, 2006 15:04:05")))
+	sb.WriteString(fmt.Sprintf("**Generated:** %s  \n", formatTimestamp(currentTime())))
 	sb.WriteString(fmt.Sprintf("**Query:** %s\n\n", escapeMarkdownSpecialChars(query)))
 	sb.WriteString("---\n\n")
 
 	// Cost Analysis
 	sb.WriteString("## Cost Analysis\n\n")
 	sb.WriteString(formatCostInfoMarkdown(costInfo))
+	// Without a threshold there is no cost table, planCost still has the execution time
+	if costInfo == nil {
+		if note := costCalibrationNote(planCost); note != "" {
+			sb.WriteString(fmt.Sprintf("\n_⏱️ %s_\n", escapeMarkdownSpecialChars(note)))
+		}
+	}
 	sb.WriteString("\n")
 
 	// Expensive Operations
@@ -86,6 +210,13 @@ func writeMarkdownPlan(plan, query, title string, costInfo *CostInfo) string {
 		sb.WriteString("\n")
 	}
 
+	// Index Recommendations
+	if indexInfo != nil {
+		sb.WriteString("## Index Recommendations\n\n")
+		sb.WriteString(formatIndexRecommendationsMarkdown(indexInfo))
+		sb.WriteString("\n")
+	}
+
 	sb.WriteString("---\n\n")
 
 	// Execution Plan
@@ -94,6 +225,9 @@ func writeMarkdownPlan(plan, query, title string, costInfo *CostInfo) string {
 	sb.WriteString(plan)
 	sb.WriteString("\n```\n\n")
 
+	sb.WriteString(formatViewDefinitionsMarkdown(reportViews))
+	sb.WriteString(formatMetadataMarkdown(reportMetadata))
+
 	sb.WriteString("---\n\n")
 	sb.WriteString("**Note:** This plan was generated using PostgreSQL EXPLAIN\n")
 
@@ -117,23 +251,22 @@ func writeMarkdownPlan(plan, query, title string, costInfo *CostInfo) string {
 	return abs
 }
 
-// writeComparisonMarkdown generates a Markdown file for compare command
-func writeComparisonMarkdown(result *ComparisonResult) {
+// writeComparisonMarkdown generates a Markdown file for compare command. detailedComparison is
+// the table of formatComparisonFieldsMarkdown, rendered from the complete plans as the plans of
+// result may be truncated by --max-plan-lines.
+func writeComparisonMarkdown(result *ComparisonResult, outputDir, detailedComparison string) {
 	title := generateTitle()
-	fileName := fmt.Sprintf("Comparison_%s.md", title)
+	fileName := filepath.Join(outputDir, comparisonFilePrefix+title+".md")
 
 	var sb strings.Builder
 
 	// Title
 	sb.WriteString("# Query Comparison Report\n\n")
-	sb.WriteString(fmt.Sprintf("**Generated:** %s\n\n", time.Now().Format("January 2, 2006 15:04:05")))
+	sb.WriteString(fmt.Sprintf("**Generated:** %s\n\n", formatTimestamp(currentTime())))
 	sb.WriteString("---\n\n")
 
 	// Winner section
-	winnerEmoji := "🏆"
-	if result.Winner == "Tie" {
-		winnerEmoji = "🤝"
-	}
+	winnerEmoji := comparisonWinnerEmoji(result.Winner)
 	sb.WriteString(fmt.Sprintf("## Winner: %s %s\n\n", result.Winner, winnerEmoji))
 
 	// Comparison metrics table
@@ -141,17 +274,13 @@ func writeComparisonMarkdown(result *ComparisonResult) {
 	sb.WriteString("|--------|-------|\n")
 	sb.WriteString(fmt.Sprintf("| Cost Difference | %.2f |\n", result.CostDiff))
 	sb.WriteString(fmt.Sprintf("| Percentage Difference | %.2f%% |\n", result.CostDiffPct))
+	if result.Metric != compareMetricCost && result.Winner != "Unknown" {
+		sb.WriteString(fmt.Sprintf("| %s Difference | %s (%.2f%%) |\n", compareMetricName(result.Metric),
+			formatCompareMetric(result.Metric, result.MetricDiff), result.MetricDiffPct))
+	}
 
-	if result.CostDiff != 0 {
-		var perfMultiplier string
-		if result.CostDiff > 0 {
-			mult := result.Cost1.TotalCost / result.Cost2.TotalCost
-			perfMultiplier = fmt.Sprintf("Query 2 is %.2fx faster", mult)
-		} else {
-			mult := result.Cost2.TotalCost / result.Cost1.TotalCost
-			perfMultiplier = fmt.Sprintf("Query 1 is %.2fx faster", mult)
-		}
-		sb.WriteString(fmt.Sprintf("| Performance Multiplier | %s |\n", perfMultiplier))
+	if result.MetricDiff != 0 {
+		sb.WriteString(fmt.Sprintf("| Performance Multiplier | %s |\n", performanceMultiplier(result)))
 	}
 	sb.WriteString("\n")
 
@@ -162,7 +291,7 @@ func writeComparisonMarkdown(result *ComparisonResult) {
 	sb.WriteString("---\n\n")
 
 	// Query 1 section
-	sb.WriteString("## Query 1\n\n")
+	sb.WriteString(fmt.Sprintf("## %s\n\n", result.Label1))
 	sb.WriteString("**SQL:**\n```sql\n")
 	sb.WriteString(result.Query1)
 	sb.WriteString("\n```\n\n")
@@ -192,7 +321,7 @@ func writeComparisonMarkdown(result *ComparisonResult) {
 	sb.WriteString("---\n\n")
 
 	// Query 2 section
-	sb.WriteString("## Query 2\n\n")
+	sb.WriteString(fmt.Sprintf("## %s\n\n", result.Label2))
 	sb.WriteString("**SQL:**\n```sql\n")
 	sb.WriteString(result.Query2)
 	sb.WriteString("\n```\n\n")
@@ -223,28 +352,7 @@ func writeComparisonMarkdown(result *ComparisonResult) {
 
 	// Detailed comparison table
 	sb.WriteString("## Detailed Comparison\n\n")
-	sb.WriteString("| Aspect | Query 1 | Query 2 |\n")
-	sb.WriteString("|--------|---------|--------|\n")
-	sb.WriteString(fmt.Sprintf("| Total Cost | %.2f | %.2f |\n", result.Cost1.TotalCost, result.Cost2.TotalCost))
-
-	if len(result.Cost1.ExpensiveOps) > 0 || len(result.Cost2.ExpensiveOps) > 0 {
-		topOp1 := "N/A"
-		topOpCost1 := "N/A"
-		if len(result.Cost1.ExpensiveOps) > 0 {
-			topOp1 = escapeMarkdownSpecialChars(result.Cost1.ExpensiveOps[0].Operation)
-			topOpCost1 = fmt.Sprintf("%.2f", result.Cost1.ExpensiveOps[0].Cost)
-		}
-
-		topOp2 := "N/A"
-		topOpCost2 := "N/A"
-		if len(result.Cost2.ExpensiveOps) > 0 {
-			topOp2 = escapeMarkdownSpecialChars(result.Cost2.ExpensiveOps[0].Operation)
-			topOpCost2 = fmt.Sprintf("%.2f", result.Cost2.ExpensiveOps[0].Cost)
-		}
-
-		sb.WriteString(fmt.Sprintf("| Top Operation | %s | %s |\n", topOp1, topOp2))
-		sb.WriteString(fmt.Sprintf("| Top Op Cost | %s | %s |\n", topOpCost1, topOpCost2))
-	}
+	sb.WriteString(detailedComparison)
 
 	// Write to file
 	file, err := os.Create(fileName)
@@ -272,7 +380,7 @@ func writeComparisonMarkdown(result *ComparisonResult) {
 		fmt.Printf("Cost Difference: %.2f (%.2f%%)\n", result.CostDiff, result.CostDiffPct)
 	}
 	fmt.Println("\n💡 Tip: Open this file in your markdown viewer to see the formatted comparison")
-	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+	fmt.Print("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n\n")
 }
 
 // writeMarkdownBatchReport generates a Markdown file for batch command (combined mode)
@@ -285,7 +393,7 @@ func writeMarkdownBatchReport(report BatchReport, fileName string) string {
 	// Title
 	sb.WriteString("# Batch Analysis Report\n\n")
 	sb.WriteString(fmt.Sprintf("**File:** %s  \n", report.FileName))
-	sb.WriteString(fmt.Sprintf("**Generated:** %s\n\n", report.GeneratedAt.Format("January 2, 2006 15:04:05")))
+	sb.WriteString(fmt.Sprintf("**Generated:** %s\n\n", formatTimestamp(report.GeneratedAt)))
 	sb.WriteString("---\n\n")
 
 	// Summary
@@ -295,12 +403,47 @@ func writeMarkdownBatchReport(report BatchReport, fileName string) string {
 	sb.WriteString(fmt.Sprintf("| Total Queries | %d |\n", report.TotalQueries))
 	sb.WriteString(fmt.Sprintf("| Successful | %d |\n", report.SuccessCount))
 	sb.WriteString(fmt.Sprintf("| Failed | %d |\n", report.FailureCount))
+	if failures := formatErrorCategories(report.Results); failures != "" {
+		sb.WriteString(fmt.Sprintf("| Failures by Category | %s |\n", failures))
+	}
 
 	if report.TotalQueries > 0 {
 		successRate := float64(report.SuccessCount) / float64(report.TotalQueries) * 100
 		sb.WriteString(fmt.Sprintf("| Success Rate | %.1f%% |\n", successRate))
 	}
+	if grades := formatGradeDistribution(report.Results); grades != "" {
+		sb.WriteString(fmt.Sprintf("| Health Grades | %s |\n", grades))
+	}
+	if aggregates := report.Aggregates; aggregates != nil {
+		sb.WriteString(fmt.Sprintf("| Total Cost | %.2f |\n", aggregates.TotalCost))
+		sb.WriteString(fmt.Sprintf("| Average Cost | %.2f |\n", aggregates.AverageCost))
+		sb.WriteString(fmt.Sprintf("| Median Cost | %.2f |\n", aggregates.MedianCost))
+		sb.WriteString(fmt.Sprintf("| P95 Cost | %.2f |\n", aggregates.P95Cost))
+		sb.WriteString(fmt.Sprintf("| Exceeded Threshold | %d of %d |\n", aggregates.ExceededThreshold, aggregates.AnalyzedQueries))
+	}
 	sb.WriteString("\n---\n\n")
+	sb.WriteString(formatTagAggregatesMarkdown(report.TagAggregates))
+	sb.WriteString(formatWorkloadRecommendationsMarkdown(report.WorkloadRecommendations))
+
+	// Most expensive queries
+	if top := topExpensiveQueries(report.Results, topQueriesLimit); len(top) > 0 {
+		sb.WriteString(fmt.Sprintf("## Top %d Most Expensive Queries\n\n", len(top)))
+		sb.WriteString("| Query # | SQL | Cost | Execution Time |\n")
+		sb.WriteString("|---------|-----|------|----------------|\n")
+		for _, result := range top {
+			costInfo := resultCost(result)
+			executionTime := "N/A"
+			if costInfo.ExecutionTimeMs > 0 {
+				executionTime = fmt.Sprintf("%.2f ms", costInfo.ExecutionTimeMs)
+			}
+			sb.WriteString(fmt.Sprintf("| %d | `%s` | %.2f | %s |\n",
+				result.QueryNumber,
+				strings.NewReplacer("`", "'", "|", "\\|").Replace(queryLabel(result.Query, 80)),
+				costInfo.TotalCost,
+				executionTime))
+		}
+		sb.WriteString("\n---\n\n")
+	}
 
 	// Query Results
 	sb.WriteString("## Query Results\n\n")
@@ -321,17 +464,26 @@ func writeMarkdownBatchReport(report BatchReport, fileName string) string {
 		sb.WriteString("\n```\n\n")
 
 		sb.WriteString(fmt.Sprintf("**Status:** %s  \n", statusText))
+		if result.Tag != "" {
+			sb.WriteString(fmt.Sprintf("**Tag:** %s  \n", escapeMarkdownSpecialChars(result.Tag)))
+		}
 
 		// Handle errors
 		if result.Error != "" {
-			sb.WriteString(fmt.Sprintf("**Error:** %s\n\n", escapeMarkdownSpecialChars(result.Error)))
+			sb.WriteString(fmt.Sprintf("**Error (%s):** %s\n\n", result.ErrorCategory, escapeMarkdownSpecialChars(result.Error)))
 			sb.WriteString("---\n\n")
 			continue
 		}
 
 		// Cost info
-		if result.CostAnalysis != nil {
-			sb.WriteString(fmt.Sprintf("**Total Cost:** %.2f\n\n", result.CostAnalysis.TotalCost))
+		if result.CostAnalysis != nil && result.CostAnalysis.Warning != "" {
+			sb.WriteString(fmt.Sprintf("**Total Cost:** unknown | ⚠️ %s\n\n", result.CostAnalysis.Warning))
+		} else if result.CostAnalysis != nil {
+			sb.WriteString(fmt.Sprintf("**Total Cost:** %.2f", result.CostAnalysis.TotalCost))
+			if result.CostAnalysis.Grade != "" {
+				sb.WriteString(fmt.Sprintf(" | **Grade:** %s %s", getGradeEmoji(result.CostAnalysis.Grade), result.CostAnalysis.Grade))
+			}
+			sb.WriteString("\n\n")
 		}
 
 		// Execution Plan
@@ -354,6 +506,13 @@ func writeMarkdownBatchReport(report BatchReport, fileName string) string {
 			}
 		}
 
+		// Index Recommendations
+		if result.IndexRecommendations != nil {
+			sb.WriteString("#### Index Recommendations\n\n")
+			sb.WriteString(formatIndexRecommendationsMarkdown(result.IndexRecommendations))
+			sb.WriteString("\n")
+		}
+
 		sb.WriteString("---\n\n")
 	}
 
@@ -369,6 +528,8 @@ func writeMarkdownBatchReport(report BatchReport, fileName string) string {
 
 			if result.Error != "" {
 				statusEmoji = "❌ Failed"
+			} else if result.CostAnalysis != nil && result.CostAnalysis.Warning != "" {
+				statusEmoji = "⚠️ No cost estimates"
 			} else if result.CostAnalysis != nil {
 				costStr = fmt.Sprintf("%.2f", result.CostAnalysis.TotalCost)
 			}
@@ -377,6 +538,11 @@ func writeMarkdownBatchReport(report BatchReport, fileName string) string {
 		}
 	}
 
+	if report.Metadata != nil {
+		sb.WriteString("\n---\n\n")
+		sb.WriteString(formatMetadataMarkdown(report.Metadata))
+	}
+
 	// Write to file
 	file, err := os.Create(mdFileName)
 	if err != nil {
@@ -396,3 +562,37 @@ func writeMarkdownBatchReport(report BatchReport, fileName string) string {
 
 	return abs
 }
+
+// writeMarkdownRecommendations generates a Markdown file for recommend command
+// Returns absolute path of generated file
+func writeMarkdownRecommendations(info *IndexRecommendationInfo, title string) string {
+	fileName := title + ".md"
+
+	var sb strings.Builder
+
+	sb.WriteString("# Index Recommendations\n\n")
+	sb.WriteString(fmt.Sprintf("**Generated:** %s  \n", formatTimestamp(currentTime())))
+	sb.WriteString(fmt.Sprintf("**Threshold:** Operations with cost >= %.0f\n\n", info.ThresholdUsed))
+	sb.WriteString("---\n\n")
+
+	sb.WriteString(fmt.Sprintf("Found %d recommendations (%d high priority)\n\n", info.TotalFound, info.HighPriority))
+	sb.WriteString(formatIndexRecommendationsMarkdown(info))
+
+	file, err := os.Create(fileName)
+	if err != nil {
+		logErrorAndExit("unable to create Markdown file: ", err)
+	}
+	defer file.Close()
+
+	_, err = file.WriteString(sb.String())
+	if err != nil {
+		logErrorAndExit("unable to write Markdown content: ", err)
+	}
+
+	abs, err := filepath.Abs(file.Name())
+	if err != nil {
+		logErrorAndExit("unable to get file absolute path: ", err)
+	}
+
+	return abs
+}
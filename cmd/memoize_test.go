@@ -0,0 +1,54 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import "testing"
+
+const poorMemoizeJSONPlan = `[{"Plan": {
+  "Node Type": "Nested Loop", "Startup Cost": 0.57, "Total Cost": 5000, "Plan Rows": 10000, "Plan Width": 16,
+  "Actual Startup Time": 0.1, "Actual Total Time": 250, "Actual Rows": 10000, "Actual Loops": 1,
+  "Plans": [
+    {"Node Type": "Seq Scan", "Relation Name": "orders", "Alias": "o", "Startup Cost": 0, "Total Cost": 200,
+     "Plan Rows": 10000, "Plan Width": 12, "Actual Startup Time": 0.01, "Actual Total Time": 5, "Actual Rows": 10000, "Actual Loops": 1},
+    {"Node Type": "Memoize", "Startup Cost": 0.3, "Total Cost": 8.31, "Plan Rows": 1, "Plan Width": 8,
+     "Actual Startup Time": 0.01, "Actual Total Time": 0.01, "Actual Rows": 1, "Actual Loops": 10000,
+     "Cache Key": "o.customer_id", "Cache Mode": "logical",
+     "Cache Hits": 1000, "Cache Misses": 9000, "Cache Evictions": 8500, "Cache Overflows": 0, "Peak Memory Usage": 4096,
+     "Plans": [
+       {"Node Type": "Index Scan", "Index Name": "customers_pkey", "Relation Name": "customers", "Alias": "c",
+        "Startup Cost": 0.29, "Total Cost": 8.3, "Plan Rows": 1, "Plan Width": 8,
+        "Actual Startup Time": 0.01, "Actual Total Time": 0.01, "Actual Rows": 1, "Actual Loops": 9000,
+        "Index Cond": "(id = o.customer_id)"}
+     ]}
+  ]
+}, "Execution Time": 260}]`
+
+func TestDetectMemoizeCachesInStructuredPlans(t *testing.T) {
+	costInfo := parseCost(planForAnalysis(poorMemoizeJSONPlan, "json"), 0, 0)
+
+	if len(costInfo.MemoizeCaches) != 1 {
+		t.Fatalf("memoize caches = %+v, want one", costInfo.MemoizeCaches)
+	}
+	cache := costInfo.MemoizeCaches[0]
+	if cache.CacheKey != "o.customer_id" || cache.Hits != 1000 || cache.Misses != 9000 || cache.Evictions != 8500 || cache.HitRatio != 0.1 {
+		t.Errorf("memoize cache = %+v, want key o.customer_id, 1000 hits, 9000 misses, 8500 evictions", cache)
+	}
+	if poor := poorMemoizeCaches(costInfo); len(poor) != 1 {
+		t.Errorf("poor memoize caches = %+v, want the cache with a 10%% hit ratio", poor)
+	}
+}
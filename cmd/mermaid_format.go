@@ -0,0 +1,174 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// PlanTreeNode is a node of a text plan, nested by the indentation of its "->" arrow
+type PlanTreeNode struct {
+	// Label is the node without its estimates, e.g. "Seq Scan on orders o"
+	Label    string
+	Cost     float64
+	Children []*PlanTreeNode
+	// Line is the index of the node's line in the plan
+	Line int
+}
+
+// parsePlanTree builds the node tree of a text plan. Detail lines such as "Filter: ..." are
+// skipped. A plan normally has a single root; InitPlans printed at the top level add more.
+// The tree is built without recursion, and a plan nested deeper than maxPlanTreeDepth is an error.
+func parsePlanTree(plan string) ([]*PlanTreeNode, error) {
+	var roots []*PlanTreeNode
+
+	// Open ancestor nodes, by the indentation of their "->" arrow (-1 for a root node)
+	type openNode struct {
+		indent int
+		node   *PlanTreeNode
+	}
+	var ancestors []openNode
+
+	i := -1
+	for line := range planLines(plan) {
+		i++
+		matches := costRegex.FindStringSubmatch(line)
+		if matches == nil {
+			continue
+		}
+		cost, err := strconv.ParseFloat(matches[2], 64)
+		if err != nil {
+			continue
+		}
+
+		label := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "->"))
+		if end := strings.Index(label, "(cost="); end != -1 {
+			label = strings.TrimSpace(label[:end])
+		}
+		node := &PlanTreeNode{Label: label, Cost: cost, Line: i}
+
+		indent := strings.Index(line, "->")
+		for len(ancestors) > 0 && ancestors[len(ancestors)-1].indent >= indent {
+			ancestors = ancestors[:len(ancestors)-1]
+		}
+		if len(ancestors) >= maxPlanTreeDepth {
+			return nil, errPlanTooDeep
+		}
+		if len(ancestors) == 0 {
+			roots = append(roots, node)
+		} else {
+			parent := ancestors[len(ancestors)-1].node
+			parent.Children = append(parent.Children, node)
+		}
+		ancestors = append(ancestors, openNode{indent: indent, node: node})
+	}
+	return roots, nil
+}
+
+// formatPlanMermaid renders the plan tree as a Mermaid flowchart, one box per node labelled with
+// the node and its cost, and an edge from each node to its children. Nodes are styled like the
+// colored text plans: expensive nodes red, Seq Scans yellow and Index Scans green. costInfo is
+// nil when no threshold was set.
+func formatPlanMermaid(plan string, costInfo *CostInfo) (string, error) {
+	var sb strings.Builder
+	sb.WriteString("graph TD\n")
+
+	roots, err := parsePlanTree(plan)
+	if err != nil {
+		return "", err
+	}
+	if len(roots) == 0 {
+		sb.WriteString("    empty[\"No plan nodes with cost estimates\"]\n")
+		return sb.String(), nil
+	}
+
+	// Without a threshold, nodes with at least half of the total cost are expensive
+	summary := costInfo
+	if summary == nil {
+		summary = parseCost(plan, 0, 0)
+	}
+	limit := summary.ThresholdValue
+	if limit <= 0 {
+		limit = summary.TotalCost * expensiveLineShare
+	}
+
+	var edges []string
+	count := 0
+	// Nodes are numbered in plan order, so the edges keep the children in plan order too
+	var writeNode func(node *PlanTreeNode)
+	writeNode = func(node *PlanTreeNode) {
+		count++
+		id := fmt.Sprintf("n%d", count)
+		sb.WriteString(fmt.Sprintf("    %s[\"%s<br/>cost %.2f\"]%s\n", id, escapeMermaidLabel(node.Label), node.Cost, mermaidNodeClass(node, limit)))
+		for _, child := range node.Children {
+			edges = append(edges, fmt.Sprintf("    %s --> n%d\n", id, count+1))
+			writeNode(child)
+		}
+	}
+	for _, root := range roots {
+		writeNode(root)
+	}
+
+	for _, edge := range edges {
+		sb.WriteString(edge)
+	}
+	sb.WriteString("    classDef expensive fill:#fdecea,stroke:#d93025,color:#000\n")
+	sb.WriteString("    classDef seqscan fill:#fef7e0,stroke:#f9ab00,color:#000\n")
+	sb.WriteString("    classDef indexscan fill:#e6f4ea,stroke:#1e8e3e,color:#000\n")
+	return sb.String(), nil
+}
+
+// mermaidNodeClass returns the ":::class" suffix that styles the node, or ""
+func mermaidNodeClass(node *PlanTreeNode, limit float64) string {
+	switch {
+	case limit > 0 && node.Cost >= limit:
+		return ":::expensive"
+	case strings.Contains(node.Label, "Seq Scan"):
+		return ":::seqscan"
+	case strings.Contains(node.Label, "Index Scan") || strings.Contains(node.Label, "Index Only Scan"):
+		return ":::indexscan"
+	}
+	return ""
+}
+
+// escapeMermaidLabel escapes the characters Mermaid does not accept inside a quoted label
+func escapeMermaidLabel(label string) string {
+	return strings.NewReplacer("\"", "#quot;", "<", "#lt;", ">", "#gt;").Replace(label)
+}
+
+// writeMermaidPlan writes the plan as a Mermaid flowchart to a .mmd file
+func writeMermaidPlan(plan, title string, costInfo *CostInfo) string {
+	fileName := title + ".mmd"
+	diagram, err := formatPlanMermaid(plan, costInfo)
+	if err != nil {
+		logErrorAndExit("unable to render Mermaid diagram: ", err)
+	}
+	if err := os.WriteFile(fileName, []byte(diagram), 0644); err != nil {
+		logErrorAndExit("unable to write Mermaid diagram: ", err)
+	}
+
+	absPath, err := filepath.Abs(fileName)
+	if err != nil {
+		logErrorAndExit("unable to get file absolute path: ", err)
+	}
+	return absPath
+}
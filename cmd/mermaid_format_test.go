@@ -0,0 +1,110 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// deepTextPlan returns a text plan of depth nodes, each the only child of the one above
+func deepTextPlan(depth int) string {
+	var sb strings.Builder
+	for level := 0; level < depth; level++ {
+		if level > 0 {
+			sb.WriteString(strings.Repeat(" ", 6*level-4) + "->  ")
+		}
+		sb.WriteString(fmt.Sprintf("Nested Loop  (cost=0.00..%d.00 rows=1 width=4)\n", depth-level))
+	}
+	return sb.String()
+}
+
+// deepJSONPlan returns a FORMAT JSON plan of depth nodes, each the only child of the one above
+func deepJSONPlan(depth int) string {
+	var sb strings.Builder
+	sb.WriteString(`[{"Plan": `)
+	for level := 0; level < depth; level++ {
+		sb.WriteString(fmt.Sprintf(`{"Node Type": "Nested Loop", "Startup Cost": 0, "Total Cost": %d, "Plan Rows": 1, "Plan Width": 4`, depth-level))
+		if level < depth-1 {
+			sb.WriteString(`, "Plans": [`)
+		}
+	}
+	for level := 0; level < depth; level++ {
+		if level > 0 {
+			sb.WriteString("]")
+		}
+		sb.WriteString("}")
+	}
+	sb.WriteString("}]")
+	return sb.String()
+}
+
+func TestPlanTreeDepthBound(t *testing.T) {
+	tests := []struct {
+		depth   int
+		tooDeep bool
+	}{
+		{depth: 500},
+		{depth: maxPlanTreeDepth},
+		{depth: 1500, tooDeep: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("parsePlanTree/%d", tt.depth), func(t *testing.T) {
+			roots, err := parsePlanTree(deepTextPlan(tt.depth))
+			if tt.tooDeep {
+				if !errors.Is(err, errPlanTooDeep) {
+					t.Fatalf("got error %v, want %v", err, errPlanTooDeep)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parsePlanTree: %v", err)
+			}
+			depth := 0
+			for node := roots[0]; node != nil; depth++ {
+				if len(node.Children) == 0 {
+					node = nil
+				} else {
+					node = node.Children[0]
+				}
+			}
+			if len(roots) != 1 || depth != tt.depth {
+				t.Errorf("got %d roots and depth %d, want 1 root and depth %d", len(roots), depth, tt.depth)
+			}
+		})
+
+		t.Run(fmt.Sprintf("renderStructuredPlan/%d", tt.depth), func(t *testing.T) {
+			text, err := renderStructuredPlan(deepJSONPlan(tt.depth), "json")
+			if tt.tooDeep {
+				if !errors.Is(err, errPlanTooDeep) {
+					t.Fatalf("got error %v, want %v", err, errPlanTooDeep)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("renderStructuredPlan: %v", err)
+			}
+			if nodes := strings.Count(text, "Nested Loop"); nodes != tt.depth {
+				t.Errorf("rendered %d nodes, want %d", nodes, tt.depth)
+			}
+		})
+	}
+}
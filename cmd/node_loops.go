@@ -0,0 +1,68 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+var (
+	// loopsRegex captures the number of times a node was executed, in EXPLAIN ANALYZE output
+	loopsRegex = regexp.MustCompile(`\bloops=(\d+)\)`)
+	// actualTotalTimeRegex captures the time of one execution of a node, in ms
+	actualTotalTimeRegex = regexp.MustCompile(`actual time=\d+\.?\d*\.\.(\d+\.?\d*)`)
+)
+
+// nodeLoops returns the loops of a plan node line, or 0 when the plan was not run with ANALYZE
+func nodeLoops(line string) int64 {
+	matches := loopsRegex.FindStringSubmatch(line)
+	if matches == nil {
+		return 0
+	}
+	loops, _ := strconv.ParseInt(matches[1], 10, 64)
+	return loops
+}
+
+// nodeActualTotals returns the time and rows of a plan node over all of its loops. EXPLAIN ANALYZE
+// prints both per loop, so the inner side of a nested loop run 50,000 times shows 1/50,000 of its
+// real work. ok is false when the plan was not run with ANALYZE. The time is 0 when timing was
+// disabled, e.g. with EXPLAIN (ANALYZE, TIMING OFF).
+func nodeActualTotals(line string) (timeMs, rows float64, loops int64, ok bool) {
+	matches := actualRowsLoopsRegex.FindStringSubmatch(line)
+	if matches == nil {
+		return 0, 0, 0, false
+	}
+	rows, _ = strconv.ParseFloat(matches[1], 64)
+	loops, _ = strconv.ParseInt(matches[2], 10, 64)
+	if timeMatches := actualTotalTimeRegex.FindStringSubmatch(line); timeMatches != nil {
+		timeMs, _ = strconv.ParseFloat(timeMatches[1], 64)
+	}
+	return timeMs * float64(loops), rows * float64(loops), loops, true
+}
+
+// actualTotalsSummary describes the work of an operation over all its loops,
+// e.g. "actual 1250.00 ms, 50,000 rows over 50,000 loops"
+func actualTotalsSummary(op ExpensiveOperation) string {
+	summary := fmt.Sprintf("%s rows over %s loops", formatThousands(int64(op.ActualRows)), formatThousands(op.Loops))
+	if op.ActualTimeMs > 0 {
+		summary = fmt.Sprintf("%.2f ms, %s", op.ActualTimeMs, summary)
+	}
+	return "actual " + summary
+}
@@ -0,0 +1,150 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"strings"
+)
+
+// oldestSupportedVersion is the oldest PostgreSQL major version whose EXPLAIN output is parsed
+const oldestSupportedVersion = 12
+
+// planNodeType is a node type printed in text plans. Since is the PostgreSQL major version
+// that introduced it, 0 when every supported version has it. It is only shown by
+// explainOperation, plans of every version are parsed the same way.
+type planNodeType struct {
+	Name        string
+	Since       int
+	Explanation string
+}
+
+// planNodeTypes are the node types extractOperationType recognizes
+var planNodeTypes = []planNodeType{
+	{"Seq Scan", 0, "Reads every row of the table from start to finish, like reading a whole book to find one sentence"},
+	{"Parallel Seq Scan", 0, "Reads every row of the table, with the work split across several worker processes"},
+	{"Index Scan", 0, "Looks up matching rows through an index, then fetches each of them from the table"},
+	{"Parallel Index Scan", 0, "Looks up matching rows through an index, with the work split across several worker processes"},
+	{"Index Only Scan", 0, "Answers the query from the index alone, without visiting the table"},
+	{"Parallel Index Only Scan", 0, "Answers the query from the index alone, with the work split across several worker processes"},
+	{"Bitmap Heap Scan", 0, "Fetches the table pages that a Bitmap Index Scan marked as holding matching rows, in disk order"},
+	{"Parallel Bitmap Heap Scan", 0, "Fetches the table pages a Bitmap Index Scan marked, with the pages shared out to several worker processes"},
+	{"Bitmap Index Scan", 0, "Searches an index to build a map of the table pages that hold matching rows"},
+	{"Tid Range Scan", 14, "Reads a range of table pages by physical row location, e.g. WHERE ctid < '(1000,0)'"},
+	{"Foreign Scan", 0, "Fetches rows from a table on another server through a foreign data wrapper such as postgres_fdw"},
+	{"Async Foreign Scan", 14, "Fetches rows from another server while the other inputs of the Append are read at the same time"},
+	{"Subquery Scan", 0, "Reads the rows of a subquery in the FROM clause"},
+	{"CTE Scan", 0, "Reads the rows of a WITH query, which is computed once and kept"},
+	{"Function Scan", 0, "Reads the rows returned by a set-returning function such as generate_series"},
+	{"Nested Loop", 0, "For every row on one side, searches the other side for matches: quick for a few rows, slow for many"},
+	{"Hash Join", 0, "Loads one side into an in-memory lookup table, then checks each row of the other side against it"},
+	{"Parallel Hash Join", 0, "A Hash Join whose worker processes build one shared lookup table together and check rows against it in parallel"},
+	{"Merge Join", 0, "Walks through two inputs sorted on the join key side by side, matching rows as it goes"},
+	{"Hash", 0, "Builds the in-memory lookup table that a Hash Join checks rows against"},
+	{"Parallel Hash", 0, "Builds the lookup table of a Parallel Hash Join, shared by all worker processes"},
+	{"Memoize", 14, "Caches the rows of the inner side of a Nested Loop by parameter value, so repeated lookups skip the work"},
+	{"Materialize", 0, "Keeps a copy of rows in memory so they can be read again without recomputing them"},
+	{"Sort", 0, "Puts rows in order, in memory or on disk when they do not fit in work_mem"},
+	{"Incremental Sort", 13, "Sorts input already ordered on the leading keys group by group, so the first rows come out early"},
+	{"Aggregate", 0, "Combines rows into summary values such as COUNT, SUM or one row per GROUP BY group"},
+	{"WindowAgg", 0, "Computes window functions such as ROW_NUMBER() or running totals over ordered rows"},
+	{"Unique", 0, "Removes duplicate rows from sorted input, e.g. for DISTINCT"},
+	{"Limit", 0, "Stops after the rows the query asks for, so the nodes below it can stop early"},
+	{"Append", 0, "Returns the rows of several inputs one after another, e.g. the partitions of a table or a UNION ALL"},
+	{"Parallel Append", 0, "Reads the inputs of an Append with the worker processes spread across them"},
+	{"Merge Append", 0, "Combines several sorted inputs, e.g. partitions, into one sorted output"},
+	{"Gather", 0, "Collects the rows produced by parallel worker processes"},
+	{"Gather Merge", 0, "Collects the sorted rows of parallel worker processes, keeping them in order"},
+	{"Result", 0, "Computes rows without reading a table, e.g. SELECT 1, or checks a condition once"},
+}
+
+// knownOperationTypes are the names of planNodeTypes, as accepted by --op-threshold
+var knownOperationTypes = planNodeTypeNames()
+
+func planNodeTypeNames() []string {
+	names := make([]string, len(planNodeTypes))
+	for i, nodeType := range planNodeTypes {
+		names[i] = nodeType.Name
+	}
+	return names
+}
+
+// planNodeName returns the node part of a plan line, e.g. "Index Scan using orders_pkey on orders o"
+// for "->  Index Scan using orders_pkey on orders o  (cost=0.29..8.31 rows=1 width=40)"
+func planNodeName(line string) string {
+	name := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "->"))
+	if i := strings.Index(name, "  ("); i >= 0 {
+		name = name[:i]
+	} else if i := strings.Index(name, " (cost="); i >= 0 {
+		name = name[:i]
+	}
+	return strings.TrimSpace(name)
+}
+
+// extractOperationType extracts the operation type from an EXPLAIN line. A node named after a
+// known type, e.g. "Parallel Hash Join", gets the longest matching type. Variants such as
+// "Finalize HashAggregate" get the known type contained in their name, and unknown nodes
+// their own name without the relation they read, e.g. "Custom Scan (Citus Adaptive)".
+func extractOperationType(line string) string {
+	name := planNodeName(line)
+	if name == "" {
+		return "Unknown Operation"
+	}
+
+	operation := ""
+	for _, nodeType := range planNodeTypes {
+		if (name == nodeType.Name || strings.HasPrefix(name, nodeType.Name+" ")) && len(nodeType.Name) > len(operation) {
+			operation = nodeType.Name
+		}
+	}
+	if operation != "" {
+		return operation
+	}
+
+	for _, nodeType := range planNodeTypes {
+		if strings.Contains(name, nodeType.Name) && len(nodeType.Name) > len(operation) {
+			operation = nodeType.Name
+		}
+	}
+	if operation != "" {
+		return operation
+	}
+
+	for _, separator := range []string{" using ", " on "} {
+		if i := strings.Index(name, separator); i >= 0 {
+			name = name[:i]
+		}
+	}
+	return name
+}
+
+// explainOperation returns a one-line description of an operation for people new to
+// execution plans, or an empty string for operations it does not know. Node types newer
+// than the oldest supported version say which version introduced them.
+func explainOperation(op string) string {
+	for _, nodeType := range planNodeTypes {
+		if nodeType.Name != op {
+			continue
+		}
+		if nodeType.Since > oldestSupportedVersion {
+			return fmt.Sprintf("%s (PostgreSQL %d and later)", nodeType.Explanation, nodeType.Since)
+		}
+		return nodeType.Explanation
+	}
+	return ""
+}
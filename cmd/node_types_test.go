@@ -0,0 +1,96 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExtractOperationType(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+	}{
+		{"->  Memoize  (cost=0.30..8.31 rows=1 width=8)", "Memoize"},
+		{"->  Incremental Sort  (cost=51000.00..58000.00 rows=100 width=16)", "Incremental Sort"},
+		{"->  Parallel Hash Join  (cost=22466.00..72398.61 rows=41667 width=40)", "Parallel Hash Join"},
+		{"->  Hash Join  (cost=22466.00..72398.61 rows=41667 width=40)", "Hash Join"},
+		{"->  Async Foreign Scan on events_2023 events_1  (cost=100.00..17805.70 rows=5065 width=44)", "Async Foreign Scan"},
+		{"->  Foreign Scan on events_2023  (cost=100.00..17805.70 rows=5065 width=44)", "Foreign Scan"},
+		{"->  HashAggregate  (cost=1.00..2.00 rows=10 width=8)", "Aggregate"},
+		{"Finalize GroupAggregate  (cost=52000.00..60000.00 rows=100 width=16)", "Aggregate"},
+		{"->  Parallel Index Scan using orders_pkey on orders  (cost=0.43..8.45 rows=1 width=4)", "Parallel Index Scan"},
+		{"->  Parallel Bitmap Heap Scan on orders  (cost=4344.51..55998.14 rows=5014 width=64)", "Parallel Bitmap Heap Scan"},
+		{"->  Custom Scan (Citus Adaptive)  (cost=0.00..0.00 rows=0 width=0)", "Custom Scan (Citus Adaptive)"},
+	}
+
+	for _, tt := range tests {
+		if got := extractOperationType(tt.line); got != tt.want {
+			t.Errorf("extractOperationType(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestPlansOfEveryVersion(t *testing.T) {
+	tests := []struct {
+		file            string
+		operations      []string
+		totalCost       float64
+		executionTimeMs float64
+	}{
+		{"pg12.txt", []string{"Aggregate", "Gather", "Aggregate", "Parallel Hash Join", "Parallel Index Scan", "Parallel Hash", "Parallel Seq Scan"}, 48210.56, 418.937},
+		{"pg13.txt", []string{"Limit", "Incremental Sort", "Index Scan"}, 126240.30, 59.461},
+		{"pg14.txt", []string{"Append", "Async Foreign Scan", "Async Foreign Scan", "Nested Loop", "Tid Range Scan", "Memoize", "Index Scan"}, 35811.40, 233.119},
+		{"pg15.txt", []string{"Sort", "Gather", "Parallel Hash Join", "Parallel Seq Scan", "Parallel Hash", "Parallel Seq Scan"}, 96763.47, 739.087},
+		{"pg16.txt", []string{"Gather", "Parallel Bitmap Heap Scan", "Bitmap Index Scan"}, 58201.44, 188.606},
+		{"pg17.txt", []string{"Aggregate", "Gather Merge", "Aggregate", "Incremental Sort", "Nested Loop", "Parallel Index Scan", "Memoize", "Index Scan"}, 60000.00, 321.000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			plan := string(data)
+
+			var operations []string
+			for _, line := range strings.Split(plan, "\n") {
+				if strings.Contains(line, "(cost=") {
+					operations = append(operations, extractOperationType(line))
+				}
+			}
+			if !reflect.DeepEqual(operations, tt.operations) {
+				t.Errorf("operations = %q, want %q", operations, tt.operations)
+			}
+
+			costInfo := parseCost(plan, 1000, 0)
+			if costInfo.TotalCost != tt.totalCost || costInfo.ExecutionTimeMs != tt.executionTimeMs {
+				t.Errorf("total cost %.2f, execution time %.3f ms, want %.2f and %.3f ms",
+					costInfo.TotalCost, costInfo.ExecutionTimeMs, tt.totalCost, tt.executionTimeMs)
+			}
+			if costInfo.NodeCount != len(tt.operations) {
+				t.Errorf("node count = %d, want %d", costInfo.NodeCount, len(tt.operations))
+			}
+		})
+	}
+}
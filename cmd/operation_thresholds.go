@@ -0,0 +1,104 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// operationThresholds are the cost thresholds per operation type for this run, keyed by the
+// operation type as returned by extractOperationType. Set from the config file and --op-threshold.
+var operationThresholds map[string]float64
+
+// thresholdForOperation returns the cost above which an operation of this type is expensive.
+// Operation thresholds refine a cost analysis, so they only apply when a threshold is set.
+func thresholdForOperation(operation string, threshold float64) float64 {
+	if threshold <= 0 {
+		return threshold
+	}
+	if operationThreshold, ok := operationThresholds[operation]; ok {
+		return operationThreshold
+	}
+	return threshold
+}
+
+// operationThresholdsFromFlags merges the thresholds of the config file with the repeated
+// --op-threshold flags, which take precedence for the same operation type
+func operationThresholdsFromFlags(cmd *cobra.Command, config *Config) (map[string]float64, error) {
+	thresholds := make(map[string]float64)
+	for name, threshold := range config.Thresholds {
+		operation, err := canonicalOperationType(name)
+		if err != nil {
+			return nil, fmt.Errorf("config thresholds: %w", err)
+		}
+		if threshold < 0 {
+			return nil, fmt.Errorf("config thresholds: threshold of %s must not be negative", operation)
+		}
+		thresholds[operation] = threshold
+	}
+
+	values, _ := cmd.Flags().GetStringArray("op-threshold")
+	for _, value := range values {
+		parts := strings.SplitN(value, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid operation threshold %q, expected OPERATION=COST", value)
+		}
+		operation, err := canonicalOperationType(parts[0])
+		if err != nil {
+			return nil, err
+		}
+		threshold, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		if err != nil || threshold < 0 {
+			return nil, fmt.Errorf("invalid cost %q for %s, expected a number of at least 0", parts[1], operation)
+		}
+		thresholds[operation] = threshold
+	}
+	return thresholds, nil
+}
+
+// canonicalOperationType returns the operation type matching name, ignoring case, so that
+// "nested loop" matches the "Nested Loop" reported in plans
+func canonicalOperationType(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	for _, operation := range knownOperationTypes {
+		if strings.EqualFold(operation, name) {
+			return operation, nil
+		}
+	}
+	return "", fmt.Errorf("unknown operation type %q, expected one of: %s", name, strings.Join(knownOperationTypes, ", "))
+}
+
+// formatOperationThresholds lists the thresholds by operation type, e.g. "Nested Loop=1000, Sort=5000"
+func formatOperationThresholds(thresholds map[string]float64) string {
+	operations := make([]string, 0, len(thresholds))
+	for operation := range thresholds {
+		operations = append(operations, operation)
+	}
+	sort.Strings(operations)
+
+	parts := make([]string, 0, len(operations))
+	for _, operation := range operations {
+		parts = append(parts, fmt.Sprintf("%s=%.0f", operation, thresholds[operation]))
+	}
+	return strings.Join(parts, ", ")
+}
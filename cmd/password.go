@@ -0,0 +1,60 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// promptedPassword holds the password entered with --prompt-password. It takes precedence
+// over PGPASSWORD and the config file, and is only ever passed to psql through its environment.
+var promptedPassword string
+
+// promptPasswordIfRequested asks for the database password when --prompt-password is set.
+// The password is only asked once per run.
+func promptPasswordIfRequested(cmd *cobra.Command) {
+	prompt, _ := cmd.Flags().GetBool("prompt-password")
+	if !prompt || promptedPassword != "" {
+		return
+	}
+
+	password, err := readPassword()
+	if err != nil {
+		logErrorAndExit("Unable to read password", err)
+	}
+	promptedPassword = password
+}
+
+// readPassword reads a password from the terminal without echoing it
+func readPassword() (string, error) {
+	if !isTerminal(os.Stdin) {
+		return "", fmt.Errorf("--prompt-password needs an interactive terminal, use PGPASSWORD or a .pgpass file instead")
+	}
+
+	fmt.Print("🔑 Database password: ")
+	password, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Println()
+	if err != nil {
+		return "", err
+	}
+	return string(password), nil
+}
@@ -0,0 +1,106 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+// exclusiveCost returns the cost of the node itself: its total cost minus the total cost of its
+// children. A Limit node can cost less than its child, as it stops early, so this is at least 0.
+func exclusiveCost(node *PlanTreeNode) float64 {
+	cost := node.Cost
+	for _, child := range node.Children {
+		cost -= child.Cost
+	}
+	return math.Max(cost, 0)
+}
+
+// annotatePlan appends each node's share of the plan's total cost to its line, e.g. "(34%)",
+// aligned in a column after the longest node line. The share uses the node's exclusive cost, so
+// it shows where the cost is spent rather than repeating the cumulative cost of the subtree.
+// Detail lines and plans without cost estimates are returned unchanged, as are plans too deeply
+// nested to parse and plans above --large-plan-bytes, after a warning.
+func annotatePlan(plan string) string {
+	if isLargePlan(plan) {
+		fmt.Printf("⚠️  Plan annotations are unavailable: %s\n", planAboveSizeLimit(plan))
+		return plan
+	}
+	roots, err := parsePlanTree(plan)
+	if err != nil {
+		fmt.Printf("⚠️  Plan annotations are unavailable: %v\n", err)
+		return plan
+	}
+	total := 0.0
+	for _, root := range roots {
+		total += root.Cost
+	}
+	if total == 0 {
+		return plan
+	}
+
+	lines := strings.Split(plan, "\n")
+	shares := make(map[int]float64)
+	width := 0
+	var visit func(node *PlanTreeNode)
+	visit = func(node *PlanTreeNode) {
+		shares[node.Line] = exclusiveCost(node) / total * 100
+		width = max(width, utf8.RuneCountInString(strings.TrimRight(lines[node.Line], " ")))
+		for _, child := range node.Children {
+			visit(child)
+		}
+	}
+	for _, root := range roots {
+		visit(root)
+	}
+
+	for i, share := range shares {
+		line := strings.TrimRight(lines[i], " ")
+		padding := strings.Repeat(" ", width-utf8.RuneCountInString(line))
+		lines[i] = fmt.Sprintf("%s%s  (%s)", line, padding, formatCostShare(share))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatCostShare formats a share of the total cost as a whole percentage, or "<1%" for a node
+// that costs something but rounds to zero
+func formatCostShare(share float64) string {
+	if share > 0 && share < 0.5 {
+		return "<1%"
+	}
+	return fmt.Sprintf("%.0f%%", share)
+}
+
+// annotateComparisonPlans returns a copy of the result with both plans annotated by annotatePlan
+func annotateComparisonPlans(result *ComparisonResult) *ComparisonResult {
+	annotated := *result
+	annotated.Plan1 = annotatePlan(result.Plan1)
+	annotated.Plan2 = annotatePlan(result.Plan2)
+	return &annotated
+}
+
+// displayAnnotatedPlan prints the plan annotated with each node's share of the total cost,
+// colored like the compare text output. costInfo is nil when no threshold was set.
+func displayAnnotatedPlan(plan string, costInfo *CostInfo) {
+	fmt.Println("📋 Execution plan (share of total cost per node):")
+	fmt.Println(colorizePlan(annotatePlan(plan), costInfo))
+	fmt.Println()
+}
@@ -0,0 +1,138 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// CachedPlan is a plan stored in the on-disk cache.
+// Timings inside the plan belong to the run that produced it, so cached plans are
+// only meant for inspecting costs and plan structure.
+type CachedPlan struct {
+	Query     string    `json:"query"`
+	Plan      string    `json:"plan"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// schemaFingerprintSQL hashes the user relations, including indexes, so adding or
+// altering a table or index changes the cache key. Statistics such as reltuples are
+// left out on purpose because autovacuum updates them constantly; use --cache-ttl to
+// bound how stale a cached plan may get.
+const schemaFingerprintSQL = `SELECT md5(coalesce(string_agg(c.oid::text || ':' || c.relname || ':' || c.relkind || ':' || c.relnatts || ':' || c.relfilenode, ',' ORDER BY c.oid), ''))
+FROM pg_class c
+JOIN pg_namespace n ON n.oid = c.relnamespace
+WHERE n.nspname NOT IN ('pg_catalog', 'information_schema') AND n.nspname NOT LIKE 'pg_toast%'`
+
+// planCacheDir returns the directory that holds cached plans
+func planCacheDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".pgexplain", "cache"), nil
+}
+
+// planCacheKey hashes the statements, the target database and the schema fingerprint.
+// The port is taken from PGPORT, like psql does, so servers on the same host get separate
+// entries. A user supplied --schema-version replaces the catalog lookup.
+func planCacheKey(config *Config, statements []string, options ExplainOptions) (string, error) {
+	schemaVersion := options.SchemaVersion
+	if schemaVersion == "" {
+		fingerprint, err := schemaFingerprint(config)
+		if err != nil {
+			return "", err
+		}
+		schemaVersion = fingerprint
+	}
+
+	user, database, host, _ := connectionSettings(config)
+	if config.Database.Service != "" {
+		host, database, user = serviceConninfo(config.Database.Service), "", ""
+	}
+	parts := append([]string{host, os.Getenv("PGPORT"), database, user, schemaVersion}, statements...)
+	hash := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// schemaFingerprint asks the database for a hash of its user relations
+func schemaFingerprint(config *Config) (string, error) {
+	output, err := runPsql(config, []string{"-q", "-A", "-t", "-c", schemaFingerprintSQL})
+	if err != nil {
+		return "", fmt.Errorf("unable to fingerprint the schema: %w", err)
+	}
+
+	fingerprint := strings.TrimSpace(output)
+	if fingerprint == "" {
+		return "", fmt.Errorf("unable to fingerprint the schema: empty result")
+	}
+	return fingerprint, nil
+}
+
+// loadCachedPlan returns the cached plan for key if it exists and is younger than ttl (0 = never expires)
+func loadCachedPlan(key string, ttl time.Duration) (*CachedPlan, bool) {
+	cacheDir, err := planCacheDir()
+	if err != nil {
+		return nil, false
+	}
+
+	content, err := os.ReadFile(filepath.Join(cacheDir, key+".json"))
+	if err != nil {
+		return nil, false
+	}
+
+	var entry CachedPlan
+	if err := json.Unmarshal(content, &entry); err != nil {
+		return nil, false
+	}
+
+	if ttl > 0 && time.Since(entry.CreatedAt) > ttl {
+		return nil, false
+	}
+	return &entry, true
+}
+
+// storeCachedPlan writes a plan to the cache. Plans can contain literal values from
+// the query, so the cache is only readable by the current user.
+func storeCachedPlan(key, query, plan string) error {
+	cacheDir, err := planCacheDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(cacheDir, 0700); err != nil {
+		return err
+	}
+
+	content, err := json.MarshalIndent(CachedPlan{
+		Query:     query,
+		Plan:      plan,
+		CreatedAt: time.Now(),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(cacheDir, key+".json"), content, 0600)
+}
@@ -0,0 +1,41 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import "testing"
+
+func TestPlanCacheKeyIncludesPort(t *testing.T) {
+	config := &Config{}
+	options := ExplainOptions{SchemaVersion: "v1"}
+	statements := []string{"SELECT 1"}
+	t.Setenv("PGHOST", "localhost")
+
+	t.Setenv("PGPORT", "5432")
+	first, err := planCacheKey(config, statements, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PGPORT", "5433")
+	second, err := planCacheKey(config, statements, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == second {
+		t.Error("plans of servers on different ports share a cache key")
+	}
+}
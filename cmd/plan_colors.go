@@ -0,0 +1,72 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"strconv"
+	"strings"
+)
+
+// expensiveLineShare is the share of the plan's total cost from which a node is highlighted
+// as expensive when no cost threshold is set
+const expensiveLineShare = 0.5
+
+// colorizePlanLine highlights a text plan line by node type: expensive nodes in red, Seq Scans
+// in yellow and Index Scans in green. A node is expensive when its cost exceeds the threshold, or
+// without a threshold, when it accounts for at least half of the plan's total cost.
+// Detail lines such as "Filter: ..." are returned unchanged.
+func colorizePlanLine(line string, costInfo *CostInfo) string {
+	matches := costRegex.FindStringSubmatch(line)
+	if matches == nil {
+		return line
+	}
+
+	if costInfo != nil && costInfo.Warning == "" {
+		if lineCost, err := strconv.ParseFloat(matches[2], 64); err == nil {
+			limit := costInfo.ThresholdValue
+			if limit <= 0 {
+				limit = costInfo.TotalCost * expensiveLineShare
+			}
+			if limit > 0 && lineCost >= limit {
+				return colorize(line, ansiRed)
+			}
+		}
+	}
+
+	switch {
+	case strings.Contains(line, "Seq Scan"):
+		return colorize(line, ansiYellow)
+	case strings.Contains(line, "Index Scan") || strings.Contains(line, "Index Only Scan"):
+		return colorize(line, ansiGreen)
+	}
+	return line
+}
+
+// colorizePlan applies colorizePlanLine to every line of a text plan
+func colorizePlan(plan string, costInfo *CostInfo) string {
+	var sb strings.Builder
+	first := true
+	for line := range planLines(plan) {
+		if !first {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(colorizePlanLine(line, costInfo))
+		first = false
+	}
+	return sb.String()
+}
@@ -0,0 +1,28 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import "fmt"
+
+// maxPlanTreeDepth bounds how deeply plan nodes may be nested, in text plans (parsePlanTree)
+// as well as JSON and YAML plans (renderStructuredPlan). Real plans stay far below it, so
+// a deeper plan is malformed or pathological and is rejected rather than walked.
+const maxPlanTreeDepth = 1000
+
+// errPlanTooDeep is returned for a plan nested more than maxPlanTreeDepth levels deep
+var errPlanTooDeep = fmt.Errorf("the plan is nested more than %d levels deep, which suggests it is malformed", maxPlanTreeDepth)
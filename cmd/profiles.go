@@ -0,0 +1,82 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// configForProfile returns a copy of config that connects with the named profile.
+// Settings the profile leaves empty fall back to the PGHOST, PGUSER, PGDATABASE and PGPASSWORD environment variables.
+func configForProfile(config *Config, name string) (*Config, error) {
+	profile, ok := config.Profiles[name]
+	if !ok {
+		if len(config.Profiles) == 0 {
+			return nil, fmt.Errorf("profile %q not found, no profiles are defined in the configuration file", name)
+		}
+		names := make([]string, 0, len(config.Profiles))
+		for profileName := range config.Profiles {
+			names = append(names, profileName)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("profile %q not found, available profiles: %s", name, strings.Join(names, ", "))
+	}
+
+	profileConfig := *config
+	profileConfig.Profile = name
+	profileConfig.Database.Host = profile.Host
+	profileConfig.Database.User = profile.User
+	profileConfig.Database.Database = profile.Database
+	profileConfig.Database.Password = profile.Password
+	profileConfig.Database.Service = profile.Service
+	return &profileConfig, nil
+}
+
+// applyServiceFlag selects the libpq service given with --service, which takes precedence over
+// the service in the configuration file
+func applyServiceFlag(cmd *cobra.Command, config *Config) {
+	if service, _ := cmd.Flags().GetString("service"); service != "" {
+		config.Database.Service = service
+	}
+}
+
+// serviceConninfo returns the psql connection string that selects a service from the
+// connection service file, e.g. service=mydb
+func serviceConninfo(service string) string {
+	if strings.ContainsAny(service, " '\\") {
+		escaped := strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(service)
+		return fmt.Sprintf("service='%s'", escaped)
+	}
+	return "service=" + service
+}
+
+// profileLabel names a profile and the database it points to, e.g. "staging (mydb@staging-db)"
+func profileLabel(config *Config) string {
+	if config.Database.Service != "" {
+		return fmt.Sprintf("%s (%s)", config.Profile, serviceConninfo(config.Database.Service))
+	}
+	_, database, host, _ := connectionSettings(config)
+	if host == "" {
+		return fmt.Sprintf("%s (%s)", config.Profile, database)
+	}
+	return fmt.Sprintf("%s (%s@%s)", config.Profile, database, host)
+}
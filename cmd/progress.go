@@ -0,0 +1,101 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// progressBarWidth is the number of cells in the progress bar
+const progressBarWidth = 30
+
+// progressBar draws a single line that is redrawn in place as queries complete
+type progressBar struct {
+	// mu serializes drawing, as the workers of a parallel batch print above the bar too
+	mu      sync.Mutex
+	total   int
+	done    int
+	started time.Time
+}
+
+// newProgressBar creates a progress bar for total items and draws it
+func newProgressBar(total int) *progressBar {
+	bar := &progressBar{total: total, started: time.Now()}
+	bar.draw()
+	return bar
+}
+
+// Update sets the number of completed items and redraws the bar
+func (p *progressBar) Update(done int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done = done
+	p.draw()
+}
+
+// Printf prints a message above the bar without breaking it
+func (p *progressBar) Printf(format string, args ...interface{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.clear()
+	fmt.Printf(format, args...)
+	p.draw()
+}
+
+// Finish removes the bar so the summary starts on a clean line
+func (p *progressBar) Finish() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.clear()
+}
+
+func (p *progressBar) clear() {
+	fmt.Print("\r\033[K")
+}
+
+func (p *progressBar) draw() {
+	percent := 0.0
+	if p.total > 0 {
+		percent = float64(p.done) / float64(p.total)
+	}
+	filled := int(percent * progressBarWidth)
+
+	// ETA uses the average time per completed item
+	eta := "--"
+	if p.done > 0 && p.done < p.total {
+		average := time.Since(p.started) / time.Duration(p.done)
+		eta = (average * time.Duration(p.total-p.done)).Round(time.Second).String()
+	} else if p.done >= p.total {
+		eta = "0s"
+	}
+
+	fmt.Printf("\r\033[K🔄 [%s%s] %3.0f%% %d/%d | ETA %s",
+		strings.Repeat("█", filled), strings.Repeat("░", progressBarWidth-filled),
+		percent*100, p.done, p.total, eta)
+}
+
+// isTerminal reports whether the file is an interactive terminal rather than a pipe or file
+func isTerminal(file *os.File) bool {
+	return term.IsTerminal(int(file.Fd()))
+}
@@ -0,0 +1,91 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/spf13/cobra"
+)
+
+// psqlPath is the psql binary to run, set once per run from --psql-path or database.psql_path
+var psqlPath = "psql"
+
+// configurePsqlPath applies the --psql-path flag, falling back to the config file. The path is
+// validated by requirePsql, so commands that never run psql are not affected by a wrong path.
+func configurePsqlPath(cmd *cobra.Command, config *Config) {
+	path, _ := cmd.Flags().GetString("psql-path")
+	if !cmd.Flags().Changed("psql-path") {
+		path = config.Database.PsqlPath
+	}
+	if path != "" {
+		psqlPath = path
+	}
+}
+
+// validatePsqlPath checks that a psql binary given with --psql-path or psql_path is an executable file
+func validatePsqlPath(path string) error {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("%s does not exist", path)
+	}
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return fmt.Errorf("%s is a directory, not the psql binary", path)
+	}
+	if _, err := exec.LookPath(path); err != nil {
+		return fmt.Errorf("%s is not executable", path)
+	}
+	return nil
+}
+
+// requirePsql exits with installation hints when psql is not on PATH, or with an error when the
+// binary set with --psql-path is not usable. Commands that connect to the database call it
+// before reading any input, so a missing client is reported up front instead of as an exec
+// error after the query has been typed in.
+func requirePsql() {
+	if psqlPath != "psql" {
+		if err := validatePsqlPath(psqlPath); err != nil {
+			logErrorAndExit("Invalid --psql-path value", err)
+		}
+		return
+	}
+	if _, err := exec.LookPath("psql"); err == nil {
+		return
+	}
+
+	fmt.Fprintln(os.Stderr, "\n❌ psql was not found on your PATH")
+	fmt.Fprintln(os.Stderr, "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	fmt.Fprintln(os.Stderr, "pg_explain runs EXPLAIN through psql, the PostgreSQL command-line client.")
+	fmt.Fprintln(os.Stderr, "Install it and make sure its directory is on your PATH:")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "   macOS:          brew install libpq && brew link --force libpq")
+	fmt.Fprintln(os.Stderr, "   Debian/Ubuntu:  sudo apt-get install postgresql-client")
+	fmt.Fprintln(os.Stderr, "   Fedora/RHEL:    sudo dnf install postgresql")
+	fmt.Fprintln(os.Stderr, "   Alpine:         apk add postgresql-client")
+	fmt.Fprintln(os.Stderr, "   Windows:        install PostgreSQL from https://www.postgresql.org/download/windows/")
+	fmt.Fprintln(os.Stderr, "                   and add its bin directory (e.g. C:\\Program Files\\PostgreSQL\\16\\bin) to PATH")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Run 'psql --version' to check the installation, or point --psql-path at a psql binary.")
+	fmt.Fprintln(os.Stderr, "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	os.Exit(1)
+}
@@ -0,0 +1,236 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// psqlQueryTerminatorRegex matches \g and its variants (\gx, \gset, \gexec, ...), which send the query like ";"
+	psqlQueryTerminatorRegex = regexp.MustCompile(`\s*\\g[a-z]*(\s.*)?$`)
+
+	// psqlVariableRegex matches :name, :'name' and :"name". Casts (::type) are matched too, so they are left alone.
+	psqlVariableRegex = regexp.MustCompile(`::|:'([A-Za-z_][A-Za-z0-9_]*)'|:"([A-Za-z_][A-Za-z0-9_]*)"|:([A-Za-z_][A-Za-z0-9_]*)`)
+
+	// psqlQuotedVariableRegex matches :'name' and :"name" at the start of the text, whose quotes
+	// are part of the variable reference rather than a literal or identifier
+	psqlQuotedVariableRegex = regexp.MustCompile(`^:(?:'[A-Za-z_][A-Za-z0-9_]*'|"[A-Za-z_][A-Za-z0-9_]*")`)
+)
+
+// psqlSegment is a run of a query line that is either inside a string literal or quoted
+// identifier, or outside of one
+type psqlSegment struct {
+	Text   string
+	Quoted bool
+}
+
+// splitPsqlQuoted splits a line into quoted and unquoted segments. '...' literals and "..."
+// identifiers are quoted, with doubled quotes as escapes; :'name' and :"name" stay unquoted.
+// A quote left open at the end of the line makes the rest of the line quoted.
+func splitPsqlQuoted(line string) []psqlSegment {
+	var segments []psqlSegment
+	start := 0
+	for i := 0; i < len(line); i++ {
+		if line[i] == ':' {
+			if loc := psqlQuotedVariableRegex.FindStringIndex(line[i:]); loc != nil {
+				i += loc[1] - 1
+			}
+			continue
+		}
+		quote := line[i]
+		if quote != '\'' && quote != '"' {
+			continue
+		}
+
+		if i > start {
+			segments = append(segments, psqlSegment{Text: line[start:i]})
+		}
+		end := i + 1
+		for end < len(line) {
+			if line[end] == quote {
+				if end+1 < len(line) && line[end+1] == quote {
+					end += 2
+					continue
+				}
+				end++
+				break
+			}
+			end++
+		}
+		segments = append(segments, psqlSegment{Text: line[i:end], Quoted: true})
+		start = end
+		i = end - 1
+	}
+	if start < len(line) {
+		segments = append(segments, psqlSegment{Text: line[start:]})
+	}
+	return segments
+}
+
+// isPsqlMetaCommand reports whether the line is a psql meta-command such as \set, \timing or \connect
+func isPsqlMetaCommand(line string) bool {
+	return strings.HasPrefix(line, "\\")
+}
+
+// applyPsqlMetaCommand records \set and \unset variable definitions. Other meta-commands
+// (\timing, \i, \connect, ...) only affect an interactive psql session and are ignored.
+func applyPsqlMetaCommand(line string, variables map[string]string) {
+	fields := psqlMetaArguments(line)
+	switch fields[0] {
+	case "\\set":
+		if len(fields) < 2 {
+			return
+		}
+		// Like psql, the values after the name are concatenated
+		variables[fields[1]] = strings.Join(fields[2:], "")
+	case "\\unset":
+		if len(fields) >= 2 {
+			delete(variables, fields[1])
+		}
+	}
+}
+
+// psqlMetaArguments splits a meta-command line into the command and its arguments the way
+// psql does: arguments are separated by whitespace, a single-quoted part loses its quotes
+// and may contain whitespace, doubled quotes and backslash escapes such as \n, and a double-quoted part
+// keeps its quotes. Adjacent quoted and unquoted parts form one argument.
+func psqlMetaArguments(line string) []string {
+	var arguments []string
+	var argument strings.Builder
+	inArgument := false
+	flush := func() {
+		if inArgument {
+			arguments = append(arguments, argument.String())
+			argument.Reset()
+			inArgument = false
+		}
+	}
+
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c == ' ' || c == '\t':
+			flush()
+		case c == '\'':
+			inArgument = true
+			for i++; i < len(line); i++ {
+				if line[i] == '\'' {
+					if i+1 < len(line) && line[i+1] == '\'' {
+						argument.WriteByte('\'')
+						i++
+						continue
+					}
+					break
+				}
+				if line[i] == '\\' && i+1 < len(line) {
+					i++
+					argument.WriteString(psqlEscape(line[i]))
+					continue
+				}
+				argument.WriteByte(line[i])
+			}
+		case c == '"':
+			inArgument = true
+			end := i + 1
+			for end < len(line) && line[end] != '"' {
+				end++
+			}
+			if end < len(line) {
+				end++
+			}
+			argument.WriteString(line[i:end])
+			i = end - 1
+		default:
+			inArgument = true
+			argument.WriteByte(c)
+		}
+	}
+	flush()
+	return arguments
+}
+
+// psqlEscape returns the character a backslash escape inside a single-quoted meta-command argument stands for
+func psqlEscape(c byte) string {
+	switch c {
+	case 'n':
+		return "\n"
+	case 't':
+		return "\t"
+	case 'r':
+		return "\r"
+	case 'b':
+		return "\b"
+	case 'f':
+		return "\f"
+	}
+	return string(c)
+}
+
+// splitPsqlQueryTerminator removes a trailing \g meta-command from a query line and
+// reports whether one was found, in which case the query ends on this line. A \g inside
+// a string literal or quoted identifier is part of the query.
+func splitPsqlQueryTerminator(line string) (string, bool) {
+	offset := 0
+	for _, segment := range splitPsqlQuoted(line) {
+		if !segment.Quoted {
+			if loc := psqlQueryTerminatorRegex.FindStringIndex(segment.Text); loc != nil {
+				return line[:offset+loc[0]], true
+			}
+		}
+		offset += len(segment.Text)
+	}
+	return line, false
+}
+
+// substitutePsqlVariables interpolates variables defined with \set the way psql does:
+// :name inserts the value as is, :'name' as a string literal and :"name" as an identifier.
+// Undefined variables and text inside string literals or quoted identifiers are left untouched.
+func substitutePsqlVariables(line string, variables map[string]string) string {
+	if len(variables) == 0 {
+		return line
+	}
+
+	var sb strings.Builder
+	for _, segment := range splitPsqlQuoted(line) {
+		if segment.Quoted {
+			sb.WriteString(segment.Text)
+			continue
+		}
+		sb.WriteString(psqlVariableRegex.ReplaceAllStringFunc(segment.Text, func(match string) string {
+			groups := psqlVariableRegex.FindStringSubmatch(match)
+			switch {
+			case groups[1] != "":
+				if value, ok := variables[groups[1]]; ok {
+					return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+				}
+			case groups[2] != "":
+				if value, ok := variables[groups[2]]; ok {
+					return `"` + strings.ReplaceAll(value, `"`, `""`) + `"`
+				}
+			case groups[3] != "":
+				if value, ok := variables[groups[3]]; ok {
+					return value
+				}
+			}
+			return match
+		}))
+	}
+	return sb.String()
+}
@@ -0,0 +1,113 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseSQLFileWithMetaCommands(t *testing.T) {
+	queries, _, err := parseSQLFile("../test_meta_commands.sql")
+	if err != nil {
+		t.Fatalf("parseSQLFile: %v", err)
+	}
+
+	want := []string{
+		"SELECT * FROM users WHERE age > 25",
+		`SELECT o.id, o.created_at::date FROM "orders" o WHERE o.status = 'pending'`,
+		"SELECT COUNT(*) FROM orders",
+	}
+	if len(queries) != len(want) {
+		t.Fatalf("got %d queries, want %d: %q", len(queries), len(want), queries)
+	}
+	for i := range want {
+		if queries[i] != want[i] {
+			t.Errorf("query %d = %q, want %q", i+1, queries[i], want[i])
+		}
+		for _, meta := range []string{`\timing`, `\connect`, `\echo`, `\i`, `\gx`, `\set`} {
+			if strings.Contains(queries[i], meta) {
+				t.Errorf("query %d contains the meta-command %s: %q", i+1, meta, queries[i])
+			}
+		}
+	}
+}
+
+func TestSplitPsqlQueryTerminator(t *testing.T) {
+	tests := []struct {
+		line       string
+		want       string
+		terminated bool
+	}{
+		{line: `SELECT 1 \gx`, want: `SELECT 1`, terminated: true},
+		{line: `SELECT 1 \g /tmp/out.txt`, want: `SELECT 1`, terminated: true},
+		{line: `\gset`, want: ``, terminated: true},
+		{line: `SELECT '\g' AS literal`, want: `SELECT '\g' AS literal`},
+		{line: `SELECT "col \g" FROM t \g`, want: `SELECT "col \g" FROM t`, terminated: true},
+		{line: `SELECT 'it''s \g' \gx`, want: `SELECT 'it''s \g'`, terminated: true},
+	}
+	for _, tt := range tests {
+		got, terminated := splitPsqlQueryTerminator(tt.line)
+		if got != tt.want || terminated != tt.terminated {
+			t.Errorf("splitPsqlQueryTerminator(%q) = %q, %v, want %q, %v", tt.line, got, terminated, tt.want, tt.terminated)
+		}
+	}
+}
+
+func TestApplyPsqlMetaCommandQuoting(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+	}{
+		{line: `\set greeting 'hello world'`, want: "hello world"},
+		{line: `\set quote 'it''s'`, want: "it's"},
+		{line: `\set lines 'a\nb'`, want: "a\nb"},
+		{line: `\set joined foo bar`, want: "foobar"},
+		{line: `\set mixed pre'fix suffix'`, want: "prefix suffix"},
+		{line: `\set ident "Mixed Case"`, want: `"Mixed Case"`},
+		{line: `\set empty`, want: ""},
+	}
+	for _, tt := range tests {
+		variables := map[string]string{}
+		applyPsqlMetaCommand(tt.line, variables)
+		name := psqlMetaArguments(tt.line)[1]
+		if got, ok := variables[name]; !ok || got != tt.want {
+			t.Errorf("%s sets %s = %q, want %q", tt.line, name, got, tt.want)
+		}
+	}
+}
+
+func TestSubstitutePsqlVariablesOutsideQuotes(t *testing.T) {
+	variables := map[string]string{"id": "42", "name": "O'Brien", "tbl": "users"}
+	tests := []struct {
+		line string
+		want string
+	}{
+		{line: `SELECT * FROM :"tbl" WHERE id = :id`, want: `SELECT * FROM "users" WHERE id = 42`},
+		{line: `WHERE name = :'name'`, want: `WHERE name = 'O''Brien'`},
+		{line: `WHERE note = ':id' AND id = :id`, want: `WHERE note = ':id' AND id = 42`},
+		{line: `SELECT ":id" FROM t`, want: `SELECT ":id" FROM t`},
+		{line: `SELECT created_at::date`, want: `SELECT created_at::date`},
+		{line: `SELECT :undefined`, want: `SELECT :undefined`},
+	}
+	for _, tt := range tests {
+		if got := substitutePsqlVariables(tt.line, variables); got != tt.want {
+			t.Errorf("substitutePsqlVariables(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
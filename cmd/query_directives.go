@@ -0,0 +1,150 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// queryDirectiveRegex matches a directive comment such as "-- @tag: reporting" or "-- @threshold: 5000"
+var queryDirectiveRegex = regexp.MustCompile(`^--\s*@([A-Za-z_]+)\s*:\s*(.*)$`)
+
+// QueryDirectives holds the per-query settings given with directive comments in a batch file
+type QueryDirectives struct {
+	Tag       string
+	Threshold float64
+}
+
+// applyQueryDirective records the directive on the line, if it is one. Unknown directives are
+// ignored, so annotations meant for other tools do not break the batch.
+func applyQueryDirective(line string, directives *QueryDirectives) error {
+	matches := queryDirectiveRegex.FindStringSubmatch(line)
+	if matches == nil {
+		return nil
+	}
+
+	value := strings.TrimSpace(matches[2])
+	switch strings.ToLower(matches[1]) {
+	case "tag":
+		directives.Tag = value
+	case "threshold":
+		threshold, err := strconv.ParseFloat(value, 64)
+		if err != nil || threshold < 0 {
+			return fmt.Errorf("invalid @threshold %q, expected a non-negative number", value)
+		}
+		directives.Threshold = threshold
+	}
+	return nil
+}
+
+// TagAggregates summarizes the queries sharing a @tag directive
+type TagAggregates struct {
+	Tag        string           `json:"tag"`
+	Queries    int              `json:"queries"`
+	Aggregates *BatchAggregates `json:"aggregates,omitempty"`
+}
+
+// computeTagAggregates groups the results by tag, in order of first appearance.
+// It returns nil when no query is tagged.
+func computeTagAggregates(results []BatchResult) []TagAggregates {
+	var tags []string
+	byTag := make(map[string][]BatchResult)
+	for _, result := range results {
+		if result.Tag == "" {
+			continue
+		}
+		if _, ok := byTag[result.Tag]; !ok {
+			tags = append(tags, result.Tag)
+		}
+		byTag[result.Tag] = append(byTag[result.Tag], result)
+	}
+
+	var groups []TagAggregates
+	for _, tag := range tags {
+		groups = append(groups, TagAggregates{
+			Tag:        tag,
+			Queries:    len(byTag[tag]),
+			Aggregates: computeBatchAggregates(byTag[tag]),
+		})
+	}
+	return groups
+}
+
+// formatTagAggregates describes one tag group, e.g. "3 queries | total 1200.00 | avg 400.00 | exceeded 1"
+func formatTagAggregates(group TagAggregates) string {
+	summary := fmt.Sprintf("%d queries", group.Queries)
+	if group.Queries == 1 {
+		summary = "1 query"
+	}
+	if aggregates := group.Aggregates; aggregates != nil {
+		summary += fmt.Sprintf(" | total %.2f | avg %.2f | p95 %.2f | exceeded %d of %d",
+			aggregates.TotalCost, aggregates.AverageCost, aggregates.P95Cost,
+			aggregates.ExceededThreshold, aggregates.AnalyzedQueries)
+	}
+	return summary
+}
+
+// formatTagAggregatesMarkdown renders the per-tag cost statistics as a Markdown section
+func formatTagAggregatesMarkdown(groups []TagAggregates) string {
+	if len(groups) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("## Cost by Tag\n\n")
+	sb.WriteString("| Tag | Queries | Total Cost | Average Cost | P95 Cost | Exceeded Threshold |\n")
+	sb.WriteString("|-----|---------|------------|--------------|----------|--------------------|\n")
+	for _, group := range groups {
+		tag := strings.ReplaceAll(escapeMarkdownSpecialChars(group.Tag), "|", "\\|")
+		if aggregates := group.Aggregates; aggregates != nil {
+			sb.WriteString(fmt.Sprintf("| %s | %d | %.2f | %.2f | %.2f | %d of %d |\n", tag, group.Queries,
+				aggregates.TotalCost, aggregates.AverageCost, aggregates.P95Cost,
+				aggregates.ExceededThreshold, aggregates.AnalyzedQueries))
+		} else {
+			sb.WriteString(fmt.Sprintf("| %s | %d | N/A | N/A | N/A | N/A |\n", tag, group.Queries))
+		}
+	}
+	sb.WriteString("\n---\n\n")
+	return sb.String()
+}
+
+// formatTagAggregatesHTML renders the per-tag cost statistics for the batch HTML report
+func formatTagAggregatesHTML(groups []TagAggregates) string {
+	if len(groups) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString(`
+        <div class="tag-aggregates">
+            <h4>Cost by Tag</h4>
+            <ul>`)
+	for _, group := range groups {
+		sb.WriteString(fmt.Sprintf(`
+                <li><strong>%s:</strong> %s</li>`, html.EscapeString(group.Tag), html.EscapeString(formatTagAggregates(group))))
+	}
+	sb.WriteString(`
+            </ul>
+        </div>
+`)
+	return sb.String()
+}
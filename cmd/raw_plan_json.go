@@ -0,0 +1,47 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// rawPlanJSON returns the EXPLAIN (FORMAT JSON) output of the query for --include-raw-json. A plan
+// produced with FORMAT JSON is used verbatim; a plan in another format is explained again as JSON.
+// A data change that is not rolled back is not run a second time.
+func rawPlanJSON(query, plan, format string, config *Config, options ExplainOptions) (json.RawMessage, error) {
+	if raw := structuredPlanJSON(plan, format); raw != nil {
+		return raw, nil
+	}
+	if isDataModifyingQuery(query) && !changesAreRolledBack(query, options) {
+		return nil, fmt.Errorf("the statement modifies data and is not rolled back, so it is not run again with FORMAT JSON")
+	}
+
+	jsonOptions := options
+	jsonOptions.Format = "json"
+	output, err := generateExecutionPlan(query, config, jsonOptions)
+	if err != nil {
+		return nil, err
+	}
+	raw := structuredPlanJSON(output, "json")
+	if raw == nil {
+		return nil, fmt.Errorf("EXPLAIN (FORMAT JSON) did not return valid JSON")
+	}
+	return raw, nil
+}
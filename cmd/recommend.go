@@ -0,0 +1,183 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var recommendCmd = &cobra.Command{
+	Use:   "recommend [PLAN_FILE]",
+	Short: "Recommend indexes from a saved EXPLAIN plan",
+	Long: `Analyze an existing EXPLAIN plan and recommend indexes without connecting to a database.
+The plan is read from a file argument, the --file flag, or STDIN, in the text, JSON or YAML
+EXPLAIN format.
+
+Example:
+  pg_explain recommend plan.txt
+  pg_explain recommend --file plan.txt --index-threshold 500
+  cat plan.txt | pg_explain recommend --format json`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  runRecommend,
+}
+
+func runRecommend(cmd *cobra.Command, args []string) {
+	plan, err := getPlanInput(cmd, args)
+	if err != nil {
+		logErrorAndExit("Failed to get plan input: ", err)
+	}
+	plan, err = savedPlanForRecommendations(plan)
+	if err != nil {
+		logErrorAndExit("Failed to read plan: ", err)
+	}
+
+	config, _ := loadConfig()
+	indexThreshold, _ := cmd.Flags().GetFloat64("index-threshold")
+	format, _ := cmd.Flags().GetString("format")
+
+	fmt.Println("\n🔍 Analyzing saved execution plan...")
+	fmt.Printf("📊 Output format: %s\n", format)
+	fmt.Println()
+
+	indexInfo := analyzeIndexOpportunities(plan, indexThreshold, recommendOptionsFromFlags(cmd, config))
+	title := recommendationsFilePrefix + generateTitle()
+
+	switch format {
+	case "text":
+		head, _ := cmd.Flags().GetInt("head")
+		displayIndexRecommendations(indexInfo, head)
+		if indexInfo.TotalFound == 0 && len(indexInfo.AlreadyApplied) == 0 && len(indexInfo.TypeMismatches) == 0 {
+			fmt.Printf("✨ No index recommendations (all operations below threshold of %.0f)\n\n", indexThreshold)
+		}
+		return
+	case "json":
+		fmt.Println("💾 Saving recommendations as JSON...")
+		fileName := writeJSONToFile(title+".json", indexInfo)
+		printRecommendationsSaved(fileName, indexInfo)
+	case "markdown":
+		fmt.Println("💾 Generating Markdown report...")
+		fileName := writeMarkdownRecommendations(indexInfo, title)
+		printRecommendationsSaved(fileName, indexInfo)
+	default:
+		logErrorAndExit("Invalid format specified", fmt.Errorf("supported formats: text, json, markdown"))
+	}
+}
+
+// savedPlanForRecommendations returns a saved plan in the text layout read by the index
+// recommender, converting plans saved with FORMAT JSON or YAML
+func savedPlanForRecommendations(plan string) (string, error) {
+	format := detectPlanFormat(plan)
+	analysisPlan := planForAnalysis(plan, format)
+	if analysisPlan == "" {
+		return "", fmt.Errorf("indexes cannot be recommended from a plan in %s format", strings.ToUpper(format))
+	}
+	return analysisPlan, nil
+}
+
+// printRecommendationsSaved prints the location of a saved recommendation report
+func printRecommendationsSaved(fileName string, indexInfo *IndexRecommendationInfo) {
+	fmt.Println("\n" + "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	fmt.Println("📁 Recommendations saved successfully!")
+	fmt.Printf("   %s\n", fileName)
+	fmt.Printf("\n🎯 Found %d recommendations (%d high priority)\n", indexInfo.TotalFound, indexInfo.HighPriority)
+	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	fmt.Println()
+}
+
+// recommendOptionsFromFlags reads the index recommendation flags, using config defaults if flags are not explicitly set
+func recommendOptionsFromFlags(cmd *cobra.Command, config *Config) RecommendOptions {
+	excludeTables, _ := cmd.Flags().GetStringSlice("exclude-tables")
+	if !cmd.Flags().Changed("exclude-tables") {
+		excludeTables = config.Recommendations.ExcludeTables
+	}
+
+	noConsolidate, _ := cmd.Flags().GetBool("no-consolidate")
+	concurrently, _ := cmd.Flags().GetBool("concurrently")
+
+	appliedFile, _ := cmd.Flags().GetString("applied-file")
+	if !cmd.Flags().Changed("applied-file") {
+		appliedFile = config.Recommendations.AppliedFile
+	}
+	var applied map[string]bool
+	if appliedFile != "" {
+		var err error
+		if applied, err = loadAppliedIndexes(appliedFile); err != nil {
+			logErrorAndExit("Invalid --applied-file value", err)
+		}
+	}
+
+	return RecommendOptions{
+		ExcludeTables: excludeTables,
+		NoConsolidate: noConsolidate,
+		Concurrently:  concurrently,
+		Applied:       applied,
+	}
+}
+
+// getPlanInput retrieves an EXPLAIN plan from a file or STDIN
+// Priority: --file flag > file argument > STDIN
+func getPlanInput(cmd *cobra.Command, args []string) (string, error) {
+	filePath, _ := cmd.Flags().GetString("file")
+	if filePath == "" && len(args) > 0 {
+		filePath = args[0]
+	}
+
+	if filePath != "" {
+		content, err := os.ReadFile(filePath)
+		if err != nil {
+			return "", fmt.Errorf("failed to read plan file %s: %w", filePath, err)
+		}
+		plan := strings.TrimSpace(string(content))
+		if plan == "" {
+			return "", fmt.Errorf("plan file %s is empty", filePath)
+		}
+		return plan, nil
+	}
+
+	stat, err := os.Stdin.Stat()
+	if err == nil && (stat.Mode()&os.ModeCharDevice) == 0 {
+		bytes, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return "", fmt.Errorf("failed to read from stdin: %w", err)
+		}
+		plan := strings.TrimSpace(string(bytes))
+		if plan == "" {
+			return "", fmt.Errorf("stdin input is empty")
+		}
+		return plan, nil
+	}
+
+	return "", fmt.Errorf("no plan provided, pass a plan file or pipe the EXPLAIN output via stdin")
+}
+
+func init() {
+	recommendCmd.Flags().StringP("file", "F", "", "Read EXPLAIN plan from file")
+	recommendCmd.Flags().StringP("format", "f", "text", "Output format (text, json, or markdown)")
+	recommendCmd.Flags().Float64("index-threshold", 100.0, "Minimum operation cost to trigger index recommendations")
+	recommendCmd.Flags().StringSlice("exclude-tables", nil, "Comma-separated table patterns to never recommend indexes for (glob or prefix, e.g. staging_*,tmp_)")
+	recommendCmd.Flags().Bool("no-consolidate", false, "Keep single-column index recommendations instead of merging them into composite indexes")
+	recommendCmd.Flags().Int("head", 0, "Show only the top N index recommendations, other formats still include all (0 = show all)")
+	recommendCmd.Flags().String("applied-file", "", "File listing indexes already created or rejected (table:column1,column2 per line), which are not recommended again")
+	recommendCmd.Flags().Bool("concurrently", false, "Emit CREATE INDEX CONCURRENTLY statements that do not block writes")
+	rootCmd.AddCommand(recommendCmd)
+}
@@ -0,0 +1,402 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var replCmd = &cobra.Command{
+	Use:   "repl",
+	Short: "Analyze queries interactively and compare the results of a tuning session",
+	Long: `Open an interactive session where every query is analyzed as soon as it is entered.
+
+End a query with a semicolon to run it. Its cost, execution time and most expensive operations
+are printed and the result is kept in the session, numbered #1, #2 and so on, so rewrites of a
+query can be compared without leaving the session.
+
+Commands:
+  :list                  List the results of the session
+  :show N                Print result N again, with its execution plan
+  :compare N M           Compare two results, in the current format
+  :save N                Write result N as a report in the current format
+  :set threshold COST    Set the cost threshold (0 = disabled)
+  :format FORMAT         Set the format used by :save and :compare
+  :help                  Show the commands
+  :quit                  Leave the session (or press Ctrl+D)
+
+Example:
+  pg_explain repl
+  pg_explain repl -t 1000 --set work_mem=64MB`,
+	Args: cobra.NoArgs,
+	Run:  runRepl,
+}
+
+// replTopOperations is the number of operations printed after each query
+const replTopOperations = 3
+
+// replFormats are the formats :format accepts. text only applies to :compare and mermaid only to :save.
+var replFormats = []string{"text", "json", "html", "markdown", "csv", "mermaid", "slack", "github"}
+
+// replEntry is one analyzed query of the session
+type replEntry struct {
+	Query string
+	Plan  string
+}
+
+// replSession holds the state changed by the session commands
+type replSession struct {
+	cmd       *cobra.Command
+	config    *Config
+	options   ExplainOptions
+	threshold float64
+	minCost   float64
+	entries   []replEntry
+}
+
+func runRepl(cmd *cobra.Command, args []string) {
+	requirePsql()
+
+	config, _ := loadConfig()
+	applyServiceFlag(cmd, config)
+	promptPasswordIfRequested(cmd)
+
+	threshold, _ := cmd.Flags().GetFloat64("threshold")
+	if !cmd.Flags().Changed("threshold") && config.Defaults.Threshold > 0 {
+		threshold = config.Defaults.Threshold
+	}
+	minCost, _ := cmd.Flags().GetFloat64("min-cost")
+	format, _ := cmd.Flags().GetString("format")
+	if err := validateReplFormat(format); err != nil {
+		logErrorAndExit("Invalid --format value", err)
+	}
+
+	transaction, _ := cmd.Flags().GetBool("transaction")
+	rollback, _ := cmd.Flags().GetBool("rollback")
+	verbose, _ := cmd.Flags().GetBool("verbose")
+	explainOptions := ExplainOptions{Rollback: transaction || rollback, Verbose: verbose}
+	if err := sessionOptionsFromFlags(cmd, &explainOptions); err != nil {
+		logErrorAndExit("Invalid --set, --guc or --role value", err)
+	}
+	if err := explainModeFromFlags(cmd, &explainOptions); err != nil {
+		logErrorAndExit("Invalid --explain-mode value", err)
+	}
+	if err := allowedStatementsFromFlags(cmd, config, &explainOptions); err != nil {
+		logErrorAndExit("Invalid --allowed-statements value", err)
+	}
+
+	session := &replSession{cmd: cmd, config: config, options: explainOptions, threshold: threshold, minCost: minCost}
+
+	fmt.Println("\n🔁 pg_explain interactive session")
+	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	fmt.Println("End a query with ; to analyze it, :help lists the commands, :quit or Ctrl+D leaves.")
+	for _, setting := range explainOptions.Settings {
+		fmt.Printf("⚙️  %s\n", setting)
+	}
+	if explainOptions.Role != "" {
+		fmt.Printf("👤 Role: %s\n", explainOptions.Role)
+	}
+	if explainOptions.Rollback {
+		fmt.Println("↩️  Transaction: changes will be rolled back")
+	}
+	fmt.Println()
+
+	var queryLines []string
+	scanner := bufio.NewScanner(os.Stdin)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	fmt.Print("pg_explain> ")
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case len(queryLines) == 0 && trimmed == "":
+		case len(queryLines) == 0 && strings.HasPrefix(trimmed, ":"):
+			if !session.runCommand(trimmed) {
+				return
+			}
+		default:
+			queryLines = append(queryLines, line)
+			if strings.HasSuffix(trimmed, ";") {
+				session.analyze(strings.TrimSpace(strings.Join(queryLines, "\n")))
+				queryLines = nil
+			}
+		}
+
+		if len(queryLines) > 0 {
+			fmt.Print("        -> ")
+		} else {
+			fmt.Print("pg_explain> ")
+		}
+	}
+	fmt.Println()
+
+	if err := scanner.Err(); err != nil {
+		logErrorAndExit("Failed to read input: ", err)
+	}
+}
+
+// analyze runs EXPLAIN for the query and keeps the plan in the session. Errors are printed,
+// so a typo does not end the session.
+func (s *replSession) analyze(query string) {
+	options, err := explainOptionsForStatement(query, s.options)
+	if err != nil {
+		fmt.Printf("❌ %v\n\n", err)
+		return
+	}
+	warnDataModifyingQuery(query, options)
+
+	plan, err := generateExecutionPlan(query, s.config, options)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		if hint := connectionLimitHint(err.Error()); hint != "" {
+			fmt.Printf("💡 %s\n", hint)
+		}
+		fmt.Println()
+		return
+	}
+
+	s.entries = append(s.entries, replEntry{Query: query, Plan: plan})
+	s.printSummary(len(s.entries))
+}
+
+// printSummary prints the cost, execution time and most expensive operations of result n
+func (s *replSession) printSummary(n int) {
+	entry := s.entries[n-1]
+	costInfo := parseCost(entry.Plan, s.threshold, s.minCost)
+	if costInfo.Warning != "" {
+		fmt.Printf("#%d ⚠️  %s\n\n", n, costInfo.Warning)
+		return
+	}
+
+	summary := fmt.Sprintf("#%d 💰 cost %.2f", n, costInfo.TotalCost)
+	if costInfo.ExecutionTimeMs > 0 {
+		summary += fmt.Sprintf(" · ⏱️  %.2f ms", costInfo.ExecutionTimeMs)
+	}
+	if costInfo.Grade != "" {
+		summary += fmt.Sprintf(" · %s %s", getGradeEmoji(costInfo.Grade), costInfo.Grade)
+	}
+	fmt.Println(summary)
+	if s.threshold > 0 && costInfo.ExceedsLimit {
+		fmt.Printf("   🚨 Exceeds the cost threshold of %.0f by %.2f\n", costInfo.ThresholdValue, costInfo.TotalCost-costInfo.ThresholdValue)
+	}
+
+	// Every operation is listed without a threshold, the most expensive first
+	operations := parseCost(entry.Plan, 0, s.minCost).ExpensiveOps
+	sort.SliceStable(operations, func(i, j int) bool { return operations[i].Cost > operations[j].Cost })
+	for i, op := range operations[:minInt(replTopOperations, len(operations))] {
+		fmt.Printf("   %d. %s (%.2f)\n", i+1, op.Operation, op.Cost)
+		if op.Loops > 1 {
+			fmt.Printf("      %s\n", actualTotalsSummary(op))
+		}
+	}
+	fmt.Println()
+}
+
+// runCommand runs a session command and reports whether the session continues
+func (s *replSession) runCommand(line string) bool {
+	fields := strings.Fields(line)
+	switch fields[0] {
+	case ":quit", ":q", ":exit":
+		return false
+	case ":help", ":h":
+		fmt.Println(strings.Trim(strings.SplitN(strings.SplitN(s.cmd.Long, "Commands:\n", 2)[1], "\n\nExample:", 2)[0], "\n"))
+		fmt.Println()
+	case ":list", ":l":
+		s.list()
+	case ":show":
+		if n, ok := s.entryArgs(fields, 1); ok {
+			entry := s.entries[n[0]-1]
+			fmt.Printf("#%d %s\n\n%s\n", n[0], entry.Query, strings.TrimRight(entry.Plan, "\n"))
+			s.printSummary(n[0])
+		}
+	case ":compare":
+		if n, ok := s.entryArgs(fields, 2); ok {
+			s.compare(n[0], n[1])
+		}
+	case ":save":
+		if n, ok := s.entryArgs(fields, 1); ok {
+			s.save(n[0])
+		}
+	case ":set":
+		s.set(fields[1:])
+	case ":format":
+		if len(fields) != 2 {
+			format, _ := s.cmd.Flags().GetString("format")
+			fmt.Printf("📊 Format: %s (one of %s)\n\n", format, strings.Join(replFormats, ", "))
+			break
+		}
+		if err := validateReplFormat(fields[1]); err != nil {
+			fmt.Printf("❌ %v\n\n", err)
+			break
+		}
+		s.cmd.Flags().Set("format", fields[1])
+		fmt.Printf("📊 Format: %s\n\n", fields[1])
+	default:
+		fmt.Printf("❌ Unknown command %s, :help lists the commands\n\n", fields[0])
+	}
+	return true
+}
+
+// list prints one line per result of the session
+func (s *replSession) list() {
+	if len(s.entries) == 0 {
+		fmt.Println("No queries analyzed yet, enter a query ending with ;")
+		fmt.Println()
+		return
+	}
+	for i, entry := range s.entries {
+		cost := "unknown"
+		if costInfo := parseCost(entry.Plan, 0, 0); costInfo.Warning == "" {
+			cost = fmt.Sprintf("%.2f", costInfo.TotalCost)
+		}
+		fmt.Printf("#%-3d cost %-12s %s\n", i+1, cost, queryLabel(entry.Query, 60))
+	}
+	fmt.Println()
+}
+
+// entryArgs parses the result numbers following a command, printing an error when they are invalid
+func (s *replSession) entryArgs(fields []string, count int) ([]int, bool) {
+	if len(fields)-1 != count {
+		fmt.Printf("❌ %s takes %d result numbers, e.g. %s\n\n", fields[0], count, strings.TrimSpace(fields[0]+strings.Repeat(" 1", count)))
+		return nil, false
+	}
+	numbers := make([]int, 0, count)
+	for _, field := range fields[1:] {
+		n, err := strconv.Atoi(strings.TrimPrefix(field, "#"))
+		if err != nil || n < 1 || n > len(s.entries) {
+			fmt.Printf("❌ No result %s, :list shows the results of the session\n\n", field)
+			return nil, false
+		}
+		numbers = append(numbers, n)
+	}
+	return numbers, true
+}
+
+// compare compares two results with the same code as the compare command
+func (s *replSession) compare(n1, n2 int) {
+	format, _ := s.cmd.Flags().GetString("format")
+	if format == "mermaid" {
+		fmt.Println("❌ Comparisons cannot be written as mermaid, choose another :format")
+		fmt.Println()
+		return
+	}
+	entry1, entry2 := s.entries[n1-1], s.entries[n2-1]
+	result := buildComparisonResult(s.cmd, entry1.Query, entry2.Query, entry1.Plan, entry2.Plan,
+		fmt.Sprintf("#%d", n1), fmt.Sprintf("#%d", n2), nil, os.Stdout)
+	writeComparisonOutput(s.cmd, result, nil, os.Stdout, os.Stdout)
+}
+
+// save writes a result as a report, like analyze does
+func (s *replSession) save(n int) {
+	format, _ := s.cmd.Flags().GetString("format")
+	outputDir, _ := s.cmd.Flags().GetString("output-dir")
+	if format == "text" || format == "github" {
+		fmt.Printf("❌ Results cannot be saved as %s, choose another :format (:show prints a result)\n\n", format)
+		return
+	}
+	if outputDir != "" {
+		if err := os.MkdirAll(outputDir, 0755); err != nil {
+			fmt.Printf("❌ Failed to create output directory: %v\n\n", err)
+			return
+		}
+	}
+
+	entry := s.entries[n-1]
+	var costInfo *CostInfo
+	if s.threshold > 0 {
+		costInfo = parseCost(entry.Plan, s.threshold, s.minCost)
+	}
+	outputName := filepath.Join(outputDir, generateTitle())
+
+	var fileName string
+	switch format {
+	case "json":
+		fileName = writeJSONPlan(entry.Plan, entry.Query, outputName, "text", costInfo, nil, nil)
+	case "html":
+		fileName = writePlan(entry.Plan, entry.Query, outputName, parseCost(entry.Plan, 0, 0))
+	case "markdown":
+		fileName = writeMarkdownPlan(entry.Plan, entry.Query, outputName, costInfo, parseCost(entry.Plan, 0, 0), nil)
+	case "csv":
+		fileName = writeCSVPlan(entry.Plan, entry.Query, outputName, costInfo, nil)
+	case "mermaid":
+		fileName = writeMermaidPlan(entry.Plan, outputName, costInfo)
+	case "slack":
+		fileName = writeJSONToFile(outputName+".slack.json", slackPlanMessage(entry.Query, entry.Plan, costInfo, nil))
+	}
+	fmt.Printf("📁 #%d saved to %s\n\n", n, fileName)
+}
+
+// set changes a session setting, e.g. :set threshold 500
+func (s *replSession) set(args []string) {
+	if len(args) != 2 || args[0] != "threshold" {
+		fmt.Println("❌ Usage: :set threshold COST")
+		fmt.Println()
+		return
+	}
+	threshold, err := strconv.ParseFloat(args[1], 64)
+	if err != nil || threshold < 0 {
+		fmt.Printf("❌ Invalid threshold %q, expected a cost such as 1000 (0 = disabled)\n\n", args[1])
+		return
+	}
+	s.threshold = threshold
+	if threshold > 0 {
+		fmt.Printf("⚡ Cost threshold: %.0f\n\n", threshold)
+	} else {
+		fmt.Println("⚡ Cost threshold disabled")
+		fmt.Println()
+	}
+}
+
+// validateReplFormat checks a format given with --format or :format
+func validateReplFormat(format string) error {
+	if !containsString(replFormats, format) {
+		return fmt.Errorf("unknown format %q, expected one of %s", format, strings.Join(replFormats, ", "))
+	}
+	return nil
+}
+
+func init() {
+	replCmd.Flags().StringP("format", "f", "text", "Format used by :compare and :save (text, json, html, markdown, csv, mermaid, slack, or github), can be changed with :format")
+	replCmd.Flags().StringP("output-dir", "o", "", "Directory to save output files (default: current directory)")
+	replCmd.Flags().Float64P("threshold", "t", 0, "Cost threshold for alerting on expensive queries, can be changed with :set threshold (0 = disabled)")
+	replCmd.Flags().Float64("min-cost", 0, "Minimum operation cost to list as an expensive operation")
+	replCmd.Flags().Float64("noise-threshold", 5.0, "Cost difference percentage below which a :compare verdict is low confidence")
+	replCmd.Flags().String("compare-metric", "cost", "Metric that decides the winner of :compare: cost, time, buffers, or rows")
+	replCmd.Flags().Int("max-plan-lines", 0, "Truncate plans in text and Markdown comparisons to N lines, keeping the first and last lines (0 = no limit)")
+	replCmd.Flags().Bool("plain", false, "Print comparisons without colors, drawing bars with # (colors are also disabled by NO_COLOR or when output is not a terminal)")
+	replCmd.Flags().Bool("transaction", false, "Run each EXPLAIN ANALYZE inside a transaction that is rolled back, discarding data changes")
+	replCmd.Flags().Bool("rollback", false, "Alias for --transaction")
+	replCmd.Flags().StringSlice("allowed-statements", nil, "Statement types that may be analyzed, e.g. select,execute; others are rejected before running (select, insert, update, delete, merge, create, execute, or declare)")
+	replCmd.Flags().String("explain-mode", "auto", "How statements are explained: auto (EXPLAIN ANALYZE for queries, rolled back for data changes, plain EXPLAIN for CREATE TABLE AS) or analyze (EXPLAIN ANALYZE as given)")
+	replCmd.Flags().Bool("verbose", false, "Run EXPLAIN VERBOSE to show the output columns of every plan node")
+	replCmd.Flags().StringArray("set", nil, "Run SET NAME=VALUE before each EXPLAIN, e.g. --set work_mem=256MB (repeatable)")
+	replCmd.Flags().StringArray("guc", nil, "Alias for --set, e.g. --guc work_mem=256MB (repeatable)")
+	replCmd.Flags().String("role", "", "Run SET ROLE before each EXPLAIN so queries are planned with that role's privileges")
+	replCmd.Flags().String("service", "", "Connect with a service from the libpq connection service file (~/.pg_service.conf), overriding the database settings")
+	replCmd.Flags().Bool("prompt-password", false, "Prompt for the database password without echoing it (instead of .pgpass or PGPASSWORD)")
+	rootCmd.AddCommand(replCmd)
+}
@@ -0,0 +1,64 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Prefixes of the report file names, in front of the title from generateTitle, or of the SQL
+// file name for batch reports. generatedFileRegex is built from them, so every writer that
+// uses them is also covered by clean.
+const (
+	// queryFilePrefix is followed by the query number, e.g. Query_3_Plan_Created_on_...
+	queryFilePrefix           = "Query_"
+	comparisonFilePrefix      = "Comparison_"
+	recommendationsFilePrefix = "Recommendations_"
+	batchFilePrefix           = "Batch_"
+)
+
+// Suffixes of the extra files compare writes after the title, e.g. Comparison_<title>_plan1.html
+const (
+	comparisonPlan1Suffix      = "_plan1"
+	comparisonPlan2Suffix      = "_plan2"
+	comparisonOperationsSuffix = "_operations"
+)
+
+// generatedFileExtensions are the extensions of report files. A name may chain several, e.g.
+// .slack.json, .github.md or .markdown.md.
+var generatedFileExtensions = []string{"html", "json", "md", "csv", "markdown", "slack", "github", "mmd", "sql"}
+
+// generatedFileRegex matches the whole names of files written by pg_explain, e.g.
+// Plan_Created_on_January_11th_2026_16:20:00.html, Query_3_Plan_Created_on_..._UTC.json,
+// Comparison_Plan_Created_on_..._plan1.html or Batch_queries_2026-01-11_16-20-00.markdown.md
+var generatedFileRegex = buildGeneratedFileRegex()
+
+func buildGeneratedFileRegex() *regexp.Regexp {
+	title := `Plan_Created_on_[A-Z][a-z]+_\d{1,2}th_\d{4}_\d{2}:\d{2}:\d{2}(_UTC)?`
+	names := []string{
+		`(` + regexp.QuoteMeta(queryFilePrefix) + `\d+_|` + regexp.QuoteMeta(recommendationsFilePrefix) + `)?` + title,
+		regexp.QuoteMeta(comparisonFilePrefix) + title + `(` + strings.Join([]string{
+			regexp.QuoteMeta(comparisonPlan1Suffix),
+			regexp.QuoteMeta(comparisonPlan2Suffix),
+			regexp.QuoteMeta(comparisonOperationsSuffix),
+		}, "|") + `)?`,
+		regexp.QuoteMeta(batchFilePrefix) + `.+_\d{4}-\d{2}-\d{2}_\d{2}-\d{2}-\d{2}(_UTC)?`,
+	}
+	return regexp.MustCompile(`^(` + strings.Join(names, "|") + `)(\.(` + strings.Join(generatedFileExtensions, "|") + `))+$`)
+}
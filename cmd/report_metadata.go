@@ -0,0 +1,122 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"html"
+	"os/exec"
+	"strings"
+)
+
+// ReportMetadata records how a plan was obtained, so a shared report documents itself.
+// The connection target names the host and database only, never credentials.
+type ReportMetadata struct {
+	ExplainSQL    string `json:"explain_sql"`
+	ServerVersion string `json:"server_version,omitempty"`
+	PsqlVersion   string `json:"psql_version,omitempty"`
+	Connection    string `json:"connection,omitempty"`
+}
+
+// reportMetadata is set once per run by --metadata and added to every report written
+var reportMetadata *ReportMetadata
+
+// batchQueryPlaceholder stands in for the query in the EXPLAIN SQL of a batch, which differs per query
+const batchQueryPlaceholder = "<query>"
+
+// collectReportMetadata gathers the EXPLAIN statements and the psql and server versions.
+// A version that cannot be read is left empty, as the report is still useful without it.
+func collectReportMetadata(config *Config, query string, options ExplainOptions) *ReportMetadata {
+	metadata := &ReportMetadata{
+		ExplainSQL: strings.Join(buildExplainStatements(query, options), ";\n") + ";",
+		Connection: connectionTarget(config),
+	}
+
+	if output, err := exec.Command(psqlPath, "--version").Output(); err == nil {
+		metadata.PsqlVersion = strings.TrimSpace(string(output))
+	}
+	if output, err := runPsql(config, []string{"-q", "-A", "-t", "-c", "SELECT version()"}); err == nil {
+		metadata.ServerVersion = strings.TrimSpace(output)
+	} else {
+		fmt.Printf("⚠️  Unable to read the server version for the report metadata: %v\n\n", err)
+	}
+	return metadata
+}
+
+// connectionTarget describes where the plan came from, e.g. "shop on db.example.com" or "service=reporting"
+func connectionTarget(config *Config) string {
+	if config.Database.Service != "" {
+		return serviceConninfo(config.Database.Service)
+	}
+	_, database, host, _ := connectionSettings(config)
+	if host == "" {
+		return database
+	}
+	return fmt.Sprintf("%s on %s", database, host)
+}
+
+// formatMetadataMarkdown renders the "Generated with" section of Markdown reports
+func formatMetadataMarkdown(metadata *ReportMetadata) string {
+	if metadata == nil {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("## Generated with\n\n")
+	sb.WriteString("| Setting | Value |\n")
+	sb.WriteString("|---------|-------|\n")
+	if metadata.ServerVersion != "" {
+		sb.WriteString(fmt.Sprintf("| Server | %s |\n", escapeGitHubTableCell(metadata.ServerVersion)))
+	}
+	if metadata.PsqlVersion != "" {
+		sb.WriteString(fmt.Sprintf("| Client | %s |\n", escapeGitHubTableCell(metadata.PsqlVersion)))
+	}
+	if metadata.Connection != "" {
+		sb.WriteString(fmt.Sprintf("| Connection | %s |\n", escapeGitHubTableCell(metadata.Connection)))
+	}
+	sb.WriteString("\n```sql\n")
+	sb.WriteString(metadata.ExplainSQL)
+	sb.WriteString("\n```\n\n")
+	return sb.String()
+}
+
+// formatMetadataHTML renders the "Generated with" section of the batch HTML report
+func formatMetadataHTML(metadata *ReportMetadata) string {
+	if metadata == nil {
+		return ""
+	}
+
+	var rows strings.Builder
+	for _, row := range [][2]string{
+		{"Server", metadata.ServerVersion},
+		{"Client", metadata.PsqlVersion},
+		{"Connection", metadata.Connection},
+	} {
+		if row[1] != "" {
+			rows.WriteString(fmt.Sprintf("<div><strong>%s:</strong> %s</div>", row[0], html.EscapeString(row[1])))
+		}
+	}
+
+	return fmt.Sprintf(`
+        <details class="metadata">
+            <summary>Generated with</summary>
+            %s
+            <pre>%s</pre>
+        </details>
+`, rows.String(), html.EscapeString(metadata.ExplainSQL))
+}
@@ -0,0 +1,61 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"strings"
+)
+
+// resultLineUnknown stands in for a value the plan does not provide, so every key is always present
+const resultLineUnknown = "-"
+
+// formatResultLine returns the single-line summary printed by --result-line, e.g.
+// "RESULT query_hash=3f2a9c1b8e7d6a50 total_cost=4521.00 exceeds=false exec_ms=12.30 grade=B".
+// Keys are always printed in this order. costInfo is nil when no threshold was set, in which
+// case the cost is parsed from the plan and there is no grade.
+func formatResultLine(query, analysisPlan string, costInfo *CostInfo) string {
+	summary := costInfo
+	if summary == nil {
+		summary = parseCost(analysisPlan, 0, 0)
+	}
+
+	totalCost := resultLineUnknown
+	if summary.Warning == "" {
+		totalCost = fmt.Sprintf("%.2f", summary.TotalCost)
+	}
+	execMs := resultLineUnknown
+	if summary.ExecutionTimeMs > 0 {
+		execMs = fmt.Sprintf("%.2f", summary.ExecutionTimeMs)
+	}
+	grade := resultLineUnknown
+	if costInfo != nil && costInfo.Grade != "" {
+		grade = costInfo.Grade
+	}
+	exceeds := costInfo != nil && costInfo.Warning == "" && costInfo.ExceedsLimit
+
+	fields := []string{
+		"RESULT",
+		"query_hash=" + fingerprintQuery(query),
+		"total_cost=" + totalCost,
+		fmt.Sprintf("exceeds=%t", exceeds),
+		"exec_ms=" + execMs,
+		"grade=" + grade,
+	}
+	return strings.Join(fields, " ")
+}
@@ -28,6 +28,13 @@ var rootCmd = &cobra.Command{
 	Use:   "pg_explain",
 	Short: "Analyze SQL queries and generate execution plans",
 	Long:  `The pg_explain is a command-line tool designed to help users analyze SQL queries and generate execution plans with ease. It utilizes Cobra, a powerful CLI library for Go, to enable efficient and intuitive interactions.`,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		config, _ := loadConfig()
+		configureTimestamps(cmd, config)
+		configurePsqlPath(cmd, config)
+		compactJSON, _ = cmd.Flags().GetBool("compact")
+		configureLargePlans(cmd)
+	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -45,6 +52,11 @@ func init() {
 	// will be global for your application.
 
 	// rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.pgexplain.yaml)")
+	rootCmd.PersistentFlags().Bool("utc", false, "Show timestamps in reports and file names in UTC")
+	rootCmd.PersistentFlags().Bool("compact", false, "Write JSON output on a single line without indentation")
+	rootCmd.PersistentFlags().Bool("rfc3339", false, "Show report timestamps in RFC 3339 format, e.g. 2006-01-02T15:04:05Z")
+	rootCmd.PersistentFlags().String("psql-path", "", "psql binary to run, e.g. /usr/lib/postgresql/16/bin/psql (default: psql from PATH)")
+	rootCmd.PersistentFlags().Int("large-plan-bytes", defaultLargePlanBytes, "Plan size in bytes above which plans are parsed line by line and shortened in HTML reports (0 = no limit)")
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
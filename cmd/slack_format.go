@@ -0,0 +1,213 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// SlackMessage is a Slack Block Kit message, as accepted by incoming webhooks.
+// Text is the plain fallback shown in notifications.
+type SlackMessage struct {
+	Text   string       `json:"text"`
+	Blocks []SlackBlock `json:"blocks"`
+}
+
+// SlackBlock is a header, section, divider or context block
+type SlackBlock struct {
+	Type     string      `json:"type"`
+	Text     *SlackText  `json:"text,omitempty"`
+	Fields   []SlackText `json:"fields,omitempty"`
+	Elements []SlackText `json:"elements,omitempty"`
+}
+
+// SlackText is a plain_text or mrkdwn text object
+type SlackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// slackQueryLength is the number of query characters shown in a Slack message
+const slackQueryLength = 500
+
+// slackWebhookTimeout bounds how long posting to a webhook may take
+const slackWebhookTimeout = 15 * time.Second
+
+// slackPlanMessage summarizes the analysis of one query. costInfo is nil when no threshold was
+// set, in which case the cost is parsed from the plan without a grade.
+func slackPlanMessage(query, analysisPlan string, costInfo *CostInfo, indexInfo *IndexRecommendationInfo) SlackMessage {
+	summary := costInfo
+	if summary == nil {
+		summary = parseCost(analysisPlan, 0, 0)
+	}
+
+	blocks := []SlackBlock{
+		slackHeader("🔍 Query analysis"),
+		slackSection("```" + slackEscape(queryLabel(query, slackQueryLength)) + "```"),
+	}
+
+	if summary.Warning != "" {
+		blocks = append(blocks, slackSection("⚠️ "+slackEscape(summary.Warning)))
+		blocks = append(blocks, slackFooter())
+		return SlackMessage{Text: "Query analysis: cost unavailable", Blocks: blocks}
+	}
+
+	fields := []SlackText{slackField("Total Cost", fmt.Sprintf("%.2f", summary.TotalCost))}
+	if costInfo != nil && costInfo.Grade != "" {
+		fields = append(fields, slackField("Health Grade", fmt.Sprintf("%s %s", getGradeEmoji(costInfo.Grade), costInfo.Grade)))
+	}
+	if len(summary.ExpensiveOps) > 0 {
+		fields = append(fields, slackField("Top Operation", slackTopOperation(summary)))
+	}
+	if summary.ExecutionTimeMs > 0 {
+		fields = append(fields, slackField("Execution Time", fmt.Sprintf("%.2f ms", summary.ExecutionTimeMs)))
+	}
+	if indexInfo != nil {
+		fields = append(fields, slackField("Index Recommendations", fmt.Sprintf("%d", indexInfo.TotalFound)))
+	}
+	blocks = append(blocks, SlackBlock{Type: "section", Fields: fields})
+
+	text := fmt.Sprintf("Query analysis: cost %.2f", summary.TotalCost)
+	if costInfo != nil && costInfo.ExceedsLimit {
+		blocks = append(blocks, slackSection(fmt.Sprintf("🚨 *Exceeds the cost threshold* of %.0f by %.2f",
+			costInfo.ThresholdValue, costInfo.TotalCost-costInfo.ThresholdValue)))
+		text += fmt.Sprintf(", exceeds threshold %.0f", costInfo.ThresholdValue)
+	}
+
+	blocks = append(blocks, slackFooter())
+	return SlackMessage{Text: text, Blocks: blocks}
+}
+
+// slackComparisonMessage summarizes a comparison: winner, both costs and the top operations
+func slackComparisonMessage(result *ComparisonResult) SlackMessage {
+	winner := fmt.Sprintf("*Winner:* %s %s (confidence %s)", comparisonWinnerEmoji(result.Winner),
+		slackEscape(result.Winner), result.Verdict.Confidence)
+	if result.MetricDiff != 0 {
+		winner += "\n" + slackEscape(performanceMultiplier(result))
+	}
+
+	fields := []SlackText{
+		slackField(result.Label1+" Cost", slackCost(result.Cost1)),
+		slackField(result.Label2+" Cost", slackCost(result.Cost2)),
+		slackField("Cost Delta", fmt.Sprintf("%.2f (%.2f%%)", result.CostDiff, result.CostDiffPct)),
+	}
+	if result.Metric != compareMetricCost && result.Winner != "Unknown" {
+		fields = append(fields, slackField(compareMetricName(result.Metric)+" Delta",
+			fmt.Sprintf("%s (%.2f%%)", formatCompareMetric(result.Metric, result.MetricDiff), result.MetricDiffPct)))
+	}
+	if len(result.Cost1.ExpensiveOps) > 0 {
+		fields = append(fields, slackField(result.Label1+" Top Operation", slackTopOperation(result.Cost1)))
+	}
+	if len(result.Cost2.ExpensiveOps) > 0 {
+		fields = append(fields, slackField(result.Label2+" Top Operation", slackTopOperation(result.Cost2)))
+	}
+
+	blocks := []SlackBlock{
+		slackHeader("🔬 Query comparison"),
+		slackSection(winner),
+		{Type: "section", Fields: fields},
+		slackSection("💡 " + slackEscape(result.Recommendation)),
+		slackFooter(),
+	}
+
+	return SlackMessage{
+		Text:   fmt.Sprintf("Query comparison: %s (cost diff %.2f%%)", result.Winner, result.CostDiffPct),
+		Blocks: blocks,
+	}
+}
+
+// postSlackMessage sends the message to a Slack incoming webhook
+func postSlackMessage(webhookURL string, message SlackMessage) error {
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("unable to encode Slack message: %w", err)
+	}
+
+	client := &http.Client{Timeout: slackWebhookTimeout}
+	response, err := client.Post(webhookURL, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		// The webhook URL is a secret, so it is left out of the error
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("unable to post to Slack: %w", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+		return fmt.Errorf("slack webhook returned %s: %s", response.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// sendSlackMessage posts the message and reports the outcome to out. A failed post is printed
+// as a warning, as the analysis itself succeeded.
+func sendSlackMessage(out io.Writer, webhookURL string, message SlackMessage) {
+	fmt.Fprintln(out, "📨 Posting summary to Slack...")
+	if err := postSlackMessage(webhookURL, message); err != nil {
+		fmt.Fprintf(out, "⚠️  %v\n\n", err)
+		return
+	}
+	fmt.Fprintln(out, "✅ Posted to Slack")
+	fmt.Fprintln(out)
+}
+
+func slackHeader(text string) SlackBlock {
+	return SlackBlock{Type: "header", Text: &SlackText{Type: "plain_text", Text: text}}
+}
+
+func slackSection(text string) SlackBlock {
+	return SlackBlock{Type: "section", Text: &SlackText{Type: "mrkdwn", Text: text}}
+}
+
+func slackField(name, value string) SlackText {
+	return SlackText{Type: "mrkdwn", Text: fmt.Sprintf("*%s*\n%s", slackEscape(name), slackEscape(value))}
+}
+
+func slackFooter() SlackBlock {
+	return SlackBlock{Type: "context", Elements: []SlackText{
+		{Type: "mrkdwn", Text: fmt.Sprintf("Generated by pg_explain on %s", formatTimestamp(currentTime()))},
+	}}
+}
+
+func slackCost(costInfo *CostInfo) string {
+	if costInfo.Warning != "" {
+		return "unknown"
+	}
+	return fmt.Sprintf("%.2f", costInfo.TotalCost)
+}
+
+func slackTopOperation(costInfo *CostInfo) string {
+	op := costInfo.ExpensiveOps[0]
+	return fmt.Sprintf("%s (%.2f)", op.Operation, op.Cost)
+}
+
+// slackEscape escapes the characters Slack reserves for links and mentions
+func slackEscape(text string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(text)
+}
@@ -0,0 +1,95 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// allowableStatements are the statement keywords accepted by allowed_statements and
+// --allowed-statements. "create" covers the statements that create a table from a query.
+var allowableStatements = []string{"select", "insert", "update", "delete", "merge", "create", "execute", "declare"}
+
+// statementKeyword returns the keyword an allowlist matches the query against, ignoring leading
+// comments, whitespace and parentheses. VALUES and TABLE are read-only queries and count as
+// select. A WITH query counts as the first INSERT, UPDATE, DELETE or MERGE inside it, so a
+// data-modifying CTE is not let through as a select.
+func statementKeyword(query string) string {
+	if classifyStatement(query) == statementCreateAs {
+		return "create"
+	}
+
+	normalized := strings.TrimLeft(normalizeQuery(query), "( ")
+	keyword := strings.ToLower(firstWord(normalized))
+	switch keyword {
+	case "values", "table":
+		return "select"
+	case "with":
+		if !isDataModifyingQuery(query) {
+			return "select"
+		}
+		for _, word := range strings.Fields(strings.ToLower(normalized)) {
+			switch word {
+			case "insert", "update", "delete", "merge":
+				return word
+			}
+		}
+	}
+	return keyword
+}
+
+// checkAllowedStatement returns an error when allowed is not empty and the query's statement
+// type is not in it
+func checkAllowedStatement(query string, allowed []string) error {
+	if len(allowed) == 0 {
+		return nil
+	}
+	keyword := statementKeyword(query)
+	if containsString(allowed, keyword) {
+		return nil
+	}
+	return fmt.Errorf("%s statements are not allowed to be analyzed, only %s (see allowed_statements and --allowed-statements)",
+		strings.ToUpper(keyword), formatAllowedStatements(allowed))
+}
+
+// allowedStatementsFromFlags reads --allowed-statements into options, falling back to
+// allowed_statements from the config file when the flag is not given
+func allowedStatementsFromFlags(cmd *cobra.Command, config *Config, options *ExplainOptions) error {
+	allowed := config.AllowedStatements
+	if cmd.Flags().Changed("allowed-statements") {
+		allowed, _ = cmd.Flags().GetStringSlice("allowed-statements")
+	}
+
+	options.AllowedStatements = nil
+	for _, statement := range allowed {
+		statement = strings.ToLower(strings.TrimSpace(statement))
+		if !containsString(allowableStatements, statement) {
+			return fmt.Errorf("unknown statement type %q, expected one of %s", statement, strings.Join(allowableStatements, ", "))
+		}
+		options.AllowedStatements = append(options.AllowedStatements, statement)
+	}
+	return nil
+}
+
+// formatAllowedStatements lists the allowed statement types, e.g. "SELECT, EXECUTE"
+func formatAllowedStatements(allowed []string) string {
+	return strings.ToUpper(strings.Join(allowed, ", "))
+}
@@ -0,0 +1,133 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// EXPLAIN modes selected with --explain-mode
+const (
+	// explainModeAuto picks the EXPLAIN for each statement from its type
+	explainModeAuto = "auto"
+	// explainModeAnalyze runs EXPLAIN ANALYZE for every statement, as given
+	explainModeAnalyze = "analyze"
+)
+
+// Statement types, as far as EXPLAIN is concerned
+const (
+	statementQuery       = "query"
+	statementDataChange  = "data-modifying"
+	statementCreateAs    = "create-as"
+	statementUnsupported = "unsupported"
+)
+
+var (
+	// createAsRegex matches statements that create a table from a query: CREATE TABLE AS,
+	// CREATE MATERIALIZED VIEW and SELECT INTO. It runs on the normalized query, so an INTO
+	// inside a string literal does not match.
+	createAsRegex = regexp.MustCompile(`(?i)^create (global |local )?((temp|temporary|unlogged) )?table .*\bas\b|^create materialized view\b|^select\b.*\binto\b`)
+
+	// explainableRegex matches the other statements EXPLAIN accepts
+	explainableRegex = regexp.MustCompile(`(?i)^(select|values|table|with|insert|update|delete|merge|execute|declare)\b`)
+)
+
+// classifyStatement returns the statement type of the query
+func classifyStatement(query string) string {
+	normalized := normalizeQuery(query)
+	switch {
+	case createAsRegex.MatchString(normalized):
+		return statementCreateAs
+	case isDataModifyingQuery(query):
+		return statementDataChange
+	case explainableRegex.MatchString(normalized) || strings.HasPrefix(normalized, "("):
+		return statementQuery
+	}
+	return statementUnsupported
+}
+
+// explainOptionsForStatement adjusts options to the statement in auto mode: a plain SELECT is
+// analyzed as it is, a data-modifying statement is analyzed in a rolled back transaction and a
+// statement that creates a table is only planned, as EXPLAIN ANALYZE would create it. Statements
+// EXPLAIN does not accept, such as CREATE INDEX or ALTER TABLE, are an error, as are statements
+// left out of options.AllowedStatements in any mode.
+func explainOptionsForStatement(query string, options ExplainOptions) (ExplainOptions, error) {
+	if err := checkAllowedStatement(query, options.AllowedStatements); err != nil {
+		return options, err
+	}
+	if options.Mode != explainModeAuto {
+		return options, nil
+	}
+
+	switch classifyStatement(query) {
+	case statementDataChange:
+		options.Rollback = true
+	case statementCreateAs:
+		options.EstimateOnly = true
+	case statementUnsupported:
+		return options, fmt.Errorf("EXPLAIN cannot analyze %s statements, only SELECT, VALUES, INSERT, UPDATE, DELETE, MERGE, EXECUTE, DECLARE and CREATE TABLE AS",
+			strings.ToUpper(firstWord(normalizeQuery(query))))
+	}
+	return options, nil
+}
+
+// statementModeNote describes how auto mode runs the statement, or returns "" when it is
+// run with EXPLAIN ANALYZE as given
+func statementModeNote(query string, options ExplainOptions) string {
+	if options.Mode != explainModeAuto {
+		return ""
+	}
+
+	switch classifyStatement(query) {
+	case statementDataChange:
+		if !options.Rollback {
+			return "Data-modifying statement: EXPLAIN ANALYZE runs in a transaction that is rolled back"
+		}
+	case statementCreateAs:
+		return "Statement creates a table: plain EXPLAIN is run, so the plan has estimates but no actual rows or timings"
+	}
+	return ""
+}
+
+// changesAreRolledBack reports whether any change the query makes under EXPLAIN ANALYZE is discarded
+func changesAreRolledBack(query string, options ExplainOptions) bool {
+	return options.Rollback || (options.Mode == explainModeAuto && isDataModifyingQuery(query))
+}
+
+// explainModeFromFlags reads --explain-mode into options
+func explainModeFromFlags(cmd *cobra.Command, options *ExplainOptions) error {
+	mode, _ := cmd.Flags().GetString("explain-mode")
+	switch mode {
+	case explainModeAuto, explainModeAnalyze:
+		options.Mode = mode
+		return nil
+	}
+	return fmt.Errorf("unknown explain mode %q, expected %s or %s", mode, explainModeAuto, explainModeAnalyze)
+}
+
+// firstWord returns the text up to the first space
+func firstWord(text string) string {
+	if i := strings.IndexByte(text, ' '); i != -1 {
+		return text[:i]
+	}
+	return text
+}
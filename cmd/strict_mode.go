@@ -0,0 +1,154 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// strictExitCode is the exit status when --strict finds a problem, distinct from
+// the status 1 of a failed run
+const strictExitCode = 3
+
+// misestimateFactor is how far the actual row count of a node must be from the estimate,
+// in either direction, to count as a severe misestimate
+const misestimateFactor = 10
+
+// misestimateMinRows ignores misestimates where both row counts are small, e.g. 1 estimated vs 12 actual
+const misestimateMinRows = 100
+
+var (
+	// diskSpillRegex matches sorts, hashes and aggregates that wrote to disk, e.g.
+	// "Sort Method: external merge  Disk: 4096kB" or "Batches: 5  Memory Usage: 4145kB  Disk Usage: 9872kB"
+	diskSpillRegex = regexp.MustCompile(`external (?:merge|sort)|Disk(?: Usage)?: \d+kB`)
+
+	// hashBatchesRegex matches the batch count of a Hash node, more than one batch means it spilled to disk
+	hashBatchesRegex = regexp.MustCompile(`Batches: (\d+)`)
+
+	// actualRowsRegex matches the actual row count of an EXPLAIN ANALYZE node, which is a decimal from PostgreSQL 18
+	actualRowsRegex = regexp.MustCompile(`\(actual(?: time=\S+)? rows=(\d+(?:\.\d+)?)`)
+)
+
+// detectDiskSpills returns the plan lines showing a sort, hash or aggregate that spilled to disk.
+// Only EXPLAIN ANALYZE plans report this.
+func detectDiskSpills(plan string) []string {
+	var spills []string
+	for line := range planLines(plan) {
+		spilled := diskSpillRegex.MatchString(line)
+		if matches := hashBatchesRegex.FindStringSubmatch(line); matches != nil {
+			if batches, err := strconv.Atoi(matches[1]); err == nil && batches > 1 {
+				spilled = true
+			}
+		}
+		if spilled {
+			spills = append(spills, strings.TrimSpace(line))
+		}
+	}
+	return spills
+}
+
+// detectMisestimates returns the plan nodes whose actual row count is at least misestimateFactor
+// times larger or smaller than the planner's estimate. Only EXPLAIN ANALYZE plans report actual rows.
+func detectMisestimates(plan string) []string {
+	var misestimates []string
+	for line := range planLines(plan) {
+		estimateMatches := estimatedRowRegex.FindStringSubmatch(line)
+		actualMatches := actualRowsRegex.FindStringSubmatch(line)
+		if estimateMatches == nil || actualMatches == nil {
+			continue
+		}
+		estimated, err1 := strconv.ParseFloat(estimateMatches[1], 64)
+		actual, err2 := strconv.ParseFloat(actualMatches[1], 64)
+		if err1 != nil || err2 != nil || math.Max(estimated, actual) < misestimateMinRows {
+			continue
+		}
+		if math.Max(estimated, actual)/math.Max(math.Min(estimated, actual), 1) >= misestimateFactor {
+			node := strings.TrimSpace(strings.TrimRight(line[:strings.Index(line, "cost=")], "( "))
+			misestimates = append(misestimates, fmt.Sprintf("%s estimated %.0f rows, got %.0f",
+				strings.TrimSpace(strings.TrimPrefix(node, "->")), estimated, actual))
+		}
+	}
+	return misestimates
+}
+
+// strictViolations lists every analyzer warning for the plan, as checked by --strict:
+// a cost above the threshold, a full table scan, wide rows, a repeated subplan, a disk spill, a severe row misestimate,
+// a complex plan, a disk-bound execution, or a plan without cost estimates.
+// costInfo is nil when no threshold was set.
+func strictViolations(costInfo *CostInfo, analysisPlan string) []string {
+	if costInfo == nil {
+		costInfo = parseCost(analysisPlan, 0, 0)
+	}
+	if costInfo.Warning != "" {
+		return []string{"no cost estimates: " + costInfo.Warning}
+	}
+
+	var violations []string
+	if costInfo.ThresholdValue > 0 && costInfo.ExceedsLimit {
+		violations = append(violations, fmt.Sprintf("threshold: cost %.2f exceeds %.0f", costInfo.TotalCost, costInfo.ThresholdValue))
+	}
+	for _, scan := range costInfo.FullScans {
+		violations = append(violations, fmt.Sprintf("full table scan: Seq Scan on %s reads ~%s rows with no filter",
+			scan.Table, formatThousands(scan.Rows)))
+	}
+	for _, operation := range costInfo.WideRows {
+		violations = append(violations, fmt.Sprintf("wide rows: %s produces ~%s", operation.Operation, formatByteSize(operation.Bytes)))
+	}
+	for _, subplan := range costInfo.Subplans {
+		violations = append(violations, fmt.Sprintf("repeated subplan: %s (%s) runs %s times", subplan.Kind, subplan.Operation, formatThousands(subplan.Loops)))
+	}
+	for _, cache := range poorMemoizeCaches(costInfo) {
+		violations = append(violations, "memoize cache: "+memoizeCacheSummary(cache))
+	}
+	for _, spill := range detectDiskSpills(analysisPlan) {
+		violations = append(violations, "disk spill: "+spill)
+	}
+	for _, misestimate := range detectMisestimates(analysisPlan) {
+		violations = append(violations, "misestimate: "+misestimate)
+	}
+	if warning := planComplexityWarning(costInfo); warning != "" {
+		violations = append(violations, "complexity: "+warning)
+	}
+	if warning := ioBoundWarning(costInfo); warning != "" {
+		violations = append(violations, "I/O: "+warning)
+	}
+	if warning := jitOverheadWarning(costInfo); warning != "" {
+		violations = append(violations, "JIT: "+warning)
+	}
+	return violations
+}
+
+// exitOnStrictViolations prints the failed checks and exits with strictExitCode, if there are any
+func exitOnStrictViolations(violations []string) {
+	if len(violations) == 0 {
+		return
+	}
+
+	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	fmt.Printf("🚫 Strict mode: %d checks failed\n", len(violations))
+	for _, violation := range violations {
+		fmt.Printf("   • %s\n", violation)
+	}
+	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	os.Exit(strictExitCode)
+}
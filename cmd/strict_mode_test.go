@@ -0,0 +1,72 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+// spillingJSONPlan is a FORMAT JSON plan whose sort, hash and aggregate all wrote to disk
+const spillingJSONPlan = `[{"Plan": {
+  "Node Type": "Sort", "Startup Cost": 900, "Total Cost": 950, "Plan Rows": 1000, "Plan Width": 16,
+  "Actual Startup Time": 80, "Actual Total Time": 90, "Actual Rows": 1000, "Actual Loops": 1,
+  "Sort Key": ["c.customer_id"], "Sort Method": "external merge", "Sort Space Used": 4712, "Sort Space Type": "Disk",
+  "Plans": [{
+    "Node Type": "Aggregate", "Strategy": "Hashed", "Startup Cost": 700, "Total Cost": 800, "Plan Rows": 1000, "Plan Width": 16,
+    "Actual Startup Time": 60, "Actual Total Time": 70, "Actual Rows": 1000, "Actual Loops": 1,
+    "HashAgg Batches": 5, "Peak Memory Usage": 4145, "Disk Usage": 7040,
+    "Plans": [{
+      "Node Type": "Hash Join", "Startup Cost": 100, "Total Cost": 600, "Plan Rows": 1000, "Plan Width": 16,
+      "Actual Startup Time": 10, "Actual Total Time": 50, "Actual Rows": 1000, "Actual Loops": 1,
+      "Hash Cond": "(o.customer_id = c.id)",
+      "Plans": [{
+        "Node Type": "Hash", "Startup Cost": 50, "Total Cost": 50, "Plan Rows": 1000, "Plan Width": 4,
+        "Actual Startup Time": 5, "Actual Total Time": 5, "Actual Rows": 1000, "Actual Loops": 1,
+        "Hash Buckets": 1024, "Original Hash Buckets": 1024, "Hash Batches": 4, "Original Hash Batches": 1, "Peak Memory Usage": 64
+      }]
+    }]
+  }]
+}, "Execution Time": 95}]`
+
+func TestDetectDiskSpillsInStructuredPlans(t *testing.T) {
+	analysisPlan := planForAnalysis(spillingJSONPlan, "json")
+
+	spills := detectDiskSpills(analysisPlan)
+	want := []string{"Sort Method: external merge", "Disk Usage: 7040kB", "Batches: 4 (originally 1)"}
+	for _, fragment := range want {
+		found := false
+		for _, spill := range spills {
+			found = found || strings.Contains(spill, fragment)
+		}
+		if !found {
+			t.Errorf("disk spills %q lack %q", spills, fragment)
+		}
+	}
+
+	violations := strictViolations(nil, analysisPlan)
+	spillViolations := 0
+	for _, violation := range violations {
+		if strings.HasPrefix(violation, "disk spill: ") {
+			spillViolations++
+		}
+	}
+	if spillViolations != len(want) {
+		t.Errorf("strict violations %q, want %d disk spills", violations, len(want))
+	}
+}
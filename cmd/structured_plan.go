@@ -0,0 +1,418 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// explainFormats lists the values accepted by --explain-format
+var explainFormats = []string{"text", "json", "yaml", "xml"}
+
+// planDetailKeys are the node properties copied into the text layout, in EXPLAIN order
+var planDetailKeys = []string{
+	"Hash Cond", "Merge Cond", "Join Filter", "Index Cond", "Recheck Cond", "Filter",
+	"Rows Removed by Join Filter", "Rows Removed by Filter", "Rows Removed by Index Recheck",
+	"Cache Key", "Cache Mode",
+}
+
+// validateExplainFormat checks an --explain-format value
+func validateExplainFormat(format string) error {
+	if containsString(explainFormats, format) {
+		return nil
+	}
+	return fmt.Errorf("unsupported EXPLAIN format %q, supported formats: %s", format, strings.Join(explainFormats, ", "))
+}
+
+// detectPlanFormat guesses the EXPLAIN format of a saved plan from its first character
+func detectPlanFormat(plan string) string {
+	trimmed := strings.TrimSpace(plan)
+	switch {
+	case strings.HasPrefix(trimmed, "[") || strings.HasPrefix(trimmed, "{"):
+		return "json"
+	case strings.HasPrefix(trimmed, "<"):
+		return "xml"
+	case strings.HasPrefix(trimmed, "- Plan:"):
+		return "yaml"
+	}
+	return "text"
+}
+
+// isStructuredFormat reports whether the EXPLAIN format is one of the machine-readable ones
+func isStructuredFormat(format string) bool {
+	return format != "" && format != "text"
+}
+
+// planForAnalysis returns the plan in the text layout read by the cost analyzer and
+// index recommender. Structured plans are converted, plans that cannot be converted
+// yield an empty string and a warning.
+func planForAnalysis(plan, format string) string {
+	if !isStructuredFormat(format) {
+		return plan
+	}
+
+	text, err := renderStructuredPlan(plan, format)
+	if err != nil {
+		fmt.Printf("⚠️  Cost analysis and index recommendations are unavailable: %v\n", err)
+		return ""
+	}
+	return text
+}
+
+// structuredPlanJSON returns the plan as raw JSON for embedding in reports, or nil
+// when the plan was not produced with FORMAT JSON
+func structuredPlanJSON(plan, format string) json.RawMessage {
+	trimmed := strings.TrimSpace(plan)
+	if format != "json" || trimmed == "" || !json.Valid([]byte(trimmed)) {
+		return nil
+	}
+	return json.RawMessage(trimmed)
+}
+
+// renderStructuredPlan converts a FORMAT JSON or FORMAT YAML plan into the layout of
+// FORMAT TEXT, which is what the rest of the analysis understands
+func renderStructuredPlan(plan, format string) (string, error) {
+	var documents []map[string]interface{}
+
+	switch format {
+	case "json":
+		if err := json.Unmarshal([]byte(plan), &documents); err != nil {
+			return "", fmt.Errorf("unable to parse JSON plan: %w", err)
+		}
+	case "yaml":
+		if err := yaml.Unmarshal([]byte(plan), &documents); err != nil {
+			return "", fmt.Errorf("unable to parse YAML plan: %w", err)
+		}
+	default:
+		return "", fmt.Errorf("plans in %s format can only be stored, use text, json or yaml for analysis", format)
+	}
+
+	var sb strings.Builder
+	for _, document := range documents {
+		root, ok := document["Plan"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if err := writePlanNode(&sb, root, 0, 0); err != nil {
+			return "", err
+		}
+
+		if planningTime, ok := numberValue(document, "Planning Time"); ok {
+			sb.WriteString(fmt.Sprintf("Planning Time: %.3f ms\n", planningTime))
+		}
+		if jit, ok := document["JIT"].(map[string]interface{}); ok {
+			writeJITSection(&sb, jit)
+		}
+		if executionTime, ok := numberValue(document, "Execution Time"); ok {
+			sb.WriteString(fmt.Sprintf("Execution Time: %.3f ms\n", executionTime))
+		}
+	}
+
+	if sb.Len() == 0 {
+		return "", fmt.Errorf("no plan found in %s output", format)
+	}
+	return sb.String(), nil
+}
+
+// writeJITSection writes the JIT section of a structured plan the way FORMAT TEXT prints it.
+// PostgreSQL 17 reports the generation time as an object with its Deform and Total times.
+func writeJITSection(sb *strings.Builder, jit map[string]interface{}) {
+	sb.WriteString("JIT:\n")
+	if functions, ok := numberValue(jit, "Functions"); ok {
+		sb.WriteString(fmt.Sprintf("  Functions: %.0f\n", functions))
+	}
+	timing, ok := jit["Timing"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	var phases []string
+	for _, phase := range []string{"Generation", "Inlining", "Optimization", "Emission", "Total"} {
+		ms, ok := numberValue(timing, phase)
+		if nested, isObject := timing[phase].(map[string]interface{}); isObject {
+			ms, ok = numberValue(nested, "Total")
+		}
+		if ok {
+			phases = append(phases, fmt.Sprintf("%s %.3f ms", phase, ms))
+		}
+	}
+	sb.WriteString("  Timing: " + strings.Join(phases, ", ") + "\n")
+}
+
+// writePlanNode writes a node and its children with the indentation used by FORMAT TEXT, the
+// "->" arrow of a child node at column indent. Nodes nested deeper than maxPlanTreeDepth are an
+// error, as the indentation alone would grow quadratically with the depth.
+func writePlanNode(sb *strings.Builder, node map[string]interface{}, depth, indent int) error {
+	if depth >= maxPlanTreeDepth {
+		return errPlanTooDeep
+	}
+	detailIndent := "  "
+
+	if depth > 0 {
+		sb.WriteString(strings.Repeat(" ", indent) + "->  ")
+		detailIndent = strings.Repeat(" ", indent+6)
+	}
+	sb.WriteString(planNodeLabel(node))
+
+	startupCost, _ := numberValue(node, "Startup Cost")
+	totalCost, _ := numberValue(node, "Total Cost")
+	planRows, _ := numberValue(node, "Plan Rows")
+	planWidth, _ := numberValue(node, "Plan Width")
+	sb.WriteString(fmt.Sprintf("  (cost=%.2f..%.2f rows=%.0f width=%.0f)", startupCost, totalCost, planRows, planWidth))
+
+	if loops, ok := numberValue(node, "Actual Loops"); ok {
+		actualRows, _ := numberValue(node, "Actual Rows")
+		if loops == 0 {
+			sb.WriteString(" (never executed)")
+		} else if startupTime, ok := numberValue(node, "Actual Startup Time"); ok {
+			totalTime, _ := numberValue(node, "Actual Total Time")
+			sb.WriteString(fmt.Sprintf(" (actual time=%.3f..%.3f rows=%.0f loops=%.0f)", startupTime, totalTime, actualRows, loops))
+		} else {
+			sb.WriteString(fmt.Sprintf(" (actual rows=%.0f loops=%.0f)", actualRows, loops))
+		}
+	}
+	sb.WriteString("\n")
+
+	if output, ok := node["Output"].([]interface{}); ok && len(output) > 0 {
+		columns := make([]string, 0, len(output))
+		for _, column := range output {
+			columns = append(columns, fmt.Sprint(column))
+		}
+		sb.WriteString(fmt.Sprintf("%sOutput: %s\n", detailIndent, strings.Join(columns, ", ")))
+	}
+
+	if sortKeys, ok := node["Sort Key"].([]interface{}); ok && len(sortKeys) > 0 {
+		keys := make([]string, 0, len(sortKeys))
+		for _, key := range sortKeys {
+			keys = append(keys, fmt.Sprint(key))
+		}
+		sb.WriteString(fmt.Sprintf("%sSort Key: %s\n", detailIndent, strings.Join(keys, ", ")))
+	}
+
+	for _, key := range planDetailKeys {
+		if value, ok := node[key]; ok {
+			sb.WriteString(fmt.Sprintf("%s%s: %v\n", detailIndent, key, value))
+		}
+	}
+
+	for _, detail := range []string{sortMethodDetail(node), hashDetail(node), hashAggDetail(node), memoizeDetail(node), buffersDetail(node), ioTimingsDetail(node)} {
+		if detail != "" {
+			sb.WriteString(detailIndent + detail + "\n")
+		}
+	}
+
+	children, _ := node["Plans"].([]interface{})
+	for _, child := range children {
+		if childNode, ok := child.(map[string]interface{}); ok {
+			// A SubPlan or InitPlan is named on a line of its own, e.g. "SubPlan 1",
+			// with its root node indented below the name
+			childIndent := len(detailIndent)
+			if name := stringValue(childNode, "Subplan Name"); name != "" {
+				sb.WriteString(detailIndent + name + "\n")
+				childIndent += 2
+			}
+			if err := writePlanNode(sb, childNode, depth+1, childIndent); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// sortMethodDetail returns the "Sort Method:" line of an executed Sort node, e.g.
+// "Sort Method: external merge  Disk: 4712kB", or "" without one
+func sortMethodDetail(node map[string]interface{}) string {
+	method := stringValue(node, "Sort Method")
+	if method == "" {
+		return ""
+	}
+	detail := "Sort Method: " + method
+	if spaceUsed, ok := numberValue(node, "Sort Space Used"); ok {
+		detail += fmt.Sprintf("  %s: %.0fkB", stringValue(node, "Sort Space Type"), spaceUsed)
+	}
+	return detail
+}
+
+// hashDetail returns the "Buckets:" line of an executed Hash node, e.g.
+// "Buckets: 131072 (originally 65536)  Batches: 8 (originally 1)  Memory Usage: 3520kB", or "" without one
+func hashDetail(node map[string]interface{}) string {
+	buckets, ok := numberValue(node, "Hash Buckets")
+	if !ok {
+		return ""
+	}
+	batches, _ := numberValue(node, "Hash Batches")
+	detail := fmt.Sprintf("Buckets: %.0f", buckets)
+	if original, ok := numberValue(node, "Original Hash Buckets"); ok && original != buckets {
+		detail += fmt.Sprintf(" (originally %.0f)", original)
+	}
+	detail += fmt.Sprintf("  Batches: %.0f", batches)
+	if original, ok := numberValue(node, "Original Hash Batches"); ok && original != batches {
+		detail += fmt.Sprintf(" (originally %.0f)", original)
+	}
+	if memory, ok := numberValue(node, "Peak Memory Usage"); ok {
+		detail += fmt.Sprintf("  Memory Usage: %.0fkB", memory)
+	}
+	return detail
+}
+
+// hashAggDetail returns the "Batches:" line of an executed HashAggregate, e.g.
+// "Batches: 5  Memory Usage: 4145kB  Disk Usage: 7040kB", or "" without one
+func hashAggDetail(node map[string]interface{}) string {
+	batches, ok := numberValue(node, "HashAgg Batches")
+	if !ok {
+		return ""
+	}
+	detail := fmt.Sprintf("Batches: %.0f", batches)
+	if memory, ok := numberValue(node, "Peak Memory Usage"); ok {
+		detail += fmt.Sprintf("  Memory Usage: %.0fkB", memory)
+	}
+	if disk, ok := numberValue(node, "Disk Usage"); ok && disk > 0 {
+		detail += fmt.Sprintf("  Disk Usage: %.0fkB", disk)
+	}
+	return detail
+}
+
+// memoizeDetail returns the cache statistics line of an executed Memoize node, e.g.
+// "Hits: 295000  Misses: 5000  Evictions: 0  Overflows: 0  Memory Usage: 400kB", or "" without one
+func memoizeDetail(node map[string]interface{}) string {
+	hits, ok := numberValue(node, "Cache Hits")
+	if !ok {
+		return ""
+	}
+	misses, _ := numberValue(node, "Cache Misses")
+	evictions, _ := numberValue(node, "Cache Evictions")
+	overflows, _ := numberValue(node, "Cache Overflows")
+	detail := fmt.Sprintf("Hits: %.0f  Misses: %.0f  Evictions: %.0f  Overflows: %.0f", hits, misses, evictions, overflows)
+	if memory, ok := numberValue(node, "Peak Memory Usage"); ok {
+		detail += fmt.Sprintf("  Memory Usage: %.0fkB", memory)
+	}
+	return detail
+}
+
+// buffersDetail returns the "Buffers:" line of a node run with BUFFERS, e.g.
+// "Buffers: shared hit=12 read=3, temp written=40", or "" when no block was touched
+func buffersDetail(node map[string]interface{}) string {
+	var groups []string
+	for _, group := range []string{"Shared", "Local", "Temp"} {
+		var counts []string
+		for _, count := range []string{"Hit", "Read", "Dirtied", "Written"} {
+			if blocks, ok := numberValue(node, group+" "+count+" Blocks"); ok && blocks > 0 {
+				counts = append(counts, fmt.Sprintf("%s=%.0f", strings.ToLower(count), blocks))
+			}
+		}
+		if len(counts) > 0 {
+			groups = append(groups, strings.ToLower(group)+" "+strings.Join(counts, " "))
+		}
+	}
+	if len(groups) == 0 {
+		return ""
+	}
+	return "Buffers: " + strings.Join(groups, ", ")
+}
+
+// ioTimingsDetail returns the "I/O Timings:" line of a node run with BUFFERS and track_io_timing,
+// e.g. "I/O Timings: read=12.345" or, from PostgreSQL 16, "I/O Timings: shared read=12.345, temp write=1.000".
+// It returns "" when no time was spent on I/O.
+func ioTimingsDetail(node map[string]interface{}) string {
+	var groups []string
+	for _, group := range []string{"", "Shared", "Local", "Temp"} {
+		var times []string
+		for _, direction := range []string{"Read", "Write"} {
+			key := strings.TrimSpace(group + " I/O " + direction + " Time")
+			if ms, ok := numberValue(node, key); ok && ms > 0 {
+				times = append(times, fmt.Sprintf("%s=%.3f", strings.ToLower(direction), ms))
+			}
+		}
+		if len(times) > 0 {
+			groups = append(groups, strings.TrimSpace(strings.ToLower(group)+" "+strings.Join(times, " ")))
+		}
+	}
+	if len(groups) == 0 {
+		return ""
+	}
+	return "I/O Timings: " + strings.Join(groups, ", ")
+}
+
+// planNodeLabel builds the node description FORMAT TEXT prints, e.g. "Index Scan using users_pkey on users u"
+func planNodeLabel(node map[string]interface{}) string {
+	nodeType := stringValue(node, "Node Type")
+
+	switch nodeType {
+	case "Aggregate":
+		switch stringValue(node, "Strategy") {
+		case "Hashed":
+			nodeType = "HashAggregate"
+		case "Sorted":
+			nodeType = "GroupAggregate"
+		case "Mixed":
+			nodeType = "MixedAggregate"
+		}
+	case "ModifyTable":
+		nodeType = stringValue(node, "Operation")
+	case "Hash Join", "Merge Join", "Nested Loop":
+		if joinType := stringValue(node, "Join Type"); joinType != "" && joinType != "Inner" {
+			nodeType = strings.TrimSuffix(nodeType, " Join") + " " + joinType + " Join"
+		}
+	}
+
+	label := nodeType
+	if parallel, _ := node["Parallel Aware"].(bool); parallel {
+		label = "Parallel " + label
+	}
+	if stringValue(node, "Scan Direction") == "Backward" {
+		label += " Backward"
+	}
+	if indexName := stringValue(node, "Index Name"); indexName != "" {
+		label += " using " + indexName
+	}
+	if relation := stringValue(node, "Relation Name"); relation != "" {
+		// VERBOSE plans carry the schema, shown as schema.relation like FORMAT TEXT does
+		if schema := stringValue(node, "Schema"); schema != "" {
+			label += " on " + schema + "." + relation
+		} else {
+			label += " on " + relation
+		}
+		if alias := stringValue(node, "Alias"); alias != "" && alias != relation {
+			label += " " + alias
+		}
+	}
+	return label
+}
+
+// stringValue returns a string property of a plan node, or "" when missing
+func stringValue(node map[string]interface{}, key string) string {
+	value, _ := node[key].(string)
+	return value
+}
+
+// numberValue returns a numeric property of a plan node. JSON decodes numbers as
+// float64 while YAML yields int for whole numbers, so both are accepted.
+func numberValue(node map[string]interface{}, key string) (float64, bool) {
+	switch value := node[key].(type) {
+	case float64:
+		return value, true
+	case int:
+		return float64(value), true
+	case int64:
+		return float64(value), true
+	}
+	return 0, false
+}
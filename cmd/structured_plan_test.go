@@ -0,0 +1,170 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+// renderedLines renders a FORMAT JSON plan and returns its lines without their indentation
+func renderedLines(t *testing.T, plan string) []string {
+	t.Helper()
+	text, err := renderStructuredPlan(plan, "json")
+	if err != nil {
+		t.Fatalf("renderStructuredPlan: %v", err)
+	}
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		lines = append(lines, strings.TrimSpace(line))
+	}
+	return lines
+}
+
+func TestRenderStructuredPlanDetails(t *testing.T) {
+	tests := []struct {
+		name string
+		node string
+		want []string
+	}{
+		{
+			name: "scan with filter",
+			node: `{"Node Type": "Seq Scan", "Relation Name": "orders", "Alias": "o", "Startup Cost": 0, "Total Cost": 1100,
+				"Plan Rows": 500, "Plan Width": 32, "Filter": "(status = 'pending'::text)", "Rows Removed by Filter": 45200}`,
+			want: []string{
+				"Seq Scan on orders o  (cost=0.00..1100.00 rows=500 width=32)",
+				"Filter: (status = 'pending'::text)",
+				"Rows Removed by Filter: 45200",
+			},
+		},
+		{
+			name: "sort spilled to disk",
+			node: `{"Node Type": "Sort", "Startup Cost": 10, "Total Cost": 20, "Plan Rows": 100, "Plan Width": 8,
+				"Actual Startup Time": 1, "Actual Total Time": 2, "Actual Rows": 100, "Actual Loops": 1,
+				"Sort Key": ["p.name"], "Sort Method": "external merge", "Sort Space Used": 4712, "Sort Space Type": "Disk"}`,
+			want: []string{
+				"Sort  (cost=10.00..20.00 rows=100 width=8) (actual time=1.000..2.000 rows=100 loops=1)",
+				"Sort Key: p.name",
+				"Sort Method: external merge  Disk: 4712kB",
+			},
+		},
+		{
+			name: "sort in memory",
+			node: `{"Node Type": "Sort", "Startup Cost": 10, "Total Cost": 20, "Plan Rows": 100, "Plan Width": 8,
+				"Sort Key": ["id"], "Sort Method": "quicksort", "Sort Space Used": 25, "Sort Space Type": "Memory"}`,
+			want: []string{"Sort Method: quicksort  Memory: 25kB"},
+		},
+		{
+			name: "hash in batches",
+			node: `{"Node Type": "Hash", "Startup Cost": 1, "Total Cost": 2, "Plan Rows": 10, "Plan Width": 4,
+				"Hash Buckets": 131072, "Original Hash Buckets": 65536, "Hash Batches": 8, "Original Hash Batches": 1, "Peak Memory Usage": 3520}`,
+			want: []string{"Buckets: 131072 (originally 65536)  Batches: 8 (originally 1)  Memory Usage: 3520kB"},
+		},
+		{
+			name: "hash aggregate spilled to disk",
+			node: `{"Node Type": "Aggregate", "Strategy": "Hashed", "Startup Cost": 1, "Total Cost": 2, "Plan Rows": 10, "Plan Width": 4,
+				"HashAgg Batches": 5, "Peak Memory Usage": 4145, "Disk Usage": 7040}`,
+			want: []string{
+				"HashAggregate  (cost=1.00..2.00 rows=10 width=4)",
+				"Batches: 5  Memory Usage: 4145kB  Disk Usage: 7040kB",
+			},
+		},
+		{
+			name: "buffers",
+			node: `{"Node Type": "Seq Scan", "Relation Name": "orders", "Startup Cost": 0, "Total Cost": 1, "Plan Rows": 1, "Plan Width": 4,
+				"Shared Hit Blocks": 12, "Shared Read Blocks": 3, "Shared Dirtied Blocks": 0, "Shared Written Blocks": 0,
+				"Local Hit Blocks": 0, "Local Read Blocks": 0, "Temp Read Blocks": 0, "Temp Written Blocks": 40}`,
+			want: []string{"Buffers: shared hit=12 read=3, temp written=40"},
+		},
+		{
+			name: "memoize",
+			node: `{"Node Type": "Memoize", "Startup Cost": 0.3, "Total Cost": 8.31, "Plan Rows": 1, "Plan Width": 8,
+				"Cache Key": "o.customer_id", "Cache Mode": "logical",
+				"Cache Hits": 295000, "Cache Misses": 5000, "Cache Evictions": 0, "Cache Overflows": 0, "Peak Memory Usage": 400}`,
+			want: []string{
+				"Memoize  (cost=0.30..8.31 rows=1 width=8)",
+				"Cache Key: o.customer_id",
+				"Cache Mode: logical",
+				"Hits: 295000  Misses: 5000  Evictions: 0  Overflows: 0  Memory Usage: 400kB",
+			},
+		},
+		{
+			name: "I/O timings before PostgreSQL 16",
+			node: `{"Node Type": "Seq Scan", "Relation Name": "orders", "Startup Cost": 0, "Total Cost": 1, "Plan Rows": 1, "Plan Width": 4,
+				"Shared Read Blocks": 30166, "I/O Read Time": 98.411, "I/O Write Time": 0}`,
+			want: []string{"I/O Timings: read=98.411"},
+		},
+		{
+			name: "I/O timings from PostgreSQL 16",
+			node: `{"Node Type": "Seq Scan", "Relation Name": "orders", "Startup Cost": 0, "Total Cost": 1, "Plan Rows": 1, "Plan Width": 4,
+				"Shared I/O Read Time": 98.411, "Shared I/O Write Time": 0, "Local I/O Read Time": 0, "Local I/O Write Time": 0,
+				"Temp I/O Read Time": 1.5, "Temp I/O Write Time": 2.25}`,
+			want: []string{"I/O Timings: shared read=98.411, temp read=1.500 write=2.250"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := renderedLines(t, `[{"Plan": `+tt.node+`}]`)
+			for _, want := range tt.want {
+				if !containsString(lines, want) {
+					t.Errorf("rendered plan lacks %q, got:\n%s", want, strings.Join(lines, "\n"))
+				}
+			}
+		})
+	}
+}
+
+func TestRenderStructuredPlanOmitsUnusedBuffers(t *testing.T) {
+	lines := renderedLines(t, `[{"Plan": {"Node Type": "Result", "Startup Cost": 0, "Total Cost": 0.01, "Plan Rows": 1, "Plan Width": 4,
+		"Shared Hit Blocks": 0, "Shared Read Blocks": 0}}]`)
+	for _, line := range lines {
+		if strings.HasPrefix(line, "Buffers:") {
+			t.Errorf("rendered %q for a node that touched no block", line)
+		}
+	}
+}
+
+func TestRenderStructuredPlanSubplans(t *testing.T) {
+	text, err := renderStructuredPlan(`[{"Plan": {
+	  "Node Type": "Seq Scan", "Relation Name": "customers", "Alias": "c", "Startup Cost": 0, "Total Cost": 9000, "Plan Rows": 1000, "Plan Width": 12,
+	  "Filter": "(SubPlan 1)",
+	  "Plans": [
+	    {"Node Type": "Result", "Parent Relationship": "InitPlan", "Subplan Name": "InitPlan 1 (returns $0)",
+	     "Startup Cost": 0, "Total Cost": 0.01, "Plan Rows": 1, "Plan Width": 4},
+	    {"Node Type": "Index Scan", "Parent Relationship": "SubPlan", "Subplan Name": "SubPlan 1", "Index Name": "orders_customer_id_idx",
+	     "Relation Name": "orders", "Alias": "o", "Startup Cost": 0.29, "Total Cost": 8.3, "Plan Rows": 1, "Plan Width": 4,
+	     "Index Cond": "(customer_id = c.id)"}
+	  ]
+	}}]`, "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `Seq Scan on customers c  (cost=0.00..9000.00 rows=1000 width=12)
+  Filter: (SubPlan 1)
+  InitPlan 1 (returns $0)
+    ->  Result  (cost=0.00..0.01 rows=1 width=4)
+  SubPlan 1
+    ->  Index Scan using orders_customer_id_idx on orders o  (cost=0.29..8.30 rows=1 width=4)
+          Index Cond: (customer_id = c.id)
+`
+	if text != want {
+		t.Errorf("rendered plan:\n%s\nwant:\n%s", text, want)
+	}
+}
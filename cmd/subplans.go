@@ -0,0 +1,142 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// repeatedSubplanLoops is the number of executions from which a subplan, or the inner side
+// of a LATERAL join, is reported
+const repeatedSubplanLoops = 1000
+
+// lateralInnerOperations are the nodes that, on the inner side of a Nested Loop, indicate a
+// LATERAL subquery or a correlated subquery the planner pulled up. A plain scan there is an
+// ordinary index nested loop and is not reported.
+var lateralInnerOperations = []string{"Limit", "Aggregate", "HashAggregate", "GroupAggregate",
+	"Subquery Scan", "Function Scan", "Result", "Sort", "Incremental Sort", "Unique", "WindowAgg"}
+
+// RepeatedSubplan is a subplan or LATERAL subquery that runs once per outer row, the
+// "executed 50,000 times" pattern of many slow queries
+type RepeatedSubplan struct {
+	// Kind is the subplan name such as "SubPlan 1", or "LATERAL" for the inner side of a Nested Loop
+	Kind string
+	// Operation is the node without its estimates, e.g. "Index Scan using orders_pkey on orders"
+	Operation string
+	Loops     int64
+	// TotalMs is the time of one execution times the loops, 0 without EXPLAIN ANALYZE
+	TotalMs float64
+	Line    string
+}
+
+// subplanRegex matches the line that introduces a SubPlan or InitPlan in text plans
+var subplanRegex = regexp.MustCompile(`^\s*((?:SubPlan|InitPlan) \d+)`)
+
+// detectRepeatedSubplans finds the SubPlans and LATERAL subqueries executed at least
+// repeatedSubplanLoops times. InitPlans run once and are never reported.
+func detectRepeatedSubplans(plan string) []RepeatedSubplan {
+	var subplans []RepeatedSubplan
+
+	// Open ancestor nodes, by the indentation of their "->" arrow
+	type planNode struct {
+		indent     int
+		nestedLoop bool
+		children   int
+	}
+	var ancestors []planNode
+	subplanName := ""
+
+	for line := range planLines(plan) {
+		if matches := subplanRegex.FindStringSubmatch(line); matches != nil {
+			subplanName = matches[1]
+			continue
+		}
+		if !costRegex.MatchString(line) {
+			continue
+		}
+
+		indent := strings.Index(line, "->")
+		for len(ancestors) > 0 && ancestors[len(ancestors)-1].indent >= indent {
+			ancestors = ancestors[:len(ancestors)-1]
+		}
+		operation := planNodeName(line)
+
+		kind := ""
+		if subplanName != "" {
+			// The root of a subplan is not a child of the node it is attached to
+			kind = subplanName
+			subplanName = ""
+		} else if len(ancestors) > 0 {
+			parent := &ancestors[len(ancestors)-1]
+			parent.children++
+			if parent.nestedLoop && parent.children == 2 && isLateralInnerOperation(operation) {
+				kind = "LATERAL"
+			}
+		}
+		ancestors = append(ancestors, planNode{indent: indent, nestedLoop: strings.HasPrefix(operation, "Nested Loop")})
+
+		timeMs, _, loops, _ := nodeActualTotals(line)
+		if kind == "" || strings.HasPrefix(kind, "InitPlan") || loops < repeatedSubplanLoops {
+			continue
+		}
+		subplans = append(subplans, RepeatedSubplan{
+			Kind:      kind,
+			Operation: operation,
+			Loops:     loops,
+			TotalMs:   timeMs,
+			Line:      strings.TrimSpace(line),
+		})
+	}
+	return subplans
+}
+
+// isLateralInnerOperation reports whether a node, e.g. "Subquery Scan on recent", is one of lateralInnerOperations
+func isLateralInnerOperation(operation string) bool {
+	for _, lateral := range lateralInnerOperations {
+		if operation == lateral || strings.HasPrefix(operation, lateral+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+// repeatedSubplanWarning explains a repeated subplan, e.g.
+// "SubPlan 1 (Index Scan) is executed 50,000 times, ~1250.00 ms in total. ..."
+func repeatedSubplanWarning(subplan RepeatedSubplan) string {
+	what := fmt.Sprintf("%s (%s)", subplan.Kind, subplan.Operation)
+	advice := "Rewrite the correlated subquery as a join or aggregate it once"
+	if subplan.Kind == "LATERAL" {
+		what = fmt.Sprintf("%s on the inner side of a Nested Loop", subplan.Operation)
+		advice = "A LATERAL or correlated subquery runs once per outer row, consider a join with a grouped subquery"
+	}
+	total := ""
+	if subplan.TotalMs > 0 {
+		total = fmt.Sprintf(", ~%.2f ms in total", subplan.TotalMs)
+	}
+	return fmt.Sprintf("%s is executed %s times%s. %s", what, formatThousands(subplan.Loops), total, advice)
+}
+
+// displayRepeatedSubplans prints a warning for every subplan executed once per outer row
+func displayRepeatedSubplans(costInfo *CostInfo) {
+	for _, subplan := range costInfo.Subplans {
+		fmt.Printf("🔁 %s\n", repeatedSubplanWarning(subplan))
+		fmt.Printf("   %s\n", subplan.Line)
+	}
+}
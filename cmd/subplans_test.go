@@ -0,0 +1,52 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import "testing"
+
+const repeatedSubplanJSONPlan = `[{"Plan": {
+  "Node Type": "Seq Scan", "Relation Name": "customers", "Alias": "c", "Startup Cost": 0, "Total Cost": 42000, "Plan Rows": 5000, "Plan Width": 12,
+  "Actual Startup Time": 0.1, "Actual Total Time": 400, "Actual Rows": 5000, "Actual Loops": 1,
+  "Filter": "((SubPlan 1) > 10)",
+  "Plans": [
+    {"Node Type": "Result", "Parent Relationship": "InitPlan", "Subplan Name": "InitPlan 1",
+     "Startup Cost": 0, "Total Cost": 0.01, "Plan Rows": 1, "Plan Width": 4,
+     "Actual Startup Time": 0.001, "Actual Total Time": 0.001, "Actual Rows": 1, "Actual Loops": 1},
+    {"Node Type": "Aggregate", "Strategy": "Plain", "Parent Relationship": "SubPlan", "Subplan Name": "SubPlan 1",
+     "Startup Cost": 8.3, "Total Cost": 8.31, "Plan Rows": 1, "Plan Width": 8,
+     "Actual Startup Time": 0.07, "Actual Total Time": 0.07, "Actual Rows": 1, "Actual Loops": 5000,
+     "Plans": [
+       {"Node Type": "Index Scan", "Parent Relationship": "Outer", "Index Name": "orders_customer_id_idx", "Relation Name": "orders", "Alias": "o",
+        "Startup Cost": 0.29, "Total Cost": 8.3, "Plan Rows": 3, "Plan Width": 0,
+        "Actual Startup Time": 0.01, "Actual Total Time": 0.06, "Actual Rows": 3, "Actual Loops": 5000,
+        "Index Cond": "(customer_id = c.id)"}
+     ]}
+  ]
+}, "Execution Time": 410}]`
+
+func TestDetectRepeatedSubplansInStructuredPlans(t *testing.T) {
+	costInfo := parseCost(planForAnalysis(repeatedSubplanJSONPlan, "json"), 0, 0)
+
+	if len(costInfo.Subplans) != 1 {
+		t.Fatalf("repeated subplans = %+v, want SubPlan 1 only", costInfo.Subplans)
+	}
+	subplan := costInfo.Subplans[0]
+	if subplan.Kind != "SubPlan 1" || subplan.Operation != "Aggregate" || subplan.Loops != 5000 {
+		t.Errorf("repeated subplan = %+v, want the Aggregate of SubPlan 1 run 5000 times", subplan)
+	}
+}
@@ -0,0 +1,84 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// humanTimestampLayout is the layout of timestamps shown in Markdown and HTML reports
+const humanTimestampLayout = "January 2, 2006 15:04:05"
+
+// Timestamp settings, set once per run from --utc / --rfc3339 and the timestamps config section
+var (
+	timestampsUTC     bool
+	timestampsRFC3339 bool
+)
+
+// configureTimestamps applies the timestamp flags, falling back to the config file
+func configureTimestamps(cmd *cobra.Command, config *Config) {
+	timestampsUTC, _ = cmd.Flags().GetBool("utc")
+	if !cmd.Flags().Changed("utc") {
+		timestampsUTC = config.Timestamps.UTC
+	}
+
+	timestampsRFC3339, _ = cmd.Flags().GetBool("rfc3339")
+	if !cmd.Flags().Changed("rfc3339") {
+		timestampsRFC3339 = config.Timestamps.RFC3339
+	}
+}
+
+// currentTime returns the current time, in UTC when --utc is set.
+// SOURCE_DATE_EPOCH (seconds since 1970) pins it, so identical input yields byte-identical reports.
+func currentTime() time.Time {
+	now := time.Now()
+	if epoch, err := strconv.ParseInt(os.Getenv("SOURCE_DATE_EPOCH"), 10, 64); err == nil {
+		now = time.Unix(epoch, 0)
+	}
+	if timestampsUTC {
+		return now.UTC()
+	}
+	return now
+}
+
+// formatTimestamp renders a timestamp for reports, e.g. "January 2, 2006 15:04:05",
+// "January 2, 2006 15:04:05 UTC" or "2006-01-02T15:04:05Z" with --rfc3339
+func formatTimestamp(t time.Time) string {
+	if timestampsUTC {
+		t = t.UTC()
+	}
+	if timestampsRFC3339 {
+		return t.Format(time.RFC3339)
+	}
+	if timestampsUTC {
+		return t.Format(humanTimestampLayout) + " UTC"
+	}
+	return t.Format(humanTimestampLayout)
+}
+
+// fileNameTimeSuffix marks file names whose timestamp is in UTC
+func fileNameTimeSuffix() string {
+	if timestampsUTC {
+		return "_UTC"
+	}
+	return ""
+}
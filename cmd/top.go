@@ -0,0 +1,145 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var topCmd = &cobra.Command{
+	Use:   "top",
+	Short: "Analyze the most expensive queries recorded by pg_stat_statements",
+	Long: `Read the top queries from pg_stat_statements and analyze them like the batch command,
+ranked from most to least expensive.
+
+pg_stat_statements stores normalized queries with $N placeholders instead of literals.
+The placeholders are replaced with NULL so the queries can be explained, which means the
+plans can differ from the ones the application gets with real values.
+
+Example:
+  pg_explain top
+  pg_explain top --limit 20 --by mean_time --combined -t 1000`,
+	Args: cobra.NoArgs,
+	Run:  runTop,
+}
+
+// topOrderValues lists the values accepted by --by
+var topOrderValues = []string{"total_time", "mean_time", "calls"}
+
+// recordSeparator separates the rows of the pg_stat_statements lookup, since query text
+// can span several lines
+const recordSeparator = "\x1e"
+
+// topStatementsSQL reads the top statements of the current database. Utility statements
+// such as VACUUM or SET cannot be explained and are left out.
+const topStatementsSQL = `SELECT calls, round(%[1]s::numeric, 2), round(%[2]s::numeric, 2), query
+FROM pg_stat_statements
+WHERE dbid = (SELECT oid FROM pg_database WHERE datname = current_database())
+  AND query ~* '^\s*(select|with|insert|update|delete|merge|values|table)\y'
+ORDER BY %[3]s DESC
+LIMIT %[4]d`
+
+func runTop(cmd *cobra.Command, args []string) {
+	requirePsql()
+	limit, _ := cmd.Flags().GetInt("limit")
+	if limit < 1 {
+		logErrorAndExit("Invalid --limit value", fmt.Errorf("expected a positive number, got %d", limit))
+	}
+	orderBy, _ := cmd.Flags().GetString("by")
+	if !containsString(topOrderValues, orderBy) {
+		logErrorAndExit("Invalid --by value", fmt.Errorf("supported values: %s", strings.Join(topOrderValues, ", ")))
+	}
+	transaction, _ := cmd.Flags().GetBool("transaction")
+	rollback, _ := cmd.Flags().GetBool("rollback")
+	// Auto mode rolls back data-modifying statements on its own
+	explainMode, _ := cmd.Flags().GetString("explain-mode")
+
+	config, _ := loadConfig()
+	applyServiceFlag(cmd, config)
+	promptPasswordIfRequested(cmd)
+
+	runBatchAnalysis(cmd, batchSource{
+		Label: "Source",
+		Name:  "pg_stat_statements",
+		Load: func() ([]string, []QueryDirectives, error) {
+			queries, err := fetchTopStatements(config, limit, orderBy, transaction || rollback || explainMode == explainModeAuto)
+			return queries, nil, err
+		},
+	})
+}
+
+// fetchTopStatements returns the top statements ranked by orderBy, each prefixed with a comment
+// holding its pg_stat_statements figures. Data-modifying statements are skipped unless
+// they run in a rolled back transaction, because NULL parameters would really be written.
+func fetchTopStatements(config *Config, limit int, orderBy string, rollback bool) ([]string, error) {
+	version, err := serverVersionNum(config)
+	if err != nil {
+		return nil, err
+	}
+
+	// pg_stat_statements renamed its timing columns in PostgreSQL 13
+	totalColumn, meanColumn := "total_exec_time", "mean_exec_time"
+	if version < 130000 {
+		totalColumn, meanColumn = "total_time", "mean_time"
+	}
+	orderColumn := map[string]string{"total_time": totalColumn, "mean_time": meanColumn, "calls": "calls"}[orderBy]
+
+	lookupSQL := fmt.Sprintf(topStatementsSQL, totalColumn, meanColumn, orderColumn, limit)
+	output, err := runPsql(config, []string{"-q", "-A", "-t", "-F", unitSeparator, "-R", recordSeparator, "-c", lookupSQL})
+	if err != nil {
+		return nil, fmt.Errorf("unable to read pg_stat_statements, is the extension installed in this database? %w", err)
+	}
+
+	var queries []string
+	skipped := 0
+	for _, record := range strings.Split(output, recordSeparator) {
+		fields := strings.SplitN(strings.TrimSpace(record), unitSeparator, 4)
+		if len(fields) != 4 {
+			continue
+		}
+
+		query := strings.TrimSpace(fields[3])
+		if !rollback && isDataModifyingQuery(query) {
+			skipped++
+			continue
+		}
+
+		queries = append(queries, fmt.Sprintf("-- pg_stat_statements: calls=%s total_time=%s ms mean_time=%s ms\n%s",
+			fields[0], fields[1], fields[2], replacePlaceholders(query)))
+	}
+
+	if skipped > 0 {
+		fmt.Printf("⏭️  Skipped %d data-modifying statement(s), use --transaction or --explain-mode auto to analyze them safely\n", skipped)
+	}
+	return queries, nil
+}
+
+// replacePlaceholders substitutes NULL for the $N placeholders of a normalized query
+func replacePlaceholders(query string) string {
+	return placeholderRegex.ReplaceAllString(query, "NULL")
+}
+
+func init() {
+	topCmd.Flags().Int("limit", 10, "Number of statements to analyze")
+	topCmd.Flags().String("by", "total_time", "Rank statements by total_time, mean_time, or calls")
+	addBatchFlags(topCmd)
+	rootCmd.AddCommand(topCmd)
+}
@@ -0,0 +1,106 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// TypeMismatch is a filter that casts a column to another type, e.g. ((user_id)::text = '5'::text).
+// An index on the column cannot serve the cast expression, so no plain index is recommended for it.
+type TypeMismatch struct {
+	TableName  string `json:"table_name"`
+	Column     string `json:"column"`
+	CastType   string `json:"cast_type"`
+	Expression string `json:"expression"`
+}
+
+// castTypePattern matches a PostgreSQL type name as printed in plans, including the multi-word
+// names, a type modifier such as (10) and array brackets
+const castTypePattern = `(?:character varying|double precision|bit varying|time(?:stamp)?(?:\(\d\))? with(?:out)? time zone|\w+)(?:\(\d+(?:,\d+)?\))?(?:\[\])*`
+
+var (
+	// castSuffixRegex matches the ::type of any cast, on a column or on a constant
+	castSuffixRegex = regexp.MustCompile(`::"?` + castTypePattern + `"?`)
+	// columnCastRegex matches a cast column, which the planner prints as (column)::type
+	columnCastRegex = regexp.MustCompile(`\((?:\w+\.)?(\w+)\)::(` + castTypePattern + `)`)
+	// textCastComparisonRegex matches a column cast to text and compared with a number,
+	// e.g. ((user_id)::text = '5'::text)
+	textCastComparisonRegex = regexp.MustCompile(`\((?:\w+\.)?(\w+)\)::(text|character varying|varchar|bpchar|character|name)(?:\(\d+\))?\s*(?:=|<>|!=|<=|>=|<|>)\s*'-?\d+(?:\.\d+)?'`)
+)
+
+// textCastTypes are casts the planner adds to varchar and char columns for text operators.
+// They do not prevent index use, so they are only reported when the compared value is a number.
+var textCastTypes = []string{"text", "character varying", "varchar", "bpchar", "character", "name"}
+
+// stripCasts removes the ::type suffixes of an expression, so type names are not mistaken for columns
+func stripCasts(expr string) string {
+	return castSuffixRegex.ReplaceAllString(expr, "")
+}
+
+// detectTypeMismatches finds the columns a filter casts to another type. A cast on the
+// constant side, such as (created_at > '2024-01-01'::date), keeps the column indexable and is not reported.
+func detectTypeMismatches(filter string) []TypeMismatch {
+	var mismatches []TypeMismatch
+	add := func(column, castType, expression string) {
+		for _, existing := range mismatches {
+			if existing.Column == column {
+				return
+			}
+		}
+		mismatches = append(mismatches, TypeMismatch{Column: column, CastType: castType, Expression: expression})
+	}
+
+	for _, match := range textCastComparisonRegex.FindAllStringSubmatch(filter, -1) {
+		add(match[1], match[2], match[0])
+	}
+	for _, match := range columnCastRegex.FindAllStringSubmatch(filter, -1) {
+		baseType := strings.TrimSuffix(strings.SplitN(match[2], "(", 2)[0], "[]")
+		if containsString(textCastTypes, baseType) {
+			continue
+		}
+		add(match[1], match[2], match[0])
+	}
+	return mismatches
+}
+
+// castColumns returns the columns of the mismatches
+func castColumns(mismatches []TypeMismatch) []string {
+	columns := make([]string, 0, len(mismatches))
+	for _, mismatch := range mismatches {
+		columns = append(columns, mismatch.Column)
+	}
+	return columns
+}
+
+// typeMismatchWarning explains why the cast defeats an index and how to avoid it
+func typeMismatchWarning(mismatch TypeMismatch) string {
+	column := mismatch.Column
+	if mismatch.TableName != "" {
+		column = mismatch.TableName + "." + mismatch.Column
+	}
+	fix := fmt.Sprintf("compare %s with a value of its own type so the cast applies to the value", mismatch.Column)
+	if mismatch.CastType == "date" {
+		fix = fmt.Sprintf("filter on a range instead (%s >= <day> AND %s < <next day>)", mismatch.Column, mismatch.Column)
+	}
+	return fmt.Sprintf("%s is cast to %s in %s, so an index on %s cannot be used: %s, or index the expression with CREATE INDEX ON %s ((%s::%s))",
+		column, mismatch.CastType, mismatch.Expression, mismatch.Column, fix,
+		mismatch.TableName, mismatch.Column, mismatch.CastType)
+}
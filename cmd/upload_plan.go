@@ -37,6 +37,28 @@ type UploadResponse struct {
 	DeleteKey string `json:"deleteKey"`
 }
 
+// planForUpload returns the plan to send to the remote service. pev2 shows timing, buffers and
+// per-node detail only for FORMAT JSON plans, so a plan in another format is explained again as
+// JSON when explainJSON is not nil (--upload-json). Without it, or when it fails, textPlan is
+// uploaded instead.
+func planForUpload(plan, textPlan, format string, explainJSON func() (string, error)) string {
+	if format == "json" {
+		return plan
+	}
+	if explainJSON != nil {
+		fmt.Println("🔁 Running EXPLAIN (FORMAT JSON) for the upload...")
+		jsonPlan, err := explainJSON()
+		if err == nil {
+			return jsonPlan
+		}
+		fmt.Printf("⚠️  JSON plan unavailable, uploading the text plan instead: %v\n", err)
+	}
+	if textPlan == "" {
+		return plan
+	}
+	return textPlan
+}
+
 // uploadPlan uploads a query execution plan and returns the access URL.
 func uploadPlan(plan, query, title string) string {
 	formData := url.Values{
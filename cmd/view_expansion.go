@@ -0,0 +1,124 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// viewDefinitionSQL returns the name and definition of the views and materialized views among
+// the given relation names. The %s placeholder receives a list of quoted names.
+const viewDefinitionSQL = `SELECT c.oid::regclass, pg_get_viewdef(c.oid, true)
+FROM pg_class c
+WHERE c.relkind IN ('v', 'm') AND c.oid IN (SELECT to_regclass(name) FROM unnest(ARRAY[%s]::text[]) AS name)`
+
+// relationNameRegex matches the relation after FROM or JOIN, optionally schema-qualified and quoted.
+// Function calls and unknown names match too, they are dropped by the catalog lookup.
+var relationNameRegex = regexp.MustCompile(`(?i)\b(?:FROM|JOIN)\s+((?:"[^"]+"|\w+)(?:\.(?:"[^"]+"|\w+))?)`)
+
+// ViewDefinition is the SQL behind a view referenced by the analyzed query
+type ViewDefinition struct {
+	Name       string `json:"name"`
+	Definition string `json:"definition"`
+}
+
+// reportViews holds the definitions collected with --expand-views for the current run
+var reportViews []ViewDefinition
+
+// queryRelationNames returns the distinct relation names the query reads FROM or JOINs
+func queryRelationNames(query string) []string {
+	var names []string
+	for _, match := range relationNameRegex.FindAllStringSubmatch(query, -1) {
+		if !containsString(names, match[1]) {
+			names = append(names, match[1])
+		}
+	}
+	return names
+}
+
+// loadViewDefinitions looks up which relations of the query are views and returns their definitions.
+// Failures only print a warning, the plan is then reported without them.
+func loadViewDefinitions(config *Config, query string) []ViewDefinition {
+	names := queryRelationNames(query)
+	if len(names) == 0 {
+		return nil
+	}
+
+	views, err := fetchViewDefinitions(config, names)
+	if err != nil {
+		fmt.Printf("⚠️  Skipping view expansion: %v\n\n", err)
+		return nil
+	}
+	return views
+}
+
+// fetchViewDefinitions runs viewDefinitionSQL and parses its unaligned output
+func fetchViewDefinitions(config *Config, names []string) ([]ViewDefinition, error) {
+	quoted := make([]string, 0, len(names))
+	for _, name := range names {
+		quoted = append(quoted, quoteLiteral(name))
+	}
+
+	// View definitions span several lines, so rows and columns use separators that SQL text does not contain
+	output, err := runPsql(config, []string{"-q", "-A", "-t", "-F", unitSeparator, "-R", recordSeparator,
+		"-c", fmt.Sprintf(viewDefinitionSQL, strings.Join(quoted, ", "))})
+	if err != nil {
+		return nil, fmt.Errorf("unable to read view definitions: %w", err)
+	}
+
+	var views []ViewDefinition
+	for _, record := range strings.Split(output, recordSeparator) {
+		name, definition, found := strings.Cut(strings.Trim(record, "\n"), unitSeparator)
+		if !found {
+			continue
+		}
+		// The first line keeps its leading space, so it stays aligned with the indented lines below
+		views = append(views, ViewDefinition{Name: name, Definition: strings.TrimRight(definition, " \n")})
+	}
+	return views, nil
+}
+
+// displayViewDefinitions prints the definition of every view the query reads
+func displayViewDefinitions(views []ViewDefinition) {
+	for _, view := range views {
+		fmt.Printf("🔎 %s is a view, the plan scans the tables of its definition:\n", view.Name)
+		for _, line := range strings.Split(view.Definition, "\n") {
+			fmt.Printf("   %s\n", line)
+		}
+		fmt.Println()
+	}
+}
+
+// formatViewDefinitionsMarkdown renders the view definitions as a Markdown section
+func formatViewDefinitionsMarkdown(views []ViewDefinition) string {
+	if len(views) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("## View Definitions\n\n")
+	for _, view := range views {
+		sb.WriteString(fmt.Sprintf("**%s**\n\n", escapeMarkdownSpecialChars(view.Name)))
+		sb.WriteString("```sql\n")
+		sb.WriteString(view.Definition)
+		sb.WriteString("\n```\n\n")
+	}
+	return sb.String()
+}
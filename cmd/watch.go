@@ -0,0 +1,202 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var watchCmd = &cobra.Command{
+	Use:   "watch [SQL_QUERY]",
+	Short: "Re-run EXPLAIN for a query at an interval and alert when its cost crosses a threshold",
+	Long: `Run EXPLAIN for the same query again and again and print how its cost and execution
+time change, e.g. while a migration runs or a table grows.
+
+With --watch-threshold, a prominent alert is printed the moment a run's cost crosses the
+threshold, and again when it drops back under it. Add --exit-on-alert to stop watching and
+exit with status 3 instead, so the command can be left running as a lightweight monitor.
+
+Example:
+  pg_explain watch -F query.sql --interval 30s
+  pg_explain watch "SELECT * FROM orders WHERE status = 'new'" --watch-threshold 5000 --exit-on-alert`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  runWatch,
+}
+
+func runWatch(cmd *cobra.Command, args []string) {
+	requirePsql()
+
+	config, _ := loadConfig()
+	applyServiceFlag(cmd, config)
+	promptPasswordIfRequested(cmd)
+
+	interval, _ := cmd.Flags().GetDuration("interval")
+	if interval <= 0 {
+		logErrorAndExit("Invalid --interval value", fmt.Errorf("the interval must be positive, got %s", interval))
+	}
+	count, _ := cmd.Flags().GetInt("count")
+	if count < 0 {
+		logErrorAndExit("Invalid --count value", fmt.Errorf("the count must not be negative, got %d", count))
+	}
+	watchThreshold, _ := cmd.Flags().GetFloat64("watch-threshold")
+	if watchThreshold < 0 {
+		logErrorAndExit("Invalid --watch-threshold value", fmt.Errorf("the threshold must not be negative, got %.2f", watchThreshold))
+	}
+	exitOnAlert, _ := cmd.Flags().GetBool("exit-on-alert")
+	if exitOnAlert && watchThreshold == 0 {
+		logErrorAndExit("Invalid --exit-on-alert value", fmt.Errorf("--exit-on-alert needs --watch-threshold"))
+	}
+
+	query, err := getQueryInput(cmd, args)
+	if err != nil {
+		logErrorAndExit("Failed to get query input: ", err)
+	}
+
+	transaction, _ := cmd.Flags().GetBool("transaction")
+	rollback, _ := cmd.Flags().GetBool("rollback")
+	explainOptions := ExplainOptions{Rollback: transaction || rollback}
+	if err := sessionOptionsFromFlags(cmd, &explainOptions); err != nil {
+		logErrorAndExit("Invalid --set, --guc or --role value", err)
+	}
+	if err := explainModeFromFlags(cmd, &explainOptions); err != nil {
+		logErrorAndExit("Invalid --explain-mode value", err)
+	}
+	if err := allowedStatementsFromFlags(cmd, config, &explainOptions); err != nil {
+		logErrorAndExit("Invalid --allowed-statements value", err)
+	}
+	explainOptions, err = explainOptionsForStatement(query, explainOptions)
+	if err != nil {
+		logErrorAndExit("Statement not allowed", err)
+	}
+
+	fmt.Println("\n👀 Watching query cost")
+	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	fmt.Printf("📝 %s\n", queryLabel(query, 80))
+	fmt.Printf("🔁 Every %s", interval)
+	if count > 0 {
+		fmt.Printf(", %d runs", count)
+	}
+	fmt.Println(" (Ctrl+C to stop)")
+	if watchThreshold > 0 {
+		fmt.Printf("🚨 Alert when the cost crosses %.0f\n", watchThreshold)
+	}
+	fmt.Println()
+	warnDataModifyingQuery(query, explainOptions)
+
+	watcher := &queryWatcher{threshold: watchThreshold, exitOnAlert: exitOnAlert}
+	for run := 1; count == 0 || run <= count; run++ {
+		if run > 1 {
+			time.Sleep(interval)
+		}
+		plan, err := generateExecutionPlan(query, config, explainOptions)
+		if err != nil {
+			// A failed run is reported and the next one is tried, the database may be restarting
+			fmt.Printf("[%s] ❌ %v\n", currentTime().Format("15:04:05"), err)
+			continue
+		}
+		watcher.record(parseCost(plan, watchThreshold, 0))
+	}
+}
+
+// queryWatcher tracks the cost of the watched query from run to run
+type queryWatcher struct {
+	threshold   float64
+	exitOnAlert bool
+	runs        int
+	firstCost   float64
+	lastCost    float64
+	exceeding   bool
+}
+
+// record prints one run and alerts when the cost crosses the threshold in either direction
+func (w *queryWatcher) record(costInfo *CostInfo) {
+	stamp := currentTime().Format("15:04:05")
+	if costInfo.Warning != "" {
+		fmt.Printf("[%s] ⚠️  %s\n", stamp, costInfo.Warning)
+		return
+	}
+
+	w.runs++
+	line := fmt.Sprintf("[%s] #%d 💰 cost %.2f", stamp, w.runs, costInfo.TotalCost)
+	if w.runs == 1 {
+		w.firstCost = costInfo.TotalCost
+	} else if w.firstCost != 0 {
+		line += fmt.Sprintf(" (%+.1f%% since start", (costInfo.TotalCost-w.firstCost)/w.firstCost*100)
+		if costInfo.TotalCost != w.lastCost {
+			line += fmt.Sprintf(", %+.2f since last run", costInfo.TotalCost-w.lastCost)
+		}
+		line += ")"
+	}
+	if costInfo.ExecutionTimeMs > 0 {
+		line += fmt.Sprintf(" · ⏱️  %.2f ms", costInfo.ExecutionTimeMs)
+	}
+	fmt.Println(line)
+	w.lastCost = costInfo.TotalCost
+
+	if w.threshold == 0 {
+		return
+	}
+	switch {
+	case costInfo.ExceedsLimit && !w.exceeding:
+		w.exceeding = true
+		displayWatchAlert(costInfo)
+		if w.exitOnAlert {
+			os.Exit(strictExitCode)
+		}
+	case !costInfo.ExceedsLimit && w.exceeding:
+		w.exceeding = false
+		fmt.Printf("✅ Cost %.2f is back within the threshold of %.0f\n", costInfo.TotalCost, costInfo.ThresholdValue)
+	}
+}
+
+// displayWatchAlert prints the alert of a run whose cost crossed the threshold
+func displayWatchAlert(costInfo *CostInfo) {
+	fmt.Println()
+	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	fmt.Printf("🚨 COST THRESHOLD CROSSED: %.2f exceeds %.0f by %.2f\n",
+		costInfo.TotalCost, costInfo.ThresholdValue, costInfo.TotalCost-costInfo.ThresholdValue)
+	if len(costInfo.ExpensiveOps) > 0 {
+		op := costInfo.ExpensiveOps[0]
+		fmt.Printf("   Top operation: %s (%.2f)\n", op.Operation, op.Cost)
+	}
+	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	fmt.Println()
+}
+
+func init() {
+	watchCmd.Flags().StringP("file", "F", "", "Read SQL query from file")
+	watchCmd.Flags().BoolP("editor", "e", false, "Open $EDITOR to write/paste query")
+	watchCmd.Flags().Duration("interval", 10*time.Second, "Time between two runs of the query")
+	watchCmd.Flags().Int("count", 0, "Stop after N runs (0 = run until interrupted)")
+	watchCmd.Flags().Float64("watch-threshold", 0, "Print an alert when a run's cost crosses this threshold (0 = disabled)")
+	watchCmd.Flags().Bool("exit-on-alert", false, "Exit with status 3 when the cost crosses --watch-threshold instead of watching on")
+	watchCmd.Flags().Bool("transaction", false, "Run each EXPLAIN ANALYZE inside a transaction that is rolled back, discarding data changes")
+	watchCmd.Flags().Bool("rollback", false, "Alias for --transaction")
+	watchCmd.Flags().StringSlice("allowed-statements", nil, "Statement types that may be analyzed, e.g. select,execute; others are rejected before running (select, insert, update, delete, merge, create, execute, or declare)")
+	watchCmd.Flags().String("explain-mode", "auto", "How statements are explained: auto (EXPLAIN ANALYZE for queries, rolled back for data changes, plain EXPLAIN for CREATE TABLE AS) or analyze (EXPLAIN ANALYZE as given)")
+	watchCmd.Flags().StringArray("set", nil, "Run SET NAME=VALUE before each EXPLAIN, e.g. --set work_mem=256MB (repeatable)")
+	watchCmd.Flags().StringArray("guc", nil, "Alias for --set, e.g. --guc work_mem=256MB (repeatable)")
+	watchCmd.Flags().String("role", "", "Run SET ROLE before each EXPLAIN so queries are planned with that role's privileges")
+	watchCmd.Flags().String("service", "", "Connect with a service from the libpq connection service file (~/.pg_service.conf), overriding the database settings")
+	watchCmd.Flags().Bool("prompt-password", false, "Prompt for the database password without echoing it (instead of .pgpass or PGPASSWORD)")
+	rootCmd.AddCommand(watchCmd)
+}
@@ -19,6 +19,7 @@ package cmd
 
 import (
 	"html/template"
+	"io"
 	"os"
 	"path/filepath"
 )
@@ -38,14 +39,38 @@ const planTemplate = `
     <link rel="stylesheet" href="https://unpkg.com/pev2/dist/style.css" />
 </head>
 <body>
+    {{ with .Metadata }}
+    <details class="container my-2">
+        <summary>Generated with</summary>
+        {{ with .ServerVersion }}<div><strong>Server:</strong> {{ . }}</div>{{ end }}
+        {{ with .PsqlVersion }}<div><strong>Client:</strong> {{ . }}</div>{{ end }}
+        {{ with .Connection }}<div><strong>Connection:</strong> {{ . }}</div>{{ end }}
+        <pre>{{ .ExplainSQL }}</pre>
+    </details>
+    {{ end }}
+    {{ range .Views }}
+    <details class="container my-2">
+        <summary>View {{ .Name }}</summary>
+        <pre>{{ .Definition }}</pre>
+    </details>
+    {{ end }}
+    {{ with .Calibration }}
+    <div class="container my-2 text-muted small">⏱️ {{ . }}</div>
+    {{ end }}
+    {{ with .PlanNotice }}
+    <div class="container my-2 alert alert-warning">🐘 {{ . }}</div>
+    {{ end }}
+    {{ if .Plan }}
     <div id="app">
         <pev2 :plan-source="plan" :plan-query="query" />
     </div>
     <script>
         const { createApp } = Vue;
 
-        const plan = "{{ .Plan }}"
-        const query = "{{ .Query }}"
+        // html/template encodes the plan and query as JavaScript string literals, so
+        // quotes, backslashes, newlines and </script> in either are embedded safely
+        const plan = {{ .Plan }}
+        const query = {{ .Query }}
 
         const app = createApp({
             data() {
@@ -58,30 +83,35 @@ const planTemplate = `
         app.component("pev2", pev2.Plan);
         app.mount("#app");
     </script>
+    {{ end }}
 </body>
 </html>
 `
 
 type TemplateData struct {
-	Title string
-	Plan  string
-	Query string
+	Title       string
+	Plan        string
+	Query       string
+	Metadata    *ReportMetadata
+	Views       []ViewDefinition
+	Calibration string
+	PlanNotice  string
 }
 
-// writePlan generates an HTML file with the execution plan and query.
+// writePlan generates an HTML file with the execution plan and query. costInfo may be nil;
+// for plans run with ANALYZE it adds the ms-per-cost-unit calibration note above the plan.
 // It returns the file absolute path of the generated file.
-func writePlan(plan, query, title string) string {
+func writePlan(plan, query, title string, costInfo *CostInfo) string {
 	name := title + ".html"
+	shownPlan, planNotice := planForPev2(plan)
 	data := TemplateData{
-		Title: title,
-		Plan:  plan,
-		Query: query,
-	}
-
-	// Parse and execute the template
-	tmpl, err := template.New("plan").Parse(planTemplate)
-	if err != nil {
-		logErrorAndExit("unable to parse plan template: ", err)
+		Title:       title,
+		Plan:        shownPlan,
+		Query:       query,
+		Metadata:    reportMetadata,
+		Views:       reportViews,
+		Calibration: costCalibrationNote(costInfo),
+		PlanNotice:  planNotice,
 	}
 
 	// Output to a file
@@ -92,8 +122,7 @@ func writePlan(plan, query, title string) string {
 	defer file.Close()
 
 	// Execute the template with data
-	err = tmpl.Execute(file, data)
-	if err != nil {
+	if err := renderPlanTemplate(file, data); err != nil {
 		logErrorAndExit("unable to render plan template: ", err)
 	}
 
@@ -105,3 +134,12 @@ func writePlan(plan, query, title string) string {
 
 	return abs
 }
+
+// renderPlanTemplate executes the pev2 plan template with data into w
+func renderPlanTemplate(w io.Writer, data TemplateData) error {
+	tmpl, err := template.New("plan").Parse(planTemplate)
+	if err != nil {
+		return err
+	}
+	return tmpl.Execute(w, data)
+}
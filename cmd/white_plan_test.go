@@ -0,0 +1,65 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestRenderPlanTemplateEscapesScriptValues(t *testing.T) {
+	query := `SELECT "name", 'a\b' FROM users WHERE note = '</script><script>alert(1)</script>'`
+	plan := `Seq Scan on users  (cost=0.00..1.01 rows=1 width=32)
+  Filter: (note = '</script>'::text)`
+
+	var buf bytes.Buffer
+	if err := renderPlanTemplate(&buf, TemplateData{Title: "escaping", Plan: plan, Query: query}); err != nil {
+		t.Fatalf("renderPlanTemplate: %v", err)
+	}
+	html := buf.String()
+
+	start := strings.Index(html, "const { createApp } = Vue;")
+	if start < 0 {
+		t.Fatal("inline script not found in rendered template")
+	}
+	end := strings.Index(html[start:], "</script>")
+	if end < 0 {
+		t.Fatal("inline script is not closed")
+	}
+	script := html[start : start+end]
+	if !strings.Contains(script, `app.mount("#app");`) {
+		t.Fatalf("inline script ends early, a raw </script> was emitted:\n%s", script)
+	}
+
+	for name, want := range map[string]string{"plan": plan, "query": query} {
+		match := regexp.MustCompile(`const ` + name + ` = (.+)\n`).FindStringSubmatch(script)
+		if match == nil {
+			t.Fatalf("%s literal not found in script:\n%s", name, script)
+		}
+		var got string
+		if err := json.Unmarshal([]byte(strings.TrimSpace(match[1])), &got); err != nil {
+			t.Fatalf("%s literal %s is not a JavaScript string: %v", name, match[1], err)
+		}
+		if got != want {
+			t.Errorf("%s does not round-trip:\ngot  %q\nwant %q", name, got, want)
+		}
+	}
+}
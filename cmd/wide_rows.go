@@ -0,0 +1,130 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// wideRowBytes is the estimated output of a node, rows times row width, from which it is
+// reported as projecting too much data
+const wideRowBytes = 100 * 1024 * 1024
+
+// WideRowOperation is a plan node estimated to produce a large volume of data, usually because
+// it carries every column of a table (SELECT *) when only a few are used
+type WideRowOperation struct {
+	Operation string
+	Rows      int64
+	Width     int64
+	Bytes     int64
+	Line      string
+}
+
+// estimatedSizeRegex captures the estimated rows and average row width in bytes of a node
+var estimatedSizeRegex = regexp.MustCompile(`cost=\d+\.?\d*\.\.\d+\.?\d* rows=(\d+) width=(\d+)`)
+
+// estimatedBytes returns the estimated rows and width of a plan node line and their product,
+// the bytes the node produces, or zeros for a line without estimates
+func estimatedBytes(line string) (rows, width, bytes int64) {
+	matches := estimatedSizeRegex.FindStringSubmatch(line)
+	if matches == nil {
+		return 0, 0, 0
+	}
+	rows, err1 := strconv.ParseInt(matches[1], 10, 64)
+	width, err2 := strconv.ParseInt(matches[2], 10, 64)
+	if err1 != nil || err2 != nil {
+		return 0, 0, 0
+	}
+	return rows, width, rows * width
+}
+
+// detectWideRowOperations finds nodes estimated to produce at least wideRowBytes. Only the
+// topmost such node of a branch is reported, as the nodes below it usually carry the same
+// columns and would repeat the warning.
+func detectWideRowOperations(plan string) []WideRowOperation {
+	var operations []WideRowOperation
+
+	// Open ancestor nodes, by the indentation of their "->" arrow
+	type planNode struct {
+		indent int
+		wide   bool
+	}
+	var ancestors []planNode
+
+	for line := range planLines(plan) {
+		rows, width, bytes := estimatedBytes(line)
+		if rows == 0 && width == 0 {
+			continue
+		}
+
+		indent := strings.Index(line, "->")
+		for len(ancestors) > 0 && ancestors[len(ancestors)-1].indent >= indent {
+			ancestors = ancestors[:len(ancestors)-1]
+		}
+		underWide := false
+		for _, ancestor := range ancestors {
+			underWide = underWide || ancestor.wide
+		}
+		wide := bytes >= wideRowBytes
+		ancestors = append(ancestors, planNode{indent: indent, wide: wide})
+
+		if wide && !underWide {
+			operations = append(operations, WideRowOperation{
+				Operation: extractOperationType(line),
+				Rows:      rows,
+				Width:     width,
+				Bytes:     bytes,
+				Line:      strings.TrimSpace(line),
+			})
+		}
+	}
+	return operations
+}
+
+// wideRowWarning explains a wide row operation, e.g.
+// "Seq Scan produces ~12,000,000 rows of ~850 bytes (~9.5 GB). Select only the columns you need"
+func wideRowWarning(operation WideRowOperation) string {
+	return fmt.Sprintf("%s produces ~%s rows of ~%s bytes (~%s). Select only the columns you need instead of SELECT *, a plan's cost does not show the time spent moving this data",
+		operation.Operation, formatThousands(operation.Rows), formatThousands(operation.Width), formatByteSize(operation.Bytes))
+}
+
+// formatByteSize formats a byte count with a binary unit, e.g. "9.5 GB"
+func formatByteSize(bytes int64) string {
+	units := []string{"bytes", "kB", "MB", "GB", "TB"}
+	size := float64(bytes)
+	unit := 0
+	for size >= 1024 && unit < len(units)-1 {
+		size /= 1024
+		unit++
+	}
+	if unit == 0 {
+		return fmt.Sprintf("%d %s", bytes, units[0])
+	}
+	return fmt.Sprintf("%.1f %s", size, units[unit])
+}
+
+// displayWideRowOperations prints a warning for every operation that produces a large volume of data
+func displayWideRowOperations(costInfo *CostInfo) {
+	for _, operation := range costInfo.WideRows {
+		fmt.Printf("📦 %s\n", wideRowWarning(operation))
+		fmt.Printf("   %s\n", operation.Line)
+	}
+}
@@ -0,0 +1,171 @@
+/*
+Package cmd
+
+# Copyright © 2024 Rohat Sahin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"html"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// WorkloadRecommendation is an index recommended for several queries of a batch,
+// so creating it once helps all of them
+type WorkloadRecommendation struct {
+	TableName       string   `json:"table_name"`
+	Columns         []string `json:"columns"`
+	OperationTypes  []string `json:"operation_types"`
+	CreateStatement string   `json:"create_statement"`
+	QueryNumbers    []int    `json:"query_numbers"`
+	TotalBenefit    float64  `json:"total_benefit"`
+}
+
+// computeWorkloadRecommendations groups the index recommendations of all queries by table and
+// columns, and returns those shared by at least two queries, the most widely useful first
+func computeWorkloadRecommendations(results []BatchResult) []WorkloadRecommendation {
+	var order []string
+	bySignature := make(map[string]*WorkloadRecommendation)
+	for _, result := range results {
+		if result.IndexRecommendations == nil {
+			continue
+		}
+		for _, rec := range result.IndexRecommendations.Recommendations {
+			signature := indexSignature(rec.TableName, rec.Columns)
+			shared, ok := bySignature[signature]
+			if !ok {
+				shared = &WorkloadRecommendation{
+					TableName:       rec.TableName,
+					Columns:         rec.Columns,
+					CreateStatement: rec.CreateStatement,
+				}
+				bySignature[signature] = shared
+				order = append(order, signature)
+			}
+			if !containsString(shared.OperationTypes, rec.OperationType) {
+				shared.OperationTypes = append(shared.OperationTypes, rec.OperationType)
+			}
+			// A query can recommend the same index for several of its operations
+			if n := len(shared.QueryNumbers); n == 0 || shared.QueryNumbers[n-1] != result.QueryNumber {
+				shared.QueryNumbers = append(shared.QueryNumbers, result.QueryNumber)
+			}
+			shared.TotalBenefit += rec.EstimatedBenefit
+		}
+	}
+
+	var recommendations []WorkloadRecommendation
+	for _, signature := range order {
+		if shared := bySignature[signature]; len(shared.QueryNumbers) >= 2 {
+			recommendations = append(recommendations, *shared)
+		}
+	}
+	sort.SliceStable(recommendations, func(i, j int) bool {
+		if len(recommendations[i].QueryNumbers) != len(recommendations[j].QueryNumbers) {
+			return len(recommendations[i].QueryNumbers) > len(recommendations[j].QueryNumbers)
+		}
+		return recommendations[i].TotalBenefit > recommendations[j].TotalBenefit
+	})
+	return recommendations
+}
+
+// formatQueryNumbers lists query numbers, e.g. "1, 4, 7"
+func formatQueryNumbers(numbers []int) string {
+	parts := make([]string, len(numbers))
+	for i, number := range numbers {
+		parts[i] = strconv.Itoa(number)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// displayWorkloadRecommendations prints the indexes that help several queries of the batch
+func displayWorkloadRecommendations(recommendations []WorkloadRecommendation) {
+	if len(recommendations) == 0 {
+		return
+	}
+
+	fmt.Println("🔁 Fix once, help many:")
+	for _, rec := range recommendations {
+		fmt.Printf("   %s(%s) helps %d queries (%s), saving up to ~%.0f cost units\n", rec.TableName,
+			strings.Join(rec.Columns, ", "), len(rec.QueryNumbers), formatQueryNumbers(rec.QueryNumbers), rec.TotalBenefit)
+		fmt.Printf("      %s\n", rec.CreateStatement)
+	}
+	fmt.Println()
+}
+
+// formatWorkloadRecommendationsMarkdown renders the shared index recommendations as a Markdown section
+func formatWorkloadRecommendationsMarkdown(recommendations []WorkloadRecommendation) string {
+	if len(recommendations) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("## Fix Once, Help Many\n\n")
+	sb.WriteString("These indexes are recommended for several queries, so each one helps the whole workload.\n\n")
+	sb.WriteString("| Queries | Table | Columns | Operations | Est. Benefit | Statement |\n")
+	sb.WriteString("|---------|-------|---------|------------|--------------|-----------|\n")
+	for _, rec := range recommendations {
+		sb.WriteString(fmt.Sprintf("| %d (%s) | %s | %s | %s | %.2f | `%s` |\n",
+			len(rec.QueryNumbers),
+			formatQueryNumbers(rec.QueryNumbers),
+			escapeMarkdownSpecialChars(rec.TableName),
+			escapeMarkdownSpecialChars(strings.Join(rec.Columns, ", ")),
+			escapeMarkdownSpecialChars(strings.Join(rec.OperationTypes, ", ")),
+			rec.TotalBenefit,
+			rec.CreateStatement))
+	}
+	sb.WriteString("\n---\n\n")
+	return sb.String()
+}
+
+// formatWorkloadRecommendationsHTML renders the shared index recommendations for the batch HTML report
+func formatWorkloadRecommendationsHTML(recommendations []WorkloadRecommendation) string {
+	if len(recommendations) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString(`
+        <div class="workload-recommendations">
+            <h4>🔁 Fix Once, Help Many</h4>
+            <table class="table table-sm">
+                <thead>
+                    <tr><th>Queries</th><th>Index</th><th>Est. Benefit</th><th>Statement</th></tr>
+                </thead>
+                <tbody>`)
+	for _, rec := range recommendations {
+		sb.WriteString(fmt.Sprintf(`
+                    <tr>
+                        <td>%d (%s)</td>
+                        <td>%s(%s)</td>
+                        <td>%.2f</td>
+                        <td class="query-label">%s</td>
+                    </tr>`,
+			len(rec.QueryNumbers),
+			formatQueryNumbers(rec.QueryNumbers),
+			html.EscapeString(rec.TableName),
+			html.EscapeString(strings.Join(rec.Columns, ", ")),
+			rec.TotalBenefit,
+			html.EscapeString(rec.CreateStatement)))
+	}
+	sb.WriteString(`
+                </tbody>
+            </table>
+        </div>
+`)
+	return sb.String()
+}
@@ -0,0 +1,23 @@
+-- Sample psql session export with meta-commands, for testing batch analysis
+-- Meta-commands are skipped; \set variables are substituted into the queries
+
+\timing on
+\connect shop
+\set min_age 25
+\set status 'pending'
+\set tbl orders
+
+-- Query 1: uses :min_age
+SELECT * FROM users WHERE age > :min_age;
+
+-- Query 2: uses :'status' as a string literal and :"tbl" as an identifier
+SELECT o.id, o.created_at::date
+FROM :"tbl" o
+WHERE o.status = :'status';
+
+-- Query 3: sent with \g instead of a semicolon
+SELECT COUNT(*) FROM orders
+\gx
+
+\echo done
+\i other_queries.sql